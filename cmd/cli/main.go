@@ -2,14 +2,20 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"dispatch-mcp-server/internal/batch"
 	"dispatch-mcp-server/internal/config"
 	"dispatch-mcp-server/internal/conversation"
 	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
 	"dispatch-mcp-server/internal/pricing"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -27,6 +33,8 @@ func main() {
 		handleEstimate()
 	case "order":
 		handleOrder()
+	case "batch":
+		handleBatch(os.Args[2:])
 	case "pricing":
 		handlePricingComparison()
 	case "chat":
@@ -56,6 +64,7 @@ func showHelp() {
 	fmt.Println("Usage:")
 	fmt.Println("  ./dispatch-cli estimate     - Create a cost estimate")
 	fmt.Println("  ./dispatch-cli order        - Create a delivery order")
+	fmt.Println("  ./dispatch-cli batch FILE   - Estimate every shipment in a CSV or JSON file")
 	fmt.Println("  ./dispatch-cli pricing      - Compare different pricing models")
 	fmt.Println("  ./dispatch-cli chat         - Conversational pricing advisor")
 	fmt.Println("  ./dispatch-cli interactive  - Interactive mode")
@@ -113,13 +122,15 @@ func handleEstimate() {
 	}
 
 	// Create client and make API call
-	client, err := dispatch.NewClient()
+	client, err := dispatch.NewAPI()
 	if err != nil {
 		log.Fatalf("Failed to create client: %v", err)
 	}
 
 	fmt.Println("🔄 Calling Dispatch API...")
-	response, err := client.CreateEstimate(input)
+	ctx, stop := interruptContext()
+	defer stop()
+	response, err := client.CreateEstimate(ctx, input)
 	if err != nil {
 		log.Fatalf("Failed to create estimate: %v", err)
 	}
@@ -130,7 +141,7 @@ func handleEstimate() {
 
 	if len(response.Data.CreateEstimate.Estimate.AvailableOrderOptions) > 0 {
 		option := response.Data.CreateEstimate.Estimate.AvailableOrderOptions[0]
-		fmt.Printf("💰 Estimated Cost: $%.2f\n", option.EstimatedOrderCost)
+		fmt.Printf("💰 Estimated Cost: $%.2f\n", option.EstimatedOrderCost.Float64())
 		fmt.Printf("🚚 Vehicle Type: %s\n", option.VehicleType)
 		fmt.Printf("⏰ Estimated Delivery: %s\n", option.EstimatedDeliveryTimeUTC)
 		fmt.Printf("🏢 Service Type: %s\n", option.ServiceType)
@@ -195,13 +206,15 @@ func handleOrder() {
 	}
 
 	// Create client and make API call
-	client, err := dispatch.NewClient()
+	client, err := dispatch.NewAPI()
 	if err != nil {
 		log.Fatalf("Failed to create client: %v", err)
 	}
 
 	fmt.Println("🔄 Calling Dispatch API...")
-	response, err := client.CreateOrder(input)
+	ctx, stop := interruptContext()
+	defer stop()
+	response, err := client.CreateOrder(ctx, input)
 	if err != nil {
 		log.Fatalf("Failed to create order: %v", err)
 	}
@@ -213,7 +226,7 @@ func handleOrder() {
 	order := response.Data.CreateOrder.Order
 	fmt.Printf("🆔 Order ID: %s\n", order.ID)
 	fmt.Printf("📊 Status: %s\n", order.Status)
-	fmt.Printf("💰 Total Cost: $%.2f\n", order.TotalCost)
+	fmt.Printf("💰 Total Cost: $%.2f\n", order.TotalCost.Float64())
 	fmt.Printf("📦 Tracking Number: %s\n", order.TrackingNumber)
 	fmt.Printf("⏰ Scheduled At: %s\n", order.ScheduledAt)
 
@@ -223,6 +236,86 @@ func handleOrder() {
 	fmt.Println(string(jsonData))
 }
 
+func handleBatch(args []string) {
+	flags := flag.NewFlagSet("batch", flag.ExitOnError)
+	concurrency := flags.Int("concurrency", batch.DefaultConcurrency, fmt.Sprintf("estimates to request at once, at most %d", batch.MaxConcurrency))
+	flags.Usage = func() {
+		fmt.Println("Usage: ./dispatch-cli batch [-concurrency N] FILE")
+		fmt.Println("")
+		fmt.Println("FILE is a .json array of shipments, as taken by the batch_create_estimates tool,")
+		fmt.Println("or a .csv file with a header row using these columns:")
+		fmt.Printf("  %s\n", strings.Join(batch.CSVColumns, ", "))
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	fmt.Println("📦 Batch Cost Estimates...")
+	fmt.Println("==========================")
+
+	// Read shipments from the file
+	path := flags.Arg(0)
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var rows []batch.Row
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.NewDecoder(file).Decode(&rows)
+	} else {
+		rows, err = batch.ParseCSV(file)
+	}
+	if err != nil {
+		log.Fatalf("Failed to read shipments: %v", err)
+	}
+	if len(rows) == 0 || len(rows) > batch.MaxRows {
+		log.Fatalf("Expected between 1 and %d shipments, got %d", batch.MaxRows, len(rows))
+	}
+
+	// Create client and make API calls
+	client, err := dispatch.NewAPI()
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+
+	fmt.Printf("🔄 Estimating %d shipments, %d at a time...\n", len(rows), *concurrency)
+	ctx, stop := interruptContext()
+	defer stop()
+	report := batch.Run(ctx, client, rows, *concurrency)
+
+	// Display results
+	fmt.Println("")
+	for _, result := range report.Results {
+		label := fmt.Sprintf("#%d", result.Row)
+		if result.Reference != "" {
+			label += " " + result.Reference
+		}
+		if result.Status == batch.StatusOK {
+			fmt.Printf("✅ %s: cheapest $%.2f (%s), fastest $%.2f (%s)\n", label,
+				result.Cheapest.EstimatedOrderCost.Float64(), result.Cheapest.ServiceType,
+				result.Fastest.EstimatedOrderCost.Float64(), result.Fastest.ServiceType)
+		} else {
+			fmt.Printf("❌ %s: %s\n", label, strings.Join(result.Errors, "; "))
+		}
+	}
+
+	totals := report.Totals
+	fmt.Println("")
+	fmt.Printf("📊 %d succeeded, %d invalid, %d failed\n", totals.Succeeded, totals.Invalid, totals.Failed)
+	fmt.Printf("💰 Cheapest Total: $%.2f\n", totals.CheapestTotal.Float64())
+	fmt.Printf("⚡ Fastest Total: $%.2f\n", totals.FastestTotal.Float64())
+
+	// Show full report in JSON
+	fmt.Println("\n📋 Full Report:")
+	jsonData, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(jsonData))
+}
+
 func handleInteractive() {
 	fmt.Println("🎮 Interactive Dispatch CLI")
 	fmt.Println("============================")
@@ -303,9 +396,11 @@ func showStatus() {
 	fmt.Printf("📡 GraphQL Endpoint: %s\n", cfg.GraphQLEndpoint)
 
 	// Test client creation
-	_, err = dispatch.NewClient()
+	_, err = dispatch.NewAPI()
 	if err != nil {
 		fmt.Printf("❌ Client Error: %v\n", err)
+	} else if dispatch.UseMockMode(cfg) {
+		fmt.Println("✅ Client created successfully (mock mode)")
 	} else {
 		fmt.Println("✅ Client created successfully")
 	}
@@ -608,12 +703,14 @@ func handlePricingComparison() {
 	}
 
 	// Create client and make API call
-	client, err := dispatch.NewClient()
+	client, err := dispatch.NewAPI()
 	if err != nil {
 		log.Fatalf("Failed to create client: %v", err)
 	}
 
-	response, err := client.CreateEstimate(input)
+	ctx, stop := interruptContext()
+	defer stop()
+	response, err := client.CreateEstimate(ctx, input)
 	if err != nil {
 		log.Fatalf("Failed to create estimate: %v", err)
 	}
@@ -625,15 +722,23 @@ func handlePricingComparison() {
 
 	originalEstimate := response.Data.CreateEstimate.Estimate.AvailableOrderOptions[0]
 
-	fmt.Printf("✅ Base estimate created: $%.2f\n", originalEstimate.EstimatedOrderCost)
+	fmt.Printf("✅ Base estimate created: $%.2f\n", originalEstimate.EstimatedOrderCost.Float64())
 	fmt.Println("")
 
 	// Now compare different pricing models
 	fmt.Println("🔍 Comparing Pricing Models...")
 	fmt.Println("===============================")
 
-	// Create pricing engine
-	engine := pricing.NewPricingEngine()
+	// Create pricing engine from the configured rules
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	engine, err := pricing.NewPricingEngineWithConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load pricing rules: %v", err)
+	}
+	fmt.Printf("📋 Pricing rules version %d\n\n", engine.Rules().Version)
 
 	// Test different scenarios
 	scenarios := []struct {
@@ -656,7 +761,7 @@ func handlePricingComparison() {
 				DeliveryCount:   2,
 				CustomerTier:    "silver",
 				OrderFrequency:  3,
-				TotalOrderValue: originalEstimate.EstimatedOrderCost * 2,
+				TotalOrderValue: originalEstimate.EstimatedOrderCost.Mul(2, money.HalfUp),
 				IsBulkOrder:     false,
 			},
 		},
@@ -666,7 +771,7 @@ func handlePricingComparison() {
 				DeliveryCount:   5,
 				CustomerTier:    "gold",
 				OrderFrequency:  8,
-				TotalOrderValue: originalEstimate.EstimatedOrderCost * 5,
+				TotalOrderValue: originalEstimate.EstimatedOrderCost.Mul(5, money.HalfUp),
 				IsBulkOrder:     false,
 			},
 		},
@@ -676,7 +781,7 @@ func handlePricingComparison() {
 				DeliveryCount:   10,
 				CustomerTier:    "gold",
 				OrderFrequency:  15,
-				TotalOrderValue: originalEstimate.EstimatedOrderCost * 10,
+				TotalOrderValue: originalEstimate.EstimatedOrderCost.Mul(10, money.HalfUp),
 				IsBulkOrder:     true,
 			},
 		},
@@ -689,7 +794,7 @@ func handlePricingComparison() {
 		comparison := engine.ComparePricingModels(&originalEstimate, scenario.context)
 
 		// Display results
-		fmt.Printf("Original Cost: $%.2f\n", originalEstimate.EstimatedOrderCost)
+		fmt.Printf("Original Cost: $%.2f\n", originalEstimate.EstimatedOrderCost.Float64())
 		fmt.Println("")
 
 		for _, result := range comparison.PricingModels {
@@ -700,8 +805,8 @@ func handlePricingComparison() {
 
 			fmt.Printf("🏷️  %s: %s\n", result.Name, status)
 			if result.Eligible {
-				fmt.Printf("   💰 Adjusted Cost: $%.2f\n", result.AdjustedCost)
-				fmt.Printf("   💸 Savings: $%.2f (%.1f%%)\n", result.Savings, result.DiscountPercent)
+				fmt.Printf("   💰 Adjusted Cost: $%.2f\n", result.AdjustedCost.Float64())
+				fmt.Printf("   💸 Savings: $%.2f (%.1f%%)\n", result.Savings.Float64(), result.DiscountPercent)
 			} else {
 				fmt.Printf("   📝 Reason: %s\n", result.Reason)
 			}
@@ -710,9 +815,13 @@ func handlePricingComparison() {
 
 		if comparison.BestOption != nil {
 			fmt.Printf("🏆 Best Option: %s\n", comparison.BestOption.Name)
-			fmt.Printf("💰 Best Price: $%.2f\n", comparison.BestOption.AdjustedCost)
-			fmt.Printf("💸 Total Savings: $%.2f (%.1f%%)\n", comparison.Savings, comparison.SavingsPercentage)
+			fmt.Printf("💰 Best Price: $%.2f\n", comparison.BestOption.AdjustedCost.Float64())
+		}
+		fmt.Printf("🧮 Combined (%s):\n", comparison.StackingPolicy)
+		for _, item := range comparison.Combined.LineItems {
+			fmt.Printf("   %s: -$%.2f → $%.2f\n", item.Name, item.Amount.Float64(), item.CostAfter.Float64())
 		}
+		fmt.Printf("💸 Total Savings: $%.2f (%.1f%%)\n", comparison.Savings.Float64(), comparison.SavingsPercentage)
 	}
 
 	fmt.Println("\n🎯 Summary:")
@@ -767,8 +876,10 @@ func handleConversationalPricing() {
 		thinkingDone := make(chan bool)
 		go showThinkingIndicator(thinkingDone)
 
-		// Process the message
-		response, err := engine.ProcessMessage(userInput, context)
+		// Process the message; Ctrl-C cancels this request only
+		ctx, stop := interruptContext()
+		response, err := engine.ProcessMessageContext(ctx, userInput, context)
+		stop()
 
 		// Stop thinking indicator
 		thinkingDone <- true
@@ -842,6 +953,12 @@ func showConversationalHelp() {
 	fmt.Println("")
 }
 
+// interruptContext returns a context that is cancelled on Ctrl-C so an
+// in-flight API call can be aborted; call stop once the call returns
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/fakegraph"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:4000", "address to listen on")
+	fixtures := flag.String("fixtures", "", "mock scenario fixtures (YAML or JSON); defaults to DISPATCH_MOCK_FIXTURES or the built-in set")
+	seed := flag.Int64("seed", 0, "random seed for latency and fault injection; defaults to DISPATCH_MOCK_SEED")
+	token := flag.String("token", "", "require this bearer token on every request")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if *fixtures != "" {
+		cfg.MockFixturesPath = *fixtures
+	}
+	if *seed != 0 {
+		cfg.MockSeed = *seed
+	}
+
+	server, err := fakegraph.NewServerWithConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create fake GraphQL server: %v", err)
+	}
+	server.SetAuthToken(*token)
+
+	fmt.Fprintf(os.Stderr, "Fake Dispatch GraphQL server listening on http://%s/graphql\n", *addr)
+	authToken := *token
+	if authToken == "" {
+		authToken = "<any value>"
+	}
+	fmt.Fprintf(os.Stderr, "Point clients at it with DISPATCH_GRAPHQL_ENDPOINT=http://%s/graphql DISPATCH_AUTH_TOKEN=%s\n", *addr, authToken)
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		log.Fatalf("Fake GraphQL server error: %v", err)
+	}
+}
@@ -1,20 +1,58 @@
 package main
 
 import (
+	"context"
+	"dispatch-mcp-server/internal/config"
 	"dispatch-mcp-server/internal/mcp"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 )
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	transport := flag.String("transport", cfg.MCPTransport, "MCP transport: stdio, sse or http (streamable HTTP); defaults to MCP_TRANSPORT")
+	addr := flag.String("addr", cfg.MCPListenAddr, "listen address for the sse and http transports; defaults to MCP_LISTEN_ADDR")
+	tokenFile := flag.String("token-file", "", "file holding the bearer token required by the sse and http transports; defaults to MCP_AUTH_TOKEN. The token isn't taken as a flag, which would expose it in ps and /proc")
+	shutdownTimeout := flag.Duration("shutdown-timeout", cfg.MCPShutdownTimeout, "how long to wait for in-flight requests on shutdown; defaults to MCP_SHUTDOWN_TIMEOUT")
+	flag.Parse()
+
+	token := cfg.MCPAuthToken
+	if *tokenFile != "" {
+		data, err := os.ReadFile(*tokenFile)
+		if err != nil {
+			log.Fatalf("Failed to read token file: %v", err)
+		}
+		if token = strings.TrimSpace(string(data)); token == "" {
+			log.Fatalf("Token file %s is empty", *tokenFile)
+		}
+	}
+
 	server, err := mcp.NewMCPServer()
 	if err != nil {
 		log.Fatalf("Failed to create MCP server: %v", err)
 	}
 
+	// Stop serving on Ctrl-C or SIGTERM, letting in-flight requests finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	fmt.Fprintf(os.Stderr, "Starting Dispatch MCP server...\n")
-	if err := server.Run(); err != nil {
+	err = server.Serve(ctx, mcp.ServeOptions{
+		Transport:       *transport,
+		Addr:            *addr,
+		AuthToken:       token,
+		ShutdownTimeout: *shutdownTimeout,
+	})
+	if err != nil {
 		log.Fatalf("MCP server error: %v", err)
 	}
 }
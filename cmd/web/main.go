@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"dispatch-mcp-server/internal/conversation"
 	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/resilience"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -115,10 +118,21 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
+	// Report degraded (but still serving) while any upstream breaker is not closed
+	breakers := resilience.DefaultRegistry.Snapshot()
+	status := "healthy"
+	for _, breaker := range breakers {
+		if breaker.State != resilience.StateClosed {
+			status = "degraded"
+			break
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":    "healthy",
-		"timestamp": time.Now().Format(time.RFC3339),
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           status,
+		"timestamp":        time.Now().Format(time.RFC3339),
+		"circuit_breakers": breakers,
 	})
 }
 
@@ -187,7 +201,12 @@ func handleChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Process message with conversation engine and history
-	response, err := engine.ProcessMessageWithHistory(request.Message, session.Context, history)
+	response, err := engine.ProcessMessageWithHistoryContext(r.Context(), request.Message, session.Context, history)
+	if errors.Is(err, context.Canceled) {
+		// The client went away; there is nobody to respond to
+		log.Printf("⚠️  Chat request cancelled by client")
+		return
+	}
 	if err != nil {
 		log.Printf("❌ Error processing message: %v", err)
 		http.Error(w, fmt.Sprintf("Error processing message: %v", err), http.StatusInternalServerError)
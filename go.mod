@@ -5,6 +5,7 @@ go 1.23
 require (
 	github.com/go-resty/resty/v2 v2.16.5
 	github.com/mark3labs/mcp-go v0.41.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -17,5 +18,4 @@ require (
 	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
 	golang.org/x/net v0.33.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -1,5 +1,7 @@
 package analysis
 
+import "dispatch-mcp-server/internal/money"
+
 // "fmt" // TODO: Will be used for error formatting
 // "time" // TODO: Will be used for date calculations
 
@@ -27,11 +29,11 @@ func (ae *AnalysisEngine) AnalyzeHistoricalSavings(request AnalysisRequest) (*An
 				StartDate: request.StartDate,
 				EndDate:   request.EndDate,
 			},
-			TotalOrders:               0,   // Will be populated from historical data
-			TotalDeliveries:           0,   // Will be populated from historical data
-			CurrentTotalCost:          0.0, // Will be populated from historical data
-			CombinedSavings:           0.0, // Will be calculated
-			CombinedSavingsPercentage: 0.0, // Will be calculated
+			TotalOrders:               0,                       // Will be populated from historical data
+			TotalDeliveries:           0,                       // Will be populated from historical data
+			CurrentTotalCost:          money.New(0, money.USD), // Will be populated from historical data
+			CombinedSavings:           money.New(0, money.USD), // Will be calculated
+			CombinedSavingsPercentage: 0.0,                     // Will be calculated
 			ImplementationTimeline:    "3-6 months",
 			ROI:                       0.0, // Will be calculated
 			Recommendations: []string{
@@ -65,12 +67,12 @@ func (ae *AnalysisEngine) analyzeBundling(request AnalysisRequest) *BundlingAnal
 	// This would analyze how individual orders could be combined
 
 	return &BundlingAnalysis{
-		CurrentOrders:     0,   // Will be calculated from historical data
-		OptimizedOrders:   0,   // Will be calculated based on bundling algorithm
-		CurrentCost:       0.0, // Will be calculated from historical data
-		OptimizedCost:     0.0, // Will be calculated with bundling discounts
-		PotentialSavings:  0.0, // Will be calculated
-		SavingsPercentage: 0.0, // Will be calculated
+		CurrentOrders:     0,                       // Will be calculated from historical data
+		OptimizedOrders:   0,                       // Will be calculated based on bundling algorithm
+		CurrentCost:       money.New(0, money.USD), // Will be calculated from historical data
+		OptimizedCost:     money.New(0, money.USD), // Will be calculated with bundling discounts
+		PotentialSavings:  money.New(0, money.USD), // Will be calculated
+		SavingsPercentage: 0.0,                     // Will be calculated
 		Recommendations: []string{
 			"Bundling analysis not yet implemented",
 			"Will analyze orders by pickup location and delivery date",
@@ -85,12 +87,12 @@ func (ae *AnalysisEngine) analyzeVolume(request AnalysisRequest) *VolumeAnalysis
 	// This would analyze how increasing order frequency could unlock volume discounts
 
 	return &VolumeAnalysis{
-		CurrentFrequency:   0,   // Will be calculated from historical data
-		TargetFrequency:    20,  // Orders per month for volume discount
-		CurrentCost:        0.0, // Will be calculated from historical data
-		VolumeDiscountCost: 0.0, // Will be calculated with volume discounts
-		PotentialSavings:   0.0, // Will be calculated
-		SavingsPercentage:  0.0, // Will be calculated
+		CurrentFrequency:   0,                       // Will be calculated from historical data
+		TargetFrequency:    20,                      // Orders per month for volume discount
+		CurrentCost:        money.New(0, money.USD), // Will be calculated from historical data
+		VolumeDiscountCost: money.New(0, money.USD), // Will be calculated with volume discounts
+		PotentialSavings:   money.New(0, money.USD), // Will be calculated
+		SavingsPercentage:  0.0,                     // Will be calculated
 		Recommendations: []string{
 			"Volume analysis not yet implemented",
 			"Will analyze current order frequency vs volume discount threshold",
@@ -105,12 +107,12 @@ func (ae *AnalysisEngine) analyzeLoyalty(request AnalysisRequest) *LoyaltyAnalys
 	// This would analyze how reaching higher loyalty tiers could unlock discounts
 
 	return &LoyaltyAnalysis{
-		CurrentTier:         "bronze", // Will be determined from historical data
-		TargetTier:          "gold",   // Target tier for loyalty discount
-		CurrentCost:         0.0,      // Will be calculated from historical data
-		LoyaltyDiscountCost: 0.0,      // Will be calculated with loyalty discounts
-		PotentialSavings:    0.0,      // Will be calculated
-		SavingsPercentage:   0.0,      // Will be calculated
+		CurrentTier:         "bronze",                // Will be determined from historical data
+		TargetTier:          "gold",                  // Target tier for loyalty discount
+		CurrentCost:         money.New(0, money.USD), // Will be calculated from historical data
+		LoyaltyDiscountCost: money.New(0, money.USD), // Will be calculated with loyalty discounts
+		PotentialSavings:    money.New(0, money.USD), // Will be calculated
+		SavingsPercentage:   0.0,                     // Will be calculated
 		Recommendations: []string{
 			"Loyalty analysis not yet implemented",
 			"Will analyze current customer tier vs target tier",
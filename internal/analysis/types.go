@@ -1,21 +1,22 @@
 package analysis
 
 import (
+	"dispatch-mcp-server/internal/money"
 	"time"
 )
 
 // HistoricalOrder represents a historical order for analysis
 type HistoricalOrder struct {
-	ID                string    `json:"id"`
-	OrderDate         time.Time `json:"order_date"`
-	DeliveryCount     int       `json:"delivery_count"`
-	TotalCost         float64   `json:"total_cost"`
-	PickupLocation    string    `json:"pickup_location"`
-	DeliveryLocations []string  `json:"delivery_locations"`
-	CustomerTier      string    `json:"customer_tier"`
-	OrderFrequency    int       `json:"order_frequency"` // orders per month
-	IsBulkOrder       bool      `json:"is_bulk_order"`
-	PricingModel      string    `json:"pricing_model"` // standard, multi_delivery, volume, loyalty, bulk
+	ID                string      `json:"id"`
+	OrderDate         time.Time   `json:"order_date"`
+	DeliveryCount     int         `json:"delivery_count"`
+	TotalCost         money.Money `json:"total_cost"`
+	PickupLocation    string      `json:"pickup_location"`
+	DeliveryLocations []string    `json:"delivery_locations"`
+	CustomerTier      string      `json:"customer_tier"`
+	OrderFrequency    int         `json:"order_frequency"` // orders per month
+	IsBulkOrder       bool        `json:"is_bulk_order"`
+	PricingModel      string      `json:"pricing_model"` // standard, multi_delivery, volume, loyalty, bulk
 }
 
 // AnalysisPeriod represents the time period for analysis
@@ -26,35 +27,35 @@ type AnalysisPeriod struct {
 
 // BundlingAnalysis represents the analysis of bundling orders
 type BundlingAnalysis struct {
-	CurrentOrders     int      `json:"current_orders"`
-	OptimizedOrders   int      `json:"optimized_orders"`
-	CurrentCost       float64  `json:"current_cost"`
-	OptimizedCost     float64  `json:"optimized_cost"`
-	PotentialSavings  float64  `json:"potential_savings"`
-	SavingsPercentage float64  `json:"savings_percentage"`
-	Recommendations   []string `json:"recommendations"`
+	CurrentOrders     int         `json:"current_orders"`
+	OptimizedOrders   int         `json:"optimized_orders"`
+	CurrentCost       money.Money `json:"current_cost"`
+	OptimizedCost     money.Money `json:"optimized_cost"`
+	PotentialSavings  money.Money `json:"potential_savings"`
+	SavingsPercentage float64     `json:"savings_percentage"`
+	Recommendations   []string    `json:"recommendations"`
 }
 
 // VolumeAnalysis represents the analysis of volume discounts
 type VolumeAnalysis struct {
-	CurrentFrequency   int      `json:"current_frequency"` // orders per month
-	TargetFrequency    int      `json:"target_frequency"`  // orders per month for volume discount
-	CurrentCost        float64  `json:"current_cost"`
-	VolumeDiscountCost float64  `json:"volume_discount_cost"`
-	PotentialSavings   float64  `json:"potential_savings"`
-	SavingsPercentage  float64  `json:"savings_percentage"`
-	Recommendations    []string `json:"recommendations"`
+	CurrentFrequency   int         `json:"current_frequency"` // orders per month
+	TargetFrequency    int         `json:"target_frequency"`  // orders per month for volume discount
+	CurrentCost        money.Money `json:"current_cost"`
+	VolumeDiscountCost money.Money `json:"volume_discount_cost"`
+	PotentialSavings   money.Money `json:"potential_savings"`
+	SavingsPercentage  float64     `json:"savings_percentage"`
+	Recommendations    []string    `json:"recommendations"`
 }
 
 // LoyaltyAnalysis represents the analysis of loyalty tier benefits
 type LoyaltyAnalysis struct {
-	CurrentTier         string   `json:"current_tier"`
-	TargetTier          string   `json:"target_tier"`
-	CurrentCost         float64  `json:"current_cost"`
-	LoyaltyDiscountCost float64  `json:"loyalty_discount_cost"`
-	PotentialSavings    float64  `json:"potential_savings"`
-	SavingsPercentage   float64  `json:"savings_percentage"`
-	Recommendations     []string `json:"recommendations"`
+	CurrentTier         string      `json:"current_tier"`
+	TargetTier          string      `json:"target_tier"`
+	CurrentCost         money.Money `json:"current_cost"`
+	LoyaltyDiscountCost money.Money `json:"loyalty_discount_cost"`
+	PotentialSavings    money.Money `json:"potential_savings"`
+	SavingsPercentage   float64     `json:"savings_percentage"`
+	Recommendations     []string    `json:"recommendations"`
 }
 
 // ComprehensiveAnalysis represents the complete analysis combining all strategies
@@ -62,17 +63,17 @@ type ComprehensiveAnalysis struct {
 	AnalysisPeriod   AnalysisPeriod `json:"analysis_period"`
 	TotalOrders      int            `json:"total_orders"`
 	TotalDeliveries  int            `json:"total_deliveries"`
-	CurrentTotalCost float64        `json:"current_total_cost"`
+	CurrentTotalCost money.Money    `json:"current_total_cost"`
 
 	BundlingAnalysis *BundlingAnalysis `json:"bundling_analysis,omitempty"`
 	VolumeAnalysis   *VolumeAnalysis   `json:"volume_analysis,omitempty"`
 	LoyaltyAnalysis  *LoyaltyAnalysis  `json:"loyalty_analysis,omitempty"`
 
-	CombinedSavings           float64  `json:"combined_savings"`
-	CombinedSavingsPercentage float64  `json:"combined_savings_percentage"`
-	ImplementationTimeline    string   `json:"implementation_timeline"`
-	ROI                       float64  `json:"roi"`
-	Recommendations           []string `json:"recommendations"`
+	CombinedSavings           money.Money `json:"combined_savings"`
+	CombinedSavingsPercentage float64     `json:"combined_savings_percentage"`
+	ImplementationTimeline    string      `json:"implementation_timeline"`
+	ROI                       float64     `json:"roi"`
+	Recommendations           []string    `json:"recommendations"`
 }
 
 // AnalysisRequest represents the request for historical analysis
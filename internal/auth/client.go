@@ -2,6 +2,7 @@ package auth
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -47,17 +48,17 @@ func NewClient(config *Config) *Client {
 	}
 }
 
-func (c *Client) GetValidToken() (string, error) {
+func (c *Client) GetValidToken(ctx context.Context) (string, error) {
 	// Check if we have a valid token
 	if c.token != nil && time.Now().Before(c.token.ExpiresAt) {
 		return c.token.AccessToken, nil
 	}
 
 	// Token expired or doesn't exist, get a new one
-	return c.refreshToken()
+	return c.refreshToken(ctx)
 }
 
-func (c *Client) refreshToken() (string, error) {
+func (c *Client) refreshToken(ctx context.Context) (string, error) {
 	tokenRequest := TokenRequest{
 		GrantType:    "client_credentials",
 		ClientID:     c.config.ClientID,
@@ -70,7 +71,7 @@ func (c *Client) refreshToken() (string, error) {
 		return "", fmt.Errorf("failed to marshal token request: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", c.config.TokenEndpoint, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.TokenEndpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create token request: %v", err)
 	}
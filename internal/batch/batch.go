@@ -0,0 +1,205 @@
+package batch
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/estimate"
+	"dispatch-mcp-server/internal/money"
+	"dispatch-mcp-server/internal/validation"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Limits on a batch
+const (
+	MaxRows            = 100
+	DefaultConcurrency = 4
+	MaxConcurrency     = 10
+)
+
+// Row statuses
+const (
+	StatusOK      = "ok"
+	StatusInvalid = "invalid" // rejected by validation, never sent
+	StatusFailed  = "failed"  // the estimate call failed
+)
+
+// Row is one shipment to quote
+type Row struct {
+	Reference        string                      `json:"reference,omitempty"`
+	PickupInfo       dispatch.PickupInfoInput    `json:"pickup_info"`
+	DropOffs         []dispatch.DropOffInfoInput `json:"drop_offs"`
+	VehicleType      string                      `json:"vehicle_type"`
+	AddOns           []string                    `json:"add_ons,omitempty"`
+	DedicatedVehicle *bool                       `json:"dedicated_vehicle,omitempty"`
+}
+
+// Input returns the estimate request for the row
+func (r Row) Input() dispatch.CreateEstimateInput {
+	return dispatch.CreateEstimateInput{
+		PickupInfo:       r.PickupInfo,
+		DropOffs:         r.DropOffs,
+		VehicleType:      r.VehicleType,
+		AddOns:           r.AddOns,
+		DedicatedVehicle: r.DedicatedVehicle,
+	}
+}
+
+// Validate runs the validation.Validator checks create_estimate relies on
+// and returns one message per problem
+func (r Row) Validate(validator *validation.Validator) []string {
+	var problems []string
+	collect := func(prefix string, result *validation.ValidationResult) {
+		if result.Valid {
+			return
+		}
+		for _, err := range result.Errors {
+			problems = append(problems, fmt.Sprintf("%s%s: %s", prefix, err.Field, err.Message))
+		}
+	}
+
+	collect("", validator.ValidateVehicleType(r.VehicleType))
+
+	var pickup map[string]interface{}
+	if err := roundTrip(r.PickupInfo, &pickup); err != nil {
+		problems = append(problems, fmt.Sprintf("pickup_info: %v", err))
+	} else {
+		collect("pickup_info.", validator.ValidatePickupInfo(pickup))
+	}
+
+	// Drop-off errors already carry their drop_offs[i] prefix
+	var dropOffs []map[string]interface{}
+	if err := roundTrip(r.DropOffs, &dropOffs); err != nil {
+		problems = append(problems, fmt.Sprintf("drop_offs: %v", err))
+	} else {
+		collect("", validator.ValidateDropOffs(dropOffs))
+	}
+	return problems
+}
+
+// Result is the outcome for one row. Row is 1-based, in input order.
+type Result struct {
+	Row       int                             `json:"row"`
+	Reference string                          `json:"reference,omitempty"`
+	Status    string                          `json:"status"`
+	Errors    []string                        `json:"errors,omitempty"`
+	Cheapest  *dispatch.AvailableOrderOption  `json:"cheapest,omitempty"`
+	Fastest   *dispatch.AvailableOrderOption  `json:"fastest,omitempty"`
+	Options   []dispatch.AvailableOrderOption `json:"options,omitempty"`
+
+	// Response is the full estimate, for callers that keep it
+	Response *dispatch.CreateEstimateResponse `json:"-"`
+}
+
+// Totals aggregates a batch. The cost totals add up each successful row's
+// cheapest and fastest option.
+type Totals struct {
+	Rows          int         `json:"rows"`
+	Succeeded     int         `json:"succeeded"`
+	Invalid       int         `json:"invalid"`
+	Failed        int         `json:"failed"`
+	CheapestTotal money.Money `json:"cheapest_total"`
+	FastestTotal  money.Money `json:"fastest_total"`
+}
+
+// Report is the outcome of a batch, with results in input order
+type Report struct {
+	Results []Result `json:"results"`
+	Totals  Totals   `json:"totals"`
+}
+
+// Run validates every row and quotes the valid ones through api, at most
+// concurrency at a time. A failing row doesn't stop the others; rows not yet
+// started when ctx is cancelled fail with its error.
+func Run(ctx context.Context, api dispatch.API, rows []Row, concurrency int) *Report {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	if concurrency > MaxConcurrency {
+		concurrency = MaxConcurrency
+	}
+
+	validator := validation.NewValidator()
+	results := make([]Result, len(rows))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, row := range rows {
+		results[i] = Result{Row: i + 1, Reference: row.Reference}
+		if problems := row.Validate(validator); len(problems) > 0 {
+			results[i].Status = StatusInvalid
+			results[i].Errors = problems
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *Result, row Row) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				result.Status = StatusFailed
+				result.Errors = []string{ctx.Err().Error()}
+				return
+			}
+			quote(ctx, api, row, result)
+		}(&results[i], row)
+	}
+	wg.Wait()
+
+	report := &Report{Results: results, Totals: Totals{Rows: len(rows)}}
+	for _, result := range results {
+		switch result.Status {
+		case StatusOK:
+			report.Totals.Succeeded++
+			report.Totals.CheapestTotal = report.Totals.CheapestTotal.Add(result.Cheapest.EstimatedOrderCost)
+			report.Totals.FastestTotal = report.Totals.FastestTotal.Add(result.Fastest.EstimatedOrderCost)
+		case StatusInvalid:
+			report.Totals.Invalid++
+		default:
+			report.Totals.Failed++
+		}
+	}
+	return report
+}
+
+// quote estimates one row and fills in result
+func quote(ctx context.Context, api dispatch.API, row Row, result *Result) {
+	response, err := api.CreateEstimate(ctx, row.Input())
+	if err != nil {
+		result.Status = StatusFailed
+		result.Errors = []string{fmt.Sprintf("failed to create estimate: %v", err)}
+		return
+	}
+
+	options := response.Data.CreateEstimate.Estimate.AvailableOrderOptions
+	cheapest, err := estimate.SelectOption(options, estimate.Criteria{Scenario: estimate.ScenarioCheapest})
+	if err != nil {
+		result.Status = StatusFailed
+		result.Errors = []string{err.Error()}
+		return
+	}
+	fastest, err := estimate.SelectOption(options, estimate.Criteria{Scenario: estimate.ScenarioFastest})
+	if err != nil {
+		result.Status = StatusFailed
+		result.Errors = []string{err.Error()}
+		return
+	}
+
+	result.Status = StatusOK
+	result.Options = options
+	result.Cheapest = &cheapest.Option
+	result.Fastest = &fastest.Option
+	result.Response = response
+}
+
+// roundTrip converts v to the generic form the validator works on
+func roundTrip(v interface{}, target interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
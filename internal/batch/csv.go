@@ -0,0 +1,117 @@
+package batch
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSVColumns lists the columns ParseCSV understands. Only vehicle_type and
+// the address columns are needed; country defaults to US.
+var CSVColumns = []string{
+	"reference", "vehicle_type",
+	"pickup_business_name", "pickup_street", "pickup_city", "pickup_state", "pickup_zip_code", "pickup_country",
+	"dropoff_business_name", "dropoff_street", "dropoff_city", "dropoff_state", "dropoff_zip_code", "dropoff_country",
+	"add_ons", "dedicated_vehicle",
+}
+
+// ParseCSV reads shipments from CSV with a header row naming columns from
+// CSVColumns, in any order. Consecutive lines with the same non-empty
+// reference form one multi-stop shipment: the first line gives the pickup
+// and each line adds a drop-off. add_ons are separated by semicolons.
+func ParseCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !containsColumn(name) {
+			return nil, fmt.Errorf("unknown CSV column %q; expected columns from: %s", name, strings.Join(CSVColumns, ", "))
+		}
+		columns[name] = i
+	}
+
+	var rows []Row
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV line %d: %v", line, err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		dropOff := dispatch.DropOffInfoInput{
+			BusinessName: field("dropoff_business_name"),
+			Location:     dispatch.LocationInput{Address: csvAddress(field, "dropoff_")},
+		}
+		reference := field("reference")
+		if n := len(rows); reference != "" && n > 0 && rows[n-1].Reference == reference {
+			rows[n-1].DropOffs = append(rows[n-1].DropOffs, dropOff)
+			continue
+		}
+
+		row := Row{
+			Reference:   reference,
+			VehicleType: field("vehicle_type"),
+			PickupInfo: dispatch.PickupInfoInput{
+				BusinessName: field("pickup_business_name"),
+				Location:     dispatch.LocationInput{Address: csvAddress(field, "pickup_")},
+			},
+			DropOffs: []dispatch.DropOffInfoInput{dropOff},
+		}
+		for _, addOn := range strings.Split(field("add_ons"), ";") {
+			if addOn = strings.TrimSpace(addOn); addOn != "" {
+				row.AddOns = append(row.AddOns, addOn)
+			}
+		}
+		if dedicated := field("dedicated_vehicle"); dedicated != "" {
+			value, err := strconv.ParseBool(dedicated)
+			if err != nil {
+				return nil, fmt.Errorf("CSV line %d: dedicated_vehicle must be true or false, got %q", line, dedicated)
+			}
+			row.DedicatedVehicle = &value
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// csvAddress builds the address from the columns starting with prefix
+func csvAddress(field func(string) string, prefix string) *dispatch.AddressInput {
+	address := &dispatch.AddressInput{
+		Street:  field(prefix + "street"),
+		City:    field(prefix + "city"),
+		State:   strings.ToUpper(field(prefix + "state")),
+		ZipCode: field(prefix + "zip_code"),
+		Country: field(prefix + "country"),
+	}
+	if address.Country == "" {
+		address.Country = "US"
+	}
+	return address
+}
+
+func containsColumn(name string) bool {
+	for _, column := range CSVColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
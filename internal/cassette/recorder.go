@@ -0,0 +1,262 @@
+package cassette
+
+import (
+	"bytes"
+	"dispatch-mcp-server/internal/config"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Matcher derives the key used to pair an incoming request with a recorded
+// one. It is given the scrubbed body so recorded and live requests compare
+// equal even though PII was redacted on disk.
+type Matcher func(method, rawURL string, body []byte) string
+
+// Recorder is an http.RoundTripper that records traffic to a cassette file or
+// replays it from one without touching the network
+type Recorder struct {
+	path    string
+	mode    Mode
+	next    http.RoundTripper
+	matcher Matcher
+
+	mu       sync.Mutex
+	cassette *Cassette
+	served   map[string]int
+}
+
+// New creates a recorder for the cassette at path. In replay mode the
+// cassette must exist; in record mode new interactions are appended to it.
+// A nil next uses http.DefaultTransport and a nil matcher uses DefaultMatcher.
+func New(path string, mode Mode, next http.RoundTripper, matcher Matcher) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if matcher == nil {
+		matcher = DefaultMatcher
+	}
+
+	recorder := &Recorder{
+		path:    path,
+		mode:    mode,
+		next:    next,
+		matcher: matcher,
+		served:  make(map[string]int),
+	}
+
+	switch mode {
+	case ModeReplay:
+		cassette, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		recorder.cassette = cassette
+	case ModeRecord:
+		if cassette, err := Load(path); err == nil {
+			recorder.cassette = cassette
+		} else {
+			recorder.cassette = &Cassette{Name: strings.TrimSuffix(filepath.Base(path), ".json")}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported cassette mode %q", mode)
+	}
+
+	return recorder, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cassette: failed to read request body: %v", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, ScrubBody(body))
+	}
+	return r.record(req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cassette: failed to read response body: %v", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: Request{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: ScrubHeaders(req.Header),
+			Body:    ScrubBody(body),
+		},
+		Response: Response{
+			Status:  resp.StatusCode,
+			Headers: ScrubHeaders(resp.Header),
+			Body:    ScrubBody(respBody),
+		},
+		RecordedAt: time.Now().UTC(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := r.cassette.Save(r.path); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// replay serves recorded interactions in recorded order for each key,
+// repeating the last one once they are used up
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	key := r.matcher(req.Method, req.URL.String(), body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matches []*Interaction
+	for i := range r.cassette.Interactions {
+		recorded := &r.cassette.Interactions[i]
+		if r.matcher(recorded.Request.Method, recorded.Request.URL, recorded.Request.Body) == key {
+			matches = append(matches, recorded)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("cassette %s: no recorded interaction for %s", r.path, key)
+	}
+
+	n := r.served[key]
+	if n >= len(matches) {
+		n = len(matches) - 1
+	}
+	r.served[key]++
+	recorded := matches[n]
+
+	header := make(http.Header, len(recorded.Response.Headers))
+	for name, value := range recorded.Response.Headers {
+		header.Set(name, value)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Response.Status, http.StatusText(recorded.Response.Status)),
+		StatusCode:    recorded.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(recorded.Response.Body)),
+		ContentLength: int64(len(recorded.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// DefaultMatcher pairs requests by method, URL path and canonical JSON body.
+// The host is ignored so cassettes recorded against one environment replay
+// against another.
+func DefaultMatcher(method, rawURL string, body []byte) string {
+	return method + " " + urlPath(rawURL) + " " + canonicalJSON(body)
+}
+
+var operationPattern = regexp.MustCompile(`^\s*(query|mutation|subscription)\s+(\w+)`)
+
+// GraphQLMatcher pairs GraphQL requests by operation name and variables so
+// whitespace changes in the query document don't invalidate cassettes
+func GraphQLMatcher(method, rawURL string, body []byte) string {
+	var payload struct {
+		Query     string          `json:"query"`
+		Variables json.RawMessage `json:"variables"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Query == "" {
+		return DefaultMatcher(method, rawURL, body)
+	}
+
+	operation := "anonymous"
+	if match := operationPattern.FindStringSubmatch(payload.Query); match != nil {
+		operation = match[2]
+	}
+	return method + " " + urlPath(rawURL) + " " + operation + " " + canonicalJSON(payload.Variables)
+}
+
+func urlPath(rawURL string) string {
+	if i := strings.Index(rawURL, "://"); i >= 0 {
+		rawURL = rawURL[i+3:]
+		if j := strings.Index(rawURL, "/"); j >= 0 {
+			return rawURL[j:]
+		}
+		return "/"
+	}
+	return rawURL
+}
+
+// canonicalJSON re-encodes JSON with sorted keys and no insignificant whitespace
+func canonicalJSON(data []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return string(data)
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return string(data)
+	}
+	return string(canonical)
+}
+
+var (
+	recorders   = make(map[string]*Recorder)
+	recordersMu sync.Mutex
+)
+
+// Transport returns the cassette transport named name (stored as
+// <CassetteDir>/<name>.json) for the mode in cfg, or nil when cassettes are
+// off. Clients sharing a name share one recorder so recordings aren't lost
+// and replays advance together.
+func Transport(cfg *config.Config, name string, matcher Matcher) (http.RoundTripper, error) {
+	mode := Mode(cfg.CassetteMode)
+	if mode == "" || mode == ModeOff {
+		return nil, nil
+	}
+
+	path := filepath.Join(cfg.CassetteDir, name+".json")
+
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+
+	if recorder, ok := recorders[path]; ok && recorder.mode == mode {
+		return recorder, nil
+	}
+
+	recorder, err := New(path, mode, nil, matcher)
+	if err != nil {
+		return nil, err
+	}
+	recorders[path] = recorder
+	return recorder, nil
+}
+
+// IsReplay reports whether cfg replays cassettes instead of calling real APIs
+func IsReplay(cfg *config.Config) bool {
+	return Mode(cfg.CassetteMode) == ModeReplay
+}
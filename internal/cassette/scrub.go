@@ -0,0 +1,107 @@
+package cassette
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces scrubbed values in cassettes
+const Redacted = "[REDACTED]"
+
+// sensitiveHeaders are never written to a cassette in clear text
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"x-api-key":           true,
+	"cookie":              true,
+	"set-cookie":          true,
+}
+
+// sensitiveFields are JSON keys whose values are replaced, compared after
+// lowercasing and removing underscores so both snake_case and camelCase match
+var sensitiveFields = map[string]bool{
+	"contactname":        true,
+	"contactphone":       true,
+	"contactphonenumber": true,
+	"contactemail":       true,
+	"phone":              true,
+	"phonenumber":        true,
+	"email":              true,
+	"password":           true,
+	"clientsecret":       true,
+	"accesstoken":        true,
+	"refreshtoken":       true,
+	"apikey":             true,
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\(?\b\d{3}\)?[\s.\-]\d{3}[\s.\-]\d{4}\b`)
+)
+
+// ScrubHeaders flattens headers to one value each and redacts credentials
+func ScrubHeaders(headers http.Header) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	scrubbed := make(map[string]string, len(headers))
+	for name, values := range headers {
+		if sensitiveHeaders[strings.ToLower(name)] {
+			scrubbed[name] = Redacted
+			continue
+		}
+		scrubbed[name] = strings.Join(values, ", ")
+	}
+	return scrubbed
+}
+
+// ScrubBody redacts contact details and secrets from a request or response
+// body. JSON bodies have sensitive fields replaced and free text (such as chat
+// messages) has email addresses and phone numbers masked.
+func ScrubBody(body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []byte(scrubText(string(body)))
+	}
+
+	scrubbed, err := json.Marshal(scrubValue(value))
+	if err != nil {
+		return body
+	}
+	return scrubbed
+}
+
+func scrubValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if sensitiveFields[strings.ReplaceAll(strings.ToLower(key), "_", "")] && field != nil {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = scrubValue(field)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = scrubValue(item)
+		}
+		return v
+	case string:
+		return scrubText(v)
+	default:
+		return v
+	}
+}
+
+func scrubText(text string) string {
+	text = emailPattern.ReplaceAllString(text, Redacted)
+	return phonePattern.ReplaceAllString(text, Redacted)
+}
@@ -2,7 +2,11 @@ package claude
 
 import (
 	"bytes"
+	"context"
+	"dispatch-mcp-server/internal/cassette"
+	"dispatch-mcp-server/internal/config"
 	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/pricing"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -20,15 +24,23 @@ type ConversationMessage struct {
 
 // Client represents a Claude API client
 type Client struct {
-	apiKey     string
-	baseURL    string
-	httpClient *http.Client
+	apiKey        string
+	baseURL       string
+	httpClient    *http.Client
+	timeout       time.Duration
+	pricingEngine *pricing.PricingEngine
 }
 
 // NewClient creates a new Claude client
 func NewClient() (*Client, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	// Replayed cassettes never reach the API, so they don't need a key
 	apiKey := os.Getenv("ANTHROPIC_API_KEY")
-	if apiKey == "" {
+	if apiKey == "" && !cassette.IsReplay(cfg) {
 		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is required")
 	}
 
@@ -46,15 +58,42 @@ func NewClient() (*Client, error) {
 		baseURL = aiHubEndpoint
 	}
 
+	httpClient := &http.Client{}
+	transport, err := cassette.Transport(cfg, "claude", MessageMatcher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up cassette: %v", err)
+	}
+	if transport != nil {
+		httpClient.Transport = transport
+	}
+
 	return &Client{
-		apiKey:  apiKey,
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		apiKey:        apiKey,
+		baseURL:       baseURL,
+		httpClient:    httpClient,
+		timeout:       cfg.LLMTimeout,
+		pricingEngine: pricing.NewPricingEngine(),
 	}, nil
 }
 
+// SetPricingEngine sets the engine whose rules the system prompt describes,
+// so the prompt follows rules reloaded from the rules file
+func (c *Client) SetPricingEngine(engine *pricing.PricingEngine) {
+	c.pricingEngine = engine
+}
+
+// MessageMatcher pairs recorded Messages API calls by the latest user message,
+// so replays survive changes to the system prompt and conversation context
+func MessageMatcher(method, rawURL string, body []byte) string {
+	var request MessageRequest
+	if err := json.Unmarshal(body, &request); err != nil || len(request.Messages) == 0 {
+		return cassette.DefaultMatcher(method, rawURL, body)
+	}
+
+	last := request.Messages[len(request.Messages)-1]
+	return method + " messages " + last.Role + ": " + last.Content
+}
+
 // MessageRequest represents a request to Claude
 type MessageRequest struct {
 	Model     string    `json:"model"`
@@ -88,13 +127,16 @@ type MessageResponse struct {
 }
 
 // CreateMessage sends a message to Claude and returns the response
-func (c *Client) CreateMessage(request MessageRequest) (*MessageResponse, error) {
+func (c *Client) CreateMessage(ctx context.Context, request MessageRequest) (*MessageResponse, error) {
+	ctx, cancel := config.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
 	jsonData, err := json.Marshal(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/messages", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/messages", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
@@ -133,7 +175,7 @@ func (c *Client) CreateMessage(request MessageRequest) (*MessageResponse, error)
 }
 
 // CreatePricingAdvisorMessageWithHistory creates a message for the pricing advisor with conversation history
-func (c *Client) CreatePricingAdvisorMessageWithHistory(userMessage string, context *PricingContext, history []ConversationMessage) (*MessageResponse, error) {
+func (c *Client) CreatePricingAdvisorMessageWithHistory(ctx context.Context, userMessage string, context *PricingContext, history []ConversationMessage) (*MessageResponse, error) {
 	systemPrompt := `You are a Dispatch order creation assistant. Your role is to help customers create delivery orders efficiently while finding them the best pricing.
 
 🎯 Your Role:
@@ -144,11 +186,7 @@ func (c *Client) CreatePricingAdvisorMessageWithHistory(userMessage string, cont
 - Be direct and efficient - focus on order creation, not marketing
 
 💰 Available Pricing Models:
-- **Standard Pricing**: 0% discount (baseline for new customers)
-- **Multi-Delivery Discount**: 15% off for 2+ deliveries in one order
-- **Volume Discount**: 20% off for 5+ deliveries + 3+ orders/month (regular customers)
-- **Loyalty Discount**: 10% off for gold tier customers (VIP status)
-- **Bulk Order Discount**: 25% off for 10+ deliveries + bulk order flag (enterprise)
+` + c.pricingEngine.Rules().Prompt() + `
 
 📊 Current Customer Context:
 - Delivery Count: ` + fmt.Sprintf("%d", context.DeliveryCount) + `
@@ -229,11 +267,11 @@ Remember: Your goal is to efficiently collect all information needed to create t
 		System:    systemPrompt,
 	}
 
-	return c.CreateMessage(request)
+	return c.CreateMessage(ctx, request)
 }
 
 // CreatePricingAdvisorMessage creates a message for the pricing advisor
-func (c *Client) CreatePricingAdvisorMessage(userMessage string, context *PricingContext) (*MessageResponse, error) {
+func (c *Client) CreatePricingAdvisorMessage(ctx context.Context, userMessage string, context *PricingContext) (*MessageResponse, error) {
 	systemPrompt := `You are a Dispatch order creation assistant. Your role is to help customers create delivery orders efficiently while finding them the best pricing.
 
 🎯 Your Role:
@@ -244,11 +282,7 @@ func (c *Client) CreatePricingAdvisorMessage(userMessage string, context *Pricin
 - Be direct and efficient - focus on order creation, not marketing
 
 💰 Available Pricing Models:
-- **Standard Pricing**: 0% discount (baseline for new customers)
-- **Multi-Delivery Discount**: 15% off for 2+ deliveries in one order
-- **Volume Discount**: 20% off for 5+ deliveries + 3+ orders/month (regular customers)
-- **Loyalty Discount**: 10% off for gold tier customers (VIP status)
-- **Bulk Order Discount**: 25% off for 10+ deliveries + bulk order flag (enterprise)
+` + c.pricingEngine.Rules().Prompt() + `
 
 📊 Current Customer Context:
 - Delivery Count: ` + fmt.Sprintf("%d", context.DeliveryCount) + `
@@ -315,7 +349,7 @@ Remember: Your goal is to efficiently collect all information needed to create t
 		System: systemPrompt,
 	}
 
-	return c.CreateMessage(request)
+	return c.CreateMessage(ctx, request)
 }
 
 // PricingContext represents the context for pricing conversations
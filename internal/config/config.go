@@ -1,7 +1,10 @@
 package config
 
 import (
+	"context"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -13,6 +16,51 @@ type Config struct {
 	ClientID        string
 	ClientSecret    string
 	Scope           string
+
+	// Default per-operation timeouts, applied when the caller's context has no earlier deadline
+	EstimateTimeout time.Duration
+	OrderTimeout    time.Duration
+	AuthTimeout     time.Duration
+	LLMTimeout      time.Duration
+
+	// Retry and circuit breaker settings for the Dispatch GraphQL endpoints
+	RetryMaxAttempts        int
+	RetryBaseDelay          time.Duration
+	RetryMaxDelay           time.Duration
+	BreakerFailureThreshold int
+	BreakerCooldown         time.Duration
+
+	// Idempotency records for order creation; an empty path keeps them in
+	// memory. Orders without an explicit key are only deduplicated within
+	// the retry window.
+	IdempotencyStorePath   string
+	IdempotencyTTL         time.Duration
+	IdempotencyRetryWindow time.Duration
+
+	// How long create_estimate results can be referenced by estimate_id
+	EstimateTTL time.Duration
+
+	// Cassette mode (off, record or replay) and directory for recorded
+	// Dispatch and LLM traffic
+	CassetteMode string
+	CassetteDir  string
+
+	// Mock client fixtures (empty uses the built-in set) and random seed for
+	// reproducible latency and fault injection (0 seeds from the clock)
+	MockFixturesPath string
+	MockSeed         int64
+
+	// Pricing rules file (empty uses the built-in rules) and how often it is
+	// checked for changes while the server runs
+	PricingRulesPath           string
+	PricingRulesReloadInterval time.Duration
+
+	// MCP transport (stdio, sse or http), listen address and bearer token for
+	// the HTTP transports, and how long shutdown waits for in-flight requests
+	MCPTransport       string
+	MCPListenAddr      string
+	MCPAuthToken       string
+	MCPShutdownTimeout time.Duration
 }
 
 func Load() (*Config, error) {
@@ -22,6 +70,36 @@ func Load() (*Config, error) {
 		GraphQLEndpoint: getEnv("DISPATCH_GRAPHQL_ENDPOINT", "https://monkey.graph.qa.dispatchfog.io/graphql"),
 		OrganizationID:  getEnv("DISPATCH_ORGANIZATION_ID", ""),
 		UseIDP:          useIDP,
+		EstimateTimeout: getDurationEnv("DISPATCH_ESTIMATE_TIMEOUT", 30*time.Second),
+		OrderTimeout:    getDurationEnv("DISPATCH_ORDER_TIMEOUT", 30*time.Second),
+		AuthTimeout:     getDurationEnv("IDP_TIMEOUT", 30*time.Second),
+		LLMTimeout:      getDurationEnv("LLM_TIMEOUT", 30*time.Second),
+
+		RetryMaxAttempts:        getIntEnv("DISPATCH_RETRY_MAX_ATTEMPTS", 3),
+		RetryBaseDelay:          getDurationEnv("DISPATCH_RETRY_BASE_DELAY", 200*time.Millisecond),
+		RetryMaxDelay:           getDurationEnv("DISPATCH_RETRY_MAX_DELAY", 5*time.Second),
+		BreakerFailureThreshold: getIntEnv("DISPATCH_BREAKER_THRESHOLD", 5),
+		BreakerCooldown:         getDurationEnv("DISPATCH_BREAKER_COOLDOWN", 30*time.Second),
+
+		IdempotencyStorePath:   getEnv("DISPATCH_IDEMPOTENCY_STORE", ""),
+		IdempotencyTTL:         getDurationEnv("DISPATCH_IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyRetryWindow: getDurationEnv("DISPATCH_IDEMPOTENCY_RETRY_WINDOW", 5*time.Minute),
+
+		EstimateTTL: getDurationEnv("DISPATCH_ESTIMATE_TTL", time.Hour),
+
+		CassetteMode: getEnv("DISPATCH_CASSETTE_MODE", "off"),
+		CassetteDir:  getEnv("DISPATCH_CASSETTE_DIR", "testdata/cassettes"),
+
+		MockFixturesPath: getEnv("DISPATCH_MOCK_FIXTURES", ""),
+		MockSeed:         int64(getIntEnv("DISPATCH_MOCK_SEED", 0)),
+
+		PricingRulesPath:           getEnv("DISPATCH_PRICING_RULES", ""),
+		PricingRulesReloadInterval: getDurationEnv("DISPATCH_PRICING_RULES_RELOAD", 30*time.Second),
+
+		MCPTransport:       getEnv("MCP_TRANSPORT", "stdio"),
+		MCPListenAddr:      getEnv("MCP_LISTEN_ADDR", "localhost:8080"),
+		MCPAuthToken:       getEnv("MCP_AUTH_TOKEN", ""),
+		MCPShutdownTimeout: getDurationEnv("MCP_SHUTDOWN_TIMEOUT", 10*time.Second),
 	}
 
 	if useIDP {
@@ -42,3 +120,31 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// getDurationEnv parses a Go duration (e.g. "30s", "2m") from the environment
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+// WithTimeout bounds ctx by timeout. A zero timeout leaves ctx unchanged, and
+// an earlier deadline already on ctx always wins.
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -1,16 +1,19 @@
 package conversation
 
 import (
+	"context"
 	"dispatch-mcp-server/internal/claude"
 	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
 	"dispatch-mcp-server/internal/order"
 	"dispatch-mcp-server/internal/pricing"
 	"dispatch-mcp-server/internal/validation"
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
 	"strings"
-	"time"
+	"unicode"
 )
 
 // ConversationMessage represents a message in the conversation history
@@ -19,6 +22,7 @@ type ConversationMessage = claude.ConversationMessage
 // ClaudeConversationEngine handles natural language pricing conversations using Claude AI
 type ClaudeConversationEngine struct {
 	claudeClient   *claude.Client
+	dispatchClient dispatch.API
 	pricingEngine  *pricing.PricingEngine
 	contextManager *ContextManager
 	useClaude      bool
@@ -55,20 +59,72 @@ func NewClaudeConversationEngine() (*ClaudeConversationEngine, error) {
 	}, nil
 }
 
+// SetDispatchClient sets the Dispatch API used for service area validation
+func (ce *ClaudeConversationEngine) SetDispatchClient(client dispatch.API) {
+	ce.dispatchClient = client
+}
+
+// SetPricingEngine sets the pricing engine used for comparisons and described
+// in the Claude system prompt
+func (ce *ClaudeConversationEngine) SetPricingEngine(engine *pricing.PricingEngine) {
+	ce.pricingEngine = engine
+	if ce.claudeClient != nil {
+		ce.claudeClient.SetPricingEngine(engine)
+	}
+}
+
+// getDispatchClient returns the configured Dispatch API, creating the
+// environment-selected implementation on first use
+func (ce *ClaudeConversationEngine) getDispatchClient() (dispatch.API, error) {
+	if ce.dispatchClient == nil {
+		client, err := dispatch.NewAPI()
+		if err != nil {
+			return nil, err
+		}
+		ce.dispatchClient = client
+	}
+	return ce.dispatchClient, nil
+}
+
 // ProcessMessage processes a natural language message using Claude AI
-func (ce *ClaudeConversationEngine) ProcessMessage(message string, context *ConversationContext) (*ConversationResponse, error) {
-	return ce.ProcessMessageWithHistory(message, context, nil)
+func (ce *ClaudeConversationEngine) ProcessMessage(message string, convContext *ConversationContext) (*ConversationResponse, error) {
+	return ce.ProcessMessageWithHistoryContext(context.Background(), message, convContext, nil)
+}
+
+// ProcessMessageContext is like ProcessMessage but aborts in-flight API calls when ctx is done
+func (ce *ClaudeConversationEngine) ProcessMessageContext(ctx context.Context, message string, convContext *ConversationContext) (*ConversationResponse, error) {
+	return ce.ProcessMessageWithHistoryContext(ctx, message, convContext, nil)
 }
 
 // ProcessMessageWithHistory processes a message with conversation history
-func (ce *ClaudeConversationEngine) ProcessMessageWithHistory(message string, context *ConversationContext, history []ConversationMessage) (*ConversationResponse, error) {
+func (ce *ClaudeConversationEngine) ProcessMessageWithHistory(message string, convContext *ConversationContext, history []ConversationMessage) (*ConversationResponse, error) {
+	return ce.ProcessMessageWithHistoryContext(context.Background(), message, convContext, history)
+}
+
+// ProcessMessageWithHistoryContext processes a message with conversation history.
+// Cancelling ctx aborts the in-flight Dispatch or LLM call and returns ctx.Err().
+func (ce *ClaudeConversationEngine) ProcessMessageWithHistoryContext(ctx context.Context, message string, context *ConversationContext, history []ConversationMessage) (*ConversationResponse, error) {
+	// Orders under review are confirmed and submitted the same way with or without the LLM
+	if context != nil && context.OrderCreation.InProgress && context.OrderCreation.Step == "review" {
+		reply := ce.handleReviewStep(ctx, message, context)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &ConversationResponse{
+			Message:         reply,
+			Recommendations: []PricingRecommendation{},
+			NextQuestions:   []string{},
+			UpdatedContext:  context,
+		}, nil
+	}
+
 	// If Claude is not available, fall back to rule-based processing
 	if !ce.useClaude || ce.claudeClient == nil {
 		return ce.processWithRules(message, context)
 	}
 
 	// Update context with new information from the message FIRST
-	updatedContext := ce.updateContextFromMessage(message, context)
+	updatedContext := ce.updateContextFromMessage(ctx, message, context)
 
 	// Check for validation errors and handle them
 	validationErrorMsg := ce.handleValidationErrors(updatedContext)
@@ -86,8 +142,12 @@ func (ce *ClaudeConversationEngine) ProcessMessageWithHistory(message string, co
 	pricingContext := ce.convertToPricingContext(updatedContext)
 
 	// Get Claude's response with updated context and conversation history
-	claudeResponse, err := ce.claudeClient.CreatePricingAdvisorMessageWithHistory(message, pricingContext, history)
+	claudeResponse, err := ce.claudeClient.CreatePricingAdvisorMessageWithHistory(ctx, message, pricingContext, history)
 	if err != nil {
+		// A cancelled request should not fall back; nobody is waiting for the answer
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		// If Claude fails, fall back to rule-based processing
 		return ce.processWithRules(message, context)
 	}
@@ -171,10 +231,10 @@ func (ce *ClaudeConversationEngine) convertToPricingContext(context *Conversatio
 }
 
 // updateContextFromMessage updates the conversation context based on the message
-func (ce *ClaudeConversationEngine) updateContextFromMessage(message string, context *ConversationContext) *ConversationContext {
+func (ce *ClaudeConversationEngine) updateContextFromMessage(ctx context.Context, message string, context *ConversationContext) *ConversationContext {
 	if context == nil {
 		context = &ConversationContext{
-			SessionID:       fmt.Sprintf("session_%d", time.Now().Unix()),
+			SessionID:       newSessionID(),
 			CustomerProfile: CustomerProfile{Tier: "bronze"},
 			DeliveryHistory: []DeliveryRequirement{},
 			PricingHistory:  []PricingComparison{},
@@ -273,13 +333,13 @@ func (ce *ClaudeConversationEngine) updateContextFromMessage(message string, con
 	ce.updateOrderCreationProgress(message, context)
 
 	// Also try to parse pickup and delivery information if not in formal order creation mode
-	ce.parseOrderInformation(message, context)
+	ce.parseOrderInformation(ctx, message, context)
 
 	return context
 }
 
 // parseOrderInformation parses pickup and delivery information from messages
-func (ce *ClaudeConversationEngine) parseOrderInformation(message string, context *ConversationContext) {
+func (ce *ClaudeConversationEngine) parseOrderInformation(ctx context.Context, message string, context *ConversationContext) {
 	// Initialize order creation if not started
 	if !context.OrderCreation.InProgress {
 		// Check if this looks like address information
@@ -306,9 +366,14 @@ func (ce *ClaudeConversationEngine) parseOrderInformation(message string, contex
 
 				// After adding delivery info, validate service area
 				if len(context.OrderCreation.DropOffs) > 0 {
-					if err := ce.validateServiceArea(context); err != nil {
-						context.OrderCreation.ValidationErrors = append(context.OrderCreation.ValidationErrors,
-							fmt.Sprintf("Service area validation failed: %s", err.Error()))
+					if err := ce.validateServiceArea(ctx, context); err != nil {
+						if dispatch.IsKind(err, dispatch.ErrorKindValidation) || errors.Is(err, errNoDeliveryOptions) {
+							context.OrderCreation.ValidationErrors = append(context.OrderCreation.ValidationErrors,
+								fmt.Sprintf("Service area validation failed: %s", err.Error()))
+						} else {
+							context.OrderCreation.ValidationErrors = append(context.OrderCreation.ValidationErrors,
+								fmt.Sprintf("Dispatch API unavailable: %s", err.Error()))
+						}
 					}
 				}
 			}
@@ -501,6 +566,8 @@ func (ce *ClaudeConversationEngine) handleValidationErrors(context *Conversation
 			errorMessages = append(errorMessages, "Please provide a valid 2-letter state code (e.g., CA, NY, TX)")
 		} else if strings.Contains(error, "required") {
 			errorMessages = append(errorMessages, "Please provide a complete address with street, city, state, and zip code")
+		} else if strings.Contains(error, "Dispatch API unavailable") {
+			errorMessages = append(errorMessages, "I couldn't reach Dispatch to confirm this address is in our service area. Please try again in a moment.")
 		} else if strings.Contains(error, "Service area validation failed") {
 			errorMessages = append(errorMessages, "Sorry, we don't currently deliver to this location. Please try a different address or contact support for service area information.")
 		} else if strings.Contains(error, "no delivery options available") {
@@ -516,8 +583,11 @@ func (ce *ClaudeConversationEngine) handleValidationErrors(context *Conversation
 	return strings.Join(errorMessages, "\n")
 }
 
+// errNoDeliveryOptions is returned when an estimate succeeds but offers no delivery options
+var errNoDeliveryOptions = errors.New("no delivery options available for this location")
+
 // validateServiceArea checks if locations are in Dispatch service area using CreateEstimate
-func (ce *ClaudeConversationEngine) validateServiceArea(context *ConversationContext) error {
+func (ce *ClaudeConversationEngine) validateServiceArea(ctx context.Context, context *ConversationContext) error {
 	// Only validate if we have both pickup and delivery info
 	if context.OrderCreation.PickupInfo == nil || len(context.OrderCreation.DropOffs) == 0 {
 		return nil // Not ready for validation
@@ -544,21 +614,21 @@ func (ce *ClaudeConversationEngine) validateServiceArea(context *ConversationCon
 		VehicleType: "cargo_van", // Default vehicle type for validation
 	}
 
-	// Create Dispatch client
-	client, err := dispatch.NewClient()
+	// Get Dispatch client
+	client, err := ce.getDispatchClient()
 	if err != nil {
 		return fmt.Errorf("failed to create Dispatch client: %v", err)
 	}
 
-	// Try to create estimate - if this fails, location might be out of service area
-	response, err := client.CreateEstimate(input)
+	// Try to create estimate - a validation error means the location is out of service area
+	response, err := client.CreateEstimate(ctx, input)
 	if err != nil {
-		return fmt.Errorf("service area validation failed: %v", err)
+		return fmt.Errorf("service area validation failed: %w", err)
 	}
 
 	// Check if we got valid delivery options
 	if len(response.Data.CreateEstimate.Estimate.AvailableOrderOptions) == 0 {
-		return fmt.Errorf("no delivery options available for this location")
+		return errNoDeliveryOptions
 	}
 
 	return nil
@@ -656,8 +726,6 @@ func (ce *ClaudeConversationEngine) updateOrderCreationProgress(message string,
 		ce.handlePickupStep(message, context)
 	case "deliveries":
 		ce.handleDeliveriesStep(message, context)
-	case "review":
-		ce.handleReviewStep(message, context)
 	}
 }
 
@@ -725,13 +793,13 @@ func (ce *ClaudeConversationEngine) generateRecommendations(context *Conversatio
 		DeliveryCount:   context.CustomerProfile.CurrentDeliveryCount,
 		CustomerTier:    context.CustomerProfile.Tier,
 		OrderFrequency:  context.CustomerProfile.OrderFrequency,
-		TotalOrderValue: context.CustomerProfile.AverageOrderValue,
+		TotalOrderValue: money.Dollars(context.CustomerProfile.AverageOrderValue),
 		IsBulkOrder:     false,
 	}
 
 	// Create a sample estimate for comparison
 	sampleEstimate := &dispatch.AvailableOrderOption{
-		EstimatedOrderCost: 50.0, // Sample cost
+		EstimatedOrderCost: money.Dollars(50), // Sample cost
 	}
 
 	// Get pricing comparison
@@ -743,7 +811,7 @@ func (ce *ClaudeConversationEngine) generateRecommendations(context *Conversatio
 		recommendations = append(recommendations, PricingRecommendation{
 			Model:          string(result.Model),
 			Name:           result.Name,
-			Savings:        result.Savings,
+			Savings:        result.Savings.Float64(),
 			SavingsPercent: result.DiscountPercent,
 			Eligible:       result.Eligible,
 			Reason:         result.Reason,
@@ -980,7 +1048,7 @@ func (ce *ClaudeConversationEngine) parseSchedulingInfo(message string, context
 }
 
 // parseStepInformation parses information based on the current step
-func (ce *ClaudeConversationEngine) parseStepInformation(message string, context *ConversationContext) {
+func (ce *ClaudeConversationEngine) parseStepInformation(ctx context.Context, message string, context *ConversationContext) {
 	if !context.OrderCreation.InProgress {
 		return
 	}
@@ -989,9 +1057,9 @@ func (ce *ClaudeConversationEngine) parseStepInformation(message string, context
 	case "multi_stop":
 		ce.parseMultiStopInfo(message, context)
 	case "pickup":
-		ce.parseOrderInformation(message, context) // Existing pickup/delivery parsing
+		ce.parseOrderInformation(ctx, message, context) // Existing pickup/delivery parsing
 	case "drop_off":
-		ce.parseOrderInformation(message, context) // Existing pickup/delivery parsing
+		ce.parseOrderInformation(ctx, message, context) // Existing pickup/delivery parsing
 	case "vehicle":
 		ce.parseVehicleInfo(message, context)
 	case "add_ons":
@@ -1003,59 +1071,108 @@ func (ce *ClaudeConversationEngine) parseStepInformation(message string, context
 	}
 }
 
-// handleReviewStep handles the review step and order creation
-func (ce *ClaudeConversationEngine) handleReviewStep(message string, context *ConversationContext) string {
-	// Generate order summary
-	summary := ce.generateOrderSummary(context)
-
-	if strings.Contains(strings.ToLower(message), "yes") ||
-		strings.Contains(strings.ToLower(message), "create") ||
-		strings.Contains(strings.ToLower(message), "confirm") {
-
-		// Convert context to order creation input
-		// Convert VehicleTypeInfo to order.VehicleTypeInfo
-		var orderVehicleType *order.VehicleTypeInfo
-		if context.OrderCreation.VehicleType != nil {
-			orderVehicleType = &order.VehicleTypeInfo{
-				VehicleTypeID:   context.OrderCreation.VehicleType.VehicleTypeID,
-				VehicleTypeName: context.OrderCreation.VehicleType.VehicleTypeName,
-				CustomTypes:     context.OrderCreation.VehicleType.CustomTypes,
-			}
-		}
+// handleReviewStep shows the order summary until the customer confirms, then
+// submits the order with the order mutation, which carries the vehicle,
+// capabilities and schedule collected in the conversation
+func (ce *ClaudeConversationEngine) handleReviewStep(ctx context.Context, message string, context *ConversationContext) string {
+	if !isOrderConfirmation(message) {
+		return ce.generateOrderSummary(context) + "\n\nShould I create this order for you?"
+	}
 
-		// Convert SchedulingInfo to order.SchedulingInput
-		var orderScheduling *order.SchedulingInput
-		if context.OrderCreation.SchedulingInfo != nil {
-			orderScheduling = &order.SchedulingInput{
-				PickupTime:   context.OrderCreation.SchedulingInfo.PickupTime,
-				DeliveryTime: context.OrderCreation.SchedulingInfo.DeliveryTime,
-				PickupDate:   context.OrderCreation.SchedulingInfo.PickupDate,
-				DeliveryDate: context.OrderCreation.SchedulingInfo.DeliveryDate,
-				TimeZone:     context.OrderCreation.SchedulingInfo.TimeZone,
-			}
-		}
+	client, err := ce.getDispatchClient()
+	if err != nil {
+		return fmt.Sprintf("I couldn't connect to Dispatch to create your order: %v. Please try again in a moment.", err)
+	}
 
-		orderInput := order.ConvertFromDispatchTypes(
-			context.OrderCreation.PickupInfo,
-			context.OrderCreation.DropOffs,
-			orderVehicleType,
-			context.OrderCreation.Capabilities,
-			orderScheduling,
-		)
+	// The order's idempotency key is derived from its contents and this
+	// conversation's session and isn't sent to Dispatch. Confirming the same
+	// order again within the retry window (DISPATCH_IDEMPOTENCY_RETRY_WINDOW)
+	// returns the order already created; after that it creates another.
+	if context.SessionID == "" {
+		context.SessionID = newSessionID()
+	}
+	orderCreator := order.NewOrderCreatorWithBackend(order.NewMutationBackend(client))
+	result, err := orderCreator.CreateOrderInSession(ctx, context.SessionID, context.OrderCreation.ToOrder())
+	if err != nil {
+		return describeOrderError(err)
+	}
 
-		// Create the order using GraphQL
-		orderCreator := order.NewOrderCreator(os.Getenv("GRAPHQL_ENDPOINT"))
-		result, err := orderCreator.CreateOrder(orderInput)
+	context.SubmittedOrder = &SubmittedOrder{
+		OrderID:        result.Order.ID,
+		TrackingNumber: result.Order.TrackingNumber,
+		Status:         result.Order.Status,
+		TotalPrice:     result.Order.Pricing.TotalPrice,
+		IdempotencyKey: result.IdempotencyKey,
+	}
+	context.OrderCreation.InProgress = false
+	context.OrderCreation.Step = "submitted"
+	context.OrderCreation.CurrentQuestion = ""
 
-		if err != nil {
-			return fmt.Sprintf("I encountered an error creating your order: %v. Please try again or contact support.", err)
+	reply := fmt.Sprintf("🎉 Order created successfully!\n\nOrder ID: %s", result.Order.ID)
+	if result.Order.TrackingNumber != "" {
+		reply += fmt.Sprintf("\nTracking Number: %s", result.Order.TrackingNumber)
+	}
+	return reply + fmt.Sprintf("\nTotal Price: $%s", result.Order.Pricing.TotalPrice.Decimal())
+}
+
+// confirmationWords are the words a reply confirming the order may be made of;
+// affirmativeWords must appear in it at least once
+var (
+	confirmationWords = map[string]bool{
+		"yes": true, "y": true, "yep": true, "yeah": true, "yup": true, "sure": true, "ok": true, "okay": true,
+		"confirm": true, "confirmed": true, "create": true, "place": true, "submit": true, "book": true, "go": true, "ahead": true,
+		"it": true, "the": true, "this": true, "that": true, "order": true, "please": true, "do": true,
+		"looks": true, "sounds": true, "good": true, "great": true, "correct": true, "perfect": true, "thanks": true, "thank": true, "you": true,
+	}
+	affirmativeWords = map[string]bool{
+		"yes": true, "y": true, "yep": true, "yeah": true, "yup": true, "sure": true, "ok": true, "okay": true,
+		"confirm": true, "confirmed": true, "create": true, "place": true, "submit": true, "book": true, "go": true,
+	}
+)
+
+// isOrderConfirmation reports whether message is an explicit go-ahead such as
+// "Yes, create it". Replies with anything else in them, like "No, don't create
+// it yet" or "yesterday", leave the order in review.
+func isOrderConfirmation(message string) bool {
+	words := strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	affirmed := false
+	for _, word := range words {
+		if !confirmationWords[word] {
+			return false
 		}
+		affirmed = affirmed || affirmativeWords[word]
+	}
+	return affirmed
+}
 
-		return fmt.Sprintf("🎉 Order created successfully!\n\nOrder ID: %s\nDruid: %s\nTotal Price: $%.2f\n\nYou'll receive a confirmation email shortly.",
-			result.Order.ID, result.Order.Druid, result.Order.Pricing.TotalPrice)
+// describeOrderError turns a failed order submission into a reply that names
+// each field the customer needs to fix
+func describeOrderError(err error) string {
+	var fields []string
+
+	var validationErr *order.ValidationError
+	var apiErr *dispatch.APIError
+	switch {
+	case errors.As(err, &validationErr):
+		for _, fieldErr := range validationErr.Errors {
+			fields = append(fields, fmt.Sprintf("- %s: %s", fieldErr.Field, fieldErr.Message))
+		}
+	case errors.As(err, &apiErr) && apiErr.Kind == dispatch.ErrorKindValidation:
+		for _, gqlErr := range apiErr.GraphQLErrors {
+			if path := gqlErr.PathString(); path != "" {
+				fields = append(fields, fmt.Sprintf("- %s: %s", path, gqlErr.Message))
+			} else {
+				fields = append(fields, "- "+gqlErr.Message)
+			}
+		}
 	}
 
-	return summary + "\n\nShould I create this order for you?"
+	if len(fields) == 0 {
+		return fmt.Sprintf("I encountered an error creating your order: %v. Please try again or contact support.", err)
+	}
+	return "I couldn't create your order yet. Please correct the following:\n" + strings.Join(fields, "\n")
 }
 
 // generateOrderSummary generates a summary of the order for review
@@ -1,6 +1,8 @@
 package conversation
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -22,7 +24,7 @@ func NewContextManager() *ContextManager {
 func (cm *ContextManager) Update(context *ConversationContext, intent *Intent) *ConversationContext {
 	if context == nil {
 		context = &ConversationContext{
-			SessionID: fmt.Sprintf("session_%d", time.Now().Unix()),
+			SessionID: newSessionID(),
 			CustomerProfile: CustomerProfile{
 				Tier: "bronze", // Default tier
 			},
@@ -184,3 +186,11 @@ func (cm *ContextManager) GetSessionStats() map[string]interface{} {
 
 	return stats
 }
+
+// newSessionID returns a session ID unique to one conversation. Orders are
+// deduplicated per session, so two conversations must never share one.
+func newSessionID() string {
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	return fmt.Sprintf("session_%d_%s", time.Now().Unix(), hex.EncodeToString(suffix))
+}
@@ -2,6 +2,7 @@ package conversation
 
 import (
 	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
 	"dispatch-mcp-server/internal/pricing"
 	"fmt"
 	"regexp"
@@ -53,6 +54,16 @@ type ConversationContext struct {
 	CurrentGoal     string                `json:"current_goal"`
 	Preferences     CustomerPreferences   `json:"preferences"`
 	OrderCreation   OrderCreationState    `json:"order_creation"`
+	SubmittedOrder  *SubmittedOrder       `json:"submitted_order,omitempty"`
+}
+
+// SubmittedOrder is the order created when the customer confirmed the review step
+type SubmittedOrder struct {
+	OrderID        string      `json:"order_id"`
+	TrackingNumber string      `json:"tracking_number,omitempty"`
+	Status         string      `json:"status"`
+	TotalPrice     money.Money `json:"total_price"`
+	IdempotencyKey string      `json:"idempotency_key,omitempty"`
 }
 
 // OrderCreationState tracks the progress of order creation
@@ -148,7 +159,7 @@ type ServiceLevelInfo struct {
 // BillingInfo represents billing information
 type BillingInfo struct {
 	BillingMethod     string        `json:"billing_method"` // "account", "credit_card", "invoice", "cod"
-	BillingAddress    *AddressInput `json:"billing_address,omitempty"`
+	BillingAddress    *dispatch.AddressInput `json:"billing_address,omitempty"`
 	PaymentTerms      string        `json:"payment_terms,omitempty"`
 	ContactEmail      string        `json:"contact_email"`
 	NotificationPhone string        `json:"notification_phone,omitempty"`
@@ -352,7 +363,7 @@ func (ce *ConversationEngine) generateRecommendations(context *ConversationConte
 		DeliveryCount:   context.CustomerProfile.OrderFrequency,
 		CustomerTier:    context.CustomerProfile.Tier,
 		OrderFrequency:  context.CustomerProfile.OrderFrequency,
-		TotalOrderValue: context.CustomerProfile.AverageOrderValue,
+		TotalOrderValue: money.Dollars(context.CustomerProfile.AverageOrderValue),
 		IsBulkOrder:     false, // Can be enhanced based on conversation
 	}
 
@@ -363,7 +374,7 @@ func (ce *ConversationEngine) generateRecommendations(context *ConversationConte
 	for _, model := range models {
 		// Create a sample estimate for comparison
 		sampleEstimate := &dispatch.AvailableOrderOption{
-			EstimatedOrderCost: 50.0, // Sample cost
+			EstimatedOrderCost: money.Dollars(50), // Sample cost
 		}
 
 		// Compare pricing models
@@ -375,7 +386,7 @@ func (ce *ConversationEngine) generateRecommendations(context *ConversationConte
 				recommendations = append(recommendations, PricingRecommendation{
 					Model:          string(result.Model),
 					Name:           result.Name,
-					Savings:        result.Savings,
+					Savings:        result.Savings.Float64(),
 					SavingsPercent: result.DiscountPercent,
 					Eligible:       result.Eligible,
 					Reason:         result.Reason,
@@ -500,7 +511,10 @@ func (ce *ConversationEngine) generateExplorationResponse(recommendations []Pric
 func (ce *ConversationEngine) generateDeliveryRequirementsResponse(intent *Intent, context *ConversationContext) string {
 	deliveryCount := intent.Entities["delivery_count"]
 	if deliveryCount != "" {
-		return fmt.Sprintf("Great! %s deliveries gives you access to our Multi-Delivery Discount (15%% off). Would you like to see all your pricing options?", deliveryCount)
+		if rule, ok := ce.pricingEngine.Rules().Rule(pricing.MultiDeliveryPricing); ok {
+			return fmt.Sprintf("Great! %s deliveries gives you access to our %s (%.0f%% off). Would you like to see all your pricing options?", deliveryCount, rule.Name, rule.DiscountPercent())
+		}
+		return fmt.Sprintf("Great! %s deliveries noted. Would you like to see all your pricing options?", deliveryCount)
 	}
 
 	return "I'd love to help you with your delivery needs! How many deliveries are you planning?"
@@ -510,7 +524,10 @@ func (ce *ConversationEngine) generateDeliveryRequirementsResponse(intent *Inten
 func (ce *ConversationEngine) generateCustomerTierResponse(intent *Intent, context *ConversationContext) string {
 	tier := intent.Entities["customer_tier"]
 	if tier != "" {
-		return fmt.Sprintf("Excellent! Your %s tier status gives you access to our Loyalty Discount (10%% off). Let me show you all available pricing options.", tier)
+		if rule, ok := ce.pricingEngine.Rules().Rule(pricing.LoyaltyDiscountPricing); ok {
+			return fmt.Sprintf("Excellent! Your %s tier status gives you access to our %s (%.0f%% off). Let me show you all available pricing options.", tier, rule.Name, rule.DiscountPercent())
+		}
+		return fmt.Sprintf("Thanks! Let me show you all available pricing options for %s tier.", tier)
 	}
 
 	return "What's your customer tier? This helps me find the best pricing options for you."
@@ -0,0 +1,47 @@
+package conversation
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/order"
+)
+
+// ToOrder converts the information collected so far into the canonical order
+// model. Missing details are left empty; order.Order.Validate reports them.
+func (s *OrderCreationState) ToOrder() *order.Order {
+	o := order.FromDispatchInput(s.dispatchInput())
+
+	if s.VehicleType != nil && s.VehicleType.VehicleTypeID != "" {
+		o.VehicleType = s.VehicleType.VehicleTypeID
+	}
+	if s.ServiceLevel != nil && s.ServiceLevel.ServiceLevel != "" {
+		o.ServiceType = s.ServiceLevel.ServiceLevel
+	}
+	if s.OrganizationInfo != nil && s.OrganizationInfo.OrganizationID != "" {
+		o.OrganizationID = s.OrganizationInfo.OrganizationID
+	}
+	o.Capabilities = s.Capabilities
+
+	if s.SchedulingInfo != nil {
+		o.Schedule = &order.Schedule{
+			PickupDate:   s.SchedulingInfo.PickupDate,
+			PickupTime:   s.SchedulingInfo.PickupTime,
+			DeliveryDate: s.SchedulingInfo.DeliveryDate,
+			DeliveryTime: s.SchedulingInfo.DeliveryTime,
+			TimeZone:     s.SchedulingInfo.TimeZone,
+		}
+	}
+
+	return o
+}
+
+// dispatchInput assembles the Dispatch-shaped parts of the state
+func (s *OrderCreationState) dispatchInput() dispatch.CreateOrderInput {
+	input := dispatch.CreateOrderInput{DropOffs: s.DropOffs}
+	if s.PickupInfo != nil {
+		input.PickupInfo = *s.PickupInfo
+	}
+	if s.DeliveryInfo != nil {
+		input.DeliveryInfo = *s.DeliveryInfo
+	}
+	return input
+}
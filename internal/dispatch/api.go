@@ -0,0 +1,59 @@
+package dispatch
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/cassette"
+	"dispatch-mcp-server/internal/config"
+)
+
+// API is the set of Dispatch operations used by the MCP server, the
+// conversation engine and the CLI. Client talks to the real GraphQL API and
+// MockClient returns fixture-driven responses; callers should depend on this
+// interface so fakes, recorders or decorators can be injected. Every call
+// honours cancellation and deadlines on the supplied context.
+type API interface {
+	CreateEstimate(ctx context.Context, input CreateEstimateInput) (*CreateEstimateResponse, error)
+	CreateOrder(ctx context.Context, input CreateOrderInput) (*CreateOrderResponse, error)
+	GetOrder(ctx context.Context, id string) (*GetOrderResponse, error)
+	ListOrders(ctx context.Context, input ListOrdersInput) (*ListOrdersResponse, error)
+	CancelOrder(ctx context.Context, input CancelOrderInput) (*CancelOrderResponse, error)
+	UpdateOrder(ctx context.Context, input UpdateOrderInput) (*UpdateOrderResponse, error)
+	ValidateOrder(ctx context.Context, input *OrderCreationInput) (*OrderValidation, error)
+	SubmitOrder(ctx context.Context, input *OrderCreationInput) (*OrderSubmission, error)
+}
+
+// Compile-time checks that both implementations satisfy API
+var (
+	_ API = (*Client)(nil)
+	_ API = (*MockClient)(nil)
+)
+
+// NewAPI returns the API implementation selected by the current configuration:
+// MockClient when no credentials are configured, otherwise the GraphQL Client.
+func NewAPI() (API, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAPIWithConfig(cfg)
+}
+
+// NewAPIWithConfig is like NewAPI but uses the supplied configuration
+func NewAPIWithConfig(cfg *config.Config) (API, error) {
+	if UseMockMode(cfg) {
+		return NewMockClientWithConfig(cfg)
+	}
+
+	return NewClientWithConfig(cfg)
+}
+
+// UseMockMode reports whether the configuration has no credentials and the
+// mock implementation should be used for demo purposes. Replaying a cassette
+// needs no credentials, so it always uses the GraphQL Client.
+func UseMockMode(cfg *config.Config) bool {
+	if cassette.IsReplay(cfg) {
+		return false
+	}
+	return cfg.AuthToken == "" && !cfg.UseIDP
+}
@@ -0,0 +1,68 @@
+package dispatch
+
+import "strings"
+
+// VehicleInfo describes a vehicle type and the capabilities it supports
+type VehicleInfo struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// Capability is a special service that can be added to an order
+type Capability struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Category    string  `json:"category"`
+}
+
+// Capabilities lists the special services the conversation engine asks about
+var Capabilities = []Capability{
+	{ID: "signature_required", Name: "Signature Required", Description: "Recipient signs on delivery", Price: 2.50, Category: "proof_of_delivery"},
+	{ID: "fragile_handling", Name: "Fragile Handling", Description: "Extra care for breakable items", Price: 7.50, Category: "handling"},
+	{ID: "unloading_assistance", Name: "Unloading Assistance", Description: "Driver helps unload at each stop", Price: 15.00, Category: "handling"},
+	{ID: "white_glove_service", Name: "White Glove Service", Description: "Inside delivery, placement and packaging removal", Price: 45.00, Category: "handling"},
+	{ID: "temperature_control", Name: "Temperature Control", Description: "Refrigerated or heated cargo area", Price: 30.00, Category: "equipment"},
+}
+
+// vehicleCatalog describes the vehicle types used by the built-in fixtures
+var vehicleCatalog = map[string]VehicleInfo{
+	"car":          {Name: "Car", Description: "Small parcels and documents", Capabilities: []string{"signature_required"}},
+	"cargo_van":    {Name: "Cargo Van", Description: "Up to 100 cubic feet and 1,500 lbs", Capabilities: []string{"signature_required", "fragile_handling", "unloading_assistance"}},
+	"sprinter_van": {Name: "Sprinter Van", Description: "Up to 350 cubic feet and 3,500 lbs", Capabilities: []string{"signature_required", "fragile_handling", "unloading_assistance", "temperature_control"}},
+	"pickup_truck": {Name: "Pickup Truck", Description: "Open bed for bulky or oversized items", Capabilities: []string{"signature_required", "unloading_assistance"}},
+	"box_truck":    {Name: "Box Truck", Description: "Palletized freight with liftgate", Capabilities: []string{"signature_required", "fragile_handling", "unloading_assistance", "white_glove_service", "temperature_control"}},
+}
+
+// LookupVehicle describes vehicle type id. Types outside the catalog, such as
+// ones added by fixture files, get a name generated from the ID.
+func LookupVehicle(id string) VehicleInfo {
+	vehicle, ok := vehicleCatalog[id]
+	if !ok {
+		vehicle = VehicleInfo{Name: displayName(id), Capabilities: []string{}}
+	}
+	vehicle.ID = id
+	return vehicle
+}
+
+// LookupCapability returns the capability with the given ID
+func LookupCapability(id string) (Capability, bool) {
+	for _, capability := range Capabilities {
+		if capability.ID == id {
+			return capability, true
+		}
+	}
+	return Capability{}, false
+}
+
+// displayName turns an identifier like "flatbed_truck" into "Flatbed Truck"
+func displayName(id string) string {
+	words := strings.Fields(strings.ReplaceAll(id, "_", " "))
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
@@ -1,50 +1,52 @@
 package dispatch
 
 import (
+	"context"
 	"dispatch-mcp-server/internal/auth"
+	"dispatch-mcp-server/internal/cassette"
 	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/resilience"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/go-resty/resty/v2"
 )
 
+// Client calls the Dispatch GraphQL API
 type Client struct {
 	client     *resty.Client
 	config     *config.Config
 	authClient *auth.Client
-	mockClient *MockClient
+	retry      resilience.Policy
+	breaker    *resilience.Breaker
 }
 
+// NewClient creates a GraphQL client using the configuration from the environment
 func NewClient() (*Client, error) {
 	cfg, err := config.Load()
 	if err != nil {
 		return nil, err
 	}
 
-	// Check if we should use mock mode (when no auth token is provided)
-	useMockMode := cfg.AuthToken == "" && !cfg.UseIDP
-
-	if useMockMode {
-		// Return a mock client for demo purposes
-		mockClient, err := NewMockClient()
-		if err != nil {
-			return nil, err
-		}
-
-		// Create a wrapper that implements the same interface
-		return &Client{
-			client:     nil, // Not used in mock mode
-			config:     cfg,
-			authClient: nil,
-			mockClient: mockClient,
-		}, nil
-	}
+	return NewClientWithConfig(cfg)
+}
 
+// NewClientWithConfig creates a GraphQL client using the supplied configuration
+func NewClientWithConfig(cfg *config.Config) (*Client, error) {
 	client := resty.New()
 	client.SetBaseURL(cfg.GraphQLEndpoint)
 	client.SetHeader("Content-Type", "application/json")
 
+	// Record or replay traffic when a cassette mode is configured
+	transport, err := cassette.Transport(cfg, "dispatch", cassette.GraphQLMatcher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up cassette: %v", err)
+	}
+	if transport != nil {
+		client.SetTransport(transport)
+	}
+
 	// Set up authentication
 	var authClient *auth.Client
 	if cfg.UseIDP {
@@ -61,22 +63,25 @@ func NewClient() (*Client, error) {
 		client:     client,
 		config:     cfg,
 		authClient: authClient,
-		mockClient: nil,
+		retry:      resilience.PolicyFromConfig(cfg),
+		breaker:    resilience.DefaultRegistry.Breaker(cfg.GraphQLEndpoint, resilience.BreakerSettingsFromConfig(cfg)),
 	}, nil
 }
 
-func (c *Client) getAuthToken() (string, error) {
+func (c *Client) getAuthToken(ctx context.Context) (string, error) {
+	// Replayed traffic never reaches the API, so don't fetch a real token
+	if cassette.IsReplay(c.config) {
+		return c.config.AuthToken, nil
+	}
 	if c.config.UseIDP && c.authClient != nil {
-		return c.authClient.GetValidToken()
+		ctx, cancel := config.WithTimeout(ctx, c.config.AuthTimeout)
+		defer cancel()
+		return c.authClient.GetValidToken(ctx)
 	}
 	return c.config.AuthToken, nil
 }
 
-func (c *Client) CreateEstimate(input CreateEstimateInput) (*CreateEstimateResponse, error) {
-	// Use mock client if available
-	if c.mockClient != nil {
-		return c.mockClient.CreateEstimate(input)
-	}
+func (c *Client) CreateEstimate(ctx context.Context, input CreateEstimateInput) (*CreateEstimateResponse, error) {
 	query := `
 		mutation CreateEstimate($input: CreateEstimateInput!) {
 			createEstimate(input: $input) {
@@ -111,43 +116,20 @@ func (c *Client) CreateEstimate(input CreateEstimateInput) (*CreateEstimateRespo
 		}
 	`
 
-	variables := map[string]interface{}{
-		"input": input,
-	}
-
-	requestBody := map[string]interface{}{
-		"query":     query,
-		"variables": variables,
-	}
-
-	// Get auth token
-	authToken, err := c.getAuthToken()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get auth token: %v", err)
-	}
-
-	resp, err := c.client.R().
-		SetHeader("Authorization", "Bearer "+authToken).
-		SetBody(requestBody).
-		Post("")
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to create estimate: %v", err)
-	}
+	ctx, cancel := config.WithTimeout(ctx, c.config.EstimateTimeout)
+	defer cancel()
 
 	var response CreateEstimateResponse
-	if err := json.Unmarshal(resp.Body(), &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v", err)
+	// Estimates have no side effects and are always safe to retry
+	call := graphQLCall{operation: "createEstimate", query: query, variables: map[string]interface{}{"input": input}, retryable: true}
+	if err := c.execute(ctx, call, &response); err != nil {
+		return nil, err
 	}
 
 	return &response, nil
 }
 
-func (c *Client) CreateOrder(input CreateOrderInput) (*CreateOrderResponse, error) {
-	// Use mock client if available
-	if c.mockClient != nil {
-		return c.mockClient.CreateOrder(input)
-	}
+func (c *Client) CreateOrder(ctx context.Context, input CreateOrderInput) (*CreateOrderResponse, error) {
 	query := `
 		mutation CreateOrder($input: CreateOrderInput!) {
 			createOrder(input: $input) {
@@ -163,34 +145,260 @@ func (c *Client) CreateOrder(input CreateOrderInput) (*CreateOrderResponse, erro
 		}
 	`
 
-	variables := map[string]interface{}{
-		"input": input,
+	ctx, cancel := config.WithTimeout(ctx, c.config.OrderTimeout)
+	defer cancel()
+
+	var response CreateOrderResponse
+	// Retrying an order without an idempotency key could book it twice
+	call := graphQLCall{
+		operation:      "createOrder",
+		query:          query,
+		variables:      map[string]interface{}{"input": input},
+		retryable:      input.IdempotencyKey != "",
+		idempotencyKey: input.IdempotencyKey,
+	}
+	if err := c.execute(ctx, call, &response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// orderSelection selects every Order field for the order queries and
+// lifecycle mutations
+const orderSelection = `
+	id
+	status
+	scheduledAt
+	totalCost
+	trackingNumber
+	estimatedArrival
+	serviceType
+	createdAt
+	pickup { businessName contactName contactPhoneNumber notes address { street city state zip_code country } }
+	dropOffs { businessName contactName contactPhoneNumber notes address { street city state zip_code country } }
+	statusHistory { status at note eta }
+	driver { name phoneNumber vehicle }
+	tracking { lat lng updatedAt estimatedArrival }
+`
+
+func (c *Client) GetOrder(ctx context.Context, id string) (*GetOrderResponse, error) {
+	query := `
+		query GetOrder($id: ID!) {
+			order(id: $id) {` + orderSelection + `}
+		}
+	`
+
+	ctx, cancel := config.WithTimeout(ctx, c.config.OrderTimeout)
+	defer cancel()
+
+	var response GetOrderResponse
+	call := graphQLCall{operation: "order", query: query, variables: map[string]interface{}{"id": id}, retryable: true}
+	if err := c.execute(ctx, call, &response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (c *Client) ListOrders(ctx context.Context, input ListOrdersInput) (*ListOrdersResponse, error) {
+	query := `
+		query ListOrders($input: ListOrdersInput) {
+			orders(input: $input) {` + orderSelection + `}
+		}
+	`
+
+	ctx, cancel := config.WithTimeout(ctx, c.config.OrderTimeout)
+	defer cancel()
+
+	var response ListOrdersResponse
+	call := graphQLCall{operation: "orders", query: query, variables: map[string]interface{}{"input": input}, retryable: true}
+	if err := c.execute(ctx, call, &response); err != nil {
+		return nil, err
 	}
 
+	return &response, nil
+}
+
+func (c *Client) CancelOrder(ctx context.Context, input CancelOrderInput) (*CancelOrderResponse, error) {
+	query := `
+		mutation CancelOrder($input: CancelOrderInput!) {
+			cancelOrder(input: $input) {
+				order {` + orderSelection + `}
+			}
+		}
+	`
+
+	ctx, cancel := config.WithTimeout(ctx, c.config.OrderTimeout)
+	defer cancel()
+
+	var response CancelOrderResponse
+	// The API takes no idempotency key for cancellations and doesn't promise
+	// that cancelling twice succeeds, so only retry a request it never acted on
+	call := graphQLCall{operation: "cancelOrder", query: query, variables: map[string]interface{}{"input": input}, retryUnsent: true}
+	if err := c.execute(ctx, call, &response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (c *Client) UpdateOrder(ctx context.Context, input UpdateOrderInput) (*UpdateOrderResponse, error) {
+	query := `
+		mutation UpdateOrder($input: UpdateOrderInput!) {
+			updateOrder(input: $input) {
+				order {` + orderSelection + `}
+			}
+		}
+	`
+
+	ctx, cancel := config.WithTimeout(ctx, c.config.OrderTimeout)
+	defer cancel()
+
+	var response UpdateOrderResponse
+	// The API takes no idempotency key for updates, and repeating one that was
+	// applied could undo a change made in between, so only retry a request it
+	// never acted on
+	call := graphQLCall{operation: "updateOrder", query: query, variables: map[string]interface{}{"input": input}, retryUnsent: true}
+	if err := c.execute(ctx, call, &response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Execute runs a GraphQL operation that has no method of its own, such as
+// the order mutations in internal/order, with the client's auth, timeouts,
+// retries and circuit breaker. out receives the whole response body. Only
+// operations without side effects should be marked retryable.
+func (c *Client) Execute(ctx context.Context, operation, query string, variables map[string]interface{}, retryable bool, out interface{}) error {
+	ctx, cancel := config.WithTimeout(ctx, c.config.OrderTimeout)
+	defer cancel()
+
+	return c.execute(ctx, graphQLCall{operation: operation, query: query, variables: variables, retryable: retryable}, out)
+}
+
+// graphQLEnvelope is the top-level shape of every GraphQL response
+type graphQLEnvelope struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []GraphQLError  `json:"errors"`
+}
+
+// graphQLCall describes a single GraphQL operation and how it may be
+// retried: after any transient failure when retryable, otherwise only after
+// failures the API never acted on (see NotSent) when retryUnsent
+type graphQLCall struct {
+	operation      string
+	query          string
+	variables      map[string]interface{}
+	retryable      bool
+	retryUnsent    bool
+	idempotencyKey string
+}
+
+// execute runs call under the client's retry policy and the endpoint's
+// circuit breaker. Non-retryable calls are still counted by the breaker.
+func (c *Client) execute(ctx context.Context, call graphQLCall, out interface{}) error {
+	policy := c.retry
+	switch {
+	case call.retryable:
+	case call.retryUnsent:
+		policy = policy.RetryOnly(NotSent)
+	default:
+		policy = policy.NoRetry()
+	}
+
+	err := resilience.Do(ctx, policy, c.breaker, func(ctx context.Context) error {
+		return c.executeOnce(ctx, call, out)
+	})
+	if errors.Is(err, resilience.ErrCircuitOpen) {
+		return &APIError{
+			Kind:      ErrorKindTransport,
+			Operation: call.operation,
+			Message:   "circuit breaker open for " + c.config.GraphQLEndpoint,
+			Err:       err,
+		}
+	}
+	return err
+}
+
+// executeOnce posts a GraphQL operation and decodes the response body into out.
+// Non-2xx status codes and top-level `errors` arrays are returned as *APIError.
+func (c *Client) executeOnce(ctx context.Context, call graphQLCall, out interface{}) error {
+	operation := call.operation
 	requestBody := map[string]interface{}{
-		"query":     query,
-		"variables": variables,
+		"query":     call.query,
+		"variables": call.variables,
 	}
 
 	// Get auth token
-	authToken, err := c.getAuthToken()
+	authToken, err := c.getAuthToken(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get auth token: %v", err)
+		return &APIError{
+			Kind:      ErrorKindAuth,
+			Operation: operation,
+			Message:   "failed to get auth token",
+			Err:       err,
+		}
 	}
 
-	resp, err := c.client.R().
+	request := c.client.R().
+		SetContext(ctx).
 		SetHeader("Authorization", "Bearer "+authToken).
-		SetBody(requestBody).
-		Post("")
+		SetBody(requestBody)
+	if call.idempotencyKey != "" {
+		request.SetHeader("Idempotency-Key", call.idempotencyKey)
+	}
 
+	resp, err := request.Post("")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create order: %v", err)
+		return newTransportError(operation, err)
 	}
 
-	var response CreateOrderResponse
-	if err := json.Unmarshal(resp.Body(), &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v", err)
+	body := resp.Body()
+
+	// Error responses may still carry a GraphQL errors array worth surfacing
+	var envelope graphQLEnvelope
+	decodeErr := json.Unmarshal(body, &envelope)
+
+	if resp.IsError() {
+		apiErr := newHTTPError(operation, resp.StatusCode(), body, envelope.Errors)
+		apiErr.RetryAfterDelay = resilience.ParseRetryAfter(resp.Header().Get("Retry-After"))
+		return apiErr
 	}
 
-	return &response, nil
+	if decodeErr != nil {
+		return &APIError{
+			Kind:       ErrorKindServer,
+			Operation:  operation,
+			StatusCode: resp.StatusCode(),
+			Message:    "failed to parse response",
+			Err:        decodeErr,
+		}
+	}
+
+	if len(envelope.Errors) > 0 {
+		return newGraphQLError(operation, resp.StatusCode(), envelope.Errors)
+	}
+
+	if len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return &APIError{
+			Kind:       ErrorKindServer,
+			Operation:  operation,
+			StatusCode: resp.StatusCode(),
+			Message:    "response contained no data",
+		}
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return &APIError{
+			Kind:       ErrorKindServer,
+			Operation:  operation,
+			StatusCode: resp.StatusCode(),
+			Message:    "failed to parse response",
+			Err:        err,
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,227 @@
+package dispatch
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/resilience"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrorKind classifies failures returned by the Dispatch API
+type ErrorKind string
+
+const (
+	ErrorKindAuth       ErrorKind = "auth"
+	ErrorKindValidation ErrorKind = "validation"
+	ErrorKindRateLimit  ErrorKind = "rate_limit"
+	ErrorKindServer     ErrorKind = "server"
+	ErrorKindTransport  ErrorKind = "transport"
+)
+
+// GraphQLError is a single entry of a GraphQL `errors` array
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Code returns the `extensions.code` value, if present
+func (e GraphQLError) Code() string {
+	if code, ok := e.Extensions["code"].(string); ok {
+		return code
+	}
+	return ""
+}
+
+// PathString returns the error path in dotted form (e.g. "createOrder.input.drop_offs.0")
+func (e GraphQLError) PathString() string {
+	parts := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		parts[i] = fmt.Sprintf("%v", p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// APIError is returned by Client when a Dispatch API call fails
+type APIError struct {
+	Kind          ErrorKind      `json:"kind"`
+	Operation     string         `json:"operation"`
+	StatusCode    int            `json:"status_code,omitempty"`
+	Message       string         `json:"message"`
+	GraphQLErrors []GraphQLError `json:"graphql_errors,omitempty"`
+	Err           error          `json:"-"`
+
+	// RetryAfterDelay is the server's Retry-After value, if it sent one
+	RetryAfterDelay time.Duration `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s error", e.Operation, e.Kind)
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (HTTP %d)", e.StatusCode)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the failure is transient: the request may
+// succeed if sent again. Rejected input and credentials will not.
+func (e *APIError) Retryable() bool {
+	switch e.Kind {
+	case ErrorKindServer, ErrorKindRateLimit:
+		return true
+	case ErrorKindTransport:
+		return !errors.Is(e.Err, context.Canceled) && !errors.Is(e.Err, resilience.ErrCircuitOpen)
+	default:
+		return false
+	}
+}
+
+// NotSent reports whether err is a failure the API can't have acted on: the
+// connection was never made, or the API turned the request away with 429 Too
+// Many Requests or 503 Service Unavailable. Such requests may be sent again
+// even when repeating them would not be safe.
+func NotSent(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	}
+	var opErr *net.OpError
+	return apiErr.Kind == ErrorKindTransport && errors.As(apiErr.Err, &opErr) && opErr.Op == "dial"
+}
+
+// RetryAfter returns the delay the server asked for before retrying
+func (e *APIError) RetryAfter() time.Duration {
+	return e.RetryAfterDelay
+}
+
+// Hint returns an actionable suggestion for the caller based on the error kind
+func (e *APIError) Hint() string {
+	switch e.Kind {
+	case ErrorKindAuth:
+		return "Check DISPATCH_AUTH_TOKEN or the IDP client credentials; the Dispatch API rejected the request."
+	case ErrorKindValidation:
+		return "Fix the fields listed in the error and retry the request."
+	case ErrorKindRateLimit:
+		return "The Dispatch API is rate limiting requests; wait a moment and retry."
+	case ErrorKindServer:
+		return "The Dispatch API failed to process the request; retry later or contact support if it persists."
+	case ErrorKindTransport:
+		return "Could not reach the Dispatch API; check DISPATCH_GRAPHQL_ENDPOINT and network connectivity."
+	default:
+		return ""
+	}
+}
+
+// IsKind reports whether err is an APIError of the given kind
+func IsKind(err error, kind ErrorKind) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Kind == kind
+}
+
+// newTransportError wraps a network-level failure
+func newTransportError(operation string, err error) *APIError {
+	return &APIError{
+		Kind:      ErrorKindTransport,
+		Operation: operation,
+		Message:   "request failed",
+		Err:       err,
+	}
+}
+
+// newHTTPError classifies a non-2xx HTTP response
+func newHTTPError(operation string, statusCode int, body []byte, graphQLErrors []GraphQLError) *APIError {
+	apiErr := &APIError{
+		Kind:          kindForStatus(statusCode),
+		Operation:     operation,
+		StatusCode:    statusCode,
+		GraphQLErrors: graphQLErrors,
+	}
+
+	if len(graphQLErrors) > 0 {
+		apiErr.Message = joinGraphQLMessages(graphQLErrors)
+	} else {
+		apiErr.Message = strings.TrimSpace(truncate(string(body), 200))
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(statusCode)
+		}
+	}
+
+	return apiErr
+}
+
+// newGraphQLError classifies a 200 response carrying a GraphQL `errors` array
+func newGraphQLError(operation string, statusCode int, graphQLErrors []GraphQLError) *APIError {
+	return &APIError{
+		Kind:          kindForGraphQLErrors(graphQLErrors),
+		Operation:     operation,
+		StatusCode:    statusCode,
+		Message:       joinGraphQLMessages(graphQLErrors),
+		GraphQLErrors: graphQLErrors,
+	}
+}
+
+func kindForStatus(statusCode int) ErrorKind {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrorKindAuth
+	case statusCode == http.StatusTooManyRequests:
+		return ErrorKindRateLimit
+	case statusCode >= 500:
+		return ErrorKindServer
+	default:
+		return ErrorKindValidation
+	}
+}
+
+// kindForGraphQLErrors maps well-known `extensions.code` values to an error kind
+func kindForGraphQLErrors(graphQLErrors []GraphQLError) ErrorKind {
+	for _, gqlErr := range graphQLErrors {
+		switch strings.ToUpper(gqlErr.Code()) {
+		case "UNAUTHENTICATED", "FORBIDDEN", "UNAUTHORIZED":
+			return ErrorKindAuth
+		case "RATE_LIMITED", "TOO_MANY_REQUESTS":
+			return ErrorKindRateLimit
+		case "INTERNAL_SERVER_ERROR", "SERVICE_UNAVAILABLE":
+			return ErrorKindServer
+		}
+	}
+	// GraphQL errors without a recognised code are schema or input problems
+	return ErrorKindValidation
+}
+
+func joinGraphQLMessages(graphQLErrors []GraphQLError) string {
+	messages := make([]string, 0, len(graphQLErrors))
+	for _, gqlErr := range graphQLErrors {
+		if path := gqlErr.PathString(); path != "" {
+			messages = append(messages, fmt.Sprintf("%s (at %s)", gqlErr.Message, path))
+		} else {
+			messages = append(messages, gqlErr.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
@@ -0,0 +1,349 @@
+package dispatch
+
+import (
+	"dispatch-mcp-server/internal/money"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Order statuses, in the order an order moves through them. Cancelled can be
+// reached from any status before pickup.
+const (
+	OrderStatusPending        = "pending"
+	OrderStatusScheduled      = "scheduled"
+	OrderStatusDriverAssigned = "driver_assigned"
+	OrderStatusPickedUp       = "picked_up"
+	OrderStatusDelivered      = "delivered"
+	OrderStatusCancelled      = "cancelled"
+)
+
+// OrderStatuses lists every order status
+var OrderStatuses = []string{
+	OrderStatusPending,
+	OrderStatusScheduled,
+	OrderStatusDriverAssigned,
+	OrderStatusPickedUp,
+	OrderStatusDelivered,
+	OrderStatusCancelled,
+}
+
+// Limits applied to ListOrdersInput.Limit
+const (
+	DefaultListOrdersLimit = 20
+	MaxListOrdersLimit     = 100
+)
+
+// IsTerminalStatus reports whether an order in status will not change again
+func IsTerminalStatus(status string) bool {
+	return status == OrderStatusDelivered || status == OrderStatusCancelled
+}
+
+// Modifiable reports whether the order can still be cancelled or amended:
+// once the driver has picked it up it can't
+func (o *Order) Modifiable() bool {
+	switch o.Status {
+	case OrderStatusPickedUp, OrderStatusDelivered, OrderStatusCancelled:
+		return false
+	default:
+		return true
+	}
+}
+
+// RecordStatus moves the order to status and appends it to the history
+func (o *Order) RecordStatus(status, note string, at time.Time) {
+	o.Status = status
+	// Copy the history so orders sharing a backing array don't see the entry
+	o.StatusHistory = append(o.StatusHistory[:len(o.StatusHistory):len(o.StatusHistory)], StatusChange{
+		Status: status,
+		At:     at.UTC().Format(time.RFC3339),
+		Note:   note,
+		ETA:    o.CurrentETA(),
+	})
+}
+
+// StatusTime returns when the order last entered status
+func (o *Order) StatusTime(status string) (time.Time, bool) {
+	for i := len(o.StatusHistory) - 1; i >= 0; i-- {
+		if o.StatusHistory[i].Status == status {
+			at, err := time.Parse(time.RFC3339, o.StatusHistory[i].At)
+			return at, err == nil
+		}
+	}
+	return time.Time{}, false
+}
+
+// CurrentETA returns the driver's live ETA once the order is tracked, and
+// the quoted EstimatedArrival before that
+func (o *Order) CurrentETA() string {
+	if o.Tracking != nil && o.Tracking.EstimatedArrival != "" {
+		return o.Tracking.EstimatedArrival
+	}
+	return o.EstimatedArrival
+}
+
+// Cancel cancels the order. Cancelling a cancelled order is a no-op, so
+// retried cancellations succeed.
+func (o *Order) Cancel(operation string, input CancelOrderInput, at time.Time) error {
+	if o.Status == OrderStatusCancelled {
+		return nil
+	}
+	if !o.Modifiable() {
+		return notModifiableError(operation, o, "cancelled")
+	}
+
+	note := "cancelled"
+	if input.Reason != nil && strings.TrimSpace(*input.Reason) != "" {
+		note += ": " + strings.TrimSpace(*input.Reason)
+	}
+	o.RecordStatus(OrderStatusCancelled, note, at)
+	o.Driver = nil
+	o.Tracking = nil
+	return nil
+}
+
+// Update applies input to the order and reports whether the drop-offs
+// changed, in which case the caller should re-price it
+func (o *Order) Update(operation string, input UpdateOrderInput, at time.Time) (bool, error) {
+	if !o.Modifiable() {
+		return false, notModifiableError(operation, o, "updated")
+	}
+
+	var changed []string
+	if input.PickupNotes != nil {
+		if o.Pickup == nil {
+			o.Pickup = &OrderStop{}
+		}
+		pickup := *o.Pickup
+		pickup.Notes = *input.PickupNotes
+		o.Pickup = &pickup
+		changed = append(changed, "pickup notes")
+	}
+	if len(input.DropOffs) > 0 {
+		o.DropOffs = nil
+		for _, dropOff := range input.DropOffs {
+			o.DropOffs = append(o.DropOffs, dropOffStop(dropOff))
+		}
+		changed = append(changed, "drop-offs")
+	}
+	if len(changed) == 0 {
+		return false, newGraphQLError(operation, 200, []GraphQLError{{
+			Message:    "nothing to update; set pickup_notes or drop_offs",
+			Path:       []interface{}{operation, "input"},
+			Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"},
+		}})
+	}
+
+	o.RecordStatus(o.Status, "updated "+strings.Join(changed, " and "), at)
+	return len(input.DropOffs) > 0, nil
+}
+
+// TimelineEntry is a status change with the ETA at that point and how far it
+// had drifted from the quoted arrival
+type TimelineEntry struct {
+	Status          string `json:"status"`
+	At              string `json:"at"`
+	Note            string `json:"note,omitempty"`
+	ETA             string `json:"eta,omitempty"`
+	ETADrift        string `json:"eta_drift,omitempty"` // e.g. "+12m0s"; positive is late
+	ETADriftSeconds int64  `json:"eta_drift_seconds"`
+}
+
+// Timeline returns the order's status changes, skipping amendments that left
+// the status unchanged, with each ETA compared against quoted
+func (o *Order) Timeline(quoted string) []TimelineEntry {
+	quotedAt, quotedErr := time.Parse(time.RFC3339, quoted)
+
+	timeline := []TimelineEntry{}
+	for i, change := range o.StatusHistory {
+		if i > 0 && change.Status == o.StatusHistory[i-1].Status {
+			continue
+		}
+		entry := TimelineEntry{Status: change.Status, At: change.At, Note: change.Note, ETA: change.ETA}
+		if eta, err := time.Parse(time.RFC3339, change.ETA); err == nil && quotedErr == nil {
+			drift := eta.Sub(quotedAt)
+			entry.ETADriftSeconds = int64(drift / time.Second)
+			entry.ETADrift = FormatDrift(drift)
+		}
+		timeline = append(timeline, entry)
+	}
+	return timeline
+}
+
+// FormatDrift formats an ETA drift with an explicit sign, e.g. "+12m0s"
+func FormatDrift(d time.Duration) string {
+	if d > 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
+
+// Reprice applies a new quote for the order's route. The quoted arrival
+// replaces any live ETA, including the one recorded with the latest change.
+func (o *Order) Reprice(totalCost money.Money, estimatedArrival string) {
+	o.TotalCost = totalCost
+	o.EstimatedArrival = estimatedArrival
+	if o.Tracking != nil {
+		tracking := *o.Tracking
+		tracking.EstimatedArrival = ""
+		o.Tracking = &tracking
+	}
+	if n := len(o.StatusHistory); n > 0 {
+		o.StatusHistory = append([]StatusChange(nil), o.StatusHistory...)
+		o.StatusHistory[n-1].ETA = estimatedArrival
+	}
+}
+
+// RouteInput rebuilds the order's stops as a CreateOrderInput, for pricing an
+// amended order the same way as a new one
+func (o *Order) RouteInput() CreateOrderInput {
+	input := CreateOrderInput{DeliveryInfo: DeliveryInfoInput{ServiceType: o.ServiceType}}
+	if o.Pickup != nil {
+		input.PickupInfo = CreateOrderPickupInfoInput{
+			BusinessName: optionalString(o.Pickup.BusinessName),
+			Location:     &LocationInput{Address: o.Pickup.Address},
+		}
+	}
+	for _, stop := range o.DropOffs {
+		input.DropOffs = append(input.DropOffs, CreateOrderDropOffInfoInput{
+			BusinessName: optionalString(stop.BusinessName),
+			Location:     &LocationInput{Address: stop.Address},
+		})
+	}
+	return input
+}
+
+// EstimateInput describes the order's stops as an estimate request for
+// vehicleType, for quoting an order without creating it
+func (in CreateOrderInput) EstimateInput(vehicleType string) CreateEstimateInput {
+	estimate := CreateEstimateInput{
+		PickupInfo:        PickupInfoInput{BusinessName: derefString(in.PickupInfo.BusinessName)},
+		VehicleType:       vehicleType,
+		AddOns:            in.AddOns,
+		OrganizationDruid: in.DeliveryInfo.OrganizationDruid,
+	}
+	if in.PickupInfo.Location != nil {
+		estimate.PickupInfo.Location = *in.PickupInfo.Location
+	}
+	for _, dropOff := range in.DropOffs {
+		stop := DropOffInfoInput{BusinessName: derefString(dropOff.BusinessName)}
+		if dropOff.Location != nil {
+			stop.Location = *dropOff.Location
+		}
+		estimate.DropOffs = append(estimate.DropOffs, stop)
+	}
+	return estimate
+}
+
+// NewOrderFromInput describes the stops of a new order created from input
+func NewOrderFromInput(input CreateOrderInput) Order {
+	o := Order{ServiceType: input.DeliveryInfo.ServiceType}
+	pickup := OrderStop{
+		BusinessName:       derefString(input.PickupInfo.BusinessName),
+		ContactName:        derefString(input.PickupInfo.ContactName),
+		ContactPhoneNumber: derefString(input.PickupInfo.ContactPhoneNumber),
+		Notes:              derefString(input.PickupInfo.PickupNotes),
+	}
+	if input.PickupInfo.Location != nil {
+		pickup.Address = input.PickupInfo.Location.Address
+	}
+	o.Pickup = &pickup
+	for _, dropOff := range input.DropOffs {
+		o.DropOffs = append(o.DropOffs, dropOffStop(dropOff))
+	}
+	return o
+}
+
+// Matches reports whether o passes the filter
+func (in ListOrdersInput) Matches(o *Order) bool {
+	if len(in.Statuses) > 0 {
+		found := false
+		for _, status := range in.Statuses {
+			if strings.EqualFold(status, o.Status) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if in.TrackingNumber != nil && *in.TrackingNumber != "" && !strings.EqualFold(*in.TrackingNumber, o.TrackingNumber) {
+		return false
+	}
+	if in.CreatedAfter != nil && *in.CreatedAfter != "" {
+		after, err := time.Parse(time.RFC3339, *in.CreatedAfter)
+		created, createdErr := time.Parse(time.RFC3339, o.CreatedAt)
+		if err == nil && (createdErr != nil || !created.After(after)) {
+			return false
+		}
+	}
+	return true
+}
+
+// MaxResults returns the limit to apply, defaulted and capped
+func (in ListOrdersInput) MaxResults() int {
+	switch {
+	case in.Limit <= 0:
+		return DefaultListOrdersLimit
+	case in.Limit > MaxListOrdersLimit:
+		return MaxListOrdersLimit
+	default:
+		return in.Limit
+	}
+}
+
+// SortOrdersNewestFirst sorts orders by creation time, newest first
+func SortOrdersNewestFirst(orders []Order) {
+	sort.SliceStable(orders, func(i, j int) bool {
+		if orders[i].CreatedAt != orders[j].CreatedAt {
+			return orders[i].CreatedAt > orders[j].CreatedAt
+		}
+		return orders[i].ID > orders[j].ID
+	})
+}
+
+// NewOrderNotFoundError is the error returned for an unknown order ID
+func NewOrderNotFoundError(operation, id string) *APIError {
+	return newGraphQLError(operation, 200, []GraphQLError{{
+		Message:    fmt.Sprintf("order %s not found", id),
+		Path:       []interface{}{operation},
+		Extensions: map[string]interface{}{"code": "NOT_FOUND"},
+	}})
+}
+
+func notModifiableError(operation string, o *Order, action string) *APIError {
+	return newGraphQLError(operation, 200, []GraphQLError{{
+		Message:    fmt.Sprintf("order %s is %s and can no longer be %s", o.ID, strings.ReplaceAll(o.Status, "_", " "), action),
+		Path:       []interface{}{operation, "input", "order_id"},
+		Extensions: map[string]interface{}{"code": "ORDER_NOT_MODIFIABLE"},
+	}})
+}
+
+func dropOffStop(dropOff CreateOrderDropOffInfoInput) OrderStop {
+	stop := OrderStop{
+		BusinessName:       derefString(dropOff.BusinessName),
+		ContactName:        derefString(dropOff.ContactName),
+		ContactPhoneNumber: derefString(dropOff.ContactPhoneNumber),
+		Notes:              derefString(dropOff.DropOffNotes),
+	}
+	if dropOff.Location != nil {
+		stop.Address = dropOff.Location.Address
+	}
+	return stop
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -1,14 +1,29 @@
 package dispatch
 
 import (
+	"context"
 	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/money"
 	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
 	"time"
 )
 
-// MockClient provides mock responses for demo purposes
+// MockClient provides fixture-driven responses for demos and tests. Prices,
+// service tiers, the service area, latency and injected faults all come from
+// a ScenarioSet; see fixtures/mock_scenarios.yaml for the built-in one.
 type MockClient struct {
-	config *config.Config
+	config    *config.Config
+	scenarios *ScenarioSet
+
+	mu  sync.Mutex
+	rng *rand.Rand
+
+	ordersMu sync.Mutex
+	orders   map[string]*Order
 }
 
 func NewMockClient() (*MockClient, error) {
@@ -17,94 +32,536 @@ func NewMockClient() (*MockClient, error) {
 		return nil, err
 	}
 
+	return NewMockClientWithConfig(cfg)
+}
+
+// NewMockClientWithConfig creates a mock client using the supplied
+// configuration: fixtures from MockFixturesPath (or the built-in set) and a
+// random source seeded with MockSeed for reproducible latency and faults
+func NewMockClientWithConfig(cfg *config.Config) (*MockClient, error) {
+	var scenarios *ScenarioSet
+	var err error
+	if cfg.MockFixturesPath != "" {
+		scenarios, err = LoadScenarios(cfg.MockFixturesPath)
+	} else {
+		scenarios, err = DefaultScenarios()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMockClientWithScenarios(cfg, scenarios), nil
+}
+
+// NewMockClientWithScenarios creates a mock client driven by scenarios
+func NewMockClientWithScenarios(cfg *config.Config, scenarios *ScenarioSet) *MockClient {
+	seed := cfg.MockSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
 	return &MockClient{
-		config: cfg,
-	}, nil
+		config:    cfg,
+		scenarios: scenarios,
+		rng:       rand.New(rand.NewSource(seed)),
+		orders:    make(map[string]*Order),
+	}
 }
 
-func (c *MockClient) CreateEstimate(input CreateEstimateInput) (*CreateEstimateResponse, error) {
-	// Simulate API delay
-	time.Sleep(500 * time.Millisecond)
-
-	// Create mock response
-	response := &CreateEstimateResponse{
-		Data: struct {
-			CreateEstimate struct {
-				Estimate struct {
-					AvailableOrderOptions []AvailableOrderOption `json:"availableOrderOptions"`
-				} `json:"estimate"`
-			} `json:"createEstimate"`
-		}{
-			CreateEstimate: struct {
-				Estimate struct {
-					AvailableOrderOptions []AvailableOrderOption `json:"availableOrderOptions"`
-				} `json:"estimate"`
-			}{
-				Estimate: struct {
-					AvailableOrderOptions []AvailableOrderOption `json:"availableOrderOptions"`
-				}{
-					AvailableOrderOptions: []AvailableOrderOption{
-						{
-							ServiceType:              "delivery",
-							EstimatedDeliveryTimeUTC: time.Now().Add(2 * time.Hour).Format(time.RFC3339),
-							EstimatedOrderCost:       45.99,
-							VehicleType:              input.VehicleType,
-							PickupLocationInfo: LocationInfo{
-								GooglePlaceID: "mock_pickup_place_id",
-								Lat:           37.7749,
-								Lng:           -122.4194,
-							},
-							DropOffLocationsInfo: []LocationInfo{
-								{
-									GooglePlaceID: "mock_dropoff_place_id",
-									Lat:           37.8044,
-									Lng:           -122.2712,
-								},
-							},
-							EstimateInfo: EstimateInfo{
-								ServiceType:               "delivery",
-								VehicleType:               input.VehicleType,
-								TollAmount:                "5.50",
-								EstimatedOrderCost:        "45.99",
-								DedicatedVehicleRequested: &[]bool{false}[0],
-								DedicatedVehicleFee:       "0.00",
-							},
-							AddOns: input.AddOns,
-						},
-					},
-				},
+// Scenarios returns the fixtures driving the client
+func (c *MockClient) Scenarios() *ScenarioSet {
+	return c.scenarios
+}
+
+func (c *MockClient) CreateEstimate(ctx context.Context, input CreateEstimateInput) (*CreateEstimateResponse, error) {
+	const operation = "createEstimate"
+
+	r := route{
+		vehicleType: input.VehicleType,
+		pickup:      stopFromLocation(input.PickupInfo.Location),
+		dedicated:   input.DedicatedVehicle != nil && *input.DedicatedVehicle,
+	}
+	for _, dropOff := range input.DropOffs {
+		r.dropOffs = append(r.dropOffs, stopFromLocation(dropOff.Location))
+	}
+
+	scenario, err := c.simulate(ctx, operation, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	if gqlErrs := c.serviceAreaErrors(operation, r); len(gqlErrs) > 0 {
+		return nil, newGraphQLError(operation, 200, gqlErrs)
+	}
+
+	now := time.Now().UTC()
+	toll := money.New(0, money.USD)
+	if scenario != nil {
+		toll = money.Dollars(scenario.TollAmount)
+	}
+
+	response := &CreateEstimateResponse{}
+	options := []AvailableOrderOption{}
+	for _, tier := range c.scenarios.tiers(scenario) {
+		cost := c.scenarios.price(tier, r, scenario)
+		option := AvailableOrderOption{
+			ServiceType:              tier.ServiceType,
+			EstimatedDeliveryTimeUTC: c.scenarios.eta(tier, r, now).Format(time.RFC3339),
+			EstimatedOrderCost:       cost,
+			VehicleType:              input.VehicleType,
+			PickupLocationInfo:       locationInfo(r.pickup, "pickup"),
+			EstimateInfo: EstimateInfo{
+				ServiceType:               tier.ServiceType,
+				VehicleType:               input.VehicleType,
+				TollAmount:                money.Quoted{Money: toll},
+				EstimatedOrderCost:        money.Quoted{Money: cost},
+				DedicatedVehicleRequested: &[]bool{r.dedicated}[0],
+				DedicatedVehicleFee:       money.Quoted{Money: money.New(0, money.USD)},
 			},
-		},
+			AddOns: input.AddOns,
+		}
+		if r.dedicated {
+			option.EstimateInfo.DedicatedVehicleFee = money.Quoted{Money: money.Dollars(c.scenarios.DedicatedVehicleFee)}
+		}
+		for i, stop := range r.dropOffs {
+			option.DropOffLocationsInfo = append(option.DropOffLocationsInfo, locationInfo(stop, fmt.Sprintf("dropoff_%d", i)))
+		}
+		options = append(options, option)
 	}
+	response.Data.CreateEstimate.Estimate.AvailableOrderOptions = options
 
 	return response, nil
 }
 
-func (c *MockClient) CreateOrder(input CreateOrderInput) (*CreateOrderResponse, error) {
-	// Simulate API delay
-	time.Sleep(500 * time.Millisecond)
-
-	// Create mock response
-	response := &CreateOrderResponse{
-		Data: struct {
-			CreateOrder struct {
-				Order Order `json:"order"`
-			} `json:"createOrder"`
-		}{
-			CreateOrder: struct {
-				Order Order `json:"order"`
-			}{
-				Order: Order{
-					ID:               fmt.Sprintf("ORD-%d", time.Now().Unix()),
-					Status:           "pending",
-					ScheduledAt:      time.Now().Add(1 * time.Hour).Format(time.RFC3339),
-					TotalCost:        45.99,
-					TrackingNumber:   fmt.Sprintf("TRK-%d", time.Now().Unix()),
-					EstimatedArrival: time.Now().Add(3 * time.Hour).Format(time.RFC3339),
-				},
-			},
-		},
+func (c *MockClient) CreateOrder(ctx context.Context, input CreateOrderInput) (*CreateOrderResponse, error) {
+	const operation = "createOrder"
+
+	quote, err := c.QuoteOrder(ctx, operation, input)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	id := c.randomID()
+	created := NewOrderFromInput(input)
+	created.ID = "ORD-" + id
+	created.TrackingNumber = "TRK-" + id
+	created.ScheduledAt = now.Add(30 * time.Minute).Format(time.RFC3339)
+	created.TotalCost = quote.TotalCost
+	created.EstimatedArrival = quote.EstimatedArrival
+	created.CreatedAt = now.Format(time.RFC3339)
+	created.RecordStatus(OrderStatusPending, "order created", now)
+
+	c.ordersMu.Lock()
+	c.orders[created.ID] = &created
+	c.ordersMu.Unlock()
+
+	// createOrder only selects the summary fields
+	response := &CreateOrderResponse{}
+	response.Data.CreateOrder.Order = Order{
+		ID:               created.ID,
+		Status:           created.Status,
+		ScheduledAt:      created.ScheduledAt,
+		TotalCost:        created.TotalCost,
+		TrackingNumber:   created.TrackingNumber,
+		EstimatedArrival: created.EstimatedArrival,
 	}
 
 	return response, nil
 }
+
+func (c *MockClient) GetOrder(ctx context.Context, id string) (*GetOrderResponse, error) {
+	const operation = "order"
+
+	found, err := c.lookupOrder(ctx, operation, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetOrderResponse{}
+	response.Data.Order = found
+	return response, nil
+}
+
+func (c *MockClient) ListOrders(ctx context.Context, input ListOrdersInput) (*ListOrdersResponse, error) {
+	const operation = "orders"
+
+	if _, err := c.simulate(ctx, operation, &route{}); err != nil {
+		return nil, err
+	}
+
+	c.ordersMu.Lock()
+	orders := []Order{}
+	for _, stored := range c.orders {
+		c.AdvanceOrder(stored, time.Now())
+		if input.Matches(stored) {
+			orders = append(orders, *stored)
+		}
+	}
+	c.ordersMu.Unlock()
+
+	SortOrdersNewestFirst(orders)
+	if limit := input.MaxResults(); len(orders) > limit {
+		orders = orders[:limit]
+	}
+
+	response := &ListOrdersResponse{}
+	response.Data.Orders = orders
+	return response, nil
+}
+
+func (c *MockClient) CancelOrder(ctx context.Context, input CancelOrderInput) (*CancelOrderResponse, error) {
+	const operation = "cancelOrder"
+
+	found, err := c.lookupOrder(ctx, operation, input.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := found.Cancel(operation, input, time.Now()); err != nil {
+		return nil, err
+	}
+	c.storeOrder(found)
+
+	response := &CancelOrderResponse{}
+	response.Data.CancelOrder.Order = found
+	return response, nil
+}
+
+func (c *MockClient) UpdateOrder(ctx context.Context, input UpdateOrderInput) (*UpdateOrderResponse, error) {
+	const operation = "updateOrder"
+
+	found, err := c.lookupOrder(ctx, operation, input.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	rerouted, err := found.Update(operation, input, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// New drop-offs change the route, so price it again
+	if rerouted {
+		quote, err := c.QuoteOrder(ctx, operation, found.RouteInput())
+		if err != nil {
+			return nil, err
+		}
+		found.Reprice(quote.TotalCost, quote.EstimatedArrival)
+	}
+	c.storeOrder(found)
+
+	response := &UpdateOrderResponse{}
+	response.Data.UpdateOrder.Order = found
+	return response, nil
+}
+
+// lookupOrder returns a copy of the stored order after the simulated call
+// for its route
+func (c *MockClient) lookupOrder(ctx context.Context, operation, id string) (Order, error) {
+	c.ordersMu.Lock()
+	stored, ok := c.orders[id]
+	var found Order
+	if ok {
+		c.AdvanceOrder(stored, time.Now())
+		found = *stored
+	}
+	c.ordersMu.Unlock()
+
+	r := orderRoute(found.RouteInput())
+	if _, err := c.simulate(ctx, operation, &r); err != nil {
+		return Order{}, err
+	}
+	if !ok {
+		return Order{}, NewOrderNotFoundError(operation, id)
+	}
+	return found, nil
+}
+
+func (c *MockClient) storeOrder(o Order) {
+	c.ordersMu.Lock()
+	defer c.ordersMu.Unlock()
+	c.orders[o.ID] = &o
+}
+
+// AdvanceOrder moves o through the statuses the fixtures' progression has
+// reached by now, recording each at the time it was due, and moves the
+// driver along the route. It reports whether o changed.
+func (c *MockClient) AdvanceOrder(o *Order, now time.Time) bool {
+	created, err := time.Parse(time.RFC3339, o.CreatedAt)
+	if err != nil || IsTerminalStatus(o.Status) {
+		return false
+	}
+	progression := c.scenarios.Progression
+	due := func(after time.Duration) (time.Time, bool) {
+		at := created.Add(after)
+		return at, !now.Before(at)
+	}
+	changed := false
+
+	if o.Status == OrderStatusPending {
+		at, ok := due(progression.Scheduled)
+		if !ok {
+			return changed
+		}
+		o.RecordStatus(OrderStatusScheduled, "scheduled for pickup", at)
+		changed = true
+	}
+
+	if o.Status == OrderStatusScheduled {
+		at, ok := due(progression.DriverAssigned)
+		if !ok {
+			return changed
+		}
+		o.Driver = c.assignDriver(progression)
+		o.Tracking = c.trackAt(o.Pickup, at, c.driftedETA(o))
+		o.RecordStatus(OrderStatusDriverAssigned, o.Driver.Name+" assigned", at)
+		changed = true
+	}
+
+	if o.Status == OrderStatusDriverAssigned {
+		at, ok := due(progression.PickedUp)
+		if !ok {
+			return changed
+		}
+		if o.Driver == nil {
+			o.Driver = c.assignDriver(progression)
+		}
+		o.Tracking = c.trackAt(o.Pickup, at, c.driftedETA(o))
+		o.RecordStatus(OrderStatusPickedUp, "picked up by "+o.Driver.Name, at)
+		changed = true
+	}
+
+	if o.Status == OrderStatusPickedUp {
+		pickedUp, ok := o.StatusTime(OrderStatusPickedUp)
+		if !ok {
+			pickedUp = created.Add(progression.PickedUp)
+		}
+		deliverAt, err := time.Parse(time.RFC3339, o.CurrentETA())
+		if err != nil || deliverAt.Before(pickedUp) {
+			deliverAt = pickedUp
+		}
+
+		var last *OrderStop
+		if len(o.DropOffs) > 0 {
+			last = &o.DropOffs[len(o.DropOffs)-1]
+		}
+		if now.Before(deliverAt) {
+			// In transit: report a position between the pickup and the last drop-off
+			from, to := c.stopPosition(o.Pickup), c.stopPosition(last)
+			progress := math.Max(0, float64(now.Sub(pickedUp))/float64(deliverAt.Sub(pickedUp)))
+			tracking := Tracking{EstimatedArrival: o.CurrentETA()}
+			if o.Tracking != nil {
+				tracking = *o.Tracking
+			}
+			tracking.Lat = from.lat + (to.lat-from.lat)*progress
+			tracking.Lng = from.lng + (to.lng-from.lng)*progress
+			tracking.UpdatedAt = now.UTC().Format(time.RFC3339)
+			o.Tracking = &tracking
+			return true
+		}
+
+		o.Tracking = c.trackAt(last, deliverAt, deliverAt.UTC().Format(time.RFC3339))
+		o.RecordStatus(OrderStatusDelivered, "delivered", deliverAt)
+		changed = true
+	}
+	return changed
+}
+
+// assignDriver picks one of the fixture drivers
+func (c *MockClient) assignDriver(progression ProgressionSpec) *Driver {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := "Dispatch Driver"
+	if len(progression.Drivers) > 0 {
+		name = progression.Drivers[c.rng.Intn(len(progression.Drivers))]
+	}
+	return &Driver{
+		Name:        name,
+		PhoneNumber: fmt.Sprintf("415-555-%04d", c.rng.Intn(10000)),
+		Vehicle:     "cargo_van",
+	}
+}
+
+// driftedETA returns the order's quoted arrival plus a sampled drift
+func (c *MockClient) driftedETA(o *Order) string {
+	quoted, err := time.Parse(time.RFC3339, o.EstimatedArrival)
+	if err != nil {
+		return o.EstimatedArrival
+	}
+	c.mu.Lock()
+	drift := c.scenarios.Progression.drift(c.rng)
+	c.mu.Unlock()
+	return quoted.Add(drift).UTC().Format(time.RFC3339)
+}
+
+// trackAt reports the driver at stop
+func (c *MockClient) trackAt(stop *OrderStop, at time.Time, eta string) *Tracking {
+	position := c.stopPosition(stop)
+	return &Tracking{
+		Lat:              position.lat,
+		Lng:              position.lng,
+		UpdatedAt:        at.UTC().Format(time.RFC3339),
+		EstimatedArrival: eta,
+	}
+}
+
+// stopPosition resolves a stop's coordinates from the zip table
+func (c *MockClient) stopPosition(stop *OrderStop) routeStop {
+	var position routeStop
+	if stop != nil && stop.Address != nil {
+		position = stopFromLocation(LocationInput{Address: stop.Address})
+	}
+	c.scenarios.locate(&position)
+	return position
+}
+
+// QuoteOrder prices input as CreateOrder would, at its requested service tier
+// or else the slowest one, without creating an order. Only TotalCost and
+// EstimatedArrival are set on the result.
+func (c *MockClient) QuoteOrder(ctx context.Context, operation string, input CreateOrderInput) (Order, error) {
+	r := orderRoute(input)
+
+	scenario, err := c.simulate(ctx, operation, &r)
+	if err != nil {
+		return Order{}, err
+	}
+
+	if gqlErrs := c.serviceAreaErrors(operation, r); len(gqlErrs) > 0 {
+		return Order{}, newGraphQLError(operation, 200, gqlErrs)
+	}
+
+	tiers := c.scenarios.tiers(scenario)
+	if len(tiers) == 0 {
+		return Order{}, newGraphQLError(operation, 200, []GraphQLError{{
+			Message: "No delivery options are available for this route",
+			Path:    []interface{}{operation, "input"},
+		}})
+	}
+	tier := tiers[len(tiers)-1]
+	for _, candidate := range tiers {
+		if strings.EqualFold(candidate.ServiceType, input.DeliveryInfo.ServiceType) {
+			tier = candidate
+			break
+		}
+	}
+
+	return Order{
+		TotalCost:        c.scenarios.price(tier, r, scenario),
+		EstimatedArrival: c.scenarios.eta(tier, r, time.Now().UTC()).Format(time.RFC3339),
+	}, nil
+}
+
+// orderRoute resolves an order's stops. Orders carry no vehicle type, so they
+// are priced as a cargo van.
+func orderRoute(input CreateOrderInput) route {
+	r := route{vehicleType: "cargo_van"}
+	if input.PickupInfo.Location != nil {
+		r.pickup = stopFromLocation(*input.PickupInfo.Location)
+	}
+	for _, dropOff := range input.DropOffs {
+		var stop routeStop
+		if dropOff.Location != nil {
+			stop = stopFromLocation(*dropOff.Location)
+		}
+		r.dropOffs = append(r.dropOffs, stop)
+	}
+	return r
+}
+
+// simulate resolves coordinates, picks the matching scenario, waits for its
+// latency and returns any injected fault
+func (c *MockClient) simulate(ctx context.Context, operation string, r *route) (*Scenario, error) {
+	c.scenarios.locate(&r.pickup)
+	for i := range r.dropOffs {
+		c.scenarios.locate(&r.dropOffs[i])
+	}
+	scenario := c.scenarios.match(*r)
+
+	c.mu.Lock()
+	latency := c.scenarios.latency(scenario, c.rng)
+	fault := c.scenarios.fault(operation, scenario, c.rng)
+	c.mu.Unlock()
+
+	// Simulate API delay
+	if err := sleepContext(ctx, latency); err != nil {
+		return nil, newTransportError(operation, err)
+	}
+
+	if fault != nil {
+		return nil, fault.apiError(operation)
+	}
+	return scenario, nil
+}
+
+// serviceAreaErrors returns one GraphQL error per stop outside the service
+// area, with paths in the same shape the real API uses
+func (c *MockClient) serviceAreaErrors(operation string, r route) []GraphQLError {
+	var errs []GraphQLError
+	if !c.scenarios.Serves(r.pickup.zip) {
+		errs = append(errs, outOfAreaError(r.pickup.zip, operation, "input", "pickup_info", "location", "address", "zip_code"))
+	}
+	for i, stop := range r.dropOffs {
+		if !c.scenarios.Serves(stop.zip) {
+			errs = append(errs, outOfAreaError(stop.zip, operation, "input", "drop_offs", i, "location", "address", "zip_code"))
+		}
+	}
+	return errs
+}
+
+func outOfAreaError(zip string, path ...interface{}) GraphQLError {
+	message := "Address is outside the Dispatch service area"
+	if zip == "" {
+		message = "Address with a zip code is required"
+	} else {
+		message += fmt.Sprintf(" (zip %s)", zip)
+	}
+	return GraphQLError{
+		Message:    message,
+		Path:       path,
+		Extensions: map[string]interface{}{"code": "OUT_OF_SERVICE_AREA"},
+	}
+}
+
+func stopFromLocation(location LocationInput) routeStop {
+	var stop routeStop
+	if location.Address != nil {
+		stop.zip = strings.TrimSpace(location.Address.ZipCode)
+	}
+	if location.GeoCoordinates != nil {
+		stop.lat = location.GeoCoordinates.Latitude
+		stop.lng = location.GeoCoordinates.Longitude
+		stop.located = true
+	}
+	return stop
+}
+
+func locationInfo(stop routeStop, kind string) LocationInfo {
+	return LocationInfo{
+		GooglePlaceID: fmt.Sprintf("mock_%s_%s", kind, stop.zip),
+		Lat:           stop.lat,
+		Lng:           stop.lng,
+	}
+}
+
+func (c *MockClient) randomID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return fmt.Sprintf("%06X", c.rng.Intn(0xFFFFFF))
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,173 @@
+package dispatch
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/money"
+)
+
+// CreateOrderMutation creates an order from an OrderCreationInput. Unlike the
+// createOrder input sent by CreateOrder, it carries the vehicle type,
+// capabilities and schedule.
+const CreateOrderMutation = `
+mutation CreateOrder($input: CreateOrderInput!) {
+  createOrder(input: $input) {
+    order {
+      id
+      druid
+      status
+      pricing {
+        totalPrice
+        basePrice
+        discounts {
+          type
+          amount
+        }
+      }
+    }
+    errors {
+      field
+      message
+    }
+  }
+}
+`
+
+// OrderSubmission is the CreateOrderMutation result: the created order, or
+// the fields that stopped it from being created
+type OrderSubmission struct {
+	Order  CreatedOrder `json:"order"`
+	Errors []FieldError `json:"errors"`
+}
+
+// CreatedOrder is an order as created by either order mutation. Druid is only
+// set by the CreateOrderMutation; the tracking and schedule fields only by
+// CreateOrder.
+type CreatedOrder struct {
+	ID               string       `json:"id"`
+	Druid            string       `json:"druid,omitempty"`
+	Status           string       `json:"status"`
+	TrackingNumber   string       `json:"trackingNumber,omitempty"`
+	ScheduledAt      string       `json:"scheduledAt,omitempty"`
+	EstimatedArrival string       `json:"estimatedArrival,omitempty"`
+	Pricing          OrderPricing `json:"pricing"`
+}
+
+// OrderPricing is the price breakdown of a created order
+type OrderPricing struct {
+	TotalPrice money.Money     `json:"totalPrice"`
+	BasePrice  money.Money     `json:"basePrice"`
+	Discounts  []OrderDiscount `json:"discounts"`
+}
+
+// OrderDiscount is a single price reduction applied to an order
+type OrderDiscount struct {
+	Type   string      `json:"type"`
+	Amount money.Money `json:"amount"`
+}
+
+// SubmitOrder sends the CreateOrderMutation. Like CreateOrder it is only
+// retried when input carries an idempotency key, which is sent as the
+// Idempotency-Key header.
+func (c *Client) SubmitOrder(ctx context.Context, input *OrderCreationInput) (*OrderSubmission, error) {
+	ctx, cancel := config.WithTimeout(ctx, c.config.OrderTimeout)
+	defer cancel()
+
+	var response struct {
+		Data struct {
+			CreateOrder OrderSubmission `json:"createOrder"`
+		} `json:"data"`
+	}
+	call := graphQLCall{
+		operation:      "createOrder",
+		query:          CreateOrderMutation,
+		variables:      map[string]interface{}{"input": input},
+		retryable:      input.IdempotencyKey != "",
+		idempotencyKey: input.IdempotencyKey,
+	}
+	if err := c.execute(ctx, call, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data.CreateOrder, nil
+}
+
+// SubmitOrder checks input with the ValidateOrderMutation rules, then creates
+// the order like CreateOrder, priced for the requested vehicle
+func (c *MockClient) SubmitOrder(ctx context.Context, input *OrderCreationInput) (*OrderSubmission, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if validation := c.scenarios.CheckOrder(input); !validation.Valid {
+		return &OrderSubmission{Errors: validation.Errors}, nil
+	}
+
+	response, err := c.CreateOrder(ctx, input.CreateOrderInput())
+	if err != nil {
+		return nil, err
+	}
+	created := response.Data.CreateOrder.Order
+	total := c.scenarios.PriceForVehicle(created.TotalCost, input.VehicleTypeID)
+
+	c.ordersMu.Lock()
+	if stored, ok := c.orders[created.ID]; ok {
+		stored.TotalCost = total
+	}
+	c.ordersMu.Unlock()
+
+	return &OrderSubmission{
+		Order: CreatedOrder{
+			ID:               created.ID,
+			Status:           created.Status,
+			TrackingNumber:   created.TrackingNumber,
+			ScheduledAt:      created.ScheduledAt,
+			EstimatedArrival: created.EstimatedArrival,
+			Pricing: OrderPricing{
+				TotalPrice: total,
+				BasePrice:  total,
+				Discounts:  []OrderDiscount{},
+			},
+		},
+		Errors: []FieldError{},
+	}, nil
+}
+
+// CreateOrderInput converts the input to the createOrder input of
+// CreateOrder, which prices orders as a cargo van. The vehicle type,
+// capabilities and schedule have no place in it and are dropped.
+func (input *OrderCreationInput) CreateOrderInput() CreateOrderInput {
+	converted := CreateOrderInput{
+		DeliveryInfo:   DeliveryInfoInput{ServiceType: "standard"},
+		IdempotencyKey: input.IdempotencyKey,
+	}
+	if input.PickupInfo != nil {
+		converted.PickupInfo = CreateOrderPickupInfoInput{
+			BusinessName:       &input.PickupInfo.BusinessName,
+			ContactName:        &input.PickupInfo.ContactName,
+			ContactPhoneNumber: &input.PickupInfo.ContactPhone,
+			Location:           input.PickupInfo.Address.location(),
+		}
+	}
+	for i := range input.DropOffs {
+		dropOff := &input.DropOffs[i]
+		converted.DropOffs = append(converted.DropOffs, CreateOrderDropOffInfoInput{
+			BusinessName:       &dropOff.BusinessName,
+			ContactName:        &dropOff.ContactName,
+			ContactPhoneNumber: &dropOff.ContactPhone,
+			Location:           dropOff.Address.location(),
+		})
+	}
+	return converted
+}
+
+func (address *OrderAddressInput) location() *LocationInput {
+	if address == nil {
+		return &LocationInput{}
+	}
+	return &LocationInput{Address: &AddressInput{
+		Street:  address.Street,
+		City:    address.City,
+		State:   address.State,
+		ZipCode: address.ZipCode,
+		Country: address.Country,
+	}}
+}
@@ -0,0 +1,175 @@
+package dispatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ValidateOrderMutation checks an order without creating it
+const ValidateOrderMutation = `
+mutation ValidateOrder($input: ValidateOrderInput!) {
+  validateOrder(input: $input) {
+    valid
+    errors {
+      field
+      message
+    }
+    warnings {
+      field
+      message
+    }
+  }
+}
+`
+
+// OrderCreationInput is the order input of the order mutations (createOrder
+// on the order API and validateOrder)
+type OrderCreationInput struct {
+	OrganizationID string                `json:"organizationId"`
+	JobName        string                `json:"jobName"`
+	PickupInfo     *OrderPickupInput     `json:"pickupInfo"`
+	DropOffs       []OrderDropOffInput   `json:"dropOffs"`
+	VehicleTypeID  string                `json:"vehicleTypeId"`
+	Capabilities   []string              `json:"capabilities"`
+	Scheduling     *OrderSchedulingInput `json:"scheduling"`
+
+	// IdempotencyKey identifies this order across retries; derived from the
+	// input when empty
+	IdempotencyKey string `json:"-"`
+}
+
+// OrderPickupInput represents pickup information
+type OrderPickupInput struct {
+	BusinessName string             `json:"businessName"`
+	ContactName  string             `json:"contactName"`
+	ContactPhone string             `json:"contactPhone"`
+	Address      *OrderAddressInput `json:"address"`
+	Notes        string             `json:"notes"`
+}
+
+// OrderDropOffInput represents delivery information
+type OrderDropOffInput struct {
+	BusinessName string             `json:"businessName"`
+	ContactName  string             `json:"contactName"`
+	ContactPhone string             `json:"contactPhone"`
+	Address      *OrderAddressInput `json:"address"`
+	Notes        string             `json:"notes"`
+}
+
+// OrderAddressInput represents address information
+type OrderAddressInput struct {
+	Street  string `json:"street"`
+	City    string `json:"city"`
+	State   string `json:"state"`
+	ZipCode string `json:"zipCode"`
+	Country string `json:"country"`
+}
+
+// OrderSchedulingInput represents scheduling information
+type OrderSchedulingInput struct {
+	PickupTime   string `json:"pickupTime"`
+	DeliveryTime string `json:"deliveryTime"`
+	PickupDate   string `json:"pickupDate"`
+	DeliveryDate string `json:"deliveryDate"`
+	TimeZone     string `json:"timeZone"`
+}
+
+// FieldError describes a problem with a single order field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// OrderValidation is the validateOrder result: whether the order can be
+// created, what would stop it, and what the caller may want to fix first
+type OrderValidation struct {
+	Valid    bool         `json:"valid"`
+	Errors   []FieldError `json:"errors"`
+	Warnings []FieldError `json:"warnings"`
+}
+
+// ValidateOrder sends the ValidateOrderMutation. Nothing is created, so the
+// call is retried like a query.
+func (c *Client) ValidateOrder(ctx context.Context, input *OrderCreationInput) (*OrderValidation, error) {
+	var response struct {
+		Data struct {
+			ValidateOrder OrderValidation `json:"validateOrder"`
+		} `json:"data"`
+	}
+	variables := map[string]interface{}{"input": input}
+	if err := c.Execute(ctx, "validateOrder", ValidateOrderMutation, variables, true, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data.ValidateOrder, nil
+}
+
+// ValidateOrder applies the ValidateOrderMutation rules in process; see
+// ScenarioSet.CheckOrder
+func (c *MockClient) ValidateOrder(ctx context.Context, input *OrderCreationInput) (*OrderValidation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.scenarios.CheckOrder(input), nil
+}
+
+// CheckOrder applies the ValidateOrderMutation rules to input against the
+// scenarios: every stop must be in the service area, the vehicle type and
+// capabilities must exist, and stops without a contact phone or an order
+// without a schedule draw warnings
+func (s *ScenarioSet) CheckOrder(input *OrderCreationInput) *OrderValidation {
+	validation := &OrderValidation{Errors: []FieldError{}, Warnings: []FieldError{}}
+	fail := func(field, message string) {
+		validation.Errors = append(validation.Errors, FieldError{Field: field, Message: message})
+	}
+	warn := func(field, message string) {
+		validation.Warnings = append(validation.Warnings, FieldError{Field: field, Message: message})
+	}
+	checkAddress := func(field string, address *OrderAddressInput) {
+		switch {
+		case address == nil || strings.TrimSpace(address.ZipCode) == "":
+			fail(field+".zipCode", "zip code is required")
+		case !s.Serves(strings.TrimSpace(address.ZipCode)):
+			fail(field+".zipCode", fmt.Sprintf("zip %s is outside the Dispatch service area", address.ZipCode))
+		}
+	}
+
+	if input.PickupInfo == nil {
+		fail("pickupInfo", "pickup information is required")
+	} else {
+		checkAddress("pickupInfo.address", input.PickupInfo.Address)
+		if input.PickupInfo.ContactPhone == "" {
+			warn("pickupInfo.contactPhone", "the driver will not be able to call ahead")
+		}
+	}
+
+	if len(input.DropOffs) == 0 {
+		fail("dropOffs", "at least one delivery location is required")
+	}
+	for i, dropOff := range input.DropOffs {
+		field := fmt.Sprintf("dropOffs.%d", i)
+		checkAddress(field+".address", dropOff.Address)
+		if dropOff.ContactPhone == "" {
+			warn(field+".contactPhone", "the driver will not be able to call ahead")
+		}
+	}
+
+	if input.VehicleTypeID == "" {
+		fail("vehicleTypeId", "vehicle type is required")
+	} else if _, ok := s.Vehicles[input.VehicleTypeID]; !ok {
+		fail("vehicleTypeId", fmt.Sprintf("unknown vehicle type %q", input.VehicleTypeID))
+	}
+
+	for i, id := range input.Capabilities {
+		if _, ok := LookupCapability(id); !ok {
+			fail(fmt.Sprintf("capabilities.%d", i), fmt.Sprintf("unknown capability %q", id))
+		}
+	}
+
+	if input.Scheduling == nil {
+		warn("scheduling", "no pickup time given; the order will be picked up as soon as possible")
+	}
+
+	validation.Valid = len(validation.Errors) == 0
+	return validation
+}
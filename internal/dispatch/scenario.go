@@ -0,0 +1,433 @@
+package dispatch
+
+import (
+	"dispatch-mcp-server/internal/money"
+	_ "embed"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed fixtures/mock_scenarios.yaml
+var defaultScenarios []byte
+
+// ScenarioSet is the fixture file driving MockClient: the service area,
+// pricing tiers and per-scenario overrides such as latency and faults.
+// Files may be YAML or JSON.
+type ScenarioSet struct {
+	Version int `yaml:"version" json:"version"`
+
+	// ServiceArea lists the zip code prefixes Dispatch serves; addresses
+	// elsewhere are rejected with a validation error
+	ServiceArea struct {
+		ZipPrefixes []string `yaml:"zip_prefixes" json:"zip_prefixes"`
+	} `yaml:"service_area" json:"service_area"`
+
+	// ZipCoordinates maps zip codes or zip prefixes to [lat, lng] and is used
+	// to compute route distance; the longest matching prefix wins
+	ZipCoordinates map[string][2]float64 `yaml:"zip_coordinates" json:"zip_coordinates"`
+
+	// Vehicles maps vehicle type to a price multiplier
+	Vehicles map[string]float64 `yaml:"vehicles" json:"vehicles"`
+
+	Tiers               []ServiceTier `yaml:"tiers" json:"tiers"`
+	MinutesPerMile      float64       `yaml:"minutes_per_mile" json:"minutes_per_mile"`
+	DedicatedVehicleFee float64       `yaml:"dedicated_vehicle_fee" json:"dedicated_vehicle_fee"`
+	Latency             LatencySpec   `yaml:"latency" json:"latency"`
+	Faults              []FaultSpec   `yaml:"faults" json:"faults"`
+
+	Progression ProgressionSpec `yaml:"progression" json:"progression"`
+
+	Scenarios []Scenario `yaml:"scenarios" json:"scenarios"`
+}
+
+// ServiceTier is one delivery option returned by an estimate
+type ServiceTier struct {
+	ServiceType string        `yaml:"service_type" json:"service_type"`
+	BaseFare    float64       `yaml:"base_fare" json:"base_fare"`
+	PerMile     float64       `yaml:"per_mile" json:"per_mile"`
+	PerStop     float64       `yaml:"per_stop" json:"per_stop"`
+	LeadTime    time.Duration `yaml:"lead_time" json:"lead_time"`
+}
+
+// LatencySpec describes how long a mock call takes. Distribution is "fixed"
+// (Mean), "uniform" (Min..Max) or "normal" (Mean, StdDev, clamped at Min/Max).
+type LatencySpec struct {
+	Distribution string        `yaml:"distribution" json:"distribution"`
+	Mean         time.Duration `yaml:"mean" json:"mean"`
+	StdDev       time.Duration `yaml:"stddev" json:"stddev"`
+	Min          time.Duration `yaml:"min" json:"min"`
+	Max          time.Duration `yaml:"max" json:"max"`
+}
+
+// FaultSpec injects a failure into a fraction of calls
+type FaultSpec struct {
+	Operation  string        `yaml:"operation" json:"operation"` // e.g. createEstimate or createOrder; empty for every operation
+	Rate       float64       `yaml:"rate" json:"rate"`           // probability in [0, 1]
+	Kind       ErrorKind     `yaml:"kind" json:"kind"`
+	StatusCode int           `yaml:"status" json:"status"`
+	Message    string        `yaml:"message" json:"message"`
+	RetryAfter time.Duration `yaml:"retry_after" json:"retry_after"`
+}
+
+// ProgressionSpec moves created orders through their statuses. Each delay
+// is counted from order creation. When the driver is assigned and again at
+// pickup, the live ETA is set to the quoted one plus a drift drawn uniformly
+// from ETADriftMin..ETADriftMax; the order is delivered at the live ETA.
+type ProgressionSpec struct {
+	Scheduled      time.Duration `yaml:"scheduled" json:"scheduled"`
+	DriverAssigned time.Duration `yaml:"driver_assigned" json:"driver_assigned"`
+	PickedUp       time.Duration `yaml:"picked_up" json:"picked_up"`
+	ETADriftMin    time.Duration `yaml:"eta_drift_min" json:"eta_drift_min"`
+	ETADriftMax    time.Duration `yaml:"eta_drift_max" json:"eta_drift_max"`
+	Drivers        []string      `yaml:"drivers" json:"drivers"`
+}
+
+// Scenario overrides the defaults for requests matching Match. The first
+// matching scenario wins. A nil field inherits the default; an empty Tiers
+// list means no delivery options are available.
+type Scenario struct {
+	Name  string        `yaml:"name" json:"name"`
+	Match ScenarioMatch `yaml:"match" json:"match"`
+
+	Tiers      []ServiceTier `yaml:"tiers" json:"tiers"`
+	Latency    *LatencySpec  `yaml:"latency" json:"latency"`
+	Faults     []FaultSpec   `yaml:"faults" json:"faults"`
+	Surge      float64       `yaml:"surge" json:"surge"`             // price multiplier, 0 means 1
+	TollAmount float64       `yaml:"toll_amount" json:"toll_amount"` // added to every tier, e.g. bridge tolls
+}
+
+// ScenarioMatch selects requests. Zip patterns may end in * to match a prefix.
+// Empty fields match anything.
+type ScenarioMatch struct {
+	VehicleTypes []string `yaml:"vehicle_types" json:"vehicle_types"`
+	PickupZips   []string `yaml:"pickup_zips" json:"pickup_zips"`
+	DropOffZips  []string `yaml:"drop_off_zips" json:"drop_off_zips"`
+	MinStops     int      `yaml:"min_stops" json:"min_stops"`
+	MaxStops     int      `yaml:"max_stops" json:"max_stops"`
+}
+
+// DefaultScenarios returns the built-in Bay Area fixtures
+func DefaultScenarios() (*ScenarioSet, error) {
+	return ParseScenarios(defaultScenarios)
+}
+
+// LoadScenarios reads a YAML or JSON fixture file
+func LoadScenarios(path string) (*ScenarioSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock scenarios: %v", err)
+	}
+
+	set, err := ParseScenarios(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return set, nil
+}
+
+// ParseScenarios decodes a fixture document. JSON is valid YAML, so both are
+// handled by the YAML decoder.
+func ParseScenarios(data []byte) (*ScenarioSet, error) {
+	var set ScenarioSet
+	if err := yaml.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse mock scenarios: %v", err)
+	}
+	if len(set.Tiers) == 0 {
+		return nil, fmt.Errorf("mock scenarios must define at least one tier")
+	}
+	return &set, nil
+}
+
+// route is a resolved estimate or order request
+type route struct {
+	vehicleType string
+	pickup      routeStop
+	dropOffs    []routeStop
+	dedicated   bool
+}
+
+type routeStop struct {
+	zip      string
+	lat, lng float64
+	located  bool
+}
+
+// match returns the first scenario matching r, or nil
+func (s *ScenarioSet) match(r route) *Scenario {
+	for i := range s.Scenarios {
+		scenario := &s.Scenarios[i]
+		if scenario.Match.matches(r) {
+			return scenario
+		}
+	}
+	return nil
+}
+
+func (m ScenarioMatch) matches(r route) bool {
+	if len(m.VehicleTypes) > 0 && !containsFold(m.VehicleTypes, r.vehicleType) {
+		return false
+	}
+	if len(m.PickupZips) > 0 && !zipMatches(m.PickupZips, r.pickup.zip) {
+		return false
+	}
+	if len(m.DropOffZips) > 0 {
+		matched := false
+		for _, stop := range r.dropOffs {
+			if zipMatches(m.DropOffZips, stop.zip) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	stops := len(r.dropOffs)
+	if m.MinStops > 0 && stops < m.MinStops {
+		return false
+	}
+	if m.MaxStops > 0 && stops > m.MaxStops {
+		return false
+	}
+	return true
+}
+
+// Serves reports whether zip is inside the service area
+func (s *ScenarioSet) Serves(zip string) bool {
+	if len(s.ServiceArea.ZipPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range s.ServiceArea.ZipPrefixes {
+		if zip != "" && strings.HasPrefix(zip, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// PriceForVehicle rescales a cargo van price, which is how CreateOrder prices
+// orders, for vehicleType. An empty vehicle type keeps the cargo van price.
+func (s *ScenarioSet) PriceForVehicle(total money.Money, vehicleType string) money.Money {
+	if vehicleType == "" {
+		return total
+	}
+	return money.Dollars(total.Float64() / s.vehicleMultiplier("cargo_van") * s.vehicleMultiplier(vehicleType))
+}
+
+func (s *ScenarioSet) vehicleMultiplier(vehicleType string) float64 {
+	if multiplier, ok := s.Vehicles[vehicleType]; ok && multiplier > 0 {
+		return multiplier
+	}
+	return 1
+}
+
+// locate fills in coordinates from the zip table when the request has none
+func (s *ScenarioSet) locate(stop *routeStop) {
+	if stop.located {
+		return
+	}
+	best := ""
+	for prefix := range s.ZipCoordinates {
+		if strings.HasPrefix(stop.zip, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best != "" {
+		coords := s.ZipCoordinates[best]
+		stop.lat, stop.lng, stop.located = coords[0], coords[1], true
+	}
+}
+
+// miles returns the pickup → drop-off → drop-off route length
+func (r route) miles() float64 {
+	total := 0.0
+	previous := r.pickup
+	for _, stop := range r.dropOffs {
+		if previous.located && stop.located {
+			total += haversineMiles(previous.lat, previous.lng, stop.lat, stop.lng)
+		}
+		previous = stop
+	}
+	return total
+}
+
+// price computes a tier's cost for the route, rounded to cents
+func (s *ScenarioSet) price(tier ServiceTier, r route, scenario *Scenario) money.Money {
+	stops := len(r.dropOffs)
+	if stops < 1 {
+		stops = 1
+	}
+
+	cost := tier.BaseFare + tier.PerMile*r.miles() + tier.PerStop*float64(stops-1)
+
+	if multiplier, ok := s.Vehicles[r.vehicleType]; ok && multiplier > 0 {
+		cost *= multiplier
+	}
+	if scenario != nil && scenario.Surge > 0 {
+		cost *= scenario.Surge
+	}
+	if scenario != nil {
+		cost += scenario.TollAmount
+	}
+	if r.dedicated {
+		cost += s.DedicatedVehicleFee
+	}
+
+	return money.Dollars(cost)
+}
+
+// eta returns when a tier would complete the route
+func (s *ScenarioSet) eta(tier ServiceTier, r route, now time.Time) time.Time {
+	driving := time.Duration(r.miles() * s.MinutesPerMile * float64(time.Minute))
+	return now.Add(tier.LeadTime + driving)
+}
+
+// tiers returns the scenario's tiers (or the defaults), fastest first
+func (s *ScenarioSet) tiers(scenario *Scenario) []ServiceTier {
+	tiers := s.Tiers
+	if scenario != nil && scenario.Tiers != nil {
+		tiers = scenario.Tiers
+	}
+
+	sorted := append([]ServiceTier(nil), tiers...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LeadTime < sorted[j].LeadTime
+	})
+	return sorted
+}
+
+// latency samples a call duration from the scenario's (or default) spec
+func (s *ScenarioSet) latency(scenario *Scenario, rng *rand.Rand) time.Duration {
+	spec := s.Latency
+	if scenario != nil && scenario.Latency != nil {
+		spec = *scenario.Latency
+	}
+
+	var d time.Duration
+	switch spec.Distribution {
+	case "uniform":
+		if spec.Max > spec.Min {
+			d = spec.Min + time.Duration(rng.Int63n(int64(spec.Max-spec.Min)))
+		} else {
+			d = spec.Min
+		}
+	case "normal":
+		d = spec.Mean + time.Duration(rng.NormFloat64()*float64(spec.StdDev))
+	default:
+		d = spec.Mean
+	}
+
+	if d < spec.Min {
+		d = spec.Min
+	}
+	if spec.Max > 0 && d > spec.Max {
+		d = spec.Max
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// drift samples how far a live ETA strays from the quoted one
+func (p ProgressionSpec) drift(rng *rand.Rand) time.Duration {
+	if p.ETADriftMax <= p.ETADriftMin {
+		return p.ETADriftMin
+	}
+	return p.ETADriftMin + time.Duration(rng.Int63n(int64(p.ETADriftMax-p.ETADriftMin)))
+}
+
+// fault returns the first injected fault that fires for operation, if any
+func (s *ScenarioSet) fault(operation string, scenario *Scenario, rng *rand.Rand) *FaultSpec {
+	faults := s.Faults
+	if scenario != nil && scenario.Faults != nil {
+		faults = scenario.Faults
+	}
+
+	for i := range faults {
+		fault := &faults[i]
+		if fault.Operation != "" && fault.Operation != operation {
+			continue
+		}
+		if fault.Rate >= 1 || rng.Float64() < fault.Rate {
+			return fault
+		}
+	}
+	return nil
+}
+
+// apiError converts an injected fault into the error the real client would return
+func (f *FaultSpec) apiError(operation string) *APIError {
+	message := f.Message
+	if message == "" {
+		message = "injected fault"
+	}
+
+	if f.Kind == ErrorKindTransport {
+		return newTransportError(operation, fmt.Errorf("%s", message))
+	}
+
+	status := f.StatusCode
+	if status == 0 {
+		status = statusForKind(f.Kind)
+	}
+	apiErr := newHTTPError(operation, status, []byte(message), nil)
+	if f.Kind != "" {
+		apiErr.Kind = f.Kind
+	}
+	apiErr.RetryAfterDelay = f.RetryAfter
+	return apiErr
+}
+
+func statusForKind(kind ErrorKind) int {
+	switch kind {
+	case ErrorKindAuth:
+		return 401
+	case ErrorKindRateLimit:
+		return 429
+	case ErrorKindValidation:
+		return 400
+	default:
+		return 502
+	}
+}
+
+func zipMatches(patterns []string, zip string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(zip, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if pattern == zip {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func haversineMiles(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusMiles = 3958.8
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMiles * math.Asin(math.Sqrt(a))
+}
@@ -1,5 +1,7 @@
 package dispatch
 
+import "dispatch-mcp-server/internal/money"
+
 // CreateEstimateInput represents the input for creating an estimate
 type CreateEstimateInput struct {
 	AddOns             []string           `json:"add_ons,omitempty"`
@@ -48,6 +50,10 @@ type CreateOrderInput struct {
 	DropOffs     []CreateOrderDropOffInfoInput `json:"drop_offs"`
 	PickupInfo   CreateOrderPickupInfoInput    `json:"pickup_info"`
 	Tags         []TagInput                    `json:"tags,omitempty"`
+
+	// IdempotencyKey is sent as the Idempotency-Key header rather than in the
+	// mutation input. Orders are only retried when it is set.
+	IdempotencyKey string `json:"-"`
 }
 
 type DeliveryInfoInput struct {
@@ -90,7 +96,7 @@ type CreateEstimateResponse struct {
 type AvailableOrderOption struct {
 	ServiceType              string         `json:"serviceType"`
 	EstimatedDeliveryTimeUTC string         `json:"estimatedDeliveryTimeUtc"`
-	EstimatedOrderCost       float64        `json:"estimatedOrderCost"`
+	EstimatedOrderCost       money.Money    `json:"estimatedOrderCost"`
 	VehicleType              string         `json:"vehicleType"`
 	PickupLocationInfo       LocationInfo   `json:"pickupLocationInfo"`
 	DropOffLocationsInfo     []LocationInfo `json:"dropOffLocationsInfo"`
@@ -104,13 +110,15 @@ type LocationInfo struct {
 	Lng           float64 `json:"lng"`
 }
 
+// EstimateInfo breaks down an option's cost. The API sends these amounts as
+// strings, so they stay strings in JSON.
 type EstimateInfo struct {
-	ServiceType               string `json:"serviceType"`
-	VehicleType               string `json:"vehicleType"`
-	TollAmount                string `json:"tollAmount"`
-	EstimatedOrderCost        string `json:"estimatedOrderCost"`
-	DedicatedVehicleRequested *bool  `json:"dedicatedVehicleRequested"`
-	DedicatedVehicleFee       string `json:"dedicatedVehicleFee"`
+	ServiceType               string       `json:"serviceType"`
+	VehicleType               string       `json:"vehicleType"`
+	TollAmount                money.Quoted `json:"tollAmount"`
+	EstimatedOrderCost        money.Quoted `json:"estimatedOrderCost"`
+	DedicatedVehicleRequested *bool        `json:"dedicatedVehicleRequested"`
+	DedicatedVehicleFee       money.Quoted `json:"dedicatedVehicleFee"`
 }
 
 type CreateOrderResponse struct {
@@ -122,10 +130,110 @@ type CreateOrderResponse struct {
 }
 
 type Order struct {
-	ID               string  `json:"id"`
-	Status           string  `json:"status"`
-	ScheduledAt      string  `json:"scheduledAt"`
-	TotalCost        float64 `json:"totalCost"`
-	TrackingNumber   string  `json:"trackingNumber"`
-	EstimatedArrival string  `json:"estimatedArrival"`
+	ID               string      `json:"id"`
+	Status           string      `json:"status"`
+	ScheduledAt      string      `json:"scheduledAt"`
+	TotalCost        money.Money `json:"totalCost"`
+	TrackingNumber   string      `json:"trackingNumber"`
+	EstimatedArrival string      `json:"estimatedArrival"`
+
+	// Returned by the order queries and lifecycle mutations; createOrder only
+	// selects the fields above
+	ServiceType   string         `json:"serviceType,omitempty"`
+	CreatedAt     string         `json:"createdAt,omitempty"`
+	Pickup        *OrderStop     `json:"pickup,omitempty"`
+	DropOffs      []OrderStop    `json:"dropOffs,omitempty"`
+	StatusHistory []StatusChange `json:"statusHistory,omitempty"`
+	Driver        *Driver        `json:"driver,omitempty"`
+	Tracking      *Tracking      `json:"tracking,omitempty"`
+}
+
+// OrderStop is the pickup or a drop-off of an order
+type OrderStop struct {
+	BusinessName       string        `json:"businessName,omitempty"`
+	ContactName        string        `json:"contactName,omitempty"`
+	ContactPhoneNumber string        `json:"contactPhoneNumber,omitempty"`
+	Address            *AddressInput `json:"address,omitempty"`
+	Notes              string        `json:"notes,omitempty"`
+}
+
+// StatusChange is an entry of an order's status history
+type StatusChange struct {
+	Status string `json:"status"`
+	At     string `json:"at"`
+	Note   string `json:"note,omitempty"`
+
+	// ETA is the order's expected delivery time as of this change, or when
+	// it was delivered
+	ETA string `json:"eta,omitempty"`
+}
+
+// Driver is the driver assigned to an order
+type Driver struct {
+	Name        string `json:"name"`
+	PhoneNumber string `json:"phoneNumber,omitempty"`
+	Vehicle     string `json:"vehicle,omitempty"`
+}
+
+// Tracking is the last reported position of an order's driver
+type Tracking struct {
+	Lat       float64 `json:"lat"`
+	Lng       float64 `json:"lng"`
+	UpdatedAt string  `json:"updatedAt"`
+
+	// EstimatedArrival is the driver's live ETA, which drifts from the
+	// order's quoted EstimatedArrival
+	EstimatedArrival string `json:"estimatedArrival,omitempty"`
+}
+
+// ListOrdersInput filters the orders returned by ListOrders
+type ListOrdersInput struct {
+	Statuses     []string `json:"statuses,omitempty"`
+	CreatedAfter *string  `json:"created_after,omitempty"`
+	Limit        int      `json:"limit,omitempty"`
+
+	// TrackingNumber matches the order with this tracking number
+	TrackingNumber *string `json:"tracking_number,omitempty"`
+}
+
+// CancelOrderInput identifies the order to cancel
+type CancelOrderInput struct {
+	OrderID string  `json:"order_id"`
+	Reason  *string `json:"reason,omitempty"`
+}
+
+// UpdateOrderInput amends an order that hasn't been picked up. Unset fields
+// are left unchanged; DropOffs replaces every drop-off.
+type UpdateOrderInput struct {
+	OrderID     string                        `json:"order_id"`
+	PickupNotes *string                       `json:"pickup_notes,omitempty"`
+	DropOffs    []CreateOrderDropOffInfoInput `json:"drop_offs,omitempty"`
+}
+
+type GetOrderResponse struct {
+	Data struct {
+		Order Order `json:"order"`
+	} `json:"data"`
+}
+
+type ListOrdersResponse struct {
+	Data struct {
+		Orders []Order `json:"orders"`
+	} `json:"data"`
+}
+
+type CancelOrderResponse struct {
+	Data struct {
+		CancelOrder struct {
+			Order Order `json:"order"`
+		} `json:"cancelOrder"`
+	} `json:"data"`
+}
+
+type UpdateOrderResponse struct {
+	Data struct {
+		UpdateOrder struct {
+			Order Order `json:"order"`
+		} `json:"updateOrder"`
+	} `json:"data"`
 }
@@ -0,0 +1,135 @@
+package estimate
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DriftError reports the fields of an order that no longer match the
+// estimate it references
+type DriftError struct {
+	EstimateID string
+	Fields     []string
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("order no longer matches estimate %s: %s changed", e.EstimateID, strings.Join(e.Fields, ", "))
+}
+
+// Option returns the delivery option at index
+func (r *Record) Option(index int) (dispatch.AvailableOrderOption, error) {
+	options := r.Response.Data.CreateEstimate.Estimate.AvailableOrderOptions
+	if index < 0 || index >= len(options) {
+		return dispatch.AvailableOrderOption{}, fmt.Errorf("option_index %d is out of range; estimate %s has %d options (0-%d)", index, r.ID, len(options), len(options)-1)
+	}
+	return options[index], nil
+}
+
+// OrderInput completes input from the estimate and its option at index. The
+// service type, stops, add-ons and organization are copied from the estimate
+// wherever input leaves them empty, so callers only add contact details and
+// notes. Anything input does set must match what was quoted; otherwise a
+// *DriftError lists the fields that changed.
+func (r *Record) OrderInput(index int, input dispatch.CreateOrderInput) (dispatch.CreateOrderInput, error) {
+	option, err := r.Option(index)
+	if err != nil {
+		return input, err
+	}
+	var drifted []string
+
+	if input.DeliveryInfo.ServiceType == "" {
+		input.DeliveryInfo.ServiceType = option.ServiceType
+	} else if !strings.EqualFold(input.DeliveryInfo.ServiceType, option.ServiceType) {
+		drifted = append(drifted, "delivery_info.service_type")
+	}
+	if input.DeliveryInfo.OrganizationDruid == nil {
+		input.DeliveryInfo.OrganizationDruid = r.Input.OrganizationDruid
+	}
+
+	if input.PickupInfo.BusinessName == nil && r.Input.PickupInfo.BusinessName != "" {
+		name := r.Input.PickupInfo.BusinessName
+		input.PickupInfo.BusinessName = &name
+	}
+	if input.PickupInfo.Location == nil {
+		location := r.Input.PickupInfo.Location
+		input.PickupInfo.Location = &location
+	} else if !sameLocation(*input.PickupInfo.Location, r.Input.PickupInfo.Location) {
+		drifted = append(drifted, "pickup_info.location")
+	}
+
+	if len(input.DropOffs) == 0 {
+		input.DropOffs = make([]dispatch.CreateOrderDropOffInfoInput, len(r.Input.DropOffs))
+	}
+	if len(input.DropOffs) != len(r.Input.DropOffs) {
+		drifted = append(drifted, fmt.Sprintf("drop_offs (%d quoted, %d given)", len(r.Input.DropOffs), len(input.DropOffs)))
+	} else {
+		dropOffs := make([]dispatch.CreateOrderDropOffInfoInput, len(input.DropOffs))
+		for i, dropOff := range input.DropOffs {
+			quoted := r.Input.DropOffs[i]
+			if dropOff.BusinessName == nil && quoted.BusinessName != "" {
+				name := quoted.BusinessName
+				dropOff.BusinessName = &name
+			}
+			if dropOff.Location == nil {
+				location := quoted.Location
+				dropOff.Location = &location
+			} else if !sameLocation(*dropOff.Location, quoted.Location) {
+				drifted = append(drifted, fmt.Sprintf("drop_offs[%d].location", i))
+			}
+			dropOffs[i] = dropOff
+		}
+		input.DropOffs = dropOffs
+	}
+
+	if input.AddOns == nil {
+		input.AddOns = r.Input.AddOns
+	} else if !sameSet(input.AddOns, r.Input.AddOns) {
+		drifted = append(drifted, "add_ons")
+	}
+
+	if len(drifted) > 0 {
+		return input, &DriftError{EstimateID: r.ID, Fields: drifted}
+	}
+	return input, nil
+}
+
+// sameLocation compares addresses ignoring case and surrounding space, and
+// coordinates exactly
+func sameLocation(a, b dispatch.LocationInput) bool {
+	if (a.Address == nil) != (b.Address == nil) || (a.GeoCoordinates == nil) != (b.GeoCoordinates == nil) {
+		return false
+	}
+	if a.GeoCoordinates != nil && *a.GeoCoordinates != *b.GeoCoordinates {
+		return false
+	}
+	if a.Address == nil {
+		return true
+	}
+	fields := func(address *dispatch.AddressInput) []string {
+		return []string{address.Street, address.City, address.State, address.ZipCode, address.Country}
+	}
+	x, y := fields(a.Address), fields(b.Address)
+	for i := range x {
+		if !strings.EqualFold(strings.TrimSpace(x[i]), strings.TrimSpace(y[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x, y := append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,294 @@
+package estimate
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Delivery scenarios understood by SelectOption
+const (
+	ScenarioFastest        = "fastest"
+	ScenarioCheapest       = "cheapest"
+	ScenarioDeliverBy      = "deliver_by"
+	ScenarioCheapestWithin = "cheapest_within"
+	ScenarioBestValue      = "best_value"
+)
+
+// scenarioAliases maps the conversational scenario names to the ones above
+var scenarioAliases = map[string]string{
+	"asap":           ScenarioFastest,
+	"urgent":         ScenarioFastest,
+	"economy":        ScenarioCheapest,
+	"sometime_today": ScenarioCheapest,
+}
+
+// Criteria describes which delivery option to pick
+type Criteria struct {
+	Scenario  string
+	DeliverBy time.Time   // deadline for deliver_by
+	Budget    money.Money // spending limit for cheapest_within
+}
+
+// ParseCriteria reads a scenario that may carry its argument inline, e.g.
+// "deliver_by 2024-01-15T17:00:00Z" or "cheapest_within $50". Aliases such
+// as "asap" are resolved.
+func ParseCriteria(scenario string) (Criteria, error) {
+	name, argument, _ := strings.Cut(strings.TrimSpace(scenario), " ")
+	name = strings.ToLower(name)
+	if canonical, ok := scenarioAliases[name]; ok {
+		name = canonical
+	}
+	criteria := Criteria{Scenario: name}
+
+	argument = strings.TrimSpace(argument)
+	if argument == "" {
+		return criteria, nil
+	}
+	switch name {
+	case ScenarioDeliverBy:
+		deadline, err := time.Parse(time.RFC3339, argument)
+		if err != nil {
+			return criteria, fmt.Errorf("deliver_by needs an RFC 3339 time, e.g. 2024-01-15T17:00:00Z: %v", err)
+		}
+		criteria.DeliverBy = deadline
+	case ScenarioCheapestWithin:
+		budget, err := money.Parse(strings.TrimPrefix(argument, "$"), money.USD)
+		if err != nil {
+			return criteria, fmt.Errorf("cheapest_within needs a budget, e.g. 50.00: %v", err)
+		}
+		criteria.Budget = budget
+	default:
+		return criteria, fmt.Errorf("%s takes no argument, got %q", name, argument)
+	}
+	return criteria, nil
+}
+
+// Verdict explains why an option was or wasn't chosen
+type Verdict struct {
+	OptionIndex      int         `json:"option_index"`
+	ServiceType      string      `json:"service_type"`
+	Cost             money.Money `json:"cost"`
+	DeliveryTime     string      `json:"delivery_time"`
+	Selected         bool        `json:"selected"`
+	Reason           string      `json:"reason"`
+	CostPerHourSaved *float64    `json:"cost_per_hour_saved,omitempty"`
+}
+
+// Selection is the option SelectOption chose, by its index in the estimate,
+// with a verdict for every option ordered by delivery time then cost
+type Selection struct {
+	OptionIndex int                           `json:"option_index"`
+	Option      dispatch.AvailableOrderOption `json:"selected_option"`
+	Description string                        `json:"description"`
+	Verdicts    []Verdict                     `json:"verdicts"`
+}
+
+// candidate is an option with its parsed delivery time
+type candidate struct {
+	index    int
+	option   dispatch.AvailableOrderOption
+	delivery time.Time
+}
+
+// SelectOption picks an option by criteria. Options are compared by their
+// actual cost and delivery time rather than their position in the estimate.
+func SelectOption(options []dispatch.AvailableOrderOption, criteria Criteria) (*Selection, error) {
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no delivery options available")
+	}
+
+	candidates := make([]candidate, 0, len(options))
+	for i, option := range options {
+		delivery, err := time.Parse(time.RFC3339, option.EstimatedDeliveryTimeUTC)
+		if err != nil {
+			return nil, fmt.Errorf("option %d has an invalid estimatedDeliveryTimeUtc %q: %v", i, option.EstimatedDeliveryTimeUTC, err)
+		}
+		candidates = append(candidates, candidate{index: i, option: option, delivery: delivery})
+	}
+	// Fastest first, cheaper first among options arriving together
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if !candidates[i].delivery.Equal(candidates[j].delivery) {
+			return candidates[i].delivery.Before(candidates[j].delivery)
+		}
+		return candidates[i].option.EstimatedOrderCost.Cmp(candidates[j].option.EstimatedOrderCost) < 0
+	})
+	fastest := candidates[0]
+	cheapest := cheapestOf(candidates)
+
+	reasons := make(map[int]string, len(candidates))
+	var ratios map[int]float64
+	var chosen candidate
+	var description string
+
+	switch criteria.Scenario {
+	case ScenarioFastest:
+		chosen = fastest
+		description = "Fastest delivery"
+		for _, c := range candidates {
+			reasons[c.index] = fmt.Sprintf("arrives %s after the fastest option", formatWait(c.delivery.Sub(fastest.delivery)))
+		}
+		reasons[chosen.index] = fmt.Sprintf("earliest delivery, at %s", chosen.option.EstimatedDeliveryTimeUTC)
+
+	case ScenarioCheapest:
+		chosen = cheapest
+		description = "Cheapest delivery"
+		for _, c := range candidates {
+			reasons[c.index] = fmt.Sprintf("costs $%s more than the cheapest option", c.option.EstimatedOrderCost.Sub(cheapest.option.EstimatedOrderCost).Decimal())
+		}
+		reasons[chosen.index] = fmt.Sprintf("lowest cost, $%s", chosen.option.EstimatedOrderCost.Decimal())
+
+	case ScenarioDeliverBy:
+		if criteria.DeliverBy.IsZero() {
+			return nil, fmt.Errorf("deliver_by needs a deadline")
+		}
+		var onTime []candidate
+		for _, c := range candidates {
+			if c.delivery.After(criteria.DeliverBy) {
+				reasons[c.index] = fmt.Sprintf("arrives %s after the %s deadline", formatWait(c.delivery.Sub(criteria.DeliverBy)), criteria.DeliverBy.UTC().Format(time.RFC3339))
+			} else {
+				onTime = append(onTime, c)
+			}
+		}
+		if len(onTime) == 0 {
+			return nil, fmt.Errorf("no option arrives by %s; the fastest arrives at %s for $%s", criteria.DeliverBy.UTC().Format(time.RFC3339), fastest.option.EstimatedDeliveryTimeUTC, fastest.option.EstimatedOrderCost.Decimal())
+		}
+		chosen = cheapestOf(onTime)
+		description = fmt.Sprintf("Cheapest delivery arriving by %s", criteria.DeliverBy.UTC().Format(time.RFC3339))
+		for _, c := range onTime {
+			reasons[c.index] = fmt.Sprintf("arrives in time but costs $%s more", c.option.EstimatedOrderCost.Sub(chosen.option.EstimatedOrderCost).Decimal())
+		}
+		reasons[chosen.index] = fmt.Sprintf("cheapest option arriving by the deadline, %s early", formatWait(criteria.DeliverBy.Sub(chosen.delivery)))
+
+	case ScenarioCheapestWithin:
+		if criteria.Budget.Amount <= 0 {
+			return nil, fmt.Errorf("cheapest_within needs a budget greater than zero")
+		}
+		var affordable []candidate
+		for _, c := range candidates {
+			if c.option.EstimatedOrderCost.Cmp(criteria.Budget) > 0 {
+				reasons[c.index] = fmt.Sprintf("costs $%s, $%s over the $%s budget", c.option.EstimatedOrderCost.Decimal(), c.option.EstimatedOrderCost.Sub(criteria.Budget).Decimal(), criteria.Budget.Decimal())
+			} else {
+				affordable = append(affordable, c)
+			}
+		}
+		if len(affordable) == 0 {
+			return nil, fmt.Errorf("no option fits a $%s budget; the cheapest costs $%s", criteria.Budget.Decimal(), cheapest.option.EstimatedOrderCost.Decimal())
+		}
+		// Within budget, the earliest delivery wins
+		chosen = affordable[0]
+		description = fmt.Sprintf("Fastest delivery within a $%s budget", criteria.Budget.Decimal())
+		for _, c := range affordable {
+			reasons[c.index] = fmt.Sprintf("within budget but arrives %s later", formatWait(c.delivery.Sub(chosen.delivery)))
+		}
+		reasons[chosen.index] = fmt.Sprintf("earliest delivery within budget, $%s to spare", criteria.Budget.Sub(chosen.option.EstimatedOrderCost).Decimal())
+
+	case ScenarioBestValue:
+		chosen, ratios = bestValue(candidates, cheapest, reasons)
+		description = "Best value: lowest price per hour saved over the cheapest option"
+
+	default:
+		return nil, fmt.Errorf("unknown delivery scenario %q", criteria.Scenario)
+	}
+
+	selection := &Selection{OptionIndex: chosen.index, Option: chosen.option, Description: description}
+	for _, c := range candidates {
+		verdict := Verdict{
+			OptionIndex:  c.index,
+			ServiceType:  c.option.ServiceType,
+			Cost:         c.option.EstimatedOrderCost,
+			DeliveryTime: c.option.EstimatedDeliveryTimeUTC,
+			Selected:     c.index == chosen.index,
+			Reason:       reasons[c.index],
+		}
+		if ratio, ok := ratios[c.index]; ok {
+			verdict.CostPerHourSaved = &ratio
+		}
+		selection.Verdicts = append(selection.Verdicts, verdict)
+	}
+	return selection, nil
+}
+
+// bestValue prices each option that is faster than the cheapest one by what
+// every hour saved costs, and picks the lowest. Options that are both slower
+// and pricier than another are dominated and never chosen. With nothing
+// faster than the cheapest option, that option is the best value.
+func bestValue(candidates []candidate, cheapest candidate, reasons map[int]string) (candidate, map[int]float64) {
+	ratios := map[int]float64{}
+	chosen := cheapest
+	best := math.Inf(1)
+
+	for _, c := range candidates {
+		if c.index == cheapest.index {
+			continue
+		}
+		if dominator, ok := dominatedBy(c, candidates); ok {
+			reasons[c.index] = fmt.Sprintf("option %d arrives no later for no more money", dominator.index)
+			continue
+		}
+		hoursSaved := cheapest.delivery.Sub(c.delivery).Hours()
+		if hoursSaved <= 0 {
+			reasons[c.index] = "no faster than the cheapest option"
+			continue
+		}
+		extra := c.option.EstimatedOrderCost.Sub(cheapest.option.EstimatedOrderCost)
+		ratio := math.Round(extra.Float64()/hoursSaved*100) / 100
+		ratios[c.index] = ratio
+		reasons[c.index] = fmt.Sprintf("saves %s for $%s more, $%.2f per hour saved", formatWait(cheapest.delivery.Sub(c.delivery)), extra.Decimal(), ratio)
+		if ratio < best {
+			best = ratio
+			chosen = c
+		}
+	}
+
+	if chosen.index == cheapest.index {
+		reasons[cheapest.index] = "cheapest option, and nothing faster is available"
+	} else {
+		reasons[cheapest.index] = "cheapest option, but the extra speed of the selected option is worth its price"
+		reasons[chosen.index] = fmt.Sprintf("lowest price per hour saved, $%.2f", best) + ": " + reasons[chosen.index]
+	}
+	return chosen, ratios
+}
+
+// dominatedBy returns an option that arrives no later and costs no more
+// than c, and is strictly better on one of the two
+func dominatedBy(c candidate, candidates []candidate) (candidate, bool) {
+	for _, other := range candidates {
+		cmp := other.option.EstimatedOrderCost.Cmp(c.option.EstimatedOrderCost)
+		if other.index == c.index || other.delivery.After(c.delivery) || cmp > 0 {
+			continue
+		}
+		if other.delivery.Before(c.delivery) || cmp < 0 {
+			return other, true
+		}
+	}
+	return candidate{}, false
+}
+
+// cheapestOf returns the lowest-cost candidate, the earliest among ties;
+// candidates must be sorted by delivery time
+func cheapestOf(candidates []candidate) candidate {
+	cheapest := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.option.EstimatedOrderCost.Cmp(cheapest.option.EstimatedOrderCost) < 0 {
+			cheapest = c
+		}
+	}
+	return cheapest
+}
+
+// formatWait renders a non-negative duration in hours and minutes
+func formatWait(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,135 @@
+package estimate
+
+import (
+	"crypto/rand"
+	"dispatch-mcp-server/internal/dispatch"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long saved estimates are kept when no TTL is configured
+const DefaultTTL = time.Hour
+
+// Errors returned by Lookup
+var (
+	ErrNotFound = errors.New("estimate not found")
+	ErrExpired  = errors.New("estimate has expired")
+)
+
+// Record is a saved create_estimate call
+type Record struct {
+	ID        string                           `json:"id"`
+	Input     dispatch.CreateEstimateInput     `json:"input"`
+	Response  *dispatch.CreateEstimateResponse `json:"response"`
+	CreatedAt time.Time                        `json:"created_at"`
+	ExpiresAt time.Time                        `json:"expires_at"`
+
+	// Owner is the MCP session that created the estimate
+	Owner string `json:"-"`
+}
+
+// Expired reports whether the record is past its expiry time
+func (r *Record) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt)
+}
+
+// Store keeps estimate responses in memory under generated IDs so they can be
+// read back without calling the Dispatch API again
+type Store struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+// NewStore creates an empty store whose records expire after ttl
+func NewStore(ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{ttl: ttl, records: make(map[string]*Record)}
+}
+
+// Save stores response under a new ID
+func (s *Store) Save(owner string, input dispatch.CreateEstimateInput, response *dispatch.CreateEstimateResponse) (*Record, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	record := &Record{
+		ID:        id,
+		Input:     input,
+		Response:  response,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+		Owner:     owner,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(now)
+	s.records[id] = record
+	return record, nil
+}
+
+// Lookup returns owner's record with the given ID. Records saved by another
+// owner are reported as not found.
+func (s *Store) Lookup(owner, id string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[id]
+	if !ok || record.Owner != owner {
+		return nil, ErrNotFound
+	}
+	if record.Expired(time.Now()) {
+		delete(s.records, id)
+		return nil, ErrExpired
+	}
+	return record, nil
+}
+
+// List returns owner's unexpired records, newest first
+func (s *Store) List(owner string) []*Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(time.Now())
+	var records []*Record
+	for _, record := range s.records {
+		if record.Owner == owner {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].CreatedAt.After(records[j].CreatedAt)
+		}
+		return records[i].ID < records[j].ID
+	})
+	return records
+}
+
+// prune drops expired records; callers hold s.mu
+func (s *Store) prune(now time.Time) {
+	for id, record := range s.records {
+		if record.Expired(now) {
+			delete(s.records, id)
+		}
+	}
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate estimate ID: %v", err)
+	}
+	return "est_" + hex.EncodeToString(buf), nil
+}
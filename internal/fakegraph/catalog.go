@@ -0,0 +1,66 @@
+package fakegraph
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"math"
+	"sort"
+)
+
+// VehicleType is an entry of the vehicleTypes query
+type VehicleType struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	BasePrice    float64  `json:"basePrice"`
+	Price        float64  `json:"price,omitempty"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// Capability is an entry of the capabilities query
+type Capability = dispatch.Capability
+
+// vehicleTypes returns the fixture's vehicle types, cheapest first. The base
+// price is the slowest tier's base fare scaled by the vehicle multiplier.
+func vehicleTypes(scenarios *dispatch.ScenarioSet) []VehicleType {
+	baseFare := 0.0
+	if len(scenarios.Tiers) > 0 {
+		baseFare = scenarios.Tiers[0].BaseFare
+		for _, tier := range scenarios.Tiers {
+			if tier.BaseFare < baseFare {
+				baseFare = tier.BaseFare
+			}
+		}
+	}
+
+	types := make([]VehicleType, 0, len(scenarios.Vehicles))
+	for id, multiplier := range scenarios.Vehicles {
+		info := dispatch.LookupVehicle(id)
+		types = append(types, VehicleType{
+			ID:           info.ID,
+			Name:         info.Name,
+			Description:  info.Description,
+			BasePrice:    roundCents(baseFare * multiplier),
+			Capabilities: info.Capabilities,
+		})
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].BasePrice != types[j].BasePrice {
+			return types[i].BasePrice < types[j].BasePrice
+		}
+		return types[i].ID < types[j].ID
+	})
+	return types
+}
+
+// vehicleMultiplier returns the fixture's price multiplier for vehicleType, or 1
+func vehicleMultiplier(scenarios *dispatch.ScenarioSet, vehicleType string) float64 {
+	if multiplier, ok := scenarios.Vehicles[vehicleType]; ok && multiplier > 0 {
+		return multiplier
+	}
+	return 1
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
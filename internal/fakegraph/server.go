@@ -0,0 +1,520 @@
+// Package fakegraph is a local stand-in for the Dispatch GraphQL gateway. It
+// serves the operations used by dispatch.Client and order.OrderCreator over
+// real HTTP, pricing requests with dispatch.MockClient scenarios and keeping
+// created orders in memory, so the CLI, web chat and MCP server can run end
+// to end without a Dispatch environment.
+package fakegraph
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/idempotency"
+	"dispatch-mcp-server/internal/money"
+	"dispatch-mcp-server/internal/order"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Server handles GraphQL requests on any path; GET /healthz reports liveness
+type Server struct {
+	mock      *dispatch.MockClient
+	store     *Store
+	authToken string
+	resolvers map[string]resolver
+}
+
+// resolver handles one root field. Returning a *dispatch.APIError controls
+// the HTTP status and GraphQL errors sent back; other errors are reported as
+// BAD_USER_INPUT.
+type resolver func(ctx context.Context, req *request) (interface{}, error)
+
+// request is a decoded GraphQL request
+type request struct {
+	Query          string                     `json:"query"`
+	OperationName  string                     `json:"operationName,omitempty"`
+	Variables      map[string]json.RawMessage `json:"variables"`
+	idempotencyKey string
+}
+
+// NewServer creates a server using the mock fixtures configured in the environment
+func NewServer() (*Server, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewServerWithConfig(cfg)
+}
+
+// NewServerWithConfig creates a server using cfg's MockFixturesPath and MockSeed
+func NewServerWithConfig(cfg *config.Config) (*Server, error) {
+	mock, err := dispatch.NewMockClientWithConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mock scenarios: %v", err)
+	}
+
+	return NewServerWithClient(mock), nil
+}
+
+// NewServerWithClient creates a server that prices requests with mock
+func NewServerWithClient(mock *dispatch.MockClient) *Server {
+	s := &Server{
+		mock:  mock,
+		store: NewStore(),
+	}
+	s.resolvers = map[string]resolver{
+		"createEstimate":  s.createEstimate,
+		"createOrder":     s.createOrder,
+		"validateOrder":   s.validateOrder,
+		"getOrderPricing": s.getOrderPricing,
+		"vehicleTypes":    s.vehicleTypes,
+		"capabilities":    s.capabilities,
+		"order":           s.order,
+		"orders":          s.orders,
+		"cancelOrder":     s.cancelOrder,
+		"updateOrder":     s.updateOrder,
+	}
+	return s
+}
+
+// SetAuthToken makes the server reject requests without "Bearer <token>".
+// An empty token accepts any request.
+func (s *Server) SetAuthToken(token string) {
+	s.authToken = token
+}
+
+// Store returns the orders created so far
+func (s *Server) Store() *Store {
+	return s.store
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/healthz" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok", "orders": len(s.store.List())})
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeErrors(w, http.StatusMethodNotAllowed, dispatch.GraphQLError{Message: "GraphQL requests must be POSTed"})
+		return
+	}
+
+	if s.authToken != "" && r.Header.Get("Authorization") != "Bearer "+s.authToken {
+		writeErrors(w, http.StatusUnauthorized, dispatch.GraphQLError{
+			Message:    "invalid or missing bearer token",
+			Extensions: map[string]interface{}{"code": "UNAUTHENTICATED"},
+		})
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrors(w, http.StatusBadRequest, dispatch.GraphQLError{Message: fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+	req.idempotencyKey = r.Header.Get("Idempotency-Key")
+
+	field := rootField(req.Query)
+	resolve, ok := s.resolvers[field]
+	if !ok {
+		writeErrors(w, http.StatusOK, dispatch.GraphQLError{
+			Message:    fmt.Sprintf("Cannot query field %q on the fake Dispatch schema", field),
+			Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+		})
+		return
+	}
+
+	result, err := resolve(r.Context(), &req)
+	if err != nil {
+		s.writeError(w, field, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{field: result},
+	})
+}
+
+// writeError reproduces the HTTP behaviour the real gateway would show for err
+func (s *Server) writeError(w http.ResponseWriter, field string, err error) {
+	var apiErr *dispatch.APIError
+	if !errors.As(err, &apiErr) {
+		writeErrors(w, http.StatusOK, dispatch.GraphQLError{
+			Message:    err.Error(),
+			Path:       []interface{}{field},
+			Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"},
+		})
+		return
+	}
+
+	// Injected network failures drop the connection without a response
+	if apiErr.Kind == dispatch.ErrorKindTransport {
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+		writeErrors(w, http.StatusBadGateway, dispatch.GraphQLError{Message: apiErr.Message})
+		return
+	}
+
+	status := apiErr.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if apiErr.RetryAfterDelay > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(apiErr.RetryAfterDelay.Seconds()))))
+	}
+
+	gqlErrs := apiErr.GraphQLErrors
+	if len(gqlErrs) == 0 {
+		gqlErrs = []dispatch.GraphQLError{{Message: apiErr.Message, Path: []interface{}{field}}}
+	}
+	writeErrors(w, status, gqlErrs...)
+}
+
+func (s *Server) createEstimate(ctx context.Context, req *request) (interface{}, error) {
+	var input dispatch.CreateEstimateInput
+	if err := req.decode("input", &input); err != nil {
+		return nil, err
+	}
+
+	response, err := s.mock.CreateEstimate(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return response.Data.CreateEstimate, nil
+}
+
+// createOrder accepts both the dispatch.Client input (snake_case) and the
+// order.OrderCreator input (camelCase with vehicleTypeId)
+func (s *Server) createOrder(ctx context.Context, req *request) (interface{}, error) {
+	input, vehicleType, err := req.orderInput()
+	if err != nil {
+		return nil, err
+	}
+	scenarios := s.mock.Scenarios()
+	if _, ok := scenarios.Vehicles[vehicleType]; vehicleType != "" && !ok {
+		return nil, fmt.Errorf("unknown vehicle type %q", vehicleType)
+	}
+
+	fingerprint, err := idempotency.Fingerprint(req.Variables["input"])
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.store.Lookup(req.idempotencyKey, fingerprint)
+	if err != nil {
+		return nil, idempotencyError(err)
+	}
+	if existing != nil {
+		return orderPayload(existing), nil
+	}
+
+	quote, err := s.mock.QuoteOrder(ctx, "createOrder", input)
+	if err != nil {
+		return nil, err
+	}
+	total := scenarios.PriceForVehicle(quote.TotalCost, vehicleType)
+
+	now := time.Now().UTC()
+	created := &Order{
+		Order:       dispatch.NewOrderFromInput(input),
+		VehicleType: vehicleType,
+		Pricing:     Pricing{TotalPrice: total, BasePrice: total, Discounts: []Discount{}},
+	}
+	created.ScheduledAt = now.Add(30 * time.Minute).Format(time.RFC3339)
+	created.TotalCost = total
+	created.EstimatedArrival = quote.EstimatedArrival
+	created.CreatedAt = now.Format(time.RFC3339)
+	created.RecordStatus(dispatch.OrderStatusPending, "order created", now)
+
+	created, err = s.store.Create(req.idempotencyKey, fingerprint, created)
+	if err != nil {
+		return nil, idempotencyError(err)
+	}
+	return orderPayload(created), nil
+}
+
+// OrderFieldError is an entry of the errors and warnings lists
+type OrderFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (s *Server) validateOrder(ctx context.Context, req *request) (interface{}, error) {
+	var input order.OrderCreationInput
+	if err := req.decode("input", &input); err != nil {
+		return nil, err
+	}
+	return order.CheckOrder(s.mock.Scenarios(), &input), nil
+}
+
+func (s *Server) getOrderPricing(ctx context.Context, req *request) (interface{}, error) {
+	var input order.OrderCreationInput
+	if err := req.decode("input", &input); err != nil {
+		return nil, err
+	}
+	if input.VehicleTypeID == "" {
+		input.VehicleTypeID = "cargo_van"
+	}
+
+	estimate := dispatch.CreateEstimateInput{VehicleType: input.VehicleTypeID}
+	if input.PickupInfo != nil {
+		estimate.PickupInfo = dispatch.PickupInfoInput{BusinessName: input.PickupInfo.BusinessName, Location: locationFromAddress(input.PickupInfo.Address)}
+	}
+	for _, dropOff := range input.DropOffs {
+		estimate.DropOffs = append(estimate.DropOffs, dispatch.DropOffInfoInput{BusinessName: dropOff.BusinessName, Location: locationFromAddress(dropOff.Address)})
+	}
+
+	response, err := s.mock.CreateEstimate(ctx, estimate)
+	if err != nil {
+		return nil, err
+	}
+	options := response.Data.CreateEstimate.Estimate.AvailableOrderOptions
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no delivery options are available for this route")
+	}
+
+	// Quote the standard tier when offered, otherwise the slowest one
+	base := options[len(options)-1].EstimatedOrderCost
+	for _, option := range options {
+		if option.ServiceType == "standard" {
+			base = option.EstimatedOrderCost
+		}
+	}
+
+	total := base
+	for _, id := range input.Capabilities {
+		if capability, ok := dispatch.LookupCapability(id); ok {
+			total = total.Add(money.Dollars(capability.Price))
+		}
+	}
+
+	scenarios := s.mock.Scenarios()
+	requested := vehicleMultiplier(scenarios, input.VehicleTypeID)
+	available := vehicleTypes(scenarios)
+	for i := range available {
+		available[i].Price = roundCents(base.Float64() / requested * vehicleMultiplier(scenarios, available[i].ID))
+	}
+
+	return map[string]interface{}{
+		"basePrice":             base,
+		"totalPrice":            total,
+		"discounts":             []Discount{},
+		"availableVehicleTypes": available,
+	}, nil
+}
+
+func (s *Server) vehicleTypes(ctx context.Context, req *request) (interface{}, error) {
+	return vehicleTypes(s.mock.Scenarios()), nil
+}
+
+func (s *Server) capabilities(ctx context.Context, req *request) (interface{}, error) {
+	return dispatch.Capabilities, nil
+}
+
+func (s *Server) order(ctx context.Context, req *request) (interface{}, error) {
+	var id string
+	if err := req.decode("id", &id); err != nil {
+		return nil, err
+	}
+
+	found := s.store.Get(id)
+	if found == nil {
+		return nil, dispatch.NewOrderNotFoundError("order", id)
+	}
+	s.advance(found)
+	return found, nil
+}
+
+// orders lists stored orders, newest first, filtered by the optional $input
+func (s *Server) orders(ctx context.Context, req *request) (interface{}, error) {
+	var input dispatch.ListOrdersInput
+	if raw, ok := req.Variables["input"]; ok && string(raw) != "null" {
+		if err := req.decode("input", &input); err != nil {
+			return nil, err
+		}
+	}
+
+	stored := s.store.List()
+	orders := []*Order{}
+	for i := len(stored) - 1; i >= 0 && len(orders) < input.MaxResults(); i-- {
+		found := s.store.Get(stored[i].ID)
+		s.advance(found)
+		if input.Matches(&found.Order) {
+			orders = append(orders, found)
+		}
+	}
+	return orders, nil
+}
+
+// advance moves a stored order through the fixtures' status progression and
+// saves it if anything changed
+func (s *Server) advance(found *Order) {
+	if s.mock.AdvanceOrder(&found.Order, time.Now()) {
+		s.store.Put(found)
+	}
+}
+
+func (s *Server) cancelOrder(ctx context.Context, req *request) (interface{}, error) {
+	var input dispatch.CancelOrderInput
+	if err := req.decode("input", &input); err != nil {
+		return nil, err
+	}
+
+	found := s.store.Get(input.OrderID)
+	if found == nil {
+		return nil, dispatch.NewOrderNotFoundError("cancelOrder", input.OrderID)
+	}
+	s.advance(found)
+	if err := found.Cancel("cancelOrder", input, time.Now()); err != nil {
+		return nil, err
+	}
+	s.store.Put(found)
+	return map[string]interface{}{"order": found}, nil
+}
+
+func (s *Server) updateOrder(ctx context.Context, req *request) (interface{}, error) {
+	var input dispatch.UpdateOrderInput
+	if err := req.decode("input", &input); err != nil {
+		return nil, err
+	}
+
+	found := s.store.Get(input.OrderID)
+	if found == nil {
+		return nil, dispatch.NewOrderNotFoundError("updateOrder", input.OrderID)
+	}
+	s.advance(found)
+	rerouted, err := found.Update("updateOrder", input, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// New drop-offs change the route, so price it again
+	if rerouted {
+		quote, err := s.mock.QuoteOrder(ctx, "updateOrder", found.RouteInput())
+		if err != nil {
+			return nil, err
+		}
+		found.Reprice(s.mock.Scenarios().PriceForVehicle(quote.TotalCost, found.VehicleType), quote.EstimatedArrival)
+		found.Pricing = Pricing{TotalPrice: found.TotalCost, BasePrice: found.TotalCost, Discounts: []Discount{}}
+	}
+	s.store.Put(found)
+	return map[string]interface{}{"order": found}, nil
+}
+
+// decode unmarshals the named variable into out
+func (r *request) decode(name string, out interface{}) error {
+	raw, ok := r.Variables[name]
+	if !ok {
+		return fmt.Errorf("variable $%s is required", name)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("invalid $%s: %v", name, err)
+	}
+	return nil
+}
+
+// orderInput decodes $input in either order shape and returns it as a
+// dispatch.CreateOrderInput plus the requested vehicle type, which is empty
+// for dispatch.Client input since it has no vehicle field
+func (r *request) orderInput() (dispatch.CreateOrderInput, string, error) {
+	var fields map[string]json.RawMessage
+	if err := r.decode("input", &fields); err != nil {
+		return dispatch.CreateOrderInput{}, "", err
+	}
+
+	if _, ok := fields["pickupInfo"]; !ok {
+		var input dispatch.CreateOrderInput
+		err := r.decode("input", &input)
+		return input, "", err
+	}
+
+	var legacy order.OrderCreationInput
+	if err := r.decode("input", &legacy); err != nil {
+		return dispatch.CreateOrderInput{}, "", err
+	}
+
+	vehicleType := legacy.VehicleTypeID
+	if vehicleType == "" {
+		vehicleType = "cargo_van"
+	}
+	return legacy.CreateOrderInput(), vehicleType, nil
+}
+
+func locationFromAddress(address *order.AddressInput) dispatch.LocationInput {
+	if address == nil {
+		return dispatch.LocationInput{}
+	}
+	return dispatch.LocationInput{Address: &dispatch.AddressInput{
+		Street:  address.Street,
+		City:    address.City,
+		State:   address.State,
+		ZipCode: address.ZipCode,
+		Country: address.Country,
+	}}
+}
+
+// orderPayload is the createOrder result shape shared by both clients
+func orderPayload(created *Order) map[string]interface{} {
+	return map[string]interface{}{
+		"order":  created,
+		"errors": []OrderFieldError{},
+	}
+}
+
+func idempotencyError(err error) error {
+	if !errors.Is(err, idempotency.ErrKeyReused) {
+		return err
+	}
+	return &dispatch.APIError{
+		Kind:       dispatch.ErrorKindValidation,
+		Operation:  "createOrder",
+		StatusCode: http.StatusUnprocessableEntity,
+		GraphQLErrors: []dispatch.GraphQLError{{
+			Message:    err.Error(),
+			Path:       []interface{}{"createOrder"},
+			Extensions: map[string]interface{}{"code": "IDEMPOTENCY_KEY_REUSED"},
+		}},
+	}
+}
+
+// operationHeader matches everything up to the first root field of a document
+var operationHeader = regexp.MustCompile(`^\s*(?:(?:query|mutation)\s*\w*\s*(?:\([^)]*\))?\s*)?\{\s*(\w+)`)
+
+// rootField returns the first field selected by query, e.g. "createEstimate"
+func rootField(query string) string {
+	if match := operationHeader.FindStringSubmatch(query); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+func nonNil(errs []OrderFieldError) []OrderFieldError {
+	if errs == nil {
+		return []OrderFieldError{}
+	}
+	return errs
+}
+
+func writeErrors(w http.ResponseWriter, status int, errs ...dispatch.GraphQLError) {
+	writeJSON(w, status, map[string]interface{}{"data": nil, "errors": errs})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("fakegraph: failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package fakegraph
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/idempotency"
+	"dispatch-mcp-server/internal/money"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Order is an order held by the fake server. It carries the fields selected
+// by both dispatch.Client and order.OrderCreator so either can read it back.
+type Order struct {
+	dispatch.Order
+	Druid       string  `json:"druid"`
+	VehicleType string  `json:"vehicleType,omitempty"`
+	Pricing     Pricing `json:"pricing"`
+}
+
+// Pricing is the price breakdown returned with an order
+type Pricing struct {
+	TotalPrice money.Money `json:"totalPrice"`
+	BasePrice  money.Money `json:"basePrice"`
+	Discounts  []Discount  `json:"discounts"`
+}
+
+// Discount is a single price reduction
+type Discount struct {
+	Type        string      `json:"type"`
+	Amount      money.Money `json:"amount"`
+	Description string      `json:"description,omitempty"`
+}
+
+// idempotentOrder remembers which order an Idempotency-Key created and the
+// request it was created from
+type idempotentOrder struct {
+	orderID     string
+	fingerprint string
+}
+
+// Store keeps orders in memory for the lifetime of the server
+type Store struct {
+	mu     sync.Mutex
+	seq    int
+	orders map[string]*Order
+	keys   map[string]idempotentOrder
+}
+
+// NewStore creates an empty order store
+func NewStore() *Store {
+	return &Store{
+		orders: make(map[string]*Order),
+		keys:   make(map[string]idempotentOrder),
+	}
+}
+
+// Lookup returns the order created under key, if any. It fails with
+// idempotency.ErrKeyReused when key was used for a different request.
+func (s *Store) Lookup(key, fingerprint string) (*Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lookup(key, fingerprint)
+}
+
+func (s *Store) lookup(key, fingerprint string) (*Order, error) {
+	previous, ok := s.keys[key]
+	if key == "" || !ok {
+		return nil, nil
+	}
+	if previous.fingerprint != fingerprint {
+		return nil, idempotency.ErrKeyReused
+	}
+	return s.orders[previous.orderID], nil
+}
+
+// Create assigns IDs to order and stores it. When key was used before with the
+// same fingerprint the original order is returned instead.
+func (s *Store) Create(key, fingerprint string, order *Order) (*Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, err := s.lookup(key, fingerprint); existing != nil || err != nil {
+		return existing, err
+	}
+
+	s.seq++
+	order.ID = fmt.Sprintf("ORD-%06d", s.seq)
+	order.Druid = fmt.Sprintf("DRU%06d", s.seq)
+	order.TrackingNumber = fmt.Sprintf("TRK-%06d", s.seq)
+	s.orders[order.ID] = order
+
+	if key != "" {
+		s.keys[key] = idempotentOrder{orderID: order.ID, fingerprint: fingerprint}
+	}
+
+	return order, nil
+}
+
+// Get returns a copy of the order with the given ID, or nil
+func (s *Store) Get(id string) *Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.orders[id]
+	if !ok {
+		return nil
+	}
+	found := *stored
+	return &found
+}
+
+// Put replaces a stored order with an amended copy
+func (s *Store) Put(order *Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[order.ID] = order
+}
+
+// List returns all orders, oldest first
+func (s *Store) List() []*Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := make([]*Order, 0, len(s.orders))
+	for _, order := range s.orders {
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ID < orders[j].ID
+	})
+	return orders
+}
@@ -2,9 +2,15 @@ package graphql
 
 import (
 	"bytes"
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/resilience"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // GraphQLClient handles GraphQL requests
@@ -12,6 +18,8 @@ type GraphQLClient struct {
 	endpoint string
 	client   *http.Client
 	headers  map[string]string
+	retry    resilience.Policy
+	breaker  *resilience.Breaker
 }
 
 // GraphQLRequest represents a GraphQL request
@@ -39,22 +47,107 @@ type GraphQLLocation struct {
 	Column int `json:"column"`
 }
 
-// NewGraphQLClient creates a new GraphQL client
-func NewGraphQLClient(endpoint string) *GraphQLClient {
+// NewGraphQLClient creates a GraphQL client for endpoint using the retry and
+// circuit breaker configuration from the environment
+func NewGraphQLClient(endpoint string) (*GraphQLClient, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGraphQLClientWithConfig(endpoint, cfg), nil
+}
+
+// NewGraphQLClientWithConfig creates a GraphQL client for endpoint using the
+// supplied configuration
+func NewGraphQLClientWithConfig(endpoint string, cfg *config.Config) *GraphQLClient {
 	return &GraphQLClient{
 		endpoint: endpoint,
 		client:   &http.Client{},
 		headers:  make(map[string]string),
+		retry:    resilience.PolicyFromConfig(cfg),
+		breaker:  resilience.DefaultRegistry.Breaker(endpoint, resilience.BreakerSettingsFromConfig(cfg)),
 	}
 }
 
+// SetRetryPolicy overrides the retry policy loaded from the environment
+func (c *GraphQLClient) SetRetryPolicy(policy resilience.Policy) {
+	c.retry = policy
+}
+
 // SetHeader sets a header for all requests
 func (c *GraphQLClient) SetHeader(key, value string) {
 	c.headers[key] = value
 }
 
-// Execute executes a GraphQL query or mutation
-func (c *GraphQLClient) Execute(query string, variables map[string]interface{}) (*GraphQLResponse, error) {
+// StatusError is returned when the endpoint responds with a non-200 status
+type StatusError struct {
+	StatusCode int
+	retryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("GraphQL request failed with status %d", e.StatusCode)
+}
+
+// Retryable reports whether the status is transient (429 or 5xx)
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// RetryAfter returns the server's Retry-After delay, if it sent one
+func (e *StatusError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// transportError marks network failures as retryable unless the caller cancelled
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return fmt.Sprintf("failed to execute request: %v", e.err)
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+func (e *transportError) Retryable() bool {
+	return !errors.Is(e.err, context.Canceled)
+}
+
+// Execute executes a GraphQL query or mutation. Queries are retried on
+// transient failures; mutations are attempted once.
+func (c *GraphQLClient) Execute(ctx context.Context, query string, variables map[string]interface{}) (*GraphQLResponse, error) {
+	return c.ExecuteWithIdempotencyKey(ctx, "", query, variables)
+}
+
+// ExecuteWithIdempotencyKey is like Execute but sends idempotencyKey as the
+// Idempotency-Key header, which makes it safe to retry mutations as well
+func (c *GraphQLClient) ExecuteWithIdempotencyKey(ctx context.Context, idempotencyKey, query string, variables map[string]interface{}) (*GraphQLResponse, error) {
+	policy := c.retry
+	if isMutation(query) && idempotencyKey == "" {
+		policy = policy.NoRetry()
+	}
+
+	var result *GraphQLResponse
+	err := resilience.Do(ctx, policy, c.breaker, func(ctx context.Context) error {
+		var err error
+		result, err = c.executeOnce(ctx, idempotencyKey, query, variables)
+		return err
+	})
+	if errors.Is(err, resilience.ErrCircuitOpen) {
+		return nil, fmt.Errorf("GraphQL endpoint %s unavailable: %w", c.endpoint, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (c *GraphQLClient) executeOnce(ctx context.Context, idempotencyKey, query string, variables map[string]interface{}) (*GraphQLResponse, error) {
 	payload := GraphQLRequest{
 		Query:     query,
 		Variables: variables,
@@ -65,7 +158,7 @@ func (c *GraphQLClient) Execute(query string, variables map[string]interface{})
 		return nil, fmt.Errorf("failed to marshal GraphQL request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", c.endpoint, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -74,15 +167,21 @@ func (c *GraphQLClient) Execute(query string, variables map[string]interface{})
 	for key, value := range c.headers {
 		req.Header.Set(key, value)
 	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, &transportError{err: err}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GraphQL request failed with status %d", resp.StatusCode)
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			retryAfter: resilience.ParseRetryAfter(resp.Header.Get("Retry-After")),
+		}
 	}
 
 	var result GraphQLResponse
@@ -96,3 +195,8 @@ func (c *GraphQLClient) Execute(query string, variables map[string]interface{})
 
 	return &result, nil
 }
+
+// isMutation reports whether the document's first operation is a mutation
+func isMutation(query string) bool {
+	return strings.HasPrefix(strings.TrimSpace(query), "mutation")
+}
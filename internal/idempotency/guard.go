@@ -0,0 +1,195 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"dispatch-mcp-server/internal/config"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrKeyReused is returned when a key is replayed with a different request body
+var ErrKeyReused = errors.New("idempotency key was already used for a different request")
+
+// DefaultRetryWindow is how long a result stored under a derived key is
+// replayed; see Guard.Retry
+const DefaultRetryWindow = 5 * time.Minute
+
+// Guard ensures an operation runs at most once per idempotency key and
+// replays the stored result for repeated keys
+type Guard struct {
+	store       Store
+	ttl         time.Duration
+	retryWindow time.Duration
+
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewGuard creates a guard backed by store. Records expire after ttl; zero
+// keeps them forever.
+func NewGuard(store Store, ttl time.Duration) *Guard {
+	return &Guard{
+		store:       store,
+		ttl:         ttl,
+		retryWindow: DefaultRetryWindow,
+		locks:       make(map[string]*keyLock),
+	}
+}
+
+// SetRetryWindow changes how long results stored by Retry are replayed
+func (g *Guard) SetRetryWindow(window time.Duration) {
+	g.retryWindow = window
+}
+
+var (
+	defaultGuard     *Guard
+	defaultGuardOnce sync.Once
+)
+
+// DefaultGuard returns the process-wide guard configured from the environment:
+// a FileStore when DISPATCH_IDEMPOTENCY_STORE is set, otherwise a MemoryStore
+func DefaultGuard() *Guard {
+	defaultGuardOnce.Do(func() {
+		cfg, _ := config.Load()
+		defaultGuard = NewGuard(NewStoreFromConfig(cfg), cfg.IdempotencyTTL)
+		defaultGuard.SetRetryWindow(cfg.IdempotencyRetryWindow)
+	})
+	return defaultGuard
+}
+
+// NewStoreFromConfig returns the store selected by cfg, falling back to memory
+// if the persistent store cannot be opened
+func NewStoreFromConfig(cfg *config.Config) Store {
+	if cfg.IdempotencyStorePath == "" {
+		return NewMemoryStore()
+	}
+
+	store, err := NewFileStore(cfg.IdempotencyStorePath)
+	if err != nil {
+		log.Printf("idempotency: %v; falling back to in-memory store", err)
+		return NewMemoryStore()
+	}
+	return store
+}
+
+// Fingerprint returns a stable hash of request's JSON encoding
+func Fingerprint(request interface{}) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint request: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// DeriveKey builds a key from a request fingerprint for callers that did not
+// supply one, so identical retries are still deduplicated
+func DeriveKey(prefix, fingerprint string) string {
+	if len(fingerprint) > 32 {
+		fingerprint = fingerprint[:32]
+	}
+	return prefix + fingerprint
+}
+
+// Do runs fn once for key and stores its JSON-encoded result. Later calls with
+// the same key and fingerprint decode the stored result into out without
+// calling fn and report replayed=true; a different fingerprint returns
+// ErrKeyReused. Failed calls are not recorded so they can be retried.
+// Concurrent calls with the same key wait for the first to finish.
+func (g *Guard) Do(key, fingerprint string, out interface{}, fn func() (interface{}, error)) (replayed bool, err error) {
+	return g.do(key, fingerprint, g.ttl, out, fn)
+}
+
+// Retry is Do for keys derived from the request rather than chosen by the
+// caller. The result is only replayed within the retry window, so a quick
+// retry is deduplicated but the same request made later runs fn again.
+func (g *Guard) Retry(key, fingerprint string, out interface{}, fn func() (interface{}, error)) (replayed bool, err error) {
+	return g.do(key, fingerprint, g.retryWindow, out, fn)
+}
+
+func (g *Guard) do(key, fingerprint string, ttl time.Duration, out interface{}, fn func() (interface{}, error)) (replayed bool, err error) {
+	unlock := g.lock(key)
+	defer unlock()
+
+	record, err := g.store.Get(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read idempotency record: %v", err)
+	}
+
+	if record != nil {
+		if record.Fingerprint != fingerprint {
+			return false, fmt.Errorf("%w: %s", ErrKeyReused, key)
+		}
+		if err := json.Unmarshal(record.Response, out); err != nil {
+			return false, fmt.Errorf("failed to decode idempotency record: %v", err)
+		}
+		return true, nil
+	}
+
+	result, err := fn()
+	if err != nil {
+		return false, err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode result: %v", err)
+	}
+
+	now := time.Now()
+	record = &Record{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Response:    data,
+		CreatedAt:   now,
+	}
+	if ttl > 0 {
+		record.ExpiresAt = now.Add(ttl)
+	}
+
+	// The operation already succeeded; failing here would invite the caller to
+	// retry and create a duplicate, so only log the store error
+	if err := g.store.Put(record); err != nil {
+		log.Printf("idempotency: failed to store record for %s: %v", key, err)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return false, fmt.Errorf("failed to decode result: %v", err)
+	}
+	return false, nil
+}
+
+// lock serializes calls for key and returns the matching unlock function
+func (g *Guard) lock(key string) func() {
+	g.mu.Lock()
+	l, ok := g.locks[key]
+	if !ok {
+		l = &keyLock{}
+		g.locks[key] = l
+	}
+	l.refs++
+	g.mu.Unlock()
+
+	l.mu.Lock()
+
+	return func() {
+		l.mu.Unlock()
+
+		g.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(g.locks, key)
+		}
+		g.mu.Unlock()
+	}
+}
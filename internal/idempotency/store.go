@@ -0,0 +1,146 @@
+package idempotency
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Record is the stored outcome of a request made with an idempotency key
+type Record struct {
+	Key         string          `json:"key"`
+	Fingerprint string          `json:"fingerprint"`
+	Response    json.RawMessage `json:"response"`
+	CreatedAt   time.Time       `json:"created_at"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+}
+
+// Expired reports whether the record is past its expiry time
+func (r *Record) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt)
+}
+
+// Store persists idempotency records. Implementations must be safe for
+// concurrent use; Get returns nil for unknown or expired keys.
+type Store interface {
+	Get(key string) (*Record, error)
+	Put(record *Record) error
+}
+
+// MemoryStore keeps records in process memory
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*Record)}
+}
+
+func (s *MemoryStore) Get(key string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return nil, nil
+	}
+	if record.Expired(time.Now()) {
+		delete(s.records, key)
+		return nil, nil
+	}
+	return record, nil
+}
+
+func (s *MemoryStore) Put(record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[record.Key] = record
+	return nil
+}
+
+// FileStore keeps records in a JSON file so replays survive restarts. The
+// whole file is rewritten on every Put, which is fine for the order volumes a
+// single MCP server handles.
+type FileStore struct {
+	path string
+
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+// NewFileStore opens (or creates) the store at path
+func NewFileStore(path string) (*FileStore, error) {
+	store := &FileStore{
+		path:    path,
+		records: make(map[string]*Record),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read idempotency store: %v", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &store.records); err != nil {
+			return nil, fmt.Errorf("failed to parse idempotency store %s: %v", path, err)
+		}
+	}
+
+	return store, nil
+}
+
+func (s *FileStore) Get(key string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok || record.Expired(time.Now()) {
+		return nil, nil
+	}
+	return record, nil
+}
+
+func (s *FileStore) Put(record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[record.Key] = record
+
+	// Drop expired records while we're rewriting the file anyway
+	now := time.Now()
+	for key, existing := range s.records {
+		if existing.Expired(now) {
+			delete(s.records, key)
+		}
+	}
+
+	return s.save()
+}
+
+// save writes the records atomically via a temp file and rename
+func (s *FileStore) save() error {
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency store: %v", err)
+	}
+
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create idempotency store directory: %v", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write idempotency store: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write idempotency store: %v", err)
+	}
+	return nil
+}
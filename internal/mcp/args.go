@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// decodeArg decodes arguments[key] into target and reports whether it was
+// given. Tool schemas declare structured objects and arrays, but clients built
+// against the earlier string-only schemas send them JSON-encoded; both forms
+// are accepted.
+func decodeArg(arguments map[string]interface{}, key string, target interface{}) (bool, error) {
+	value, ok := arguments[key]
+	if !ok || value == nil {
+		return false, nil
+	}
+
+	var data []byte
+	if raw, isString := value.(string); isString {
+		if strings.TrimSpace(raw) == "" {
+			return false, nil
+		}
+		data = []byte(raw)
+	} else {
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			return true, fmt.Errorf("failed to parse %s: %v", key, err)
+		}
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return true, fmt.Errorf("failed to parse %s: %v", key, err)
+	}
+	return true, nil
+}
+
+// requireArg is decodeArg for required arguments
+func requireArg(arguments map[string]interface{}, key string, target interface{}) error {
+	present, err := decodeArg(arguments, key, target)
+	if err != nil {
+		return err
+	}
+	if !present {
+		return fmt.Errorf("%s is required", key)
+	}
+	return nil
+}
+
+// getScalarArg returns a string, number or boolean argument in string form,
+// so typed values and the legacy quoted form go through the same validation
+func getScalarArg(arguments map[string]interface{}, key string) string {
+	switch value := arguments[key].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		return ""
+	}
+}
+
+// getStringListArg returns an array argument of strings. The legacy form, a
+// JSON-encoded array or a comma-separated list, is also accepted.
+func getStringListArg(arguments map[string]interface{}, key string) ([]string, error) {
+	if raw, ok := arguments[key].(string); ok && !strings.HasPrefix(strings.TrimSpace(raw), "[") {
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		return values, nil
+	}
+
+	var values []string
+	if _, err := decodeArg(arguments, key, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func getStringArg(arguments map[string]interface{}, key string) string {
+	if value, ok := arguments[key].(string); ok {
+		return value
+	}
+	return ""
+}
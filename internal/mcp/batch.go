@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/batch"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// batchEstimateRow is a batch result with the saved estimate's ID, which
+// select_delivery_option and create_order accept
+type batchEstimateRow struct {
+	batch.Result
+	EstimateID string `json:"estimate_id,omitempty"`
+}
+
+// batchEstimatesResult is the batch_create_estimates response
+type batchEstimatesResult struct {
+	Results []batchEstimateRow `json:"results"`
+	Totals  batch.Totals       `json:"totals"`
+}
+
+func (s *MCPServer) batchCreateEstimatesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Cast arguments to the correct type
+	arguments, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("invalid arguments format"), nil
+	}
+
+	// Parse shipments or csv
+	var rows []batch.Row
+	if _, err := decodeArg(arguments, "shipments", &rows); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if csvText := getStringArg(arguments, "csv"); strings.TrimSpace(csvText) != "" {
+		if len(rows) > 0 {
+			return mcp.NewToolResultError("pass shipments or csv, not both"), nil
+		}
+		parsed, err := batch.ParseCSV(strings.NewReader(csvText))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		rows = parsed
+	}
+	if len(rows) == 0 {
+		return mcp.NewToolResultError("shipments or csv is required"), nil
+	}
+	if len(rows) > batch.MaxRows {
+		return mcp.NewToolResultError(fmt.Sprintf("a batch can hold at most %d shipments, got %d", batch.MaxRows, len(rows))), nil
+	}
+
+	concurrency := batch.DefaultConcurrency
+	if value := getScalarArg(arguments, "concurrency"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > batch.MaxConcurrency {
+			return mcp.NewToolResultError(fmt.Sprintf("concurrency must be a whole number between 1 and %d", batch.MaxConcurrency)), nil
+		}
+		concurrency = n
+	}
+
+	// Call API
+	report := batch.Run(ctx, s.dispatchClient, rows, concurrency)
+
+	// Save each estimate so it can be selected and booked like a single one
+	result := batchEstimatesResult{Totals: report.Totals}
+	owner := sessionID(ctx)
+	for _, row := range report.Results {
+		entry := batchEstimateRow{Result: row}
+		if row.Response != nil {
+			record, err := s.estimates.Save(owner, rows[row.Row-1].Input(), row.Response)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			entry.EstimateID = record.ID
+		}
+		result.Results = append(result.Results, entry)
+	}
+	if report.Totals.Succeeded > 0 {
+		s.notifyResourceUpdated(ctx, EstimatesURI)
+	}
+
+	// Format response
+	responseJSON, _ := json.MarshalIndent(result, "", "  ")
+	return mcp.NewToolResultText(string(responseJSON)), nil
+}
@@ -0,0 +1,120 @@
+package mcp
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
+	"dispatch-mcp-server/internal/order"
+	"fmt"
+	"strings"
+)
+
+// orderPreview is the create_order dry_run response: what validateOrder
+// reported, what the order would cost, and the createOrder call that would
+// be sent
+type orderPreview struct {
+	DryRun      bool               `json:"dry_run"`
+	Valid       bool               `json:"valid"`
+	Errors      []order.FieldError `json:"errors"`
+	Warnings    []order.FieldError `json:"warnings"`
+	Pricing     *orderPricing      `json:"pricing,omitempty"`
+	Mutation    previewMutation    `json:"mutation"`
+	EstimateID  string             `json:"estimate_id,omitempty"`
+	OptionIndex *int               `json:"option_index,omitempty"`
+}
+
+// orderPricing is the current price of the order's service type. QuotedCost
+// is set when the order is booked from a saved estimate.
+type orderPricing struct {
+	ServiceType      string       `json:"service_type"`
+	VehicleType      string       `json:"vehicle_type"`
+	TotalCost        money.Money  `json:"total_cost"`
+	EstimatedArrival string       `json:"estimated_arrival"`
+	QuotedCost       *money.Money `json:"quoted_cost,omitempty"`
+}
+
+// previewMutation is the createOrder operation exactly as it would be sent;
+// an explicit idempotency key travels in the Idempotency-Key header
+type previewMutation struct {
+	Operation      string                 `json:"operation"`
+	Variables      map[string]interface{} `json:"variables"`
+	IdempotencyKey string                 `json:"idempotency_key,omitempty"`
+}
+
+// previewOrder validates the order with the backend's validateOrder and
+// prices it with an estimate for its vehicle type, without creating
+// anything. The previewed mutation is the createOrder input the order would
+// be sent as, defaults included. Problems with the order are reported in the
+// preview; only failures to reach the API are returned as errors.
+func (s *MCPServer) previewOrder(ctx context.Context, canonical *order.Order, quoted *dispatch.AvailableOrderOption) (*orderPreview, error) {
+	input := canonical.ToDispatchInput()
+	preview := &orderPreview{
+		DryRun: true,
+		Mutation: previewMutation{
+			Operation:      "createOrder",
+			Variables:      map[string]interface{}{"input": input},
+			IdempotencyKey: input.IdempotencyKey,
+		},
+	}
+
+	validation, err := order.NewDispatchBackend(s.dispatchClient).Validate(ctx, canonical)
+	if err != nil {
+		return nil, err
+	}
+	preview.Errors = append([]order.FieldError{}, validation.Errors...)
+	preview.Warnings = append([]order.FieldError{}, validation.Warnings...)
+
+	// Price only orders the backend would accept
+	if validation.Valid {
+		pricing, problem, err := s.priceOrder(ctx, input, canonical.VehicleType)
+		if err != nil {
+			return nil, err
+		}
+		if problem != nil {
+			preview.Errors = append(preview.Errors, *problem)
+		}
+		if pricing != nil && quoted != nil {
+			quotedCost := quoted.EstimatedOrderCost
+			pricing.QuotedCost = &quotedCost
+			if pricing.TotalCost != quotedCost {
+				preview.Warnings = append(preview.Warnings, order.FieldError{
+					Field:   "pricing",
+					Message: fmt.Sprintf("the price has changed from the quoted $%s to $%s", quotedCost.Decimal(), pricing.TotalCost.Decimal()),
+				})
+			}
+		}
+		preview.Pricing = pricing
+	}
+
+	preview.Valid = len(preview.Errors) == 0
+	return preview, nil
+}
+
+// priceOrder estimates the order's route and picks its service type. A route
+// or service type Dispatch won't quote is returned as a field error.
+func (s *MCPServer) priceOrder(ctx context.Context, input dispatch.CreateOrderInput, vehicleType string) (*orderPricing, *order.FieldError, error) {
+	response, err := s.dispatchClient.CreateEstimate(ctx, input.EstimateInput(vehicleType))
+	if dispatch.IsKind(err, dispatch.ErrorKindValidation) {
+		return nil, &order.FieldError{Field: "pricing", Message: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var offered []string
+	for _, option := range response.Data.CreateEstimate.Estimate.AvailableOrderOptions {
+		if strings.EqualFold(option.ServiceType, input.DeliveryInfo.ServiceType) {
+			return &orderPricing{
+				ServiceType:      option.ServiceType,
+				VehicleType:      vehicleType,
+				TotalCost:        option.EstimatedOrderCost,
+				EstimatedArrival: option.EstimatedDeliveryTimeUTC,
+			}, nil, nil
+		}
+		offered = append(offered, option.ServiceType)
+	}
+	return nil, &order.FieldError{
+		Field:   "delivery_info.service_type",
+		Message: fmt.Sprintf("service type %q is not offered for this route; available: %s", input.DeliveryInfo.ServiceType, strings.Join(offered, ", ")),
+	}, nil
+}
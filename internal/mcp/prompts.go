@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/validation"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Prompt names served by the MCP server
+const (
+	QuoteMultiStopPrompt   = "quote_multi_stop_delivery"
+	CheapestTomorrowPrompt = "find_cheapest_option_for_tomorrow"
+	AuditSpendPrompt       = "audit_monthly_spend"
+)
+
+// registerPrompts adds prompt templates for the common dispatch workflows.
+// Prompt arguments are strings on the wire; each handler validates and
+// converts them before expanding the instructions.
+func (s *MCPServer) registerPrompts(srv *server.MCPServer) {
+	srv.AddPrompt(mcp.NewPrompt(QuoteMultiStopPrompt,
+		mcp.WithPromptDescription("Quote a delivery with several drop-offs and check which multi-delivery discounts apply"),
+		mcp.WithArgument("pickup_address", mcp.RequiredArgument(), mcp.ArgumentDescription("Pickup street address, city, state and ZIP")),
+		mcp.WithArgument("drop_off_addresses", mcp.RequiredArgument(), mcp.ArgumentDescription("Drop-off addresses, one per line or separated by semicolons")),
+		mcp.WithArgument("vehicle_type", mcp.ArgumentDescription("One of "+strings.Join(validation.VehicleTypes, ", ")+" (optional)")),
+		mcp.WithArgument("customer_tier", mcp.ArgumentDescription("One of "+strings.Join(validation.CustomerTiers, ", ")+" (default: bronze)")),
+		mcp.WithArgument("orders_per_month", mcp.ArgumentDescription("How many orders the customer places per month, a positive integer (default: 1)")),
+	), s.quoteMultiStopPrompt)
+
+	srv.AddPrompt(mcp.NewPrompt(CheapestTomorrowPrompt,
+		mcp.WithPromptDescription("Find the cheapest delivery option that still arrives tomorrow"),
+		mcp.WithArgument("pickup_address", mcp.RequiredArgument(), mcp.ArgumentDescription("Pickup street address, city, state and ZIP")),
+		mcp.WithArgument("drop_off_address", mcp.RequiredArgument(), mcp.ArgumentDescription("Drop-off street address, city, state and ZIP")),
+		mcp.WithArgument("vehicle_type", mcp.ArgumentDescription("One of "+strings.Join(validation.VehicleTypes, ", ")+" (optional)")),
+		mcp.WithArgument("deliver_by", mcp.ArgumentDescription("Latest acceptable delivery time tomorrow, as HH:MM in 24-hour time in the server's time zone (default: end of day)")),
+	), s.cheapestTomorrowPrompt)
+
+	srv.AddPrompt(mcp.NewPrompt(AuditSpendPrompt,
+		mcp.WithPromptDescription("Audit a month of delivery spend and find the savings different pricing strategies would have given"),
+		mcp.WithArgument("month", mcp.ArgumentDescription("Month to audit as YYYY-MM (default: last month)")),
+		mcp.WithArgument("customer_id", mcp.ArgumentDescription("Customer to audit (default: the authenticated customer)")),
+	), s.auditSpendPrompt)
+}
+
+func (s *MCPServer) quoteMultiStopPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	arguments := request.Params.Arguments
+	validator := validation.NewValidator()
+
+	pickup := strings.TrimSpace(arguments["pickup_address"])
+	if pickup == "" {
+		return nil, fmt.Errorf("pickup_address is required")
+	}
+	var dropOffs []string
+	for _, address := range strings.FieldsFunc(arguments["drop_off_addresses"], func(r rune) bool { return r == '\n' || r == ';' }) {
+		if address = strings.TrimSpace(address); address != "" {
+			dropOffs = append(dropOffs, address)
+		}
+	}
+	if len(dropOffs) == 0 {
+		return nil, fmt.Errorf("drop_off_addresses is required")
+	}
+
+	vehicleType := arguments["vehicle_type"]
+	if vehicleType != "" {
+		if err := validationError(validator.ValidateVehicleType(vehicleType)); err != nil {
+			return nil, err
+		}
+	}
+	customerTier := strings.ToLower(arguments["customer_tier"])
+	if customerTier == "" {
+		customerTier = "bronze"
+	}
+	if err := validationError(validator.ValidateCustomerTier(customerTier)); err != nil {
+		return nil, err
+	}
+	ordersPerMonth := 1
+	if value := arguments["orders_per_month"]; value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("orders_per_month must be a positive integer, got %q", value)
+		}
+		ordersPerMonth = n
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Quote a delivery from %s to %d drop-offs:\n", pickup, len(dropOffs))
+	for i, address := range dropOffs {
+		fmt.Fprintf(&text, "%d. %s\n", i+1, address)
+	}
+	text.WriteString("\nSteps:\n")
+	if vehicleType == "" {
+		fmt.Fprintf(&text, "1. Read the %s resource and pick the smallest vehicle that fits the load; ask me if you can't tell.\n", VehicleTypesURI)
+	} else {
+		fmt.Fprintf(&text, "1. Use vehicle_type %q.\n", vehicleType)
+	}
+	text.WriteString("2. Call create_estimate with the pickup as pickup_info and every address above, in order, as drop_offs. Split each address into street, city, state and zip_code; ask me for anything missing rather than guessing.\n")
+	fmt.Fprintf(&text, "3. Call compare_pricing_models with the cheapest availableOrderOption as original_estimate, delivery_count %d, customer_tier %q and order_frequency %d.\n", len(dropOffs), customerTier, ordersPerMonth)
+	text.WriteString("4. Reply with a table of the delivery options (delivery time and cost), then the pricing models that apply with their savings and the reason any model is ineligible. Finish with the estimate_id so the quote can be turned into an order.\n")
+
+	return mcp.NewGetPromptResult(
+		fmt.Sprintf("Quote a %d-stop delivery", len(dropOffs)),
+		[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text.String()))},
+	), nil
+}
+
+func (s *MCPServer) cheapestTomorrowPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	arguments := request.Params.Arguments
+	validator := validation.NewValidator()
+
+	pickup := strings.TrimSpace(arguments["pickup_address"])
+	if pickup == "" {
+		return nil, fmt.Errorf("pickup_address is required")
+	}
+	dropOff := strings.TrimSpace(arguments["drop_off_address"])
+	if dropOff == "" {
+		return nil, fmt.Errorf("drop_off_address is required")
+	}
+	vehicleType := arguments["vehicle_type"]
+	if vehicleType != "" {
+		if err := validationError(validator.ValidateVehicleType(vehicleType)); err != nil {
+			return nil, err
+		}
+	}
+
+	// The deadline is tomorrow in the server's time zone: deliver_by when
+	// given, otherwise the end of the day
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	deadline := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 23, 59, 59, 0, tomorrow.Location())
+	if deliverBy := arguments["deliver_by"]; deliverBy != "" {
+		clock, err := time.Parse("15:04", deliverBy)
+		if err != nil {
+			return nil, fmt.Errorf("deliver_by must be HH:MM in 24-hour time, got %q", deliverBy)
+		}
+		deadline = time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), clock.Hour(), clock.Minute(), 0, 0, tomorrow.Location())
+	}
+	zone, _ := deadline.Zone()
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Find the cheapest way to deliver from %s to %s tomorrow, %s.\n\nSteps:\n", pickup, dropOff, deadline.Format("2006-01-02"))
+	if vehicleType == "" {
+		fmt.Fprintf(&text, "1. Read the %s resource and pick the smallest vehicle that fits the load; ask me if you can't tell.\n", VehicleTypesURI)
+	} else {
+		fmt.Fprintf(&text, "1. Use vehicle_type %q.\n", vehicleType)
+	}
+	text.WriteString("2. Call create_estimate for this route. Split each address into street, city, state and zip_code; ask me for anything missing rather than guessing.\n")
+	fmt.Fprintf(&text, "3. Call select_delivery_option with the estimate_id, delivery_scenario \"deliver_by\" and deliver_by %q (%s in the %s time zone). It picks the cheapest option arriving by then; if none does, tell me and give the fastest option's delivery time and cost.\n", deadline.Format(time.RFC3339), deadline.Format("15:04"), zone)
+	fmt.Fprintf(&text, "4. Reply with the chosen option's cost and delivery time in %s, how much cheaper it is than the fastest option, and the estimate_id and option_index. Don't create the order until I confirm; then call create_order with them.\n", zone)
+
+	return mcp.NewGetPromptResult(
+		"Find the cheapest delivery option for tomorrow",
+		[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text.String()))},
+	), nil
+}
+
+func (s *MCPServer) auditSpendPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	arguments := request.Params.Arguments
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	if month := arguments["month"]; month != "" {
+		parsed, err := time.Parse("2006-01", month)
+		if err != nil {
+			return nil, fmt.Errorf("month must be YYYY-MM, got %q", month)
+		}
+		start = parsed
+	}
+	end := start.AddDate(0, 1, -1)
+
+	call := fmt.Sprintf("start_date %q, end_date %q", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if customerID := strings.TrimSpace(arguments["customer_id"]); customerID != "" {
+		call += fmt.Sprintf(", customer_id %q", customerID)
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Audit our delivery spend for %s.\n\nSteps:\n", start.Format("January 2006"))
+	fmt.Fprintf(&text, "1. Call analyze_historical_savings with %s, analysis_types [\"comprehensive\"] and include_recommendations true.\n", call)
+	fmt.Fprintf(&text, "2. Read the %s resource so you can explain each strategy's thresholds.\n", PricingRulesURI)
+	text.WriteString("3. Reply with the month's total spend, the savings each strategy (bundling, volume, loyalty) would have given, and the top recommendations ordered by savings. Call out orders that could have been combined.\n")
+
+	return mcp.NewGetPromptResult(
+		fmt.Sprintf("Audit delivery spend for %s", start.Format("January 2006")),
+		[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text.String()))},
+	), nil
+}
+
+// validationError converts a failed validation result into an error
+func validationError(result *validation.ValidationResult) error {
+	if result.Valid {
+		return nil
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%s: %s", result.Errors[0].Field, result.Errors[0].Message)
+	}
+	return fmt.Errorf("%s", result.Message)
+}
@@ -0,0 +1,165 @@
+package mcp
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
+	"dispatch-mcp-server/internal/pricing"
+	"dispatch-mcp-server/internal/validation"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Resource URIs served by the MCP server
+const (
+	VehicleTypesURI  = "dispatch://vehicle-types"
+	CapabilitiesURI  = "dispatch://capabilities"
+	PricingRulesURI  = "dispatch://pricing-rules"
+	EstimatesURI     = "dispatch://estimates"
+	estimateURIStart = EstimatesURI + "/"
+)
+
+// EstimateURI returns the resource URI of a saved estimate
+func EstimateURI(id string) string {
+	return estimateURIStart + id
+}
+
+// estimateSummary is an entry of the dispatch://estimates resource
+type estimateSummary struct {
+	ID          string      `json:"id"`
+	URI         string      `json:"uri"`
+	VehicleType string      `json:"vehicle_type"`
+	DropOffs    int         `json:"drop_offs"`
+	Options     int         `json:"options"`
+	LowestCost  money.Money `json:"lowest_cost"`
+	CreatedAt   time.Time   `json:"created_at"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+}
+
+// registerResources adds the reference data and saved estimate resources
+func (s *MCPServer) registerResources(srv *server.MCPServer) {
+	srv.AddResource(mcp.NewResource(VehicleTypesURI, "Vehicle types",
+		mcp.WithResourceDescription("Vehicle types accepted by create_estimate, with the capabilities each supports"),
+		mcp.WithMIMEType("application/json"),
+	), s.vehicleTypesResource)
+
+	srv.AddResource(mcp.NewResource(CapabilitiesURI, "Capabilities",
+		mcp.WithResourceDescription("Special services (add-ons) that can be requested on an order, with prices"),
+		mcp.WithMIMEType("application/json"),
+	), s.capabilitiesResource)
+
+	srv.AddResource(mcp.NewResource(PricingRulesURI, "Pricing rules",
+		mcp.WithResourceDescription("Pricing models compare_pricing_models evaluates, with their discount ranges and thresholds"),
+		mcp.WithMIMEType("application/json"),
+	), s.pricingRulesResource)
+	s.pricingEngine.OnRulesChanged(func(*pricing.RuleSet) {
+		srv.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{"uri": PricingRulesURI})
+	})
+
+	srv.AddResource(mcp.NewResource(EstimatesURI, "Saved estimates",
+		mcp.WithResourceDescription("Estimates this session created with create_estimate that have not expired, newest first"),
+		mcp.WithMIMEType("application/json"),
+	), s.estimatesResource)
+
+	srv.AddResourceTemplate(mcp.NewResourceTemplate(EstimatesURI+"/{id}", "Saved estimate",
+		mcp.WithTemplateDescription("A create_estimate request and response, by the estimate_id create_estimate returned"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), s.estimateResource)
+}
+
+func (s *MCPServer) vehicleTypesResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	vehicles := make([]dispatch.VehicleInfo, 0, len(validation.VehicleTypes))
+	for _, id := range validation.VehicleTypes {
+		vehicles = append(vehicles, dispatch.LookupVehicle(id))
+	}
+	return jsonResource(request.Params.URI, vehicles)
+}
+
+func (s *MCPServer) capabilitiesResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return jsonResource(request.Params.URI, dispatch.Capabilities)
+}
+
+func (s *MCPServer) pricingRulesResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	rules := s.pricingEngine.GetAvailableModels()
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Model < rules[j].Model })
+	return jsonResource(request.Params.URI, rules)
+}
+
+// watchPricingRules reloads the pricing rules file until ctx is done. A file
+// that fails validation is logged and the previous rules stay in effect.
+func (s *MCPServer) watchPricingRules(ctx context.Context) {
+	if s.config == nil || s.config.PricingRulesPath == "" {
+		return
+	}
+
+	path := s.config.PricingRulesPath
+	s.pricingEngine.OnRulesChanged(func(rules *pricing.RuleSet) {
+		fmt.Fprintf(os.Stderr, "Reloaded pricing rules version %d from %s\n", rules.Version, path)
+	})
+	go s.pricingEngine.WatchRules(ctx, path, s.config.PricingRulesReloadInterval, func(err error) {
+		fmt.Fprintf(os.Stderr, "⚠️  Keeping pricing rules version %d: %v\n", s.pricingEngine.Rules().Version, err)
+	})
+}
+
+func (s *MCPServer) estimatesResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	summaries := []estimateSummary{}
+	for _, record := range s.estimates.List(sessionID(ctx)) {
+		summary := estimateSummary{
+			ID:          record.ID,
+			URI:         EstimateURI(record.ID),
+			VehicleType: record.Input.VehicleType,
+			DropOffs:    len(record.Input.DropOffs),
+			CreatedAt:   record.CreatedAt,
+			ExpiresAt:   record.ExpiresAt,
+		}
+		options := record.Response.Data.CreateEstimate.Estimate.AvailableOrderOptions
+		summary.Options = len(options)
+		for i, option := range options {
+			if i == 0 || option.EstimatedOrderCost.Cmp(summary.LowestCost) < 0 {
+				summary.LowestCost = option.EstimatedOrderCost
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return jsonResource(request.Params.URI, summaries)
+}
+
+func (s *MCPServer) estimateResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id := strings.TrimPrefix(request.Params.URI, estimateURIStart)
+	record, err := s.estimates.Lookup(sessionID(ctx), id)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s; call create_estimate again", err, id)
+	}
+	return jsonResource(request.Params.URI, record)
+}
+
+// notifyResourceUpdated tells the calling client that the resource at uri
+// changed. mcp-go doesn't track resources/subscribe, so the notification goes
+// to the session whose call changed it.
+func (s *MCPServer) notifyResourceUpdated(ctx context.Context, uri string) {
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return
+	}
+	// Clients that haven't finished initializing just miss the notification
+	_ = srv.SendNotificationToClient(ctx, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+}
+
+func jsonResource(uri string, v interface{}) ([]mcp.ResourceContents, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %v", uri, err)
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      uri,
+		MIMEType: "application/json",
+		Text:     string(data),
+	}}, nil
+}
@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"dispatch-mcp-server/internal/money"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// withInputArg adds an argument whose schema is generated from v's type, the
+// dispatch input struct the tool decodes it into. Fields that are neither
+// pointers nor omitempty are listed as required.
+func withInputArg(name string, v interface{}, opts ...mcp.PropertyOption) mcp.ToolOption {
+	return withSchemaArg(name, schemaFor(reflect.TypeOf(v), true, nil), opts...)
+}
+
+// withDocumentArg adds an argument whose schema is generated from v's type
+// without required fields, for arguments that echo back an earlier tool
+// response or partial state
+func withDocumentArg(name string, v interface{}, opts ...mcp.PropertyOption) mcp.ToolOption {
+	return withSchemaArg(name, schemaFor(reflect.TypeOf(v), false, nil), opts...)
+}
+
+// withSchemaArg adds schema as the tool argument name. mcp.Required() marks the
+// argument itself as required, as with mcp.WithObject.
+func withSchemaArg(name string, schema map[string]interface{}, opts ...mcp.PropertyOption) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		fieldsRequired, hasRequiredFields := schema["required"]
+		delete(schema, "required")
+
+		for _, opt := range opts {
+			opt(schema)
+		}
+
+		if required, ok := schema["required"].(bool); ok && required {
+			t.InputSchema.Required = append(t.InputSchema.Required, name)
+		}
+		delete(schema, "required")
+		if hasRequiredFields {
+			schema["required"] = fieldsRequired
+		}
+
+		t.InputSchema.Properties[name] = schema
+	}
+}
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	moneyType  = reflect.TypeOf(money.Money{})
+	quotedType = reflect.TypeOf(money.Quoted{})
+)
+
+// schemaFor generates a JSON schema for t from its json tags. seen guards
+// against recursive types.
+func schemaFor(t reflect.Type, strict bool, seen map[reflect.Type]bool) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), strict, seen)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object"}
+	case reflect.Struct:
+		switch t {
+		case timeType:
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		case moneyType:
+			// Written as a plain number, e.g. 45.99
+			return map[string]interface{}{"type": "number"}
+		case quotedType:
+			// Written as a decimal string, e.g. "45.99"
+			return map[string]interface{}{"type": "string"}
+		}
+		if seen[t] {
+			return map[string]interface{}{"type": "object"}
+		}
+		if seen == nil {
+			seen = map[reflect.Type]bool{}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		properties := map[string]interface{}{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, omitempty := jsonField(field)
+			if name == "-" {
+				continue
+			}
+			properties[name] = schemaFor(field.Type, strict, seen)
+			if strict && !omitempty && field.Type.Kind() != reflect.Ptr {
+				required = append(required, name)
+			}
+		}
+
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// jsonField returns the JSON name of field and whether it is omitempty
+func jsonField(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "" {
+		return field.Name, false
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
@@ -1,88 +1,218 @@
 package mcp
 
 import (
+	"context"
+	"dispatch-mcp-server/internal/batch"
+	"dispatch-mcp-server/internal/config"
 	"dispatch-mcp-server/internal/conversation"
 	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/estimate"
+	"dispatch-mcp-server/internal/idempotency"
+	"dispatch-mcp-server/internal/order"
+	"dispatch-mcp-server/internal/pricing"
+	"dispatch-mcp-server/internal/validation"
 	"fmt"
-	"log"
-	"os"
+	"strings"
+	"sync"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
 type MCPServer struct {
-	dispatchClient     *dispatch.Client
+	config             *config.Config
+	dispatchClient     dispatch.API
 	conversationEngine *conversation.ClaudeConversationEngine
+	orderCreator       *order.OrderCreator
+	pricingEngine      *pricing.PricingEngine
+	estimates          *estimate.Store
+
+	serverOnce sync.Once
+	server     *server.MCPServer
 }
 
+// NewMCPServer creates an MCP server backed by the Dispatch API implementation
+// selected from the environment
 func NewMCPServer() (*MCPServer, error) {
-	dispatchClient, err := dispatch.NewClient()
+	dispatchClient, err := dispatch.NewAPI()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create dispatch client: %v", err)
 	}
 
+	return NewMCPServerWithClient(dispatchClient)
+}
+
+// NewMCPServerWithClient creates an MCP server backed by the given Dispatch API,
+// which is also used by the conversation engine for service area checks
+func NewMCPServerWithClient(dispatchClient dispatch.API) (*MCPServer, error) {
 	conversationEngine, err := conversation.NewClaudeConversationEngine()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create conversation engine: %v", err)
 	}
+	conversationEngine.SetDispatchClient(dispatchClient)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %v", err)
+	}
+
+	pricingEngine, err := pricing.NewPricingEngineWithConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pricing rules: %v", err)
+	}
+	conversationEngine.SetPricingEngine(pricingEngine)
 
 	return &MCPServer{
+		config:             cfg,
 		dispatchClient:     dispatchClient,
 		conversationEngine: conversationEngine,
+		orderCreator:       order.NewOrderCreatorWithBackend(order.NewDispatchBackend(dispatchClient)),
+		pricingEngine:      pricingEngine,
+		estimates:          estimate.NewStore(cfg.EstimateTTL),
 	}, nil
 }
 
+// SetIdempotencyGuard replaces the guard used to deduplicate create_order calls
+func (s *MCPServer) SetIdempotencyGuard(guard *idempotency.Guard) {
+	s.orderCreator.SetIdempotencyGuard(guard)
+}
+
+// PricingEngine returns the engine behind compare_pricing_models and the
+// pricing rules resource
+func (s *MCPServer) PricingEngine() *pricing.PricingEngine {
+	return s.pricingEngine
+}
+
+// Run serves the MCP protocol over stdio
 func (s *MCPServer) Run() error {
-	fmt.Fprintf(os.Stderr, "Starting Dispatch MCP server...\n")
+	return s.Serve(context.Background(), ServeOptions{Transport: TransportStdio})
+}
 
+// Server returns the MCP protocol server with every Dispatch tool, resource
+// and prompt registered. It is built on first use and shared by every
+// transport, so the pricing rules listener is registered only once.
+func (s *MCPServer) Server() *server.MCPServer {
+	s.serverOnce.Do(func() {
+		s.server = s.newServer()
+	})
+	return s.server
+}
+
+// newServer builds the MCP protocol server.
+// Object and array arguments are described by schemas generated from the
+// dispatch input types; the handlers also accept them JSON-encoded as strings.
+func (s *MCPServer) newServer() *server.MCPServer {
 	srv := server.NewMCPServer(
 		"dispatch-mcp-server",
 		"1.0.0",
+		server.WithResourceCapabilities(false, true),
 	)
 
 	// Register create_estimate tool
 	estimateTool := mcp.NewTool("create_estimate",
 		mcp.WithDescription("Create a cost estimate for a delivery or service order"),
-		mcp.WithString("pickup_info", mcp.Required(), mcp.Description("Pickup location information")),
-		mcp.WithString("drop_offs", mcp.Required(), mcp.Description("Drop-off locations array")),
-		mcp.WithString("vehicle_type", mcp.Required(), mcp.Description("Type of vehicle required")),
-		mcp.WithString("add_ons", mcp.Description("Optional add-ons for delivery")),
-		mcp.WithString("dedicated_vehicle", mcp.Description("Whether dedicated vehicle is requested")),
+		withInputArg("pickup_info", dispatch.PickupInfoInput{}, mcp.Required(), mcp.Description("Pickup location information")),
+		withInputArg("drop_offs", []dispatch.DropOffInfoInput{}, mcp.Required(), mcp.Description("Drop-off locations array")),
+		mcp.WithString("vehicle_type", mcp.Required(), mcp.Enum(validation.VehicleTypes...), mcp.Description("Type of vehicle required")),
+		mcp.WithArray("add_ons", mcp.WithStringItems(), mcp.Description("Optional add-ons for delivery")),
+		mcp.WithBoolean("dedicated_vehicle", mcp.Description("Whether dedicated vehicle is requested")),
 		mcp.WithString("organization_druid", mcp.Description("Organization ID")),
 	)
 
 	srv.AddTool(estimateTool, s.createEstimateTool)
 
+	batchEstimateTool := mcp.NewTool("batch_create_estimates",
+		mcp.WithDescription(fmt.Sprintf("Create estimates for up to %d shipments in one call, given as shipments or as CSV. Each row is validated like create_estimate and gets its own result; one failing row doesn't stop the rest. Returns per-row results with estimate_ids and totals.", batch.MaxRows)),
+		withInputArg("shipments", []batch.Row{}, mcp.Description("Shipments to quote, each with the create_estimate arguments and an optional reference; either this or csv is required")),
+		mcp.WithString("csv", mcp.Description("Shipments as CSV with a header row. Columns: "+strings.Join(batch.CSVColumns, ", ")+". Consecutive lines sharing a reference are one multi-stop shipment; add_ons are separated by semicolons.")),
+		mcp.WithNumber("concurrency", mcp.Min(1), mcp.Max(batch.MaxConcurrency), mcp.Description(fmt.Sprintf("How many estimates to request at once (default: %d)", batch.DefaultConcurrency))),
+	)
+
+	srv.AddTool(batchEstimateTool, s.batchCreateEstimatesTool)
+
 	// Register create_order tool
 	orderTool := mcp.NewTool("create_order",
 		mcp.WithDescription("Create a new order for delivery or service"),
-		mcp.WithString("delivery_info", mcp.Required(), mcp.Description("Delivery information")),
-		mcp.WithString("pickup_info", mcp.Required(), mcp.Description("Pickup information")),
-		mcp.WithString("drop_offs", mcp.Required(), mcp.Description("Drop-off locations array")),
-		mcp.WithString("tags", mcp.Description("Optional order tags")),
+		mcp.WithString("estimate_id", mcp.Description("estimate_id returned by create_estimate. The order is built from the estimate, so delivery_info, pickup_info and drop_offs are only needed for contact details and notes; stops that are given must match the estimate.")),
+		mcp.WithNumber("option_index", mcp.Min(0), mcp.Description("Index of the availableOrderOption to book, as returned by select_delivery_option; required with estimate_id")),
+		withInputArg("delivery_info", dispatch.DeliveryInfoInput{}, mcp.Description("Delivery information; required without estimate_id")),
+		withInputArg("pickup_info", dispatch.CreateOrderPickupInfoInput{}, mcp.Description("Pickup information; required without estimate_id")),
+		withInputArg("drop_offs", []dispatch.CreateOrderDropOffInfoInput{}, mcp.Description("Drop-off locations array; required without estimate_id")),
+		withInputArg("tags", []dispatch.TagInput{}, mcp.Description("Optional order tags")),
+		mcp.WithString("idempotency_key", mcp.Description("Optional key identifying this order; retries with the same key return the original order instead of creating another. When omitted, identical requests from this session within the retry window (DISPATCH_IDEMPOTENCY_RETRY_WINDOW, 5 minutes by default) are replayed; the same order placed after that is created again.")),
+		mcp.WithBoolean("dry_run", mcp.Description("Validate and price the order and return warnings and the exact createOrder variables without creating it. Use this to confirm details with the customer before booking.")),
 	)
 
 	srv.AddTool(orderTool, s.createOrderTool)
 
+	// Register order lifecycle tools
+	getOrderTool := mcp.NewTool("get_order",
+		mcp.WithDescription("Look up an order with its stops, status history, driver and tracking"),
+		mcp.WithString("order_id", mcp.Required(), mcp.Description("Order ID returned by create_order")),
+	)
+
+	srv.AddTool(getOrderTool, s.getOrderTool)
+
+	listOrdersTool := mcp.NewTool("list_orders",
+		mcp.WithDescription("List recent orders, newest first"),
+		mcp.WithArray("statuses", mcp.WithStringEnumItems(dispatch.OrderStatuses), mcp.Description("Only return orders in these statuses")),
+		mcp.WithString("created_after", mcp.Description("Only return orders created after this RFC 3339 time, e.g. 2024-01-15T00:00:00Z")),
+		mcp.WithNumber("limit", mcp.Min(1), mcp.Max(dispatch.MaxListOrdersLimit), mcp.Description(fmt.Sprintf("Maximum number of orders (default: %d)", dispatch.DefaultListOrdersLimit))),
+	)
+
+	srv.AddTool(listOrdersTool, s.listOrdersTool)
+
+	cancelOrderTool := mcp.NewTool("cancel_order",
+		mcp.WithDescription("Cancel an order that hasn't been picked up. Cancelling an already cancelled order succeeds."),
+		mcp.WithString("order_id", mcp.Required(), mcp.Description("Order ID returned by create_order")),
+		mcp.WithString("reason", mcp.Description("Why the order is being cancelled")),
+	)
+
+	srv.AddTool(cancelOrderTool, s.cancelOrderTool)
+
+	updateOrderTool := mcp.NewTool("update_order",
+		mcp.WithDescription("Amend the pickup notes or drop-offs of an order that hasn't been picked up. New drop-offs re-price the order."),
+		mcp.WithString("order_id", mcp.Required(), mcp.Description("Order ID returned by create_order")),
+		mcp.WithString("pickup_notes", mcp.Description("Replacement pickup notes")),
+		withInputArg("drop_offs", []dispatch.CreateOrderDropOffInfoInput{}, mcp.Description("Replacement drop-offs; every drop-off must be given, including unchanged ones")),
+	)
+
+	srv.AddTool(updateOrderTool, s.updateOrderTool)
+
+	trackOrderTool := mcp.NewTool("track_order",
+		mcp.WithDescription("Follow an order until it is delivered or cancelled, or the timeout passes. Sends a progress notification on each status change and returns the status timeline with ETA drift against the quoted delivery time."),
+		mcp.WithString("order_id", mcp.Description("Order ID returned by create_order; either this or tracking_number is required")),
+		mcp.WithString("tracking_number", mcp.Description("Tracking number returned by create_order")),
+		mcp.WithNumber("timeout_seconds", mcp.Min(1), mcp.Max(maxTrackTimeout.Seconds()), mcp.Description(fmt.Sprintf("How long to keep polling (default: %.0f)", defaultTrackTimeout.Seconds()))),
+		mcp.WithNumber("poll_interval_seconds", mcp.Min(minTrackPollInterval.Seconds()), mcp.Description(fmt.Sprintf("How often to poll Dispatch (default: %.0f)", defaultTrackPollInterval.Seconds()))),
+		mcp.WithString("estimated_delivery_time_utc", mcp.Description("Quoted estimatedDeliveryTimeUtc to measure ETA drift against (default: the order's estimatedArrival)")),
+	)
+
+	srv.AddTool(trackOrderTool, s.trackOrderTool)
+
 	// Register compare_pricing_models tool
 	pricingTool := mcp.NewTool("compare_pricing_models",
 		mcp.WithDescription("Compare different pricing models (multi-delivery, volume discounts, etc.) against an existing estimate"),
-		mcp.WithString("original_estimate", mcp.Required(), mcp.Description("Original estimate data in JSON format")),
-		mcp.WithString("delivery_count", mcp.Description("Number of deliveries in the order (default: 1)")),
-		mcp.WithString("customer_tier", mcp.Description("Customer loyalty tier (bronze, silver, gold)")),
-		mcp.WithString("order_frequency", mcp.Description("Number of orders per month (default: 1)")),
-		mcp.WithString("total_order_value", mcp.Description("Total value of the order")),
-		mcp.WithString("is_bulk_order", mcp.Description("Whether this is a bulk order (true/false)")),
+		withDocumentArg("original_estimate", dispatch.AvailableOrderOption{}, mcp.Required(), mcp.Description("One of the availableOrderOptions returned by create_estimate")),
+		mcp.WithNumber("delivery_count", mcp.Min(1), mcp.Max(100), mcp.Description("Number of deliveries in the order (default: 1)")),
+		mcp.WithString("customer_tier", mcp.Enum(validation.CustomerTiers...), mcp.Description("Customer loyalty tier (default: bronze)")),
+		mcp.WithNumber("order_frequency", mcp.Min(1), mcp.Max(100), mcp.Description("Number of orders per month (default: 1)")),
+		mcp.WithNumber("total_order_value", mcp.Description("Total value of the order")),
+		mcp.WithBoolean("is_bulk_order", mcp.Description("Whether this is a bulk order")),
+		mcp.WithString("stacking_policy", mcp.Enum(pricing.StackingPolicies...), mcp.Description("How eligible discounts combine: 'exclusive' for the single best, 'additive' to add percentages, 'multiplicative' to apply them in turn, 'best_of_group' for the best of each rule group (default: the pricing rules' policy)")),
+		mcp.WithString("sort_by", mcp.Enum(pricing.SortOrders...), mcp.Description("Order of pricing_models: 'priority' by rule priority then cost, 'cost' cheapest first, 'name' alphabetically (default: priority)")),
 	)
 
 	srv.AddTool(pricingTool, s.comparePricingModelsTool)
 
 	// Register select delivery option tool
 	selectOptionTool := mcp.NewTool("select_delivery_option",
-		mcp.WithDescription("Select the appropriate delivery option based on customer scenario (fastest vs cheapest)"),
-		mcp.WithString("estimate_response", mcp.Required(), mcp.Description("Full estimate response from create_estimate tool")),
-		mcp.WithString("delivery_scenario", mcp.Required(), mcp.Description("Delivery scenario: 'fastest' for urgent delivery, 'cheapest' for economy delivery")),
+		mcp.WithDescription("Select a delivery option by comparing cost and delivery time: fastest, cheapest, the cheapest arriving by a deadline, the fastest within a budget, or the best value per hour saved. Explains why each option was chosen or rejected."),
+		mcp.WithString("estimate_id", mcp.Description("estimate_id returned by create_estimate; either this or estimate_response is required")),
+		withDocumentArg("estimate_response", dispatch.CreateEstimateResponse{}, mcp.Description("Full estimate response from create_estimate tool")),
+		mcp.WithString("delivery_scenario", mcp.Required(), mcp.Enum(validation.DeliveryScenarios...), mcp.Description("Delivery scenario: 'fastest' for urgent delivery, 'cheapest' for economy delivery, 'deliver_by' for the cheapest option arriving by deliver_by, 'cheapest_within' for the fastest option costing at most max_cost, 'best_value' for the lowest price per hour saved")),
+		mcp.WithString("deliver_by", mcp.Description("Deadline for the deliver_by scenario in RFC 3339, e.g. 2024-01-15T17:00:00Z")),
+		mcp.WithNumber("max_cost", mcp.Description("Budget in dollars for the cheapest_within scenario")),
 	)
 
 	srv.AddTool(selectOptionTool, s.selectDeliveryOptionTool)
@@ -91,8 +221,8 @@ func (s *MCPServer) Run() error {
 	advisorTool := mcp.NewTool("conversational_pricing_advisor",
 		mcp.WithDescription("Get personalized pricing advice through natural conversation"),
 		mcp.WithString("user_message", mcp.Required(), mcp.Description("User's natural language message")),
-		mcp.WithString("conversation_context", mcp.Description("Previous conversation context in JSON format")),
-		mcp.WithString("customer_profile", mcp.Description("Customer information and preferences in JSON format")),
+		withDocumentArg("conversation_context", conversation.ConversationContext{}, mcp.Description("Previous conversation context, as returned in updated_context")),
+		withDocumentArg("customer_profile", conversation.CustomerProfile{}, mcp.Description("Customer information and preferences")),
 	)
 
 	srv.AddTool(advisorTool, s.conversationalPricingAdvisorTool)
@@ -103,16 +233,14 @@ func (s *MCPServer) Run() error {
 		mcp.WithString("start_date", mcp.Required(), mcp.Description("Start date for analysis (YYYY-MM-DD)")),
 		mcp.WithString("end_date", mcp.Required(), mcp.Description("End date for analysis (YYYY-MM-DD)")),
 		mcp.WithString("customer_id", mcp.Description("Customer ID for historical data (optional, uses authenticated user if not provided)")),
-		mcp.WithString("analysis_types", mcp.Description("Comma-separated analysis types: bundling,volume,loyalty,comprehensive (default: comprehensive)")),
-		mcp.WithString("include_recommendations", mcp.Description("Include actionable recommendations (default: true)")),
+		mcp.WithArray("analysis_types", mcp.WithStringEnumItems([]string{"bundling", "volume", "loyalty", "comprehensive"}), mcp.Description("Analysis types to run (default: comprehensive)")),
+		mcp.WithBoolean("include_recommendations", mcp.Description("Include actionable recommendations (default: true)")),
 	)
 
 	srv.AddTool(historicalTool, s.analyzeHistoricalSavingsTool)
 
-	fmt.Fprintf(os.Stderr, "MCP server initialized and listening...\n")
+	s.registerResources(srv)
+	s.registerPrompts(srv)
 
-	if err := server.ServeStdio(srv); err != nil {
-		log.Fatalf("Server error: %v", err)
-	}
-	return nil
+	return srv
 }
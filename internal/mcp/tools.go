@@ -5,15 +5,21 @@ import (
 	"dispatch-mcp-server/internal/analysis"
 	"dispatch-mcp-server/internal/conversation"
 	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/estimate"
+	"dispatch-mcp-server/internal/idempotency"
+	"dispatch-mcp-server/internal/money"
+	"dispatch-mcp-server/internal/order"
 	"dispatch-mcp-server/internal/pricing"
 	"dispatch-mcp-server/internal/validation"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 )
 
 func (s *MCPServer) createEstimateTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -26,44 +32,15 @@ func (s *MCPServer) createEstimateTool(ctx context.Context, request mcp.CallTool
 	// Initialize validator
 	validator := validation.NewValidator()
 
-	// Parse and validate pickup_info
-	pickupInfoRaw, ok := arguments["pickup_info"].(string)
-	if !ok {
-		return mcp.NewToolResultError("pickup_info is required and must be a string"), nil
-	}
-
-	// Validate JSON format
-	if result := validator.ValidateJSONString(pickupInfoRaw, "pickup_info"); !result.Valid {
-		errorMsg := fmt.Sprintf("pickup_info validation failed: %s", result.Message)
-		if len(result.Errors) > 0 {
-			errorMsg += fmt.Sprintf(" - %s", result.Errors[0].Message)
-		}
-		return mcp.NewToolResultError(errorMsg), nil
-	}
-
+	// Parse pickup_info and drop_offs
 	var pickupInfo dispatch.PickupInfoInput
-	if err := json.Unmarshal([]byte(pickupInfoRaw), &pickupInfo); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to parse pickup_info: %v", err)), nil
-	}
-
-	// Parse and validate drop_offs
-	dropOffsRaw, ok := arguments["drop_offs"].(string)
-	if !ok {
-		return mcp.NewToolResultError("drop_offs is required and must be a string"), nil
-	}
-
-	// Validate JSON format
-	if result := validator.ValidateJSONString(dropOffsRaw, "drop_offs"); !result.Valid {
-		errorMsg := fmt.Sprintf("drop_offs validation failed: %s", result.Message)
-		if len(result.Errors) > 0 {
-			errorMsg += fmt.Sprintf(" - %s", result.Errors[0].Message)
-		}
-		return mcp.NewToolResultError(errorMsg), nil
+	if err := requireArg(arguments, "pickup_info", &pickupInfo); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	var dropOffs []dispatch.DropOffInfoInput
-	if err := json.Unmarshal([]byte(dropOffsRaw), &dropOffs); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to parse drop_offs: %v", err)), nil
+	if err := requireArg(arguments, "drop_offs", &dropOffs); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	// Validate vehicle_type
@@ -84,14 +61,13 @@ func (s *MCPServer) createEstimateTool(ctx context.Context, request mcp.CallTool
 	}
 
 	// Optional fields
-	if addOns, ok := arguments["add_ons"].(string); ok && addOns != "" {
-		var addOnsList []string
-		if err := json.Unmarshal([]byte(addOns), &addOnsList); err == nil {
-			input.AddOns = addOnsList
-		}
+	addOns, err := getStringListArg(arguments, "add_ons")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
+	input.AddOns = addOns
 
-	if dedicatedVehicle, ok := arguments["dedicated_vehicle"].(string); ok && dedicatedVehicle != "" {
+	if dedicatedVehicle := getScalarArg(arguments, "dedicated_vehicle"); dedicatedVehicle != "" {
 		if dedicatedVehicle == "true" {
 			input.DedicatedVehicle = &[]bool{true}[0]
 		} else if dedicatedVehicle == "false" {
@@ -104,16 +80,42 @@ func (s *MCPServer) createEstimateTool(ctx context.Context, request mcp.CallTool
 	}
 
 	// Call API
-	response, err := s.dispatchClient.CreateEstimate(input)
+	response, err := s.dispatchClient.CreateEstimate(ctx, input)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to create estimate: %v", err)), nil
+		return dispatchErrorResult("create estimate", err), nil
 	}
 
+	if len(response.Data.CreateEstimate.Estimate.AvailableOrderOptions) == 0 {
+		return mcp.NewToolResultError("the Dispatch API returned no delivery options for this route; the pickup or drop-off may be outside the service area or the vehicle type may be unavailable"), nil
+	}
+
+	// Save the estimate so it can be read back as a resource
+	record, err := s.estimates.Save(sessionID(ctx), input, response)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	s.notifyResourceUpdated(ctx, EstimatesURI)
+
 	// Format response
-	responseJSON, _ := json.MarshalIndent(response, "", "  ")
+	result := createEstimateResult{
+		CreateEstimateResponse: response,
+		EstimateID:             record.ID,
+		EstimateURI:            EstimateURI(record.ID),
+		ExpiresAt:              record.ExpiresAt,
+	}
+	responseJSON, _ := json.MarshalIndent(result, "", "  ")
 	return mcp.NewToolResultText(string(responseJSON)), nil
 }
 
+// createEstimateResult is the create_estimate tool response: the Dispatch
+// response plus where the saved copy can be read until it expires
+type createEstimateResult struct {
+	*dispatch.CreateEstimateResponse
+	EstimateID  string    `json:"estimate_id"`
+	EstimateURI string    `json:"estimate_uri"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
 func (s *MCPServer) createOrderTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Cast arguments to the correct type
 	arguments, ok := request.Params.Arguments.(map[string]interface{})
@@ -121,58 +123,186 @@ func (s *MCPServer) createOrderTool(ctx context.Context, request mcp.CallToolReq
 		return mcp.NewToolResultError("invalid arguments format"), nil
 	}
 
-	// Parse delivery_info
-	deliveryInfoRaw, ok := arguments["delivery_info"].(string)
-	if !ok {
-		return mcp.NewToolResultError("delivery_info is required and must be a string"), nil
+	// Parse delivery_info, pickup_info and drop_offs, which are only
+	// optional when booking a saved estimate
+	estimateID := strings.TrimSpace(getStringArg(arguments, "estimate_id"))
+	decode := requireArg
+	if estimateID != "" {
+		decode = func(arguments map[string]interface{}, key string, target interface{}) error {
+			_, err := decodeArg(arguments, key, target)
+			return err
+		}
+	}
+
+	var input dispatch.CreateOrderInput
+	if err := decode(arguments, "delivery_info", &input.DeliveryInfo); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := decode(arguments, "pickup_info", &input.PickupInfo); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := decode(arguments, "drop_offs", &input.DropOffs); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Optional fields
+	if _, err := decodeArg(arguments, "tags", &input.Tags); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	var deliveryInfo dispatch.DeliveryInfoInput
-	if err := json.Unmarshal([]byte(deliveryInfoRaw), &deliveryInfo); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to parse delivery_info: %v", err)), nil
+	dryRun := getScalarArg(arguments, "dry_run")
+	if dryRun != "" && dryRun != "true" && dryRun != "false" {
+		return mcp.NewToolResultError(fmt.Sprintf("dry_run must be true or false, got %q", dryRun)), nil
 	}
 
-	// Parse pickup_info
-	pickupInfoRaw, ok := arguments["pickup_info"].(string)
+	// Fill in the order from the saved estimate, rejecting it if the estimate
+	// expired or the stops no longer match what was quoted
+	var optionIndex *int
+	var quoted *dispatch.AvailableOrderOption
+	if estimateID != "" {
+		indexStr := getScalarArg(arguments, "option_index")
+		if indexStr == "" {
+			return mcp.NewToolResultError("option_index is required with estimate_id; use the option_index returned by select_delivery_option"), nil
+		}
+		index, err := strconv.Atoi(indexStr)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("option_index must be an integer, got %q", indexStr)), nil
+		}
+
+		record, err := s.estimates.Lookup(sessionID(ctx), estimateID)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to create order: %v: %s; call create_estimate again", err, estimateID)), nil
+		}
+		// The createOrder input has no vehicle field and always books a
+		// DefaultVehicleType, so an estimate for another vehicle can't be honored
+		if vehicle := record.Input.VehicleType; vehicle != "" && vehicle != order.DefaultVehicleType {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to create order: estimate %s is for a %s, but create_order can only book a %s; call create_estimate again with vehicle_type %s", estimateID, vehicle, order.DefaultVehicleType, order.DefaultVehicleType)), nil
+		}
+		if input, err = record.OrderInput(index, input); err != nil {
+			var drift *estimate.DriftError
+			if errors.As(err, &drift) {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to create order: %v; call create_estimate again for the new details", err)), nil
+			}
+			return mcp.NewToolResultError(fmt.Sprintf("failed to create order: %v", err)), nil
+		}
+		optionIndex = &index
+		if option, err := record.Option(index); err == nil {
+			quoted = &option
+		}
+	} else if getScalarArg(arguments, "option_index") != "" {
+		return mcp.NewToolResultError("option_index needs an estimate_id"), nil
+	}
+
+	// Deduplicate retries: an explicit key wins, otherwise identical requests
+	// from the same client session share a derived key for the retry window
+	session := sessionID(ctx)
+	input.IdempotencyKey = getStringArg(arguments, "idempotency_key")
+	canonical := order.FromDispatchInput(input)
+
+	// Check and price the order without creating it
+	if dryRun == "true" {
+		preview, err := s.previewOrder(ctx, canonical, quoted)
+		if err != nil {
+			return dispatchErrorResult("preview order", err), nil
+		}
+		preview.EstimateID = estimateID
+		preview.OptionIndex = optionIndex
+		responseJSON, _ := json.MarshalIndent(preview, "", "  ")
+		return mcp.NewToolResultText(string(responseJSON)), nil
+	}
+
+	// Call API, or replay the order this session already created for this key
+	result, err := s.orderCreator.CreateOrderInSession(ctx, session, canonical)
+	if errors.Is(err, idempotency.ErrKeyReused) {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to create order: %v; use a new idempotency_key for a different order", err)), nil
+	}
+	if err != nil {
+		return dispatchErrorResult("create order", err), nil
+	}
+
+	// Format response
+	responseJSON, _ := json.MarshalIndent(createOrderResult{
+		CreateOrderResponse: result.ToDispatchResponse(),
+		IdempotencyKey:      result.IdempotencyKey,
+		Replayed:            result.Replayed,
+		EstimateID:          estimateID,
+		OptionIndex:         optionIndex,
+	}, "", "  ")
+	return mcp.NewToolResultText(string(responseJSON)), nil
+}
+
+// createOrderResult is the create_order tool response: the Dispatch response
+// plus the idempotency key and whether the order was replayed from a prior call
+type createOrderResult struct {
+	*dispatch.CreateOrderResponse
+	IdempotencyKey string `json:"idempotency_key"`
+	Replayed       bool   `json:"replayed"`
+	EstimateID     string `json:"estimate_id,omitempty"`
+	OptionIndex    *int   `json:"option_index,omitempty"`
+}
+
+func (s *MCPServer) getOrderTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Cast arguments to the correct type
+	arguments, ok := request.Params.Arguments.(map[string]interface{})
 	if !ok {
-		return mcp.NewToolResultError("pickup_info is required and must be a string"), nil
+		return mcp.NewToolResultError("invalid arguments format"), nil
+	}
+
+	orderID := strings.TrimSpace(getStringArg(arguments, "order_id"))
+	if orderID == "" {
+		return mcp.NewToolResultError("order_id is required"), nil
 	}
 
-	var pickupInfo dispatch.CreateOrderPickupInfoInput
-	if err := json.Unmarshal([]byte(pickupInfoRaw), &pickupInfo); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to parse pickup_info: %v", err)), nil
+	// Call API
+	response, err := s.dispatchClient.GetOrder(ctx, orderID)
+	if err != nil {
+		return dispatchErrorResult("get order", err), nil
 	}
 
-	// Parse drop_offs
-	dropOffsRaw, ok := arguments["drop_offs"].(string)
+	// Format response
+	responseJSON, _ := json.MarshalIndent(response, "", "  ")
+	return mcp.NewToolResultText(string(responseJSON)), nil
+}
+
+func (s *MCPServer) listOrdersTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Cast arguments to the correct type
+	arguments, ok := request.Params.Arguments.(map[string]interface{})
 	if !ok {
-		return mcp.NewToolResultError("drop_offs is required and must be a string"), nil
+		return mcp.NewToolResultError("invalid arguments format"), nil
 	}
 
-	var dropOffs []dispatch.CreateOrderDropOffInfoInput
-	if err := json.Unmarshal([]byte(dropOffsRaw), &dropOffs); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to parse drop_offs: %v", err)), nil
+	var input dispatch.ListOrdersInput
+
+	statuses, err := getStringListArg(arguments, "statuses")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	for _, status := range statuses {
+		if !containsString(dispatch.OrderStatuses, status) {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid status %q; must be one of: %s", status, strings.Join(dispatch.OrderStatuses, ", "))), nil
+		}
 	}
+	input.Statuses = statuses
 
-	// Build input
-	input := dispatch.CreateOrderInput{
-		DeliveryInfo: deliveryInfo,
-		PickupInfo:   pickupInfo,
-		DropOffs:     dropOffs,
+	if createdAfter := getStringArg(arguments, "created_after"); createdAfter != "" {
+		if _, err := time.Parse(time.RFC3339, createdAfter); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid created_after, expected an RFC 3339 time: %v", err)), nil
+		}
+		input.CreatedAfter = &createdAfter
 	}
 
-	// Optional fields
-	if tags, ok := arguments["tags"].(string); ok && tags != "" {
-		var tagsList []dispatch.TagInput
-		if err := json.Unmarshal([]byte(tags), &tagsList); err == nil {
-			input.Tags = tagsList
+	if limitStr := getScalarArg(arguments, "limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > dispatch.MaxListOrdersLimit {
+			return mcp.NewToolResultError(fmt.Sprintf("limit must be an integer between 1 and %d", dispatch.MaxListOrdersLimit)), nil
 		}
+		input.Limit = limit
 	}
 
 	// Call API
-	response, err := s.dispatchClient.CreateOrder(input)
+	response, err := s.dispatchClient.ListOrders(ctx, input)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to create order: %v", err)), nil
+		return dispatchErrorResult("list orders", err), nil
 	}
 
 	// Format response
@@ -180,34 +310,94 @@ func (s *MCPServer) createOrderTool(ctx context.Context, request mcp.CallToolReq
 	return mcp.NewToolResultText(string(responseJSON)), nil
 }
 
-func (s *MCPServer) comparePricingModelsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (s *MCPServer) cancelOrderTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Cast arguments to the correct type
 	arguments, ok := request.Params.Arguments.(map[string]interface{})
 	if !ok {
 		return mcp.NewToolResultError("invalid arguments format"), nil
 	}
 
-	// Initialize validator
-	validator := validation.NewValidator()
+	input := dispatch.CancelOrderInput{OrderID: strings.TrimSpace(getStringArg(arguments, "order_id"))}
+	if input.OrderID == "" {
+		return mcp.NewToolResultError("order_id is required"), nil
+	}
+	if reason := getStringArg(arguments, "reason"); reason != "" {
+		input.Reason = &reason
+	}
 
-	// Parse and validate original_estimate
-	originalEstimateRaw, ok := arguments["original_estimate"].(string)
+	// Call API
+	response, err := s.dispatchClient.CancelOrder(ctx, input)
+	if err != nil {
+		return dispatchErrorResult("cancel order", err), nil
+	}
+
+	// Format response
+	responseJSON, _ := json.MarshalIndent(response, "", "  ")
+	return mcp.NewToolResultText(string(responseJSON)), nil
+}
+
+func (s *MCPServer) updateOrderTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Cast arguments to the correct type
+	arguments, ok := request.Params.Arguments.(map[string]interface{})
 	if !ok {
-		return mcp.NewToolResultError("original_estimate is required and must be a string"), nil
+		return mcp.NewToolResultError("invalid arguments format"), nil
 	}
 
-	// Validate JSON format
-	if result := validator.ValidateJSONString(originalEstimateRaw, "original_estimate"); !result.Valid {
-		errorMsg := fmt.Sprintf("original_estimate validation failed: %s", result.Message)
-		if len(result.Errors) > 0 {
-			errorMsg += fmt.Sprintf(" - %s", result.Errors[0].Message)
+	input := dispatch.UpdateOrderInput{OrderID: strings.TrimSpace(getStringArg(arguments, "order_id"))}
+	if input.OrderID == "" {
+		return mcp.NewToolResultError("order_id is required"), nil
+	}
+	if pickupNotes, ok := arguments["pickup_notes"].(string); ok {
+		input.PickupNotes = &pickupNotes
+	}
+	present, err := decodeArg(arguments, "drop_offs", &input.DropOffs)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Validate the replacement drop-offs, which must each be complete
+	if present {
+		var dropOffs []map[string]interface{}
+		if _, err := decodeArg(arguments, "drop_offs", &dropOffs); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
 		}
-		return mcp.NewToolResultError(errorMsg), nil
+		if result := validation.NewValidator().ValidateDropOffs(dropOffs); !result.Valid {
+			errorMsg := fmt.Sprintf("drop_offs validation failed: %s", result.Message)
+			if len(result.Errors) > 0 {
+				errorMsg += fmt.Sprintf(" - %s: %s", result.Errors[0].Field, result.Errors[0].Message)
+			}
+			return mcp.NewToolResultError(errorMsg), nil
+		}
+	}
+	if input.PickupNotes == nil && len(input.DropOffs) == 0 {
+		return mcp.NewToolResultError("nothing to update; set pickup_notes or drop_offs"), nil
+	}
+
+	// Call API
+	response, err := s.dispatchClient.UpdateOrder(ctx, input)
+	if err != nil {
+		return dispatchErrorResult("update order", err), nil
+	}
+
+	// Format response
+	responseJSON, _ := json.MarshalIndent(response, "", "  ")
+	return mcp.NewToolResultText(string(responseJSON)), nil
+}
+
+func (s *MCPServer) comparePricingModelsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Cast arguments to the correct type
+	arguments, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("invalid arguments format"), nil
 	}
 
+	// Initialize validator
+	validator := validation.NewValidator()
+
+	// Parse original_estimate
 	var originalEstimate dispatch.AvailableOrderOption
-	if err := json.Unmarshal([]byte(originalEstimateRaw), &originalEstimate); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to parse original_estimate: %v", err)), nil
+	if err := requireArg(arguments, "original_estimate", &originalEstimate); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	// Parse context parameters with defaults
@@ -220,7 +410,7 @@ func (s *MCPServer) comparePricingModelsTool(ctx context.Context, request mcp.Ca
 	}
 
 	// Parse and validate delivery_count
-	if deliveryCountStr, ok := arguments["delivery_count"].(string); ok && deliveryCountStr != "" {
+	if deliveryCountStr := getScalarArg(arguments, "delivery_count"); deliveryCountStr != "" {
 		if result := validator.ValidateNumericString(deliveryCountStr, "delivery_count", 1, 100); !result.Valid {
 			errorMsg := fmt.Sprintf("delivery_count validation failed: %s", result.Message)
 			if len(result.Errors) > 0 {
@@ -246,7 +436,7 @@ func (s *MCPServer) comparePricingModelsTool(ctx context.Context, request mcp.Ca
 	}
 
 	// Parse and validate order_frequency
-	if orderFreqStr, ok := arguments["order_frequency"].(string); ok && orderFreqStr != "" {
+	if orderFreqStr := getScalarArg(arguments, "order_frequency"); orderFreqStr != "" {
 		if result := validator.ValidateNumericString(orderFreqStr, "order_frequency", 1, 100); !result.Valid {
 			errorMsg := fmt.Sprintf("order_frequency validation failed: %s", result.Message)
 			if len(result.Errors) > 0 {
@@ -260,8 +450,8 @@ func (s *MCPServer) comparePricingModelsTool(ctx context.Context, request mcp.Ca
 	}
 
 	// Parse and validate total_order_value
-	if totalValueStr, ok := arguments["total_order_value"].(string); ok && totalValueStr != "" {
-		if value, err := strconv.ParseFloat(totalValueStr, 64); err != nil {
+	if totalValueStr := getScalarArg(arguments, "total_order_value"); totalValueStr != "" {
+		if value, err := money.Parse(totalValueStr, money.USD); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("total_order_value must be a valid number: %v", err)), nil
 		} else {
 			context.TotalOrderValue = value
@@ -269,7 +459,7 @@ func (s *MCPServer) comparePricingModelsTool(ctx context.Context, request mcp.Ca
 	}
 
 	// Parse and validate is_bulk_order
-	if isBulkStr, ok := arguments["is_bulk_order"].(string); ok && isBulkStr != "" {
+	if isBulkStr := getScalarArg(arguments, "is_bulk_order"); isBulkStr != "" {
 		if result := validator.ValidateBooleanString(isBulkStr, "is_bulk_order"); !result.Valid {
 			errorMsg := fmt.Sprintf("is_bulk_order validation failed: %s", result.Message)
 			if len(result.Errors) > 0 {
@@ -280,9 +470,15 @@ func (s *MCPServer) comparePricingModelsTool(ctx context.Context, request mcp.Ca
 		context.IsBulkOrder = (isBulkStr == "true")
 	}
 
-	// Create pricing engine and compare models
-	engine := pricing.NewPricingEngine()
-	comparison := engine.ComparePricingModels(&originalEstimate, context)
+	// Compare models, stacking and ordering them as requested
+	opts := pricing.CompareOptions{
+		StackingPolicy: pricing.StackingPolicy(getStringArg(arguments, "stacking_policy")),
+		SortBy:         pricing.SortOrder(getStringArg(arguments, "sort_by")),
+	}
+	comparison, err := s.pricingEngine.ComparePricingModelsWithOptions(&originalEstimate, context, opts)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	// Format response
 	responseJSON, _ := json.MarshalIndent(comparison, "", "  ")
@@ -299,24 +495,17 @@ func (s *MCPServer) selectDeliveryOptionTool(ctx context.Context, request mcp.Ca
 	// Initialize validator
 	validator := validation.NewValidator()
 
-	// Parse and validate estimate response
-	estimateResponseRaw, ok := arguments["estimate_response"].(string)
-	if !ok {
-		return mcp.NewToolResultError("estimate_response is required and must be a string"), nil
-	}
-
-	// Validate JSON format
-	if result := validator.ValidateJSONString(estimateResponseRaw, "estimate_response"); !result.Valid {
-		errorMsg := fmt.Sprintf("estimate_response validation failed: %s", result.Message)
-		if len(result.Errors) > 0 {
-			errorMsg += fmt.Sprintf(" - %s", result.Errors[0].Message)
-		}
-		return mcp.NewToolResultError(errorMsg), nil
-	}
-
+	// Use the saved estimate, or else the response passed in
+	estimateID := strings.TrimSpace(getStringArg(arguments, "estimate_id"))
 	var estimateResponse dispatch.CreateEstimateResponse
-	if err := json.Unmarshal([]byte(estimateResponseRaw), &estimateResponse); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to parse estimate_response: %v", err)), nil
+	if estimateID != "" {
+		record, err := s.estimates.Lookup(sessionID(ctx), estimateID)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("%v: %s; call create_estimate again", err, estimateID)), nil
+		}
+		estimateResponse = *record.Response
+	} else if err := requireArg(arguments, "estimate_response", &estimateResponse); err != nil {
+		return mcp.NewToolResultError("estimate_id or estimate_response is required"), nil
 	}
 
 	// Parse and validate delivery scenario
@@ -325,45 +514,58 @@ func (s *MCPServer) selectDeliveryOptionTool(ctx context.Context, request mcp.Ca
 		return mcp.NewToolResultError("delivery_scenario is required and must be a string"), nil
 	}
 
-	// Validate delivery scenario
-	if result := validator.ValidateDeliveryScenario(scenario); !result.Valid {
+	// Validate delivery scenario; deliver_by and cheapest_within may carry
+	// their argument inline, e.g. "cheapest_within 50"
+	scenarioName, _, _ := strings.Cut(strings.TrimSpace(scenario), " ")
+	if result := validator.ValidateDeliveryScenario(scenarioName); !result.Valid {
 		errorMsg := fmt.Sprintf("delivery_scenario validation failed: %s", result.Message)
 		if len(result.Errors) > 0 {
 			errorMsg += fmt.Sprintf(" - %s", result.Errors[0].Message)
 		}
 		return mcp.NewToolResultError(errorMsg), nil
 	}
-
-	// Get available options
-	options := estimateResponse.Data.CreateEstimate.Estimate.AvailableOrderOptions
-	if len(options) == 0 {
-		return mcp.NewToolResultError("no delivery options available"), nil
+	criteria, err := estimate.ParseCriteria(scenario)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	var selectedOption dispatch.AvailableOrderOption
-	var scenarioDescription string
+	// Parse deliver_by and max_cost, which take precedence over inline values
+	if deliverBy := strings.TrimSpace(getStringArg(arguments, "deliver_by")); deliverBy != "" {
+		deadline, err := time.Parse(time.RFC3339, deliverBy)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("deliver_by must be an RFC 3339 time, e.g. 2024-01-15T17:00:00Z: %v", err)), nil
+		}
+		criteria.DeliverBy = deadline
+	}
+	if maxCost := getScalarArg(arguments, "max_cost"); maxCost != "" {
+		budget, err := money.Parse(maxCost, money.USD)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("max_cost must be a valid number: %v", err)), nil
+		}
+		criteria.Budget = budget
+	}
 
-	switch scenario {
-	case "fastest", "asap", "urgent":
-		// First option = fastest delivery, most expensive
-		selectedOption = options[0]
-		scenarioDescription = "Fastest delivery (most expensive)"
-	case "cheapest", "economy", "sometime_today":
-		// Last option = slowest delivery, cheapest
-		selectedOption = options[len(options)-1]
-		scenarioDescription = "Cheapest delivery (slowest)"
-	default:
-		return mcp.NewToolResultError("delivery_scenario must be 'fastest' or 'cheapest'"), nil
+	// Compare the options by cost and delivery time
+	options := estimateResponse.Data.CreateEstimate.Estimate.AvailableOrderOptions
+	selection, err := estimate.SelectOption(options, criteria)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	// Create response with selected option and context
+	// Create response with selected option and context; option_index and
+	// estimate_id let create_order book the option without restating it
 	response := map[string]interface{}{
-		"selected_option": selectedOption,
+		"selected_option": selection.Option,
+		"option_index":    selection.OptionIndex,
 		"scenario":        scenario,
-		"description":     scenarioDescription,
+		"description":     selection.Description,
+		"verdicts":        selection.Verdicts,
 		"total_options":   len(options),
 		"all_options":     options,
 	}
+	if estimateID != "" {
+		response["estimate_id"] = estimateID
+	}
 
 	// Format response
 	responseJSON, _ := json.MarshalIndent(response, "", "  ")
@@ -385,24 +587,27 @@ func (s *MCPServer) conversationalPricingAdvisorTool(ctx context.Context, reques
 
 	// Parse conversation context (optional)
 	var conversationContext *conversation.ConversationContext
-	if contextRaw, ok := arguments["conversation_context"].(string); ok && contextRaw != "" {
-		if err := json.Unmarshal([]byte(contextRaw), &conversationContext); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to parse conversation_context: %v", err)), nil
-		}
+	if _, err := decodeArg(arguments, "conversation_context", &conversationContext); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	// Parse customer profile (optional)
-	if profileRaw, ok := arguments["customer_profile"].(string); ok && profileRaw != "" {
+	// Profile fields given here override those in conversation_context
+	var customerProfile conversation.CustomerProfile
+	if conversationContext != nil {
+		customerProfile = conversationContext.CustomerProfile
+	}
+	if present, err := decodeArg(arguments, "customer_profile", &customerProfile); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	} else if present {
 		if conversationContext == nil {
 			conversationContext = &conversation.ConversationContext{}
 		}
-		if err := json.Unmarshal([]byte(profileRaw), &conversationContext.CustomerProfile); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to parse customer_profile: %v", err)), nil
-		}
+		conversationContext.CustomerProfile = customerProfile
 	}
 
 	// Process the message through the conversation engine
-	response, err := s.conversationEngine.ProcessMessage(userMessage, conversationContext)
+	response, err := s.conversationEngine.ProcessMessageContext(ctx, userMessage, conversationContext)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("failed to process message: %v", err)), nil
 	}
@@ -443,22 +648,19 @@ func (s *MCPServer) analyzeHistoricalSavingsTool(ctx context.Context, request mc
 
 	// Parse optional parameters
 	customerID := getStringArg(arguments, "customer_id")
-	analysisTypesStr := getStringArg(arguments, "analysis_types")
-	if analysisTypesStr == "" {
-		analysisTypesStr = "comprehensive"
+	analysisTypes, err := getStringListArg(arguments, "analysis_types")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(analysisTypes) == 0 {
+		analysisTypes = []string{"comprehensive"}
 	}
 
-	includeRecommendations := getStringArg(arguments, "include_recommendations")
+	includeRecommendations := getScalarArg(arguments, "include_recommendations")
 	if includeRecommendations == "" {
 		includeRecommendations = "true"
 	}
 
-	// Parse analysis types
-	analysisTypes := strings.Split(analysisTypesStr, ",")
-	for i, t := range analysisTypes {
-		analysisTypes[i] = strings.TrimSpace(t)
-	}
-
 	// Create analysis request
 	analysisRequest := analysis.AnalysisRequest{
 		StartDate:              startDate,
@@ -480,9 +682,57 @@ func (s *MCPServer) analyzeHistoricalSavingsTool(ctx context.Context, request mc
 	return mcp.NewToolResultText(string(responseJSON)), nil
 }
 
-func getStringArg(arguments map[string]interface{}, key string) string {
-	if value, ok := arguments[key].(string); ok {
-		return value
+// dispatchErrorResult converts a Dispatch API failure into a tool error that
+// tells the model what went wrong and how to recover
+func dispatchErrorResult(action string, err error) *mcp.CallToolResult {
+	// Orders rejected by the canonical model's checks never reached the API
+	var invalid *order.ValidationError
+	if errors.As(err, &invalid) {
+		var message strings.Builder
+		message.WriteString(fmt.Sprintf("failed to %s: the order is incomplete\n", action))
+		message.WriteString(fmt.Sprintf("error_kind: %s\n", dispatch.ErrorKindValidation))
+		for _, fieldErr := range invalid.Errors {
+			message.WriteString(fmt.Sprintf("- %s: %s\n", fieldErr.Field, fieldErr.Message))
+		}
+		return mcp.NewToolResultError(strings.TrimSpace(message.String()))
+	}
+
+	var apiErr *dispatch.APIError
+	if !errors.As(err, &apiErr) {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to %s: %v", action, err))
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("failed to %s: %s\n", action, apiErr.Error()))
+	message.WriteString(fmt.Sprintf("error_kind: %s\n", apiErr.Kind))
+	for _, gqlErr := range apiErr.GraphQLErrors {
+		field := gqlErr.PathString()
+		if field == "" {
+			field = "(request)"
+		}
+		message.WriteString(fmt.Sprintf("- %s: %s\n", field, gqlErr.Message))
+	}
+	if hint := apiErr.Hint(); hint != "" {
+		message.WriteString("hint: " + hint)
+	}
+
+	return mcp.NewToolResultError(strings.TrimSpace(message.String()))
+}
+
+// sessionID identifies the calling client's MCP session. The HTTP transports
+// serve many clients from one server, so per-client state is keyed by it.
+func sessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
 	}
 	return ""
 }
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
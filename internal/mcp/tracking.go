@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/dispatch"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Polling limits for track_order
+const (
+	defaultTrackTimeout      = 5 * time.Minute
+	maxTrackTimeout          = 30 * time.Minute
+	defaultTrackPollInterval = 15 * time.Second
+	minTrackPollInterval     = 500 * time.Millisecond
+)
+
+// trackingProgress is the progress value reported for each status, out of
+// the delivered step
+var trackingProgress = map[string]float64{
+	dispatch.OrderStatusScheduled:      1,
+	dispatch.OrderStatusDriverAssigned: 2,
+	dispatch.OrderStatusPickedUp:       3,
+	dispatch.OrderStatusDelivered:      4,
+	dispatch.OrderStatusCancelled:      4,
+}
+
+// trackOrderResult is the track_order response
+type trackOrderResult struct {
+	OrderID        string                   `json:"order_id"`
+	TrackingNumber string                   `json:"tracking_number"`
+	Status         string                   `json:"status"`
+	Final          bool                     `json:"final"`
+	TimedOut       bool                     `json:"timed_out"`
+	OriginalETA    string                   `json:"original_eta"`
+	CurrentETA     string                   `json:"current_eta"`
+	ETADrift       string                   `json:"eta_drift,omitempty"`
+	Driver         *dispatch.Driver         `json:"driver,omitempty"`
+	Tracking       *dispatch.Tracking       `json:"tracking,omitempty"`
+	Timeline       []dispatch.TimelineEntry `json:"timeline"`
+	Polls          int                      `json:"polls"`
+}
+
+func (s *MCPServer) trackOrderTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Cast arguments to the correct type
+	arguments, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("invalid arguments format"), nil
+	}
+
+	orderID := strings.TrimSpace(getStringArg(arguments, "order_id"))
+	trackingNumber := strings.TrimSpace(getStringArg(arguments, "tracking_number"))
+	if orderID == "" && trackingNumber == "" {
+		return mcp.NewToolResultError("order_id or tracking_number is required"), nil
+	}
+
+	timeout, err := durationArg(arguments, "timeout_seconds", defaultTrackTimeout, time.Second, maxTrackTimeout)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	interval, err := durationArg(arguments, "poll_interval_seconds", defaultTrackPollInterval, minTrackPollInterval, 0)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	originalETA := getStringArg(arguments, "estimated_delivery_time_utc")
+	if originalETA != "" {
+		if _, err := time.Parse(time.RFC3339, originalETA); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid estimated_delivery_time_utc, expected an RFC 3339 time: %v", err)), nil
+		}
+	}
+
+	if orderID == "" {
+		listed, err := s.dispatchClient.ListOrders(ctx, dispatch.ListOrdersInput{TrackingNumber: &trackingNumber, Limit: 1})
+		if err != nil {
+			return dispatchErrorResult("look up tracking number", err), nil
+		}
+		if len(listed.Data.Orders) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("no order has tracking number %s", trackingNumber)), nil
+		}
+		orderID = listed.Data.Orders[0].ID
+	}
+
+	deadline := time.Now().Add(timeout)
+	notified := 0
+	for polls := 1; ; polls++ {
+		response, err := s.dispatchClient.GetOrder(ctx, orderID)
+		if err != nil {
+			return dispatchErrorResult("track order", err), nil
+		}
+		order := response.Data.Order
+
+		result := trackOrderResult{
+			OrderID:        order.ID,
+			TrackingNumber: order.TrackingNumber,
+			Status:         order.Status,
+			Final:          dispatch.IsTerminalStatus(order.Status),
+			OriginalETA:    originalETA,
+			CurrentETA:     order.CurrentETA(),
+			Driver:         order.Driver,
+			Tracking:       order.Tracking,
+			Polls:          polls,
+		}
+		if result.OriginalETA == "" {
+			result.OriginalETA = order.EstimatedArrival
+		}
+		result.Timeline = order.Timeline(result.OriginalETA)
+		if len(result.Timeline) > 0 {
+			result.ETADrift = result.Timeline[len(result.Timeline)-1].ETADrift
+		}
+
+		for ; notified < len(result.Timeline); notified++ {
+			s.notifyTrackingProgress(ctx, request, result.Timeline[notified])
+		}
+
+		remaining := time.Until(deadline)
+		if result.Final || remaining <= 0 {
+			result.TimedOut = !result.Final
+			responseJSON, _ := json.MarshalIndent(result, "", "  ")
+			return mcp.NewToolResultText(string(responseJSON)), nil
+		}
+
+		wait := interval
+		if wait > remaining {
+			wait = remaining
+		}
+		select {
+		case <-ctx.Done():
+			return mcp.NewToolResultError(fmt.Sprintf("tracking %s stopped: %v", orderID, ctx.Err())), nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// notifyTrackingProgress reports a status change to the client if the call
+// asked for progress notifications
+func (s *MCPServer) notifyTrackingProgress(ctx context.Context, request mcp.CallToolRequest, entry dispatch.TimelineEntry) {
+	progress, ok := trackingProgress[entry.Status]
+	if !ok || request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return
+	}
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return
+	}
+
+	message := fmt.Sprintf("%s at %s", strings.ReplaceAll(entry.Status, "_", " "), entry.At)
+	if entry.ETA != "" && entry.Status != dispatch.OrderStatusDelivered {
+		message += ", ETA " + entry.ETA
+	}
+	if entry.ETADrift != "" {
+		message += fmt.Sprintf(" (%s against the quote)", entry.ETADrift)
+	}
+
+	_ = srv.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+		"progressToken": request.Params.Meta.ProgressToken,
+		"progress":      progress,
+		"total":         trackingProgress[dispatch.OrderStatusDelivered],
+		"message":       message,
+	})
+}
+
+// durationArg reads a number of seconds, falling back to def and checking it
+// against min and, when non-zero, max
+func durationArg(arguments map[string]interface{}, key string, def, min, max time.Duration) (time.Duration, error) {
+	value := getScalarArg(arguments, key)
+	if value == "" {
+		return def, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	d := time.Duration(seconds * float64(time.Second))
+	if err != nil || d < min || (max > 0 && d > max) {
+		if max > 0 {
+			return 0, fmt.Errorf("%s must be a number between %g and %g", key, min.Seconds(), max.Seconds())
+		}
+		return 0, fmt.Errorf("%s must be a number of at least %g", key, min.Seconds())
+	}
+	return d, nil
+}
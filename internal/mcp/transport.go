@@ -0,0 +1,160 @@
+package mcp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Transports the MCP server can be served over
+const (
+	TransportStdio = "stdio"
+	TransportSSE   = "sse"
+	TransportHTTP  = "http"
+)
+
+// defaultShutdownTimeout applies when ServeOptions.ShutdownTimeout is unset
+const defaultShutdownTimeout = 10 * time.Second
+
+// ServeOptions selects the transport and, for the HTTP transports, how it listens
+type ServeOptions struct {
+	Transport string
+
+	// Addr is the listen address for the SSE and streamable HTTP transports
+	Addr string
+
+	// AuthToken, when set, is required as "Authorization: Bearer <token>" on
+	// every HTTP request except GET /healthz
+	AuthToken string
+
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// once the server is asked to stop
+	ShutdownTimeout time.Duration
+}
+
+// Serve runs the MCP server on the selected transport until ctx is done, then
+// shuts down gracefully. The SSE transport serves GET /sse and POST /message;
+// streamable HTTP serves /mcp. Each client gets its own MCP session.
+func (s *MCPServer) Serve(ctx context.Context, opts ServeOptions) error {
+	s.watchPricingRules(ctx)
+
+	if opts.Transport == "" || opts.Transport == TransportStdio {
+		err := server.NewStdioServer(s.Server()).Listen(ctx, os.Stdin, os.Stdout)
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	}
+
+	listener, err := net.Listen("tcp", opts.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", opts.Addr, err)
+	}
+
+	httpServer := &http.Server{}
+	handler, shutdown, err := s.httpTransport(opts, httpServer)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	httpServer.Handler = handler
+
+	fmt.Fprintf(os.Stderr, "MCP server listening on %s (%s transport)\n", listener.Addr(), opts.Transport)
+	if opts.AuthToken == "" {
+		fmt.Fprintf(os.Stderr, "⚠️  No MCP auth token configured; any client that can reach %s can use the server\n", listener.Addr())
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintf(os.Stderr, "Shutting down MCP server...\n")
+	timeout := opts.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := shutdown(shutdownCtx); err != nil {
+		// Streams still open after the timeout are cut off
+		httpServer.Close()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("failed to shut down MCP server: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  MCP server did not drain within %s; closed remaining connections\n", timeout)
+	}
+	return nil
+}
+
+// HTTPHandler returns the handler Serve uses for the SSE or streamable HTTP
+// transport, for embedding the MCP server in another HTTP server
+func (s *MCPServer) HTTPHandler(opts ServeOptions) (http.Handler, error) {
+	handler, _, err := s.httpTransport(opts, nil)
+	return handler, err
+}
+
+// httpTransport builds the handler for an HTTP transport and the function that
+// shuts it and httpServer down, closing open sessions
+func (s *MCPServer) httpTransport(opts ServeOptions, httpServer *http.Server) (http.Handler, func(context.Context) error, error) {
+	mux := http.NewServeMux()
+	var shutdown func(context.Context) error
+
+	switch opts.Transport {
+	case TransportSSE:
+		sseServer := server.NewSSEServer(s.Server(),
+			server.WithHTTPServer(httpServer),
+			server.WithUseFullURLForMessageEndpoint(false),
+			server.WithKeepAlive(true),
+		)
+		mux.Handle(sseServer.CompleteSsePath(), requireBearer(opts.AuthToken, sseServer))
+		mux.Handle(sseServer.CompleteMessagePath(), requireBearer(opts.AuthToken, sseServer))
+		shutdown = sseServer.Shutdown
+	case TransportHTTP:
+		httpTransport := server.NewStreamableHTTPServer(s.Server(),
+			server.WithStreamableHTTPServer(httpServer),
+		)
+		mux.Handle("/mcp", requireBearer(opts.AuthToken, httpTransport))
+		shutdown = httpTransport.Shutdown
+	default:
+		return nil, nil, fmt.Errorf("unknown MCP transport %q (expected %s, %s or %s)", opts.Transport, TransportStdio, TransportSSE, TransportHTTP)
+	}
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok", "transport": opts.Transport})
+	})
+
+	return mux, shutdown, nil
+}
+
+// requireBearer rejects requests that don't carry token as a bearer token
+func requireBearer(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	expected := []byte("Bearer " + token)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="dispatch-mcp-server"`)
+			http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,327 @@
+// Package money represents currency amounts as integer minor units (cents for
+// USD) so sums and comparisons are exact. Rounding only happens when an
+// amount is scaled or converted from a decimal, and always by an explicit
+// RoundingMode.
+package money
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency code
+type Currency string
+
+const (
+	USD Currency = "USD"
+	CAD Currency = "CAD"
+	EUR Currency = "EUR"
+	GBP Currency = "GBP"
+	JPY Currency = "JPY"
+
+	// DefaultCurrency is assumed for amounts that don't carry a currency,
+	// such as the plain numbers the Dispatch API returns
+	DefaultCurrency = USD
+)
+
+// minorUnits lists the ISO 4217 exponents that differ from the usual 2
+var minorUnits = map[Currency]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLP": 0, "ISK": 0, "JPY": 0, "KRW": 0, "PYG": 0, "UGX": 0, "VND": 0,
+}
+
+// MinorUnits is the number of decimal places the currency uses
+func (c Currency) MinorUnits() int {
+	if exponent, ok := minorUnits[c]; ok {
+		return exponent
+	}
+	return 2
+}
+
+func (c Currency) scale() int64 {
+	scale := int64(1)
+	for i := 0; i < c.MinorUnits(); i++ {
+		scale *= 10
+	}
+	return scale
+}
+
+// RoundingMode decides which way an amount between two minor units goes
+type RoundingMode int
+
+const (
+	// HalfUp rounds to the nearest minor unit, halves away from zero
+	HalfUp RoundingMode = iota
+	// HalfEven rounds to the nearest minor unit, halves to the even one
+	HalfEven
+	// Down rounds toward zero
+	Down
+	// Up rounds away from zero
+	Up
+)
+
+// Money is an amount in a currency's minor units. The zero value is zero in
+// no particular currency and combines with any currency.
+type Money struct {
+	Amount   int64    // minor units, e.g. cents
+	Currency Currency // empty only for the zero value
+}
+
+// New returns amount minor units of currency
+func New(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// FromFloat converts a decimal amount, e.g. 39.095, rounding HalfUp. The
+// float is read as the shortest decimal that represents it, so 0.285 is
+// treated as exactly 0.285.
+func FromFloat(amount float64, currency Currency) Money {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		panic(fmt.Sprintf("money: cannot convert %g", amount))
+	}
+	m, ok := fromRat(floatRat(amount), currency, HalfUp)
+	if !ok {
+		panic(fmt.Sprintf("money: %g is out of range", amount))
+	}
+	return m
+}
+
+// Dollars converts a decimal amount of US dollars, rounding HalfUp
+func Dollars(amount float64) Money {
+	return FromFloat(amount, USD)
+}
+
+// Parse reads a decimal amount such as "39.09" or "-4.5", rounding any
+// extra decimal places HalfUp. Amounts too large for int64 minor units are
+// rejected.
+func Parse(text string, currency Currency) (Money, error) {
+	text = strings.TrimSpace(text)
+	rat, ok := new(big.Rat).SetString(text)
+	if !ok || strings.Contains(text, "/") {
+		return Money{}, fmt.Errorf("invalid amount %q", text)
+	}
+	m, ok := fromRat(rat, currency, HalfUp)
+	if !ok {
+		return Money{}, fmt.Errorf("amount out of range: %q", text)
+	}
+	return m, nil
+}
+
+// Add returns m + other. It panics if the currencies differ or the sum
+// doesn't fit.
+func (m Money) Add(other Money) Money {
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		panic(fmt.Sprintf("money: %s + %s is out of range", m.Decimal(), other.Decimal()))
+	}
+	return Money{Amount: sum, Currency: m.combine(other)}
+}
+
+// Sub returns m - other. It panics if the currencies differ or the
+// difference doesn't fit.
+func (m Money) Sub(other Money) Money {
+	difference := m.Amount - other.Amount
+	if (other.Amount > 0 && difference > m.Amount) || (other.Amount < 0 && difference < m.Amount) {
+		panic(fmt.Sprintf("money: %s - %s is out of range", m.Decimal(), other.Decimal()))
+	}
+	return Money{Amount: difference, Currency: m.combine(other)}
+}
+
+// Neg returns -m. It panics for the one amount whose negation doesn't fit.
+func (m Money) Neg() Money {
+	if m.Amount == math.MinInt64 {
+		panic(fmt.Sprintf("money: -(%s) is out of range", m.Decimal()))
+	}
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Mul scales m by factor, e.g. a 0.85 price multiplier, rounding by mode.
+// It panics if the result doesn't fit.
+func (m Money) Mul(factor float64, mode RoundingMode) Money {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), floatRat(factor))
+	return Money{Amount: mustRound(product, mode), Currency: m.Currency}
+}
+
+// Percent returns percent% of m, rounding by mode. It panics if the result
+// doesn't fit.
+func (m Money) Percent(percent float64, mode RoundingMode) Money {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), floatRat(percent))
+	product.Quo(product, big.NewRat(100, 1))
+	return Money{Amount: mustRound(product, mode), Currency: m.Currency}
+}
+
+// PercentOf is m as a percentage of total, or 0 when total is zero
+func (m Money) PercentOf(total Money) float64 {
+	if total.Amount == 0 {
+		return 0
+	}
+	m.combine(total)
+	return float64(m.Amount) / float64(total.Amount) * 100
+}
+
+// Cmp returns -1, 0 or 1 as m is less than, equal to or greater than other.
+// It panics if the currencies differ.
+func (m Money) Cmp(other Money) int {
+	m.combine(other)
+	switch {
+	case m.Amount < other.Amount:
+		return -1
+	case m.Amount > other.Amount:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether m is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative reports whether m is below zero
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Float64 is m in major units, e.g. 39.09, for display and ratios
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / float64(m.Currency.scale())
+}
+
+// Decimal formats m with the currency's decimal places, e.g. "39.09"
+func (m Money) Decimal() string {
+	exponent := m.Currency.MinorUnits()
+	text := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	if exponent == 0 {
+		return sign + text
+	}
+	if len(text) <= exponent {
+		text = strings.Repeat("0", exponent-len(text)+1) + text
+	}
+	return sign + text[:len(text)-exponent] + "." + text[len(text)-exponent:]
+}
+
+// String formats m with its currency, e.g. "39.09 USD"
+func (m Money) String() string {
+	if m.Currency == "" {
+		return m.Decimal()
+	}
+	return m.Decimal() + " " + string(m.Currency)
+}
+
+// MarshalJSON writes m as a JSON number, e.g. 39.09, as the float amounts
+// it replaces were written
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+// UnmarshalJSON reads a JSON number or numeric string. The amount is taken to
+// be in m's currency, or DefaultCurrency when m has none.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	if strings.HasPrefix(text, `"`) {
+		unquoted, err := strconv.Unquote(text)
+		if err != nil {
+			return fmt.Errorf("invalid amount %s", text)
+		}
+		text = unquoted
+	}
+
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if strings.TrimSpace(text) == "" {
+		*m = Money{Currency: currency}
+		return nil
+	}
+	parsed, err := Parse(text, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Quoted is Money written to JSON as a string, e.g. "39.09", for the API
+// fields that carry amounts as text
+type Quoted struct {
+	Money
+}
+
+// MarshalJSON writes q as a JSON string
+func (q Quoted) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(q.Decimal())), nil
+}
+
+// combine returns the currency of a result combining m and other
+func (m Money) combine(other Money) Currency {
+	switch {
+	case m.Currency == "":
+		return other.Currency
+	case other.Currency == "" || other.Currency == m.Currency:
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money: cannot combine %s with %s", m.Currency, other.Currency))
+}
+
+// fromRat converts an amount in major units, or reports false when it is
+// out of range
+func fromRat(amount *big.Rat, currency Currency, mode RoundingMode) (Money, bool) {
+	minor := new(big.Rat).Mul(amount, new(big.Rat).SetInt64(currency.scale()))
+	rounded, ok := round(minor, mode)
+	return Money{Amount: rounded, Currency: currency}, ok
+}
+
+// floatRat is the shortest decimal that reads back as f, as an exact
+// fraction. It panics if f is NaN or infinite, which no amount or factor can be.
+func floatRat(f float64) *big.Rat {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		panic(fmt.Sprintf("money: cannot scale by %g", f))
+	}
+	rat, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return rat
+}
+
+// mustRound is round for results of arithmetic, which panics on overflow
+func mustRound(r *big.Rat, mode RoundingMode) int64 {
+	rounded, ok := round(r, mode)
+	if !ok {
+		panic(fmt.Sprintf("money: %s is out of range", r.FloatString(0)))
+	}
+	return rounded
+}
+
+// round rounds r to an integer by mode, or reports false when the result
+// doesn't fit in an int64
+func round(r *big.Rat, mode RoundingMode) (int64, bool) {
+	quotient, remainder := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if remainder.Sign() != 0 {
+		away := mode == Up
+		if mode == HalfUp || mode == HalfEven {
+			twice := new(big.Int).Abs(remainder)
+			switch twice.Lsh(twice, 1).Cmp(r.Denom()) {
+			case 1:
+				away = true
+			case 0:
+				away = mode == HalfUp || quotient.Bit(0) == 1
+			}
+		}
+		if away {
+			quotient.Add(quotient, big.NewInt(int64(r.Num().Sign())))
+		}
+	}
+	if !quotient.IsInt64() {
+		return 0, false
+	}
+	return quotient.Int64(), true
+}
@@ -0,0 +1,203 @@
+package order
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/graphql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Backend submits a canonical order to one API schema
+type Backend interface {
+	Submit(ctx context.Context, o *Order) (*OrderResult, error)
+}
+
+// DispatchBackend submits orders through dispatch.API (the GraphQL Client
+// or MockClient) using the createOrder input in internal/dispatch. That input
+// can't carry every order field (see Order.ToDispatchInput), so orders that
+// set the others are rejected rather than booked without them.
+type DispatchBackend struct {
+	api dispatch.API
+}
+
+// NewDispatchBackend creates a backend that submits through api
+func NewDispatchBackend(api dispatch.API) *DispatchBackend {
+	return &DispatchBackend{api: api}
+}
+
+// Submit implements Backend. Fields the createOrder input can't carry are
+// reported as a *ValidationError.
+func (b *DispatchBackend) Submit(ctx context.Context, o *Order) (*OrderResult, error) {
+	if errs := o.DispatchUnsupported(); len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+
+	response, err := b.api.CreateOrder(ctx, o.ToDispatchInput())
+	if err != nil {
+		return nil, err
+	}
+
+	created := response.Data.CreateOrder.Order
+	result := &OrderResult{Errors: []FieldError{}}
+	result.Order = OrderDetails{
+		ID:               created.ID,
+		Status:           created.Status,
+		TrackingNumber:   created.TrackingNumber,
+		ScheduledAt:      created.ScheduledAt,
+		EstimatedArrival: created.EstimatedArrival,
+		Pricing: Pricing{
+			TotalPrice: created.TotalCost,
+			BasePrice:  created.TotalCost,
+			Discounts:  []Discount{},
+		},
+	}
+	return result, nil
+}
+
+// Validate implements Validator with the API's ValidateOrder, adding an error
+// for each field Submit would reject
+func (b *DispatchBackend) Validate(ctx context.Context, o *Order) (*Validation, error) {
+	validation, err := b.api.ValidateOrder(ctx, o.ToGraphQLInput())
+	if err != nil {
+		return nil, err
+	}
+	if errs := o.DispatchUnsupported(); len(errs) > 0 {
+		validation.Errors = append(validation.Errors, errs...)
+		validation.Valid = false
+	}
+	return validation, nil
+}
+
+// MutationBackend submits orders through dispatch.API with the
+// CreateOrderMutation, whose input carries the vehicle type, capabilities and
+// schedule that DispatchBackend can't send
+type MutationBackend struct {
+	api dispatch.API
+}
+
+// NewMutationBackend creates a backend that submits through api
+func NewMutationBackend(api dispatch.API) *MutationBackend {
+	return &MutationBackend{api: api}
+}
+
+// Submit implements Backend. Field errors returned by the mutation are
+// reported as a *ValidationError.
+func (b *MutationBackend) Submit(ctx context.Context, o *Order) (*OrderResult, error) {
+	submission, err := b.api.SubmitOrder(ctx, o.ToGraphQLInput())
+	if err != nil {
+		return nil, err
+	}
+	if len(submission.Errors) > 0 {
+		return nil, &ValidationError{Errors: submission.Errors}
+	}
+	return &OrderResult{Order: submission.Order, Errors: []FieldError{}}, nil
+}
+
+// Validate implements Validator with the API's ValidateOrder
+func (b *MutationBackend) Validate(ctx context.Context, o *Order) (*Validation, error) {
+	return b.api.ValidateOrder(ctx, o.ToGraphQLInput())
+}
+
+// GraphQLBackend submits orders with the CreateOrderMutation in queries.go.
+// Each call is bounded by DISPATCH_ORDER_TIMEOUT, like the order operations
+// of dispatch.Client.
+type GraphQLBackend struct {
+	client  *graphql.GraphQLClient
+	timeout time.Duration
+}
+
+// NewGraphQLBackend creates a backend for endpoint, authenticating with
+// GRAPHQL_API_KEY and GRAPHQL_AUTH_TOKEN when they are set
+func NewGraphQLBackend(endpoint string) (*GraphQLBackend, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGraphQLBackendWithConfig(endpoint, cfg), nil
+}
+
+// NewGraphQLBackendWithConfig creates a backend for endpoint using the
+// supplied configuration
+func NewGraphQLBackendWithConfig(endpoint string, cfg *config.Config) *GraphQLBackend {
+	client := graphql.NewGraphQLClientWithConfig(endpoint, cfg)
+
+	// Set authentication headers if available
+	if apiKey := os.Getenv("GRAPHQL_API_KEY"); apiKey != "" {
+		client.SetHeader("X-API-Key", apiKey)
+	}
+	if authToken := os.Getenv("GRAPHQL_AUTH_TOKEN"); authToken != "" {
+		client.SetHeader("Authorization", "Bearer "+authToken)
+	}
+
+	return &GraphQLBackend{client: client, timeout: cfg.OrderTimeout}
+}
+
+// Submit implements Backend. Field errors returned by the mutation are
+// reported as a *ValidationError.
+func (b *GraphQLBackend) Submit(ctx context.Context, o *Order) (*OrderResult, error) {
+	ctx, cancel := config.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	response, err := b.client.ExecuteWithIdempotencyKey(ctx, o.IdempotencyKey, CreateOrderMutation, map[string]interface{}{
+		"input": o.ToGraphQLInput(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Data is decoded generically by the GraphQL client; re-decode it
+	data, err := json.Marshal(response.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse order result: %w", err)
+	}
+	var payload struct {
+		CreateOrder OrderResult `json:"createOrder"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse order result: %w", err)
+	}
+
+	result := &payload.CreateOrder
+	if len(result.Errors) > 0 {
+		return nil, &ValidationError{Errors: result.Errors}
+	}
+	return result, nil
+}
+
+// Validate implements Validator with the ValidateOrderMutation in queries.go
+func (b *GraphQLBackend) Validate(ctx context.Context, o *Order) (*Validation, error) {
+	ctx, cancel := config.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	response, err := b.client.Execute(ctx, ValidateOrderMutation, map[string]interface{}{
+		"input": o.ToGraphQLInput(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Data is decoded generically by the GraphQL client; re-decode it
+	data, err := json.Marshal(response.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse order validation: %w", err)
+	}
+	var payload struct {
+		ValidateOrder Validation `json:"validateOrder"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse order validation: %w", err)
+	}
+	return &payload.ValidateOrder, nil
+}
+
+// Compile-time checks that every backend can validate orders
+var (
+	_ Validator = (*DispatchBackend)(nil)
+	_ Validator = (*MutationBackend)(nil)
+	_ Validator = (*GraphQLBackend)(nil)
+)
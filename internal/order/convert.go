@@ -0,0 +1,255 @@
+package order
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"fmt"
+)
+
+// DefaultVehicleType is used when an order source has no vehicle selection
+const DefaultVehicleType = "cargo_van"
+
+// DefaultServiceType is used when an order source has no service level
+const DefaultServiceType = "standard"
+
+// FromDispatchInput converts the Dispatch API order input (as taken by the
+// create_order MCP tool) to the canonical model. The Dispatch schema has no
+// vehicle field, so the order uses DefaultVehicleType; see ToDispatchInput
+// for the other fields it can't carry.
+func FromDispatchInput(input dispatch.CreateOrderInput) *Order {
+	o := &Order{
+		Pickup: Stop{
+			BusinessName: deref(input.PickupInfo.BusinessName),
+			ContactName:  deref(input.PickupInfo.ContactName),
+			ContactPhone: deref(input.PickupInfo.ContactPhoneNumber),
+			Notes:        deref(input.PickupInfo.PickupNotes),
+		},
+		VehicleType:    DefaultVehicleType,
+		ServiceType:    input.DeliveryInfo.ServiceType,
+		AddOns:         input.AddOns,
+		IdempotencyKey: input.IdempotencyKey,
+	}
+	o.Pickup.Address, o.Pickup.Coordinates = fromDispatchLocation(input.PickupInfo.Location)
+
+	for _, dropOff := range input.DropOffs {
+		stop := Stop{
+			BusinessName: deref(dropOff.BusinessName),
+			ContactName:  deref(dropOff.ContactName),
+			ContactPhone: deref(dropOff.ContactPhoneNumber),
+			Notes:        deref(dropOff.DropOffNotes),
+		}
+		stop.Address, stop.Coordinates = fromDispatchLocation(dropOff.Location)
+		o.DropOffs = append(o.DropOffs, stop)
+	}
+
+	for _, tag := range input.Tags {
+		o.Tags = append(o.Tags, Tag{Name: tag.Name, Value: tag.Value})
+	}
+	if input.DeliveryInfo.OrganizationDruid != nil {
+		o.OrganizationID = *input.DeliveryInfo.OrganizationDruid
+	}
+
+	return o
+}
+
+// ToDispatchInput converts the order to the Dispatch API createOrder input.
+// The mapping is lossy: that input has no field for the job name, vehicle
+// type, capabilities or schedule, so they are dropped and the order is booked
+// as a DefaultVehicleType as soon as possible. DispatchUnsupported lists the
+// fields o would lose; use ToGraphQLInput to send them.
+func (o *Order) ToDispatchInput() dispatch.CreateOrderInput {
+	serviceType := o.ServiceType
+	if serviceType == "" {
+		serviceType = DefaultServiceType
+	}
+
+	input := dispatch.CreateOrderInput{
+		DeliveryInfo: dispatch.DeliveryInfoInput{
+			ServiceType:       serviceType,
+			OrganizationDruid: optional(o.OrganizationID),
+		},
+		PickupInfo: dispatch.CreateOrderPickupInfoInput{
+			BusinessName:       optional(o.Pickup.BusinessName),
+			ContactName:        optional(o.Pickup.ContactName),
+			ContactPhoneNumber: optional(o.Pickup.ContactPhone),
+			Location:           o.Pickup.dispatchLocation(),
+			PickupNotes:        optional(o.Pickup.Notes),
+		},
+		AddOns:         o.AddOns,
+		IdempotencyKey: o.IdempotencyKey,
+	}
+
+	for _, stop := range o.DropOffs {
+		input.DropOffs = append(input.DropOffs, dispatch.CreateOrderDropOffInfoInput{
+			BusinessName:       optional(stop.BusinessName),
+			ContactName:        optional(stop.ContactName),
+			ContactPhoneNumber: optional(stop.ContactPhone),
+			Location:           stop.dispatchLocation(),
+			DropOffNotes:       optional(stop.Notes),
+		})
+	}
+
+	for _, tag := range o.Tags {
+		input.Tags = append(input.Tags, dispatch.TagInput{Name: tag.Name, Value: tag.Value})
+	}
+
+	return input
+}
+
+// DispatchUnsupported returns an error for each field o sets that
+// ToDispatchInput would drop
+func (o *Order) DispatchUnsupported() []FieldError {
+	unsupported := func(field, name string) FieldError {
+		return FieldError{Field: field, Message: fmt.Sprintf("the Dispatch createOrder input has no %s; submit with the order mutation to send it", name)}
+	}
+
+	var errs []FieldError
+	if o.JobName != "" {
+		errs = append(errs, unsupported("jobName", "job name"))
+	}
+	if o.VehicleType != "" && o.VehicleType != DefaultVehicleType {
+		errs = append(errs, unsupported("vehicleType", "vehicle type and books a "+DefaultVehicleType))
+	}
+	if len(o.Capabilities) > 0 {
+		errs = append(errs, unsupported("capabilities", "capabilities"))
+	}
+	if o.Schedule != nil {
+		errs = append(errs, unsupported("schedule", "schedule"))
+	}
+	return errs
+}
+
+// ToDispatchResponse converts the result to the Dispatch API createOrder
+// response, which selects the same order fields
+func (r *OrderResult) ToDispatchResponse() *dispatch.CreateOrderResponse {
+	response := &dispatch.CreateOrderResponse{}
+	response.Data.CreateOrder.Order = dispatch.Order{
+		ID:               r.Order.ID,
+		Status:           r.Order.Status,
+		ScheduledAt:      r.Order.ScheduledAt,
+		TotalCost:        r.Order.Pricing.TotalPrice,
+		TrackingNumber:   r.Order.TrackingNumber,
+		EstimatedArrival: r.Order.EstimatedArrival,
+	}
+	return response
+}
+
+// ToGraphQLInput converts the order to the OrderCreationInput used by the
+// CreateOrderMutation and ValidateOrderMutation in queries.go
+func (o *Order) ToGraphQLInput() *OrderCreationInput {
+	input := &OrderCreationInput{
+		OrganizationID: o.OrganizationID,
+		JobName:        o.JobName,
+		PickupInfo: &PickupInfoInput{
+			BusinessName: o.Pickup.BusinessName,
+			ContactName:  o.Pickup.ContactName,
+			ContactPhone: o.Pickup.ContactPhone,
+			Address:      o.Pickup.graphQLAddress(),
+			Notes:        o.Pickup.Notes,
+		},
+		DropOffs:       make([]DropOffInfoInput, len(o.DropOffs)),
+		VehicleTypeID:  o.VehicleType,
+		Capabilities:   o.Capabilities,
+		IdempotencyKey: o.IdempotencyKey,
+	}
+
+	for i, stop := range o.DropOffs {
+		input.DropOffs[i] = DropOffInfoInput{
+			BusinessName: stop.BusinessName,
+			ContactName:  stop.ContactName,
+			ContactPhone: stop.ContactPhone,
+			Address:      stop.graphQLAddress(),
+			Notes:        stop.Notes,
+		}
+	}
+
+	if o.Schedule != nil {
+		input.Scheduling = &SchedulingInput{
+			PickupTime:   o.Schedule.PickupTime,
+			DeliveryTime: o.Schedule.DeliveryTime,
+			PickupDate:   o.Schedule.PickupDate,
+			DeliveryDate: o.Schedule.DeliveryDate,
+			TimeZone:     o.Schedule.TimeZone,
+		}
+	}
+
+	return input
+}
+
+func (s Stop) dispatchLocation() *dispatch.LocationInput {
+	if s.Address == nil && s.Coordinates == nil {
+		return nil
+	}
+
+	location := &dispatch.LocationInput{}
+	if s.Address != nil {
+		location.Address = &dispatch.AddressInput{
+			Street:  s.Address.Street,
+			City:    s.Address.City,
+			State:   s.Address.State,
+			ZipCode: s.Address.ZipCode,
+			Country: s.Address.Country,
+		}
+	}
+	if s.Coordinates != nil {
+		location.GeoCoordinates = &dispatch.GeoCoordinatesInput{
+			Latitude:  s.Coordinates.Latitude,
+			Longitude: s.Coordinates.Longitude,
+		}
+	}
+	return location
+}
+
+func (s Stop) graphQLAddress() *AddressInput {
+	if s.Address == nil {
+		return nil
+	}
+	return &AddressInput{
+		Street:  s.Address.Street,
+		City:    s.Address.City,
+		State:   s.Address.State,
+		ZipCode: s.Address.ZipCode,
+		Country: s.Address.Country,
+	}
+}
+
+func fromDispatchLocation(location *dispatch.LocationInput) (*Address, *Coordinates) {
+	if location == nil {
+		return nil, nil
+	}
+
+	var address *Address
+	if location.Address != nil {
+		address = &Address{
+			Street:  location.Address.Street,
+			City:    location.Address.City,
+			State:   location.Address.State,
+			ZipCode: location.Address.ZipCode,
+			Country: location.Address.Country,
+		}
+	}
+
+	var coordinates *Coordinates
+	if location.GeoCoordinates != nil {
+		coordinates = &Coordinates{
+			Latitude:  location.GeoCoordinates.Latitude,
+			Longitude: location.GeoCoordinates.Longitude,
+		}
+	}
+
+	return address, coordinates
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// optional returns nil for empty strings so omitempty drops them
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
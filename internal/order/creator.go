@@ -1,211 +1,129 @@
 package order
 
 import (
+	"context"
 	"dispatch-mcp-server/internal/dispatch"
-	"dispatch-mcp-server/internal/graphql"
+	"dispatch-mcp-server/internal/idempotency"
 	"fmt"
-	"os"
 )
 
-// OrderCreator handles order creation via GraphQL
+// OrderCreator validates canonical orders and submits them through a Backend,
+// deduplicating retries by idempotency key
 type OrderCreator struct {
-	graphqlClient *graphql.GraphQLClient
+	backend Backend
+	guard   *idempotency.Guard
 }
 
 // OrderResult represents the result of order creation
 type OrderResult struct {
-	Order struct {
-		ID      string `json:"id"`
-		Druid   string `json:"druid"`
-		Status  string `json:"status"`
-		Pricing struct {
-			TotalPrice float64 `json:"totalPrice"`
-			BasePrice  float64 `json:"basePrice"`
-			Discounts  []struct {
-				Type   string  `json:"type"`
-				Amount float64 `json:"amount"`
-			} `json:"discounts"`
-		} `json:"pricing"`
-	} `json:"order"`
-	Errors []struct {
-		Field   string `json:"field"`
-		Message string `json:"message"`
-	} `json:"errors"`
+	Order  OrderDetails `json:"order"`
+	Errors []FieldError `json:"errors"`
+
+	// IdempotencyKey is the key the order was created under, and Replayed is
+	// true when the result came from an earlier call with the same key
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	Replayed       bool   `json:"replayed,omitempty"`
 }
 
-// NewOrderCreator creates a new order creator
-func NewOrderCreator(graphqlEndpoint string) *OrderCreator {
-	client := graphql.NewGraphQLClient(graphqlEndpoint)
+// OrderDetails is the order as created by the backend
+type OrderDetails = dispatch.CreatedOrder
 
-	// Set authentication headers if available
-	if apiKey := os.Getenv("GRAPHQL_API_KEY"); apiKey != "" {
-		client.SetHeader("X-API-Key", apiKey)
-	}
-	if authToken := os.Getenv("GRAPHQL_AUTH_TOKEN"); authToken != "" {
-		client.SetHeader("Authorization", "Bearer "+authToken)
-	}
+// Pricing is the price breakdown of a created order
+type Pricing = dispatch.OrderPricing
 
-	return &OrderCreator{
-		graphqlClient: client,
+// Discount is a single price reduction applied to an order
+type Discount = dispatch.OrderDiscount
+
+// NewOrderCreator creates an order creator that submits with the GraphQL
+// order mutation at graphqlEndpoint
+func NewOrderCreator(graphqlEndpoint string) (*OrderCreator, error) {
+	backend, err := NewGraphQLBackend(graphqlEndpoint)
+	if err != nil {
+		return nil, err
 	}
+	return NewOrderCreatorWithBackend(backend), nil
 }
 
-// CreateOrder creates an order from order creation input
-func (oc *OrderCreator) CreateOrder(input *OrderCreationInput) (*OrderResult, error) {
-	// Validate order first
-	if err := oc.validateOrder(input); err != nil {
-		return nil, fmt.Errorf("order validation failed: %w", err)
+// NewOrderCreatorWithBackend creates an order creator that submits through backend
+func NewOrderCreatorWithBackend(backend Backend) *OrderCreator {
+	return &OrderCreator{
+		backend: backend,
+		guard:   idempotency.DefaultGuard(),
 	}
+}
 
-	// Create the order
-	result, err := oc.executeCreateOrder(input)
-	if err != nil {
-		return nil, fmt.Errorf("order creation failed: %w", err)
-	}
+// SetIdempotencyGuard replaces the guard used to deduplicate orders
+func (oc *OrderCreator) SetIdempotencyGuard(guard *idempotency.Guard) {
+	oc.guard = guard
+}
 
-	return result, nil
+// CreateOrder validates o and submits it. Invalid orders fail with a
+// *ValidationError before anything is sent.
+func (oc *OrderCreator) CreateOrder(ctx context.Context, o *Order) (*OrderResult, error) {
+	return oc.CreateOrderInSession(ctx, "", o)
 }
 
-// ConvertFromDispatchTypes converts dispatch types to order types
-func ConvertFromDispatchTypes(pickupInfo *dispatch.CreateOrderPickupInfoInput, dropOffs []dispatch.CreateOrderDropOffInfoInput, vehicleType *VehicleTypeInfo, capabilities []string, scheduling *SchedulingInput) *OrderCreationInput {
-	input := &OrderCreationInput{
-		OrganizationID: "default-org-id",       // TODO: Get from context or user
-		JobName:        "Conversational Order", // TODO: Get from context
-		VehicleTypeID:  "cargo_van",            // Default
-		Capabilities:   capabilities,
-		Scheduling:     scheduling,
+// CreateOrderInSession is CreateOrder for one client session of a server
+// shared by many, such as the create_order MCP tool. Keys are deduplicated
+// per session, so the same order or key from another session creates that
+// session's own order.
+func (oc *OrderCreator) CreateOrderInSession(ctx context.Context, session string, o *Order) (*OrderResult, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
 	}
 
-	// Convert pickup info
-	if pickupInfo != nil {
-		notes := ""
-		if pickupInfo.PickupNotes != nil {
-			notes = *pickupInfo.PickupNotes
-		}
-		input.PickupInfo = &PickupInfoInput{
-			BusinessName: *pickupInfo.BusinessName,
-			ContactName:  *pickupInfo.ContactName,
-			ContactPhone: *pickupInfo.ContactPhoneNumber,
-			Address:      convertAddress(pickupInfo.Location.Address),
-			Notes:        notes,
-		}
+	fingerprint, err := idempotency.Fingerprint(o)
+	if err != nil {
+		return nil, err
 	}
-
-	// Convert drop-offs
-	input.DropOffs = make([]DropOffInfoInput, len(dropOffs))
-	for i, dropOff := range dropOffs {
-		notes := ""
-		if dropOff.DropOffNotes != nil {
-			notes = *dropOff.DropOffNotes
-		}
-		input.DropOffs[i] = DropOffInfoInput{
-			BusinessName: *dropOff.BusinessName,
-			ContactName:  *dropOff.ContactName,
-			ContactPhone: *dropOff.ContactPhoneNumber,
-			Address:      convertAddress(dropOff.Location.Address),
-			Notes:        notes,
-		}
+	key, err := IdempotencyKey(session, o)
+	if err != nil {
+		return nil, err
 	}
 
-	// Set vehicle type
-	if vehicleType != nil {
-		input.VehicleTypeID = vehicleType.VehicleTypeID
+	guardKey := key
+	if session != "" {
+		guardKey = session + "/" + key
 	}
 
-	return input
-}
-
-// convertAddress converts dispatch address to order address
-func convertAddress(address *dispatch.AddressInput) *AddressInput {
-	if address == nil {
-		return nil
+	// Create the order, or return the one already created for this key. A
+	// key derived from the order only covers quick retries: it isn't sent to
+	// the backend, and the same order placed after the retry window is a new
+	// order.
+	do := oc.guard.Do
+	if o.IdempotencyKey == "" {
+		do = oc.guard.Retry
 	}
 
-	return &AddressInput{
-		Street:  address.Street,
-		City:    address.City,
-		State:   address.State,
-		ZipCode: address.ZipCode,
-		Country: address.Country,
+	var result OrderResult
+	replayed, err := do(guardKey, fingerprint, &result, func() (interface{}, error) {
+		return oc.backend.Submit(ctx, o)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("order creation failed: %w", err)
 	}
-}
 
-// validateOrder validates the order before creation
-func (oc *OrderCreator) validateOrder(input *OrderCreationInput) error {
-	// Check required fields
-	if input.PickupInfo == nil {
-		return fmt.Errorf("pickup information is required")
-	}
-	if len(input.DropOffs) == 0 {
-		return fmt.Errorf("at least one delivery location is required")
-	}
-	if input.VehicleTypeID == "" {
-		return fmt.Errorf("vehicle type is required")
-	}
-	return nil
+	result.IdempotencyKey = key
+	result.Replayed = replayed
+	return &result, nil
 }
 
-// executeCreateOrder executes the GraphQL create order mutation
-func (oc *OrderCreator) executeCreateOrder(input *OrderCreationInput) (*OrderResult, error) {
-	// For now, return a mock result since we don't have a real GraphQL endpoint
-	// In production, this would call the actual GraphQL endpoint
-
-	mockResult := &OrderResult{
-		Order: struct {
-			ID      string `json:"id"`
-			Druid   string `json:"druid"`
-			Status  string `json:"status"`
-			Pricing struct {
-				TotalPrice float64 `json:"totalPrice"`
-				BasePrice  float64 `json:"basePrice"`
-				Discounts  []struct {
-					Type   string  `json:"type"`
-					Amount float64 `json:"amount"`
-				} `json:"discounts"`
-			} `json:"pricing"`
-		}{
-			ID:     "ORD-12345",
-			Druid:  "ABC123",
-			Status: "pending",
-			Pricing: struct {
-				TotalPrice float64 `json:"totalPrice"`
-				BasePrice  float64 `json:"basePrice"`
-				Discounts  []struct {
-					Type   string  `json:"type"`
-					Amount float64 `json:"amount"`
-				} `json:"discounts"`
-			}{
-				TotalPrice: 45.00,
-				BasePrice:  50.00,
-				Discounts: []struct {
-					Type   string  `json:"type"`
-					Amount float64 `json:"amount"`
-				}{
-					{Type: "multi_delivery", Amount: 5.00},
-				},
-			},
-		},
+// IdempotencyKey returns the key o is created under in session: its own
+// IdempotencyKey, or one derived from its contents so identical retries
+// within the guard's retry window are deduplicated
+func IdempotencyKey(session string, o *Order) (string, error) {
+	if o.IdempotencyKey != "" {
+		return o.IdempotencyKey, nil
 	}
 
-	return mockResult, nil
-}
-
-// TODO: Implement actual GraphQL calls when endpoint is available
-/*
-func (oc *OrderCreator) executeCreateOrder(input map[string]interface{}) (*OrderResult, error) {
-	response, err := oc.graphqlClient.Execute(CreateOrderMutation, map[string]interface{}{
-		"input": input,
-	})
+	fingerprint, err := idempotency.Fingerprint(o)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-
-	// Parse the response
-	var result OrderResult
-	if err := json.Unmarshal(response.Data, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse order result: %w", err)
+	if session != "" {
+		if fingerprint, err = idempotency.Fingerprint([]string{session, fingerprint}); err != nil {
+			return "", err
+		}
 	}
-
-	return &result, nil
+	return idempotency.DeriveKey("order:", fingerprint), nil
 }
-*/
@@ -0,0 +1,117 @@
+package order
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"fmt"
+	"strings"
+)
+
+// Order is the canonical order domain model. Every way of collecting an
+// order (MCP tool arguments, the conversation engine) produces one, and
+// converters translate it to the schema each backend expects.
+type Order struct {
+	OrganizationID string    `json:"organizationId,omitempty"`
+	JobName        string    `json:"jobName,omitempty"`
+	Pickup         Stop      `json:"pickup"`
+	DropOffs       []Stop    `json:"dropOffs"`
+	VehicleType    string    `json:"vehicleType"`
+	ServiceType    string    `json:"serviceType,omitempty"`
+	Capabilities   []string  `json:"capabilities,omitempty"`
+	AddOns         []string  `json:"addOns,omitempty"`
+	Tags           []Tag     `json:"tags,omitempty"`
+	Schedule       *Schedule `json:"schedule,omitempty"`
+
+	// IdempotencyKey identifies this order across retries; derived from the
+	// order when empty
+	IdempotencyKey string `json:"-"`
+}
+
+// Stop is a pickup or drop-off location with its on-site contact
+type Stop struct {
+	BusinessName string       `json:"businessName"`
+	ContactName  string       `json:"contactName,omitempty"`
+	ContactPhone string       `json:"contactPhone,omitempty"`
+	Address      *Address     `json:"address,omitempty"`
+	Coordinates  *Coordinates `json:"coordinates,omitempty"`
+	Notes        string       `json:"notes,omitempty"`
+}
+
+// Address is a postal address
+type Address struct {
+	Street  string `json:"street"`
+	City    string `json:"city"`
+	State   string `json:"state"`
+	ZipCode string `json:"zipCode"`
+	Country string `json:"country"`
+}
+
+// Coordinates is a latitude/longitude pair
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Schedule holds the requested pickup and delivery times as the customer
+// gave them
+type Schedule struct {
+	PickupDate   string `json:"pickupDate,omitempty"`
+	PickupTime   string `json:"pickupTime,omitempty"`
+	DeliveryDate string `json:"deliveryDate,omitempty"`
+	DeliveryTime string `json:"deliveryTime,omitempty"`
+	TimeZone     string `json:"timeZone,omitempty"`
+}
+
+// Tag is a free-form name/value label attached to an order
+type Tag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// FieldError describes a problem with a single order field
+type FieldError = dispatch.FieldError
+
+// ValidationError lists every field that prevents an order from being submitted
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		messages[i] = fmt.Sprintf("%s: %s", fieldErr.Field, fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks that the order has everything a backend needs and returns
+// a *ValidationError naming each missing field
+func (o *Order) Validate() error {
+	var errs []FieldError
+
+	errs = append(errs, o.Pickup.validate("pickup")...)
+	if len(o.DropOffs) == 0 {
+		errs = append(errs, FieldError{Field: "dropOffs", Message: "at least one delivery location is required"})
+	}
+	for i, dropOff := range o.DropOffs {
+		errs = append(errs, dropOff.validate(fmt.Sprintf("dropOffs.%d", i))...)
+	}
+	if o.VehicleType == "" {
+		errs = append(errs, FieldError{Field: "vehicleType", Message: "vehicle type is required"})
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func (s Stop) validate(field string) []FieldError {
+	var errs []FieldError
+	if strings.TrimSpace(s.BusinessName) == "" {
+		errs = append(errs, FieldError{Field: field + ".businessName", Message: "business name is required"})
+	}
+	if s.Coordinates == nil && (s.Address == nil || strings.TrimSpace(s.Address.ZipCode) == "") {
+		errs = append(errs, FieldError{Field: field + ".address", Message: "an address with a zip code is required"})
+	}
+	return errs
+}
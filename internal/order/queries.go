@@ -1,46 +1,14 @@
 package order
 
+import "dispatch-mcp-server/internal/dispatch"
+
 // GraphQL queries and mutations for order creation
 
-const CreateOrderMutation = `
-mutation CreateOrder($input: CreateOrderInput!) {
-  createOrder(input: $input) {
-    order {
-      id
-      druid
-      status
-      pricing {
-        totalPrice
-        basePrice
-        discounts {
-          type
-          amount
-        }
-      }
-    }
-    errors {
-      field
-      message
-    }
-  }
-}
-`
+// CreateOrderMutation is also sent by dispatch.Client.SubmitOrder
+const CreateOrderMutation = dispatch.CreateOrderMutation
 
-const ValidateOrderMutation = `
-mutation ValidateOrder($input: ValidateOrderInput!) {
-  validateOrder(input: $input) {
-    valid
-    errors {
-      field
-      message
-    }
-    warnings {
-      field
-      message
-    }
-  }
-}
-`
+// ValidateOrderMutation is also sent by dispatch.Client.ValidateOrder
+const ValidateOrderMutation = dispatch.ValidateOrderMutation
 
 const GetPricingQuery = `
 query GetOrderPricing($input: PricingInput!) {
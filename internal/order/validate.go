@@ -0,0 +1,22 @@
+package order
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/dispatch"
+)
+
+// Validation is the validateOrder result: whether the order can be created,
+// what would stop it, and what the caller may want to fix first
+type Validation = dispatch.OrderValidation
+
+// Validator is implemented by backends that can check an order without
+// creating it
+type Validator interface {
+	Validate(ctx context.Context, o *Order) (*Validation, error)
+}
+
+// CheckOrder applies the ValidateOrderMutation rules to input against the
+// mock scenarios; see dispatch.ScenarioSet.CheckOrder
+func CheckOrder(scenarios *dispatch.ScenarioSet, input *OrderCreationInput) *Validation {
+	return scenarios.CheckOrder(input)
+}
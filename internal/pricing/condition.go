@@ -0,0 +1,641 @@
+package pricing
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Condition is a compiled eligibility expression over a PricingContext, e.g.
+// `delivery_count >= 5 && order_frequency >= 3`. Expressions combine
+// comparisons (>=, >, <=, <, ==, !=) of fields, numbers and quoted strings
+// with &&, || and !, and may name the rule's own parameters
+// (volume_threshold, min_order_frequency, loyalty_tier) in place of literals.
+type Condition struct {
+	source string
+	root   conditionNode
+}
+
+// conditionField describes a name an expression may use
+type conditionField struct {
+	kind  valueKind
+	noun  string // e.g. "deliveries"; empty for rule parameters
+	money bool
+}
+
+// conditionFields are the PricingContext fields and rule parameters an
+// eligibility expression may name
+var conditionFields = map[string]conditionField{
+	"delivery_count":     {kind: kindNumber, noun: "deliveries"},
+	"order_frequency":    {kind: kindNumber, noun: "orders/month"},
+	"total_order_value":  {kind: kindNumber, noun: "order value", money: true},
+	"customer_tier":      {kind: kindString, noun: "tier"},
+	"organization_druid": {kind: kindString, noun: "organization"},
+	"is_bulk_order":      {kind: kindBool, noun: "bulk order"},
+
+	"volume_threshold":    {kind: kindNumber},
+	"min_order_frequency": {kind: kindNumber},
+	"loyalty_tier":        {kind: kindString},
+}
+
+type valueKind int
+
+const (
+	kindNumber valueKind = iota
+	kindString
+	kindBool
+)
+
+func (k valueKind) String() string {
+	return [...]string{"number", "string", "boolean"}[k]
+}
+
+type value struct {
+	kind valueKind
+	num  float64
+	str  string
+	b    bool
+}
+
+// conditionEnv holds the values of every conditionFields name
+type conditionEnv map[string]value
+
+func newConditionEnv(rule PricingRule, context PricingContext) conditionEnv {
+	return conditionEnv{
+		"delivery_count":     {kind: kindNumber, num: float64(context.DeliveryCount)},
+		"order_frequency":    {kind: kindNumber, num: float64(context.OrderFrequency)},
+		"total_order_value":  {kind: kindNumber, num: context.TotalOrderValue.Float64()},
+		"customer_tier":      {kind: kindString, str: context.CustomerTier},
+		"organization_druid": {kind: kindString, str: context.OrganizationDruid},
+		"is_bulk_order":      {kind: kindBool, b: context.IsBulkOrder},
+
+		"volume_threshold":    {kind: kindNumber, num: float64(rule.VolumeThreshold)},
+		"min_order_frequency": {kind: kindNumber, num: float64(rule.MinOrderFrequency)},
+		"loyalty_tier":        {kind: kindString, str: rule.LoyaltyTier},
+	}
+}
+
+// ParseCondition compiles an eligibility expression. An empty expression is
+// always true.
+func ParseCondition(source string) (*Condition, error) {
+	if strings.TrimSpace(source) == "" {
+		return &Condition{}, nil
+	}
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &conditionParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos+1)
+	}
+	return &Condition{source: source, root: root}, nil
+}
+
+// String returns the expression as written
+func (c *Condition) String() string {
+	return c.source
+}
+
+// Eval reports whether context satisfies the condition for rule, and if not,
+// a reason built from the clauses that failed, e.g.
+// "Requires 3+ orders/month, you have 1 orders/month"
+func (c *Condition) Eval(rule PricingRule, context PricingContext) (bool, string) {
+	if c.root == nil {
+		return true, ""
+	}
+
+	env := newConditionEnv(rule, context)
+	if c.root.eval(env) {
+		return true, ""
+	}
+	requires, has := c.root.explain(env)
+	reason := "Requires " + strings.Join(requires, " and ")
+	if has = unique(has); len(has) > 0 {
+		reason += ", you have " + strings.Join(has, " and ")
+	}
+	return false, reason
+}
+
+// Requirements describes every clause of the condition for rule, e.g.
+// ["5+ deliveries", "3+ orders/month"]
+func (c *Condition) Requirements(rule PricingRule) []string {
+	if c.root == nil {
+		return nil
+	}
+	return c.root.describe(newConditionEnv(rule, PricingContext{}))
+}
+
+// conditionNode is a boolean expression
+type conditionNode interface {
+	eval(env conditionEnv) bool
+	// explain describes the failing clauses and the values that failed them
+	explain(env conditionEnv) (requires, has []string)
+	// describe describes every clause
+	describe(env conditionEnv) []string
+}
+
+type andNode struct{ terms []conditionNode }
+
+func (n andNode) eval(env conditionEnv) bool {
+	for _, term := range n.terms {
+		if !term.eval(env) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n andNode) explain(env conditionEnv) (requires, has []string) {
+	for _, term := range n.terms {
+		if !term.eval(env) {
+			r, h := term.explain(env)
+			requires = append(requires, r...)
+			has = append(has, h...)
+		}
+	}
+	return requires, has
+}
+
+func (n andNode) describe(env conditionEnv) []string {
+	var clauses []string
+	for _, term := range n.terms {
+		clauses = append(clauses, term.describe(env)...)
+	}
+	return clauses
+}
+
+type orNode struct{ terms []conditionNode }
+
+func (n orNode) eval(env conditionEnv) bool {
+	for _, term := range n.terms {
+		if term.eval(env) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n orNode) explain(env conditionEnv) (requires, has []string) {
+	var alternatives []string
+	for _, term := range n.terms {
+		r, h := term.explain(env)
+		alternatives = append(alternatives, strings.Join(r, " and "))
+		has = append(has, h...)
+	}
+	return []string{strings.Join(alternatives, " or ")}, has
+}
+
+func (n orNode) describe(env conditionEnv) []string {
+	var alternatives []string
+	for _, term := range n.terms {
+		alternatives = append(alternatives, strings.Join(term.describe(env), " and "))
+	}
+	return []string{strings.Join(alternatives, " or ")}
+}
+
+type notNode struct {
+	term   conditionNode
+	source string
+}
+
+func (n notNode) eval(env conditionEnv) bool {
+	return !n.term.eval(env)
+}
+
+func (n notNode) explain(env conditionEnv) (requires, has []string) {
+	return n.describe(env), nil
+}
+
+func (n notNode) describe(env conditionEnv) []string {
+	// A negated flag reads naturally; anything else is quoted
+	if flag, ok := n.term.(flagNode); ok {
+		return []string{"not a " + conditionFields[flag.name].noun}
+	}
+	return []string{"not " + n.source}
+}
+
+// flagNode is a boolean field used on its own, e.g. is_bulk_order
+type flagNode struct{ name string }
+
+func (n flagNode) eval(env conditionEnv) bool {
+	return env[n.name].b
+}
+
+func (n flagNode) explain(env conditionEnv) (requires, has []string) {
+	return n.describe(env), []string{"no " + conditionFields[n.name].noun}
+}
+
+func (n flagNode) describe(env conditionEnv) []string {
+	return []string{conditionFields[n.name].noun}
+}
+
+// compareNode compares two operands. Comparisons are normalized so that a
+// context field, when there is one, is on the left.
+type compareNode struct {
+	op          string
+	left, right operand
+	source      string
+}
+
+type operand struct {
+	name    string // a conditionFields name, or empty for a literal
+	literal value
+}
+
+func (o operand) value(env conditionEnv) value {
+	if o.name != "" {
+		return env[o.name]
+	}
+	return o.literal
+}
+
+// isContextField reports whether o names a PricingContext field rather than a
+// rule parameter or literal
+func (o operand) isContextField() bool {
+	return o.name != "" && conditionFields[o.name].noun != ""
+}
+
+func (n compareNode) eval(env conditionEnv) bool {
+	left, right := n.left.value(env), n.right.value(env)
+	switch left.kind {
+	case kindNumber:
+		switch n.op {
+		case ">=":
+			return left.num >= right.num
+		case ">":
+			return left.num > right.num
+		case "<=":
+			return left.num <= right.num
+		case "<":
+			return left.num < right.num
+		case "==":
+			return left.num == right.num
+		case "!=":
+			return left.num != right.num
+		}
+	case kindString:
+		equal := strings.EqualFold(left.str, right.str)
+		return equal == (n.op == "==")
+	case kindBool:
+		return (left.b == right.b) == (n.op == "==")
+	}
+	return false
+}
+
+func (n compareNode) explain(env conditionEnv) (requires, has []string) {
+	requires = n.describe(env)
+	if n.left.isContextField() && !n.right.isContextField() {
+		has = []string{formatField(n.left.name, n.left.value(env))}
+	}
+	return requires, has
+}
+
+func (n compareNode) describe(env conditionEnv) []string {
+	if !n.left.isContextField() || n.right.isContextField() {
+		return []string{n.source}
+	}
+
+	field := conditionFields[n.left.name]
+	target := n.right.value(env)
+	switch field.kind {
+	case kindString:
+		if n.op == "==" {
+			return []string{formatField(n.left.name, target)}
+		}
+		return []string{fmt.Sprintf("%s other than %s", field.noun, target.str)}
+	case kindBool:
+		if target.b == (n.op == "==") {
+			return []string{field.noun}
+		}
+		return []string{"not a " + field.noun}
+	}
+
+	amount := formatAmount(field, target.num)
+	switch n.op {
+	case ">=":
+		return []string{fmt.Sprintf("%s+ %s", amount, field.noun)}
+	case ">":
+		return []string{fmt.Sprintf("more than %s %s", amount, field.noun)}
+	case "<=":
+		return []string{fmt.Sprintf("at most %s %s", amount, field.noun)}
+	case "<":
+		return []string{fmt.Sprintf("under %s %s", amount, field.noun)}
+	case "==":
+		return []string{fmt.Sprintf("exactly %s %s", amount, field.noun)}
+	default:
+		return []string{fmt.Sprintf("other than %s %s", amount, field.noun)}
+	}
+}
+
+// formatField describes a context field's value, e.g. "3 deliveries" or
+// "silver tier"
+func formatField(name string, v value) string {
+	field := conditionFields[name]
+	switch field.kind {
+	case kindString:
+		if v.str == "" {
+			return "no " + field.noun
+		}
+		if name == "organization_druid" {
+			return field.noun + " " + v.str
+		}
+		return v.str + " " + field.noun
+	case kindBool:
+		if v.b {
+			return "a " + field.noun
+		}
+		return "no " + field.noun
+	}
+	return formatAmount(field, v.num) + " " + field.noun
+}
+
+func formatAmount(field conditionField, n float64) string {
+	if field.money {
+		return fmt.Sprintf("$%.2f", n)
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func unique(values []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+type token struct {
+	kind string // "ident", "number", "string" or the operator itself
+	text string
+	pos  int
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(source) && (source[i] == '_' || unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: "ident", text: source[start:i], pos: start})
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(source) && (unicode.IsDigit(rune(source[i])) || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: "number", text: source[start:i], pos: start})
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(source[i+1:], source[i])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			tokens = append(tokens, token{kind: "string", text: source[i+1 : i+1+end], pos: i})
+			i += end + 2
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", ">=", "<=", "==", "!=", ">", "<", "!", "(", ")"} {
+				if strings.HasPrefix(source[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i+1)
+			}
+			tokens = append(tokens, token{kind: op, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// conditionParser is a recursive descent parser:
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" or ")" | comparison
+//	comparison = operand [ op operand ]
+type conditionParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *conditionParser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *conditionParser) accept(kind string) *token {
+	if t := p.peek(); t != nil && t.kind == kind {
+		p.pos++
+		return t
+	}
+	return nil
+}
+
+// sourceFrom returns the expression text from token start up to the current
+// position
+func (p *conditionParser) sourceFrom(start int) string {
+	parts := make([]string, 0, p.pos-start)
+	for _, t := range p.tokens[start:p.pos] {
+		if t.kind == "string" {
+			parts = append(parts, strconv.Quote(t.text))
+		} else {
+			parts = append(parts, t.text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (p *conditionParser) parseOr() (conditionNode, error) {
+	terms, err := p.parseList("||", p.parseAnd)
+	if err != nil || len(terms) == 1 {
+		return first(terms), err
+	}
+	return orNode{terms: terms}, nil
+}
+
+func (p *conditionParser) parseAnd() (conditionNode, error) {
+	terms, err := p.parseList("&&", p.parseUnary)
+	if err != nil || len(terms) == 1 {
+		return first(terms), err
+	}
+	return andNode{terms: terms}, nil
+}
+
+func (p *conditionParser) parseList(separator string, parseTerm func() (conditionNode, error)) ([]conditionNode, error) {
+	var terms []conditionNode
+	for {
+		term, err := parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		// Flatten nested groups of the same operator
+		switch grouped := term.(type) {
+		case andNode:
+			if separator == "&&" {
+				terms = append(terms, grouped.terms...)
+				break
+			}
+			terms = append(terms, term)
+		case orNode:
+			if separator == "||" {
+				terms = append(terms, grouped.terms...)
+				break
+			}
+			terms = append(terms, term)
+		default:
+			terms = append(terms, term)
+		}
+		if p.accept(separator) == nil {
+			return terms, nil
+		}
+	}
+}
+
+func first(terms []conditionNode) conditionNode {
+	if len(terms) == 0 {
+		return nil
+	}
+	return terms[0]
+}
+
+func (p *conditionParser) parseUnary() (conditionNode, error) {
+	start := p.pos
+	if p.accept("!") != nil {
+		term, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{term: term, source: p.sourceFrom(start + 1)}, nil
+	}
+	if p.accept("(") != nil {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.accept(")") == nil {
+			return nil, p.expected("\")\"")
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *conditionParser) parseComparison() (conditionNode, error) {
+	start := p.pos
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	var op string
+	if t := p.peek(); t != nil {
+		switch t.kind {
+		case ">=", ">", "<=", "<", "==", "!=":
+			op = t.kind
+			p.pos++
+		}
+	}
+	if op == "" {
+		// A boolean field on its own
+		if left.name == "" || conditionFields[left.name].kind != kindBool {
+			return nil, fmt.Errorf("%s is not a condition; compare it with >=, >, <=, <, == or !=", p.sourceFrom(start))
+		}
+		return flagNode{name: left.name}, nil
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	source := p.sourceFrom(start)
+
+	leftKind, rightKind := operandKind(left), operandKind(right)
+	if leftKind != rightKind {
+		return nil, fmt.Errorf("%s compares a %s with a %s", source, leftKind, rightKind)
+	}
+	if leftKind != kindNumber && op != "==" && op != "!=" {
+		return nil, fmt.Errorf("%s: %s values can only be compared with == or !=", source, leftKind)
+	}
+
+	// Keep the context field on the left so reasons read naturally
+	if right.isContextField() && !left.isContextField() {
+		left, right = right, left
+		op = map[string]string{">=": "<=", ">": "<", "<=": ">=", "<": ">", "==": "==", "!=": "!="}[op]
+	}
+	return compareNode{op: op, left: left, right: right, source: source}, nil
+}
+
+func (p *conditionParser) parseOperand() (operand, error) {
+	t := p.peek()
+	if t == nil {
+		return operand{}, p.expected("a field, number or string")
+	}
+	p.pos++
+
+	switch t.kind {
+	case "number":
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return operand{}, fmt.Errorf("invalid number %q at position %d", t.text, t.pos+1)
+		}
+		return operand{literal: value{kind: kindNumber, num: n}}, nil
+	case "string":
+		return operand{literal: value{kind: kindString, str: t.text}}, nil
+	case "ident":
+		switch t.text {
+		case "true", "false":
+			return operand{literal: value{kind: kindBool, b: t.text == "true"}}, nil
+		}
+		if _, ok := conditionFields[t.text]; !ok {
+			return operand{}, fmt.Errorf("unknown field %q; expected one of: %s", t.text, strings.Join(ConditionFieldNames(), ", "))
+		}
+		return operand{name: t.text}, nil
+	}
+	p.pos--
+	return operand{}, p.expected("a field, number or string")
+}
+
+func (p *conditionParser) expected(what string) error {
+	if t := p.peek(); t != nil {
+		return fmt.Errorf("expected %s at position %d, got %q", what, t.pos+1, t.text)
+	}
+	return fmt.Errorf("expected %s at the end of the expression", what)
+}
+
+func operandKind(o operand) valueKind {
+	if o.name != "" {
+		return conditionFields[o.name].kind
+	}
+	return o.literal.kind
+}
+
+// ConditionFieldNames lists the names an eligibility expression may use
+func ConditionFieldNames() []string {
+	names := make([]string, 0, len(conditionFields))
+	for name := range conditionFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
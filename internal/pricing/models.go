@@ -1,9 +1,11 @@
 package pricing
 
 import (
+	"dispatch-mcp-server/internal/config"
 	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
 	"fmt"
-	"math"
+	"sync"
 )
 
 // PricingModel represents different pricing strategies
@@ -19,14 +21,18 @@ const (
 
 // PricingRule defines how a pricing model should be applied
 type PricingRule struct {
-	Model           PricingModel `json:"model"`
-	Name            string       `json:"name"`
-	Description     string       `json:"description"`
-	BaseMultiplier  float64      `json:"base_multiplier"`  // Base price multiplier (1.0 = no change)
-	MinDiscount     float64      `json:"min_discount"`     // Minimum discount percentage
-	MaxDiscount     float64      `json:"max_discount"`     // Maximum discount percentage
-	VolumeThreshold int          `json:"volume_threshold"` // Minimum deliveries for discount
-	LoyaltyTier     string       `json:"loyalty_tier"`     // Required loyalty tier
+	Model             PricingModel `yaml:"model" json:"model"`
+	Name              string       `yaml:"name" json:"name"`
+	Description       string       `yaml:"description" json:"description"`
+	BaseMultiplier    float64      `yaml:"base_multiplier" json:"base_multiplier"`         // Base price multiplier (1.0 = no change)
+	MinDiscount       float64      `yaml:"min_discount" json:"min_discount"`               // Minimum discount percentage
+	MaxDiscount       float64      `yaml:"max_discount" json:"max_discount"`               // Maximum discount percentage
+	VolumeThreshold   int          `yaml:"volume_threshold" json:"volume_threshold"`       // Minimum deliveries for discount
+	MinOrderFrequency int          `yaml:"min_order_frequency" json:"min_order_frequency"` // Minimum orders per month
+	LoyaltyTier       string       `yaml:"loyalty_tier" json:"loyalty_tier"`               // Required loyalty tier
+	Eligibility       string       `yaml:"eligibility" json:"eligibility,omitempty"`       // Condition on the PricingContext; empty is always eligible
+	Group             string       `yaml:"group" json:"group,omitempty"`                   // Rules in a group compete under the best_of_group stacking policy
+	Priority          int          `yaml:"priority" json:"priority,omitempty"`             // Lower numbers are listed and preferred first; defaults to 0
 }
 
 // PricingComparison represents the result of comparing pricing models
@@ -34,131 +40,162 @@ type PricingComparison struct {
 	OriginalEstimate  *dispatch.AvailableOrderOption `json:"original_estimate"`
 	PricingModels     []PricingResult                `json:"pricing_models"`
 	BestOption        *PricingResult                 `json:"best_option"`
-	Savings           float64                        `json:"savings"`
+	Savings           money.Money                    `json:"savings"`
 	SavingsPercentage float64                        `json:"savings_percentage"`
+	RulesVersion      int                            `json:"rules_version"`
+	StackingPolicy    StackingPolicy                 `json:"stacking_policy"`
+	Combined          *CombinedPricing               `json:"combined"` // the eligible discounts stacked under StackingPolicy
+	SortBy            SortOrder                      `json:"sort_by"`  // the order of PricingModels
 }
 
 // PricingResult represents the result of applying a specific pricing model
 type PricingResult struct {
 	Model           PricingModel `json:"model"`
 	Name            string       `json:"name"`
-	OriginalCost    float64      `json:"original_cost"`
-	AdjustedCost    float64      `json:"adjusted_cost"`
-	Discount        float64      `json:"discount"`
+	OriginalCost    money.Money  `json:"original_cost"`
+	AdjustedCost    money.Money  `json:"adjusted_cost"`
+	Discount        money.Money  `json:"discount"`
 	DiscountPercent float64      `json:"discount_percent"`
-	Savings         float64      `json:"savings"`
+	Savings         money.Money  `json:"savings"`
 	Eligible        bool         `json:"eligible"`
 	Reason          string       `json:"reason,omitempty"`
 }
 
-// PricingEngine handles pricing model calculations
+// PricingEngine handles pricing model calculations. Its rules can be
+// replaced while it is in use, e.g. when the rules file is reloaded.
 type PricingEngine struct {
-	rules map[PricingModel]PricingRule
+	mu        sync.RWMutex
+	rules     *RuleSet
+	listeners []func(*RuleSet)
 }
 
-// NewPricingEngine creates a new pricing engine with default rules
+// NewPricingEngine creates a new pricing engine with the built-in rules
 func NewPricingEngine() *PricingEngine {
-	engine := &PricingEngine{
-		rules: make(map[PricingModel]PricingRule),
+	rules, err := DefaultRules()
+	if err != nil {
+		panic(fmt.Sprintf("built-in pricing rules are invalid: %v", err))
 	}
-
-	// Initialize default pricing rules
-	engine.initializeDefaultRules()
-
-	return engine
+	return NewPricingEngineWithRules(rules)
 }
 
-// initializeDefaultRules sets up default pricing models
-func (pe *PricingEngine) initializeDefaultRules() {
-	pe.rules[StandardPricing] = PricingRule{
-		Model:          StandardPricing,
-		Name:           "Standard Pricing",
-		Description:    "Standard pricing with no discounts",
-		BaseMultiplier: 1.0,
-		MinDiscount:    0.0,
-		MaxDiscount:    0.0,
+// NewPricingEngineWithConfig creates a pricing engine with the rules file at
+// PricingRulesPath, or the built-in rules when it is empty
+func NewPricingEngineWithConfig(cfg *config.Config) (*PricingEngine, error) {
+	if cfg.PricingRulesPath == "" {
+		return NewPricingEngine(), nil
 	}
 
-	pe.rules[MultiDeliveryPricing] = PricingRule{
-		Model:           MultiDeliveryPricing,
-		Name:            "Multi-Delivery Discount",
-		Description:     "Discount for multiple deliveries in the same order",
-		BaseMultiplier:  0.85, // 15% discount
-		MinDiscount:     5.0,  // 5% minimum
-		MaxDiscount:     25.0, // 25% maximum
-		VolumeThreshold: 2,    // 2+ deliveries
+	rules, err := LoadRules(cfg.PricingRulesPath)
+	if err != nil {
+		return nil, err
 	}
+	return NewPricingEngineWithRules(rules), nil
+}
 
-	pe.rules[VolumeDiscountPricing] = PricingRule{
-		Model:           VolumeDiscountPricing,
-		Name:            "Volume Discount",
-		Description:     "Discount based on order volume and frequency",
-		BaseMultiplier:  0.80, // 20% discount
-		MinDiscount:     10.0, // 10% minimum
-		MaxDiscount:     30.0, // 30% maximum
-		VolumeThreshold: 5,    // 5+ deliveries
-	}
+// NewPricingEngineWithRules creates a pricing engine with already validated rules
+func NewPricingEngineWithRules(rules *RuleSet) *PricingEngine {
+	return &PricingEngine{rules: rules}
+}
 
-	pe.rules[LoyaltyDiscountPricing] = PricingRule{
-		Model:          LoyaltyDiscountPricing,
-		Name:           "Loyalty Discount",
-		Description:    "Discount for loyal customers",
-		BaseMultiplier: 0.90, // 10% discount
-		MinDiscount:    5.0,  // 5% minimum
-		MaxDiscount:    15.0, // 15% maximum
-		LoyaltyTier:    "gold",
+// SetRules validates rules and replaces the engine's, then tells every
+// OnRulesChanged listener
+func (pe *PricingEngine) SetRules(rules *RuleSet) error {
+	if err := rules.Validate(); err != nil {
+		return err
 	}
 
-	pe.rules[BulkOrderPricing] = PricingRule{
-		Model:           BulkOrderPricing,
-		Name:            "Bulk Order Discount",
-		Description:     "Discount for large bulk orders",
-		BaseMultiplier:  0.75, // 25% discount
-		MinDiscount:     15.0, // 15% minimum
-		MaxDiscount:     40.0, // 40% maximum
-		VolumeThreshold: 10,   // 10+ deliveries
+	pe.mu.Lock()
+	pe.rules = rules
+	listeners := pe.listeners
+	pe.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(rules)
 	}
+	return nil
+}
+
+// Rules returns the rule set in use
+func (pe *PricingEngine) Rules() *RuleSet {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+	return pe.rules
 }
 
-// ComparePricingModels compares different pricing models against an original estimate
+// OnRulesChanged registers listener to be called with the new rules after
+// each SetRules
+func (pe *PricingEngine) OnRulesChanged(listener func(*RuleSet)) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.listeners = append(pe.listeners, listener)
+}
+
+// ComparePricingModels compares different pricing models against an original
+// estimate and stacks the eligible discounts under the rules' stacking policy
 func (pe *PricingEngine) ComparePricingModels(originalEstimate *dispatch.AvailableOrderOption, context PricingContext) *PricingComparison {
+	return pe.compare(originalEstimate, context, pe.Rules(), CompareOptions{})
+}
+
+// ComparePricingModelsWithOptions is ComparePricingModels with the stacking
+// policy or the order of the results changed by opts; the cap and floor
+// still apply
+func (pe *PricingEngine) ComparePricingModelsWithOptions(originalEstimate *dispatch.AvailableOrderOption, context PricingContext, opts CompareOptions) (*PricingComparison, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return pe.compare(originalEstimate, context, pe.Rules(), opts), nil
+}
+
+func (pe *PricingEngine) compare(originalEstimate *dispatch.AvailableOrderOption, context PricingContext, ruleSet *RuleSet, opts CompareOptions) *PricingComparison {
+	stacking := ruleSet.Stacking
+	if opts.StackingPolicy != "" {
+		stacking.Policy = opts.StackingPolicy
+	}
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = SortByPriority
+	}
+
 	comparison := &PricingComparison{
 		OriginalEstimate: originalEstimate,
 		PricingModels:    []PricingResult{},
+		RulesVersion:     ruleSet.Version,
+		StackingPolicy:   stacking.Policy,
+		SortBy:           sortBy,
 	}
 
 	originalCost := originalEstimate.EstimatedOrderCost
 
 	// Apply each pricing model
-	for _, rule := range pe.rules {
-		result := pe.applyPricingModel(originalCost, rule, context)
+	for _, rule := range ruleSet.Rules {
+		result := pe.applyPricingModel(originalCost, rule, context, stacking.MinCostPercent)
 		comparison.PricingModels = append(comparison.PricingModels, result)
 	}
+	sortResults(comparison.PricingModels, ruleSet, sortBy)
 
 	// Find the best option (lowest cost)
-	bestOption := pe.findBestOption(comparison.PricingModels)
-	comparison.BestOption = bestOption
+	comparison.BestOption = pe.findBestOption(comparison.PricingModels, ruleSet)
 
-	if bestOption != nil {
-		comparison.Savings = originalCost - bestOption.AdjustedCost
-		comparison.SavingsPercentage = (comparison.Savings / originalCost) * 100
-	}
+	// Savings are what the combined discounts take off
+	comparison.Combined = combine(originalCost, ruleSet, comparison.PricingModels, stacking)
+	comparison.Savings = comparison.Combined.Discount
+	comparison.SavingsPercentage = comparison.Combined.DiscountPercent
 
 	return comparison
 }
 
 // PricingContext provides context for pricing calculations
 type PricingContext struct {
-	DeliveryCount     int     `json:"delivery_count"`
-	CustomerTier      string  `json:"customer_tier"`
-	OrderFrequency    int     `json:"order_frequency"` // orders per month
-	TotalOrderValue   float64 `json:"total_order_value"`
-	IsBulkOrder       bool    `json:"is_bulk_order"`
-	OrganizationDruid string  `json:"organization_druid"`
+	DeliveryCount     int         `json:"delivery_count"`
+	CustomerTier      string      `json:"customer_tier"`
+	OrderFrequency    int         `json:"order_frequency"` // orders per month
+	TotalOrderValue   money.Money `json:"total_order_value"`
+	IsBulkOrder       bool        `json:"is_bulk_order"`
+	OrganizationDruid string      `json:"organization_druid"`
 }
 
 // applyPricingModel applies a specific pricing model to calculate adjusted cost
-func (pe *PricingEngine) applyPricingModel(originalCost float64, rule PricingRule, context PricingContext) PricingResult {
+func (pe *PricingEngine) applyPricingModel(originalCost money.Money, rule PricingRule, context PricingContext, minCostPercent float64) PricingResult {
 	result := PricingResult{
 		Model:        rule.Model,
 		Name:         rule.Name,
@@ -167,73 +204,49 @@ func (pe *PricingEngine) applyPricingModel(originalCost float64, rule PricingRul
 	}
 
 	// Check eligibility based on context
-	if !pe.isEligibleForModel(rule, context) {
+	if eligible, reason := rule.Eligible(context); !eligible {
 		result.Eligible = false
 		result.AdjustedCost = originalCost
-		result.Discount = 0.0
+		result.Discount = money.New(0, originalCost.Currency)
 		result.DiscountPercent = 0.0
-		result.Savings = 0.0
-		result.Reason = pe.getIneligibilityReason(rule, context)
+		result.Savings = result.Discount
+		result.Reason = reason
 		return result
 	}
 
-	// Calculate adjusted cost
-	adjustedCost := originalCost * rule.BaseMultiplier
+	// Calculate adjusted cost, rounding each step to the nearest cent
+	adjustedCost := originalCost.Mul(rule.BaseMultiplier, money.HalfUp)
 
 	// Apply additional discounts based on context
 	additionalDiscount := pe.calculateAdditionalDiscount(rule, context)
 	if additionalDiscount > 0 {
-		discountAmount := adjustedCost * (additionalDiscount / 100)
-		adjustedCost -= discountAmount
+		discountAmount := adjustedCost.Percent(additionalDiscount, money.HalfUp)
+		adjustedCost = adjustedCost.Sub(discountAmount)
 	}
 
-	// Ensure we don't go below minimum cost (e.g., 50% of original)
-	minCost := originalCost * 0.5
-	if adjustedCost < minCost {
+	// Ensure we don't go below the stacking floor (e.g., 50% of original),
+	// rounding the floor up so it is never undercut by a fraction of a cent
+	minCost := originalCost.Percent(minCostPercent, money.Up)
+	if adjustedCost.Cmp(minCost) < 0 {
 		adjustedCost = minCost
 	}
 
 	result.AdjustedCost = adjustedCost
-	result.Discount = originalCost - adjustedCost
-	result.DiscountPercent = (result.Discount / originalCost) * 100
+	result.Discount = originalCost.Sub(adjustedCost)
+	result.DiscountPercent = result.Discount.PercentOf(originalCost)
 	result.Savings = result.Discount
 
 	return result
 }
 
-// isEligibleForModel checks if the context makes the customer eligible for a pricing model
-func (pe *PricingEngine) isEligibleForModel(rule PricingRule, context PricingContext) bool {
-	switch rule.Model {
-	case StandardPricing:
-		return true
-	case MultiDeliveryPricing:
-		return context.DeliveryCount >= rule.VolumeThreshold
-	case VolumeDiscountPricing:
-		return context.DeliveryCount >= rule.VolumeThreshold && context.OrderFrequency >= 3
-	case LoyaltyDiscountPricing:
-		return context.CustomerTier == rule.LoyaltyTier
-	case BulkOrderPricing:
-		return context.IsBulkOrder && context.DeliveryCount >= rule.VolumeThreshold
-	default:
-		return false
-	}
-}
-
-// getIneligibilityReason returns a human-readable reason why a model is not eligible
-func (pe *PricingEngine) getIneligibilityReason(rule PricingRule, context PricingContext) string {
-	switch rule.Model {
-	case MultiDeliveryPricing:
-		return fmt.Sprintf("Requires %d+ deliveries, you have %d", rule.VolumeThreshold, context.DeliveryCount)
-	case VolumeDiscountPricing:
-		return fmt.Sprintf("Requires %d+ deliveries and 3+ orders/month, you have %d deliveries and %d orders/month",
-			rule.VolumeThreshold, context.DeliveryCount, context.OrderFrequency)
-	case LoyaltyDiscountPricing:
-		return fmt.Sprintf("Requires %s tier, you are %s", rule.LoyaltyTier, context.CustomerTier)
-	case BulkOrderPricing:
-		return fmt.Sprintf("Requires bulk order with %d+ deliveries, you have %d", rule.VolumeThreshold, context.DeliveryCount)
-	default:
-		return "Not eligible for this pricing model"
+// Eligible evaluates the rule's eligibility condition against context and,
+// when it fails, explains which requirements weren't met
+func (r PricingRule) Eligible(context PricingContext) (bool, string) {
+	condition, err := ParseCondition(r.Eligibility)
+	if err != nil {
+		return false, fmt.Sprintf("Invalid eligibility condition: %v", err)
 	}
+	return condition.Eval(r, context)
 }
 
 // calculateAdditionalDiscount calculates additional discounts based on context
@@ -252,7 +265,7 @@ func (pe *PricingEngine) calculateAdditionalDiscount(rule PricingRule, context P
 	}
 
 	// Value-based additional discount
-	if context.TotalOrderValue > 1000 {
+	if context.TotalOrderValue.Float64() > 1000 {
 		additionalDiscount += 3.0 // 3% for high value orders
 	}
 
@@ -264,15 +277,26 @@ func (pe *PricingEngine) calculateAdditionalDiscount(rule PricingRule, context P
 	return additionalDiscount
 }
 
-// findBestOption finds the pricing model with the lowest cost
-func (pe *PricingEngine) findBestOption(results []PricingResult) *PricingResult {
+// findBestOption finds the eligible pricing model with the lowest cost. Ties
+// go to the lower priority number, then the model name.
+func (pe *PricingEngine) findBestOption(results []PricingResult, rules *RuleSet) *PricingResult {
 	var best *PricingResult
-	lowestCost := math.Inf(1)
 
 	for i := range results {
-		if results[i].Eligible && results[i].AdjustedCost < lowestCost {
-			lowestCost = results[i].AdjustedCost
+		if !results[i].Eligible {
+			continue
+		}
+		if best == nil {
 			best = &results[i]
+			continue
+		}
+		switch results[i].AdjustedCost.Cmp(best.AdjustedCost) {
+		case -1:
+			best = &results[i]
+		case 0:
+			if rules.outranks(results[i].Model, best.Model) {
+				best = &results[i]
+			}
 		}
 	}
 
@@ -281,9 +305,5 @@ func (pe *PricingEngine) findBestOption(results []PricingResult) *PricingResult
 
 // GetAvailableModels returns all available pricing models
 func (pe *PricingEngine) GetAvailableModels() []PricingRule {
-	var models []PricingRule
-	for _, rule := range pe.rules {
-		models = append(models, rule)
-	}
-	return models
+	return append([]PricingRule(nil), pe.Rules().Rules...)
 }
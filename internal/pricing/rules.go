@@ -0,0 +1,201 @@
+package pricing
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed fixtures/pricing_rules.yaml
+var defaultRules []byte
+
+// RuleSet is a versioned pricing rules file. Files may be YAML or JSON.
+type RuleSet struct {
+	Version  int           `yaml:"version" json:"version"`
+	Stacking Stacking      `yaml:"stacking" json:"stacking"`
+	Rules    []PricingRule `yaml:"rules" json:"rules"`
+}
+
+// DefaultRules returns the built-in rules
+func DefaultRules() (*RuleSet, error) {
+	return ParseRules(defaultRules)
+}
+
+// LoadRules reads and validates a YAML or JSON rules file
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing rules: %v", err)
+	}
+
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return rules, nil
+}
+
+// ParseRules decodes and validates a rules document. JSON is valid YAML, so
+// both are handled by the YAML decoder; unknown fields are rejected so typos
+// don't silently fall back to zero values.
+func ParseRules(data []byte) (*RuleSet, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	// Settings the file leaves out keep their defaults
+	rules := RuleSet{Stacking: DefaultStacking()}
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing rules: %v", err)
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// Validate checks the rules against the schema the engine relies on and
+// reports every problem found
+func (rs *RuleSet) Validate() error {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if rs.Version < 1 {
+		fail("version must be a positive integer")
+	}
+	if len(rs.Rules) == 0 {
+		fail("at least one rule is required")
+	}
+	problems = append(problems, rs.Stacking.Validate()...)
+
+	seen := map[PricingModel]bool{}
+	for i, rule := range rs.Rules {
+		field := fmt.Sprintf("rules[%d]", i)
+		if strings.TrimSpace(string(rule.Model)) == "" {
+			fail("%s: model is required", field)
+			continue
+		}
+		field = fmt.Sprintf("%s (%s)", field, rule.Model)
+		if seen[rule.Model] {
+			fail("%s: model is defined more than once", field)
+		}
+		seen[rule.Model] = true
+
+		if strings.TrimSpace(rule.Name) == "" {
+			fail("%s: name is required", field)
+		}
+		if rule.BaseMultiplier <= 0 || rule.BaseMultiplier > 1 {
+			fail("%s: base_multiplier must be greater than 0 and at most 1, got %g", field, rule.BaseMultiplier)
+		}
+		if rule.MinDiscount < 0 || rule.MaxDiscount > 100 || rule.MinDiscount > rule.MaxDiscount {
+			fail("%s: discounts must satisfy 0 <= min_discount <= max_discount <= 100, got %g and %g", field, rule.MinDiscount, rule.MaxDiscount)
+		}
+		if rule.VolumeThreshold < 0 || rule.MinOrderFrequency < 0 {
+			fail("%s: volume_threshold and min_order_frequency must not be negative", field)
+		}
+		if _, err := ParseCondition(rule.Eligibility); err != nil {
+			fail("%s: eligibility: %v", field, err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid pricing rules: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Rule returns the rule for model
+func (rs *RuleSet) Rule(model PricingModel) (PricingRule, bool) {
+	for _, rule := range rs.Rules {
+		if rule.Model == model {
+			return rule, true
+		}
+	}
+	return PricingRule{}, false
+}
+
+// Prompt describes each rule as a markdown bullet, for system prompts that
+// explain the pricing models, followed by how discounts combine when more
+// than one can apply
+func (rs *RuleSet) Prompt() string {
+	var text strings.Builder
+	for _, rule := range rs.Rules {
+		fmt.Fprintf(&text, "- **%s**: %s\n", rule.Name, rule.Summary())
+	}
+	if rs.Stacking.Policy != StackExclusive || rs.Stacking.MaxDiscount > 0 {
+		fmt.Fprintf(&text, "- **Combining discounts**: %s\n", rs.Stacking.Describe())
+	}
+	return strings.TrimSuffix(text.String(), "\n")
+}
+
+// DiscountPercent is the discount BaseMultiplier gives, in percent
+func (r PricingRule) DiscountPercent() float64 {
+	return (1 - r.BaseMultiplier) * 100
+}
+
+// Summary describes the discount and who qualifies, e.g.
+// "15% off for 2+ deliveries"
+func (r PricingRule) Summary() string {
+	summary := fmt.Sprintf("%.0f%% off", r.DiscountPercent())
+	if condition, err := ParseCondition(r.Eligibility); err == nil {
+		if requirements := condition.Requirements(r); len(requirements) > 0 {
+			summary += " for " + strings.Join(requirements, " + ")
+		}
+	}
+	if r.Description != "" {
+		summary += " (" + r.Description + ")"
+	}
+	return summary
+}
+
+// WatchRules reloads the engine from path whenever the file changes, checking
+// every interval until ctx is done. A file that fails to load or validate is
+// reported to onError and the engine keeps its current rules; a file with the
+// rules already in use is left alone.
+func (pe *PricingEngine) WatchRules(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	var last os.FileInfo
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			// Report a missing file once, when it goes away
+			if onError != nil && (last != nil || !errors.Is(err, os.ErrNotExist)) {
+				onError(fmt.Errorf("failed to read pricing rules: %v", err))
+			}
+			last = nil
+			continue
+		}
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+
+		rules, err := LoadRules(path)
+		if err == nil && reflect.DeepEqual(rules, pe.Rules()) {
+			continue
+		}
+		if err == nil {
+			err = pe.SetRules(rules)
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
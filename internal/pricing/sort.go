@@ -0,0 +1,84 @@
+package pricing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortOrder decides the order of PricingComparison.PricingModels
+type SortOrder string
+
+const (
+	// SortByPriority lists rules by priority, then by adjusted cost
+	SortByPriority SortOrder = "priority"
+	// SortByCost lists the cheapest adjusted cost first, then by priority
+	SortByCost SortOrder = "cost"
+	// SortByName lists rules alphabetically by name
+	SortByName SortOrder = "name"
+)
+
+// SortOrders lists the accepted sort orders
+var SortOrders = []string{string(SortByPriority), string(SortByCost), string(SortByName)}
+
+// CompareOptions adjusts a comparison. Zero values use the rules' stacking
+// policy and SortByPriority.
+type CompareOptions struct {
+	StackingPolicy StackingPolicy
+	SortBy         SortOrder
+}
+
+// Validate reports options the engine doesn't understand
+func (o CompareOptions) Validate() error {
+	if o.StackingPolicy != "" && !validStackingPolicy(o.StackingPolicy) {
+		return fmt.Errorf("stacking policy must be one of %v, got %q", StackingPolicies, o.StackingPolicy)
+	}
+	switch o.SortBy {
+	case "", SortByPriority, SortByCost, SortByName:
+		return nil
+	}
+	return fmt.Errorf("sort_by must be one of %v, got %q", SortOrders, o.SortBy)
+}
+
+// sortResults orders results in place. Ties are always broken the same way,
+// by priority, then adjusted cost, then eligible before ineligible, then
+// model name, so the order never depends on how the rules are listed.
+func sortResults(results []PricingResult, rules *RuleSet, order SortOrder) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch order {
+		case SortByCost:
+			if c := a.AdjustedCost.Cmp(b.AdjustedCost); c != 0 {
+				return c < 0
+			}
+		case SortByName:
+			if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+				return c < 0
+			}
+		}
+		if pa, pb := rules.priority(a.Model), rules.priority(b.Model); pa != pb {
+			return pa < pb
+		}
+		if c := a.AdjustedCost.Cmp(b.AdjustedCost); c != 0 {
+			return c < 0
+		}
+		if a.Eligible != b.Eligible {
+			return a.Eligible
+		}
+		return a.Model < b.Model
+	})
+}
+
+// outranks reports whether a should be preferred over b when both save the
+// same amount: the lower priority number wins, then the model name
+func (rs *RuleSet) outranks(a, b PricingModel) bool {
+	if pa, pb := rs.priority(a), rs.priority(b); pa != pb {
+		return pa < pb
+	}
+	return a < b
+}
+
+func (rs *RuleSet) priority(model PricingModel) int {
+	rule, _ := rs.Rule(model)
+	return rule.Priority
+}
@@ -0,0 +1,209 @@
+package pricing
+
+import (
+	"dispatch-mcp-server/internal/money"
+	"fmt"
+	"sort"
+)
+
+// StackingPolicy decides how the discounts of several eligible models combine
+type StackingPolicy string
+
+const (
+	// StackExclusive applies only the single largest discount
+	StackExclusive StackingPolicy = "exclusive"
+	// StackAdditive adds the discount percentages and applies the sum to the
+	// original cost
+	StackAdditive StackingPolicy = "additive"
+	// StackMultiplicative applies each discount in turn to what is left
+	// after the previous ones, largest first
+	StackMultiplicative StackingPolicy = "multiplicative"
+	// StackBestOfGroup keeps the largest discount of each rule group and
+	// applies the groups' discounts multiplicatively
+	StackBestOfGroup StackingPolicy = "best_of_group"
+)
+
+// StackingPolicies lists the accepted policies
+var StackingPolicies = []string{string(StackExclusive), string(StackAdditive), string(StackMultiplicative), string(StackBestOfGroup)}
+
+// Stacking is the rules file's stacking section: how discounts combine and
+// the limits on the combined result
+type Stacking struct {
+	Policy         StackingPolicy `yaml:"policy" json:"policy"`
+	MaxDiscount    float64        `yaml:"max_discount" json:"max_discount"`         // Cap on the combined discount, percent of the original cost; 0 is no cap
+	MinCostPercent float64        `yaml:"min_cost_percent" json:"min_cost_percent"` // Floor on the final cost, percent of the original cost
+}
+
+// DefaultStacking applies the best single discount and never charges less
+// than half the original cost
+func DefaultStacking() Stacking {
+	return Stacking{Policy: StackExclusive, MinCostPercent: 50}
+}
+
+// Validate reports problems with the stacking section
+func (s Stacking) Validate() []string {
+	var problems []string
+	if !validStackingPolicy(s.Policy) {
+		problems = append(problems, fmt.Sprintf("stacking.policy must be one of %v, got %q", StackingPolicies, s.Policy))
+	}
+	if s.MaxDiscount < 0 || s.MaxDiscount > 100 {
+		problems = append(problems, fmt.Sprintf("stacking.max_discount must be between 0 and 100, got %g", s.MaxDiscount))
+	}
+	if s.MinCostPercent < 0 || s.MinCostPercent > 100 {
+		problems = append(problems, fmt.Sprintf("stacking.min_cost_percent must be between 0 and 100, got %g", s.MinCostPercent))
+	}
+	return problems
+}
+
+// Describe summarizes how discounts combine, for system prompts
+func (s Stacking) Describe() string {
+	var text string
+	switch s.Policy {
+	case StackAdditive:
+		text = "Eligible discounts add together"
+	case StackMultiplicative:
+		text = "Eligible discounts apply one after another, largest first"
+	case StackBestOfGroup:
+		text = "The best discount from each group applies, one after another"
+	default:
+		text = "Only the single best discount applies"
+	}
+	if s.MaxDiscount > 0 {
+		text += fmt.Sprintf(", up to %.0f%% off in total", s.MaxDiscount)
+	}
+	return text
+}
+
+func validStackingPolicy(policy StackingPolicy) bool {
+	for _, p := range StackingPolicies {
+		if string(policy) == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Line item kinds
+const (
+	LineItemDiscount = "discount"
+	LineItemCap      = "cap"   // gives back discount above Stacking.MaxDiscount
+	LineItemFloor    = "floor" // gives back discount below Stacking.MinCostPercent
+)
+
+// LineItem is one step from the original cost to the combined cost. Amount is
+// taken off the cost (negative for cap and floor adjustments, which add it
+// back) and CostAfter is the running cost after this step.
+type LineItem struct {
+	Kind      string       `json:"kind"`
+	Model     PricingModel `json:"model,omitempty"`
+	Name      string       `json:"name"`
+	Group     string       `json:"group,omitempty"`
+	Percent   float64      `json:"percent"` // the model's own discount, or the limit for cap and floor
+	Amount    money.Money  `json:"amount"`
+	CostAfter money.Money  `json:"cost_after"`
+}
+
+// CombinedPricing is the price after stacking every eligible discount under
+// the policy. The line item amounts add up to Discount exactly.
+type CombinedPricing struct {
+	Policy          StackingPolicy `json:"policy"`
+	OriginalCost    money.Money    `json:"original_cost"`
+	AdjustedCost    money.Money    `json:"adjusted_cost"`
+	Discount        money.Money    `json:"discount"`
+	DiscountPercent float64        `json:"discount_percent"`
+	LineItems       []LineItem     `json:"line_items"`
+}
+
+// combine stacks the discounts of the eligible results under stacking
+func combine(originalCost money.Money, rules *RuleSet, results []PricingResult, stacking Stacking) *CombinedPricing {
+	// The discounts that could apply, largest first; ties are broken as for
+	// the best option so exclusive always agrees with it
+	var candidates []PricingResult
+	for _, result := range results {
+		if result.Eligible && result.DiscountPercent > 0 {
+			candidates = append(candidates, result)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.DiscountPercent != b.DiscountPercent {
+			return a.DiscountPercent > b.DiscountPercent
+		}
+		return rules.outranks(a.Model, b.Model)
+	})
+
+	group := func(model PricingModel) string {
+		if rule, ok := rules.Rule(model); ok && rule.Group != "" {
+			return rule.Group
+		}
+		return string(model)
+	}
+
+	switch stacking.Policy {
+	case StackExclusive:
+		if len(candidates) > 1 {
+			candidates = candidates[:1]
+		}
+	case StackBestOfGroup:
+		seen := map[string]bool{}
+		best := candidates[:0:0]
+		for _, candidate := range candidates {
+			if g := group(candidate.Model); !seen[g] {
+				seen[g] = true
+				best = append(best, candidate)
+			}
+		}
+		candidates = best
+	}
+
+	combined := &CombinedPricing{
+		Policy:       stacking.Policy,
+		OriginalCost: originalCost,
+		LineItems:    []LineItem{},
+	}
+	cost := originalCost
+	add := func(item LineItem) {
+		cost = cost.Sub(item.Amount)
+		item.CostAfter = cost
+		combined.LineItems = append(combined.LineItems, item)
+	}
+
+	for _, candidate := range candidates {
+		item := LineItem{
+			Kind:    LineItemDiscount,
+			Model:   candidate.Model,
+			Name:    candidate.Name,
+			Percent: candidate.DiscountPercent,
+		}
+		if rule, ok := rules.Rule(candidate.Model); ok {
+			item.Group = rule.Group
+		}
+		if stacking.Policy == StackAdditive {
+			item.Amount = originalCost.Percent(candidate.DiscountPercent, money.HalfUp)
+		} else {
+			item.Amount = cost.Percent(candidate.DiscountPercent, money.HalfUp)
+		}
+		// Additive discounts past 100% can't take the cost below zero
+		if item.Amount.Cmp(cost) > 0 {
+			item.Amount = cost
+		}
+		add(item)
+	}
+
+	// The cap rounds down and the floor up, so neither is overshot by a
+	// fraction of a cent
+	if stacking.MaxDiscount > 0 {
+		maxDiscount := originalCost.Percent(stacking.MaxDiscount, money.Down)
+		if excess := originalCost.Sub(cost).Sub(maxDiscount); excess.Amount > 0 {
+			add(LineItem{Kind: LineItemCap, Name: fmt.Sprintf("Discounts capped at %g%%", stacking.MaxDiscount), Percent: stacking.MaxDiscount, Amount: excess.Neg()})
+		}
+	}
+	if floor := originalCost.Percent(stacking.MinCostPercent, money.Up); cost.Cmp(floor) < 0 {
+		add(LineItem{Kind: LineItemFloor, Name: fmt.Sprintf("Cost floor of %g%% of the original", stacking.MinCostPercent), Percent: stacking.MinCostPercent, Amount: cost.Sub(floor)})
+	}
+
+	combined.AdjustedCost = cost
+	combined.Discount = originalCost.Sub(cost)
+	combined.DiscountPercent = combined.Discount.PercentOf(originalCost)
+	return combined
+}
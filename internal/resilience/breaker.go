@@ -0,0 +1,218 @@
+package resilience
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a breaker rejects a call without attempting it
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// State is the state of a circuit breaker
+type State string
+
+const (
+	StateClosed   State = "closed"    // calls flow normally
+	StateOpen     State = "open"      // calls are rejected until the cooldown expires
+	StateHalfOpen State = "half_open" // a single probe call is allowed through
+)
+
+// BreakerSettings configures when a breaker trips and how long it stays open
+type BreakerSettings struct {
+	FailureThreshold int           // consecutive failures before tripping; <= 0 disables the breaker
+	Cooldown         time.Duration // time spent open before allowing a probe
+}
+
+// Breaker is a consecutive-failure circuit breaker for a single endpoint.
+// A nil *Breaker allows every call.
+type Breaker struct {
+	endpoint string
+	settings BreakerSettings
+
+	mu                  sync.Mutex
+	state               State
+	consecutiveFailures int
+	openedAt            time.Time
+	probeInFlight       bool
+	lastError           string
+	lastFailure         time.Time
+}
+
+// NewBreaker creates a closed breaker for endpoint
+func NewBreaker(endpoint string, settings BreakerSettings) *Breaker {
+	return &Breaker{
+		endpoint: endpoint,
+		settings: settings,
+		state:    StateClosed,
+	}
+}
+
+// Allow reports whether a call may proceed. An open breaker moves to half-open
+// once the cooldown has elapsed and then admits a single probe.
+func (b *Breaker) Allow() bool {
+	if b == nil || b.settings.FailureThreshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.settings.Cooldown {
+			return false
+		}
+		b.state = StateHalfOpen
+		b.probeInFlight = true
+		return true
+	case StateHalfOpen:
+		if b.probeInFlight {
+			return false
+		}
+		b.probeInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess closes the breaker and resets the failure count
+func (b *Breaker) RecordSuccess() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = StateClosed
+	b.consecutiveFailures = 0
+	b.probeInFlight = false
+}
+
+// RecordFailure counts a failed call, tripping the breaker at the threshold
+// or immediately if the failure was a half-open probe
+func (b *Breaker) RecordFailure(err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFailures++
+	b.lastFailure = time.Now()
+	if err != nil {
+		b.lastError = err.Error()
+	}
+
+	if b.settings.FailureThreshold <= 0 {
+		return
+	}
+
+	if b.state == StateHalfOpen || b.consecutiveFailures >= b.settings.FailureThreshold {
+		b.state = StateOpen
+		b.openedAt = time.Now()
+	}
+	b.probeInFlight = false
+}
+
+// Release gives up an admitted call without recording an outcome, e.g. when
+// the caller cancelled it
+func (b *Breaker) Release() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probeInFlight = false
+}
+
+// BreakerStatus is a point-in-time view of a breaker, suitable for health endpoints
+type BreakerStatus struct {
+	Endpoint            string     `json:"endpoint"`
+	State               State      `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+}
+
+// Status returns the breaker's current state
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		Endpoint:            b.endpoint,
+		State:               b.state,
+		ConsecutiveFailures: b.consecutiveFailures,
+		LastError:           b.lastError,
+	}
+	if !b.lastFailure.IsZero() {
+		lastFailure := b.lastFailure
+		status.LastFailure = &lastFailure
+	}
+	if b.state == StateOpen {
+		retryAt := b.openedAt.Add(b.settings.Cooldown)
+		status.RetryAt = &retryAt
+		// Report half-open once the cooldown has passed even if no call has probed yet
+		if time.Now().After(retryAt) {
+			status.State = StateHalfOpen
+		}
+	}
+	return status
+}
+
+// Registry holds one breaker per endpoint so every client talking to the same
+// endpoint shares its health
+type Registry struct {
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewRegistry creates an empty breaker registry
+func NewRegistry() *Registry {
+	return &Registry{breakers: make(map[string]*Breaker)}
+}
+
+// DefaultRegistry is the process-wide registry used by the Dispatch clients
+// and reported by the web server's health check
+var DefaultRegistry = NewRegistry()
+
+// Breaker returns the breaker for endpoint, creating it with settings on first use
+func (r *Registry) Breaker(endpoint string, settings BreakerSettings) *Breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if breaker, ok := r.breakers[endpoint]; ok {
+		return breaker
+	}
+
+	breaker := NewBreaker(endpoint, settings)
+	r.breakers[endpoint] = breaker
+	return breaker
+}
+
+// Snapshot returns the status of every breaker, ordered by endpoint
+func (r *Registry) Snapshot() []BreakerStatus {
+	r.mu.Lock()
+	breakers := make([]*Breaker, 0, len(r.breakers))
+	for _, breaker := range r.breakers {
+		breakers = append(breakers, breaker)
+	}
+	r.mu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(breakers))
+	for _, breaker := range breakers {
+		statuses = append(statuses, breaker.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Endpoint < statuses[j].Endpoint
+	})
+	return statuses
+}
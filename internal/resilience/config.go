@@ -0,0 +1,20 @@
+package resilience
+
+import "dispatch-mcp-server/internal/config"
+
+// PolicyFromConfig builds the retry policy described by cfg
+func PolicyFromConfig(cfg *config.Config) Policy {
+	return Policy{
+		MaxAttempts: cfg.RetryMaxAttempts,
+		BaseDelay:   cfg.RetryBaseDelay,
+		MaxDelay:    cfg.RetryMaxDelay,
+	}
+}
+
+// BreakerSettingsFromConfig builds the circuit breaker settings described by cfg
+func BreakerSettingsFromConfig(cfg *config.Config) BreakerSettings {
+	return BreakerSettings{
+		FailureThreshold: cfg.BreakerFailureThreshold,
+		Cooldown:         cfg.BreakerCooldown,
+	}
+}
@@ -0,0 +1,184 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy controls how many times an operation is attempted and how long to
+// wait between attempts
+type Policy struct {
+	MaxAttempts int           // total attempts including the first; <= 1 disables retries
+	BaseDelay   time.Duration // delay before the first retry, doubled for each later retry
+	MaxDelay    time.Duration // upper bound for a single backoff delay
+
+	// RetryIf, when set, further limits retries to the transient failures it
+	// accepts; the rest are returned after the attempt that failed
+	RetryIf func(err error) bool
+}
+
+// NoRetry returns a copy of the policy that makes a single attempt
+func (p Policy) NoRetry() Policy {
+	p.MaxAttempts = 1
+	return p
+}
+
+// RetryOnly returns a copy of the policy that only retries the transient
+// failures accepted by retry
+func (p Policy) RetryOnly(retry func(err error) bool) Policy {
+	p.RetryIf = retry
+	return p
+}
+
+// Backoff returns the jittered delay before retry number n (starting at 1).
+// Half of the delay is fixed and half is random so concurrent clients spread
+// out without ever retrying immediately.
+func (p Policy) Backoff(n int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < n; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			delay = p.MaxDelay
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Retryable is implemented by errors that know whether the failed call may be
+// attempted again (e.g. 502s and timeouts, but not validation errors)
+type Retryable interface {
+	Retryable() bool
+}
+
+// RetryAfterer is implemented by errors carrying a server-provided
+// Retry-After delay
+type RetryAfterer interface {
+	RetryAfter() time.Duration
+}
+
+// IsRetryable reports whether err, or an error it wraps, is marked retryable
+func IsRetryable(err error) bool {
+	var r Retryable
+	return errors.As(err, &r) && r.Retryable()
+}
+
+// retryAfter returns the server-requested delay carried by err, if any
+func retryAfter(err error) time.Duration {
+	var r RetryAfterer
+	if errors.As(err, &r) {
+		return r.RetryAfter()
+	}
+	return 0
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, or the policy
+// runs out of attempts. Each attempt must be admitted by breaker (which may be
+// nil); a rejected attempt returns ErrCircuitOpen. Retryable failures are
+// reported to the breaker, anything else counts as the endpoint responding.
+func Do(ctx context.Context, policy Policy, breaker *Breaker, fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if !breaker.Allow() {
+			if err != nil {
+				return err
+			}
+			return ErrCircuitOpen
+		}
+
+		err = fn(ctx)
+		if err == nil {
+			breaker.RecordSuccess()
+			return nil
+		}
+
+		// A caller giving up says nothing about the endpoint's health
+		if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+			breaker.Release()
+			return err
+		}
+
+		if !IsRetryable(err) {
+			breaker.RecordSuccess()
+			return err
+		}
+		breaker.RecordFailure(err)
+
+		if attempt >= policy.MaxAttempts || (policy.RetryIf != nil && !policy.RetryIf(err)) {
+			return err
+		}
+
+		// Honour the server's Retry-After, but never wait longer than the
+		// policy allows for a single backoff
+		delay := retryAfter(err)
+		if delay <= 0 {
+			delay = policy.Backoff(attempt)
+		} else if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+
+		// Don't start a wait that will outlive the caller's deadline; the last
+		// real error is more useful than a context error
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ParseRetryAfter parses a Retry-After header given either as a number of
+// seconds or as an HTTP date. It returns 0 if the header is absent or invalid.
+func ParseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
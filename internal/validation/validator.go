@@ -20,6 +20,15 @@ type ValidationResult struct {
 	Message string            `json:"message,omitempty"`
 }
 
+// VehicleTypes lists the vehicle types the Dispatch API accepts
+var VehicleTypes = []string{"pickup_truck", "cargo_van", "sprinter_van", "box_truck"}
+
+// DeliveryScenarios lists the scenarios select_delivery_option understands
+var DeliveryScenarios = []string{"fastest", "asap", "urgent", "cheapest", "economy", "sometime_today", "deliver_by", "cheapest_within", "best_value"}
+
+// CustomerTiers lists the customer loyalty tiers
+var CustomerTiers = []string{"bronze", "silver", "gold"}
+
 // Validator provides validation functions for MCP tool inputs
 type Validator struct{}
 
@@ -30,7 +39,7 @@ func NewValidator() *Validator {
 
 // ValidateVehicleType validates the vehicle type parameter
 func (v *Validator) ValidateVehicleType(vehicleType string) *ValidationResult {
-	validTypes := []string{"pickup_truck", "cargo_van", "sprinter_van", "box_truck"}
+	validTypes := VehicleTypes
 
 	if vehicleType == "" {
 		return &ValidationResult{
@@ -65,7 +74,7 @@ func (v *Validator) ValidateVehicleType(vehicleType string) *ValidationResult {
 
 // ValidateDeliveryScenario validates the delivery scenario parameter
 func (v *Validator) ValidateDeliveryScenario(scenario string) *ValidationResult {
-	validScenarios := []string{"fastest", "asap", "urgent", "cheapest", "economy", "sometime_today"}
+	validScenarios := DeliveryScenarios
 
 	if scenario == "" {
 		return &ValidationResult{
@@ -296,7 +305,7 @@ func (v *Validator) ValidateJSONString(jsonStr, fieldName string) *ValidationRes
 
 // ValidateCustomerTier validates customer tier parameter
 func (v *Validator) ValidateCustomerTier(tier string) *ValidationResult {
-	validTiers := []string{"bronze", "silver", "gold"}
+	validTiers := CustomerTiers
 
 	if tier == "" {
 		return &ValidationResult{Valid: true} // Optional field
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/batch"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrencyProbe wraps an API and records the most estimates in flight
+type concurrencyProbe struct {
+	dispatch.API
+
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (p *concurrencyProbe) CreateEstimate(ctx context.Context, input dispatch.CreateEstimateInput) (*dispatch.CreateEstimateResponse, error) {
+	p.mu.Lock()
+	p.inFlight++
+	if p.inFlight > p.peak {
+		p.peak = p.inFlight
+	}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.inFlight--
+		p.mu.Unlock()
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	return p.API.CreateEstimate(ctx, input)
+}
+
+const shipmentsCSV = `reference,vehicle_type,pickup_business_name,pickup_street,pickup_city,pickup_state,pickup_zip_code,dropoff_street,dropoff_city,dropoff_state,dropoff_zip_code,add_ons
+A1,cargo_van,Warehouse,123 Market St,San Francisco,ca,94105,456 Oak Ave,Oakland,CA,94610,white_glove;signature_required
+A1,cargo_van,Warehouse,123 Market St,San Francisco,CA,94105,789 Pine St,Berkeley,CA,94710,
+B2,spaceship,Warehouse,123 Market St,San Francisco,CA,94105,456 Oak Ave,Oakland,CA,9461,
+`
+
+func TestParseShipmentsCSV(t *testing.T) {
+	rows, err := batch.ParseCSV(strings.NewReader(shipmentsCSV))
+	if err != nil {
+		t.Fatalf("ParseCSV failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected lines sharing a reference to form one shipment, got %d rows", len(rows))
+	}
+	first := rows[0]
+	if len(first.DropOffs) != 2 || first.DropOffs[1].Location.Address.City != "Berkeley" || len(first.AddOns) != 2 {
+		t.Errorf("Expected two drop-offs and two add-ons, got %+v", first)
+	}
+	if address := first.PickupInfo.Location.Address; address.State != "CA" || address.Country != "US" {
+		t.Errorf("Expected the state upper-cased and the country defaulted, got %+v", address)
+	}
+
+	if _, err := batch.ParseCSV(strings.NewReader("reference,weight\nA1,10\n")); err == nil || !strings.Contains(err.Error(), `"weight"`) {
+		t.Errorf("Expected an unknown column to be rejected, got %v", err)
+	}
+}
+
+func TestBatchRunBoundsConcurrency(t *testing.T) {
+	probe := &concurrencyProbe{API: dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t))}
+
+	row := batch.Row{
+		PickupInfo:  recordedEstimateInput().PickupInfo,
+		DropOffs:    recordedEstimateInput().DropOffs,
+		VehicleType: "cargo_van",
+	}
+	rows := make([]batch.Row, 12)
+	for i := range rows {
+		rows[i] = row
+	}
+	rows[5].VehicleType = "spaceship"
+	rows[7].DropOffs = nil
+
+	report := batch.Run(context.Background(), probe, rows, 3)
+	if probe.peak > 3 || probe.peak < 2 {
+		t.Errorf("Expected up to 3 estimates in flight, peaked at %d", probe.peak)
+	}
+
+	totals := report.Totals
+	if totals.Rows != 12 || totals.Succeeded != 10 || totals.Invalid != 2 || totals.Failed != 0 {
+		t.Errorf("Expected 10 successes and 2 invalid rows, got %+v", totals)
+	}
+	if report.Results[5].Status != batch.StatusInvalid || report.Results[5].Row != 6 || !strings.Contains(report.Results[5].Errors[0], "spaceship") {
+		t.Errorf("Expected row 6 to name the invalid vehicle type, got %+v", report.Results[5])
+	}
+	if !strings.Contains(strings.Join(report.Results[7].Errors, ";"), "drop_offs") {
+		t.Errorf("Expected row 8 to be missing its drop-offs, got %+v", report.Results[7])
+	}
+	first := report.Results[0]
+	if first.Status != batch.StatusOK || first.Cheapest.EstimatedOrderCost.Cmp(first.Fastest.EstimatedOrderCost) > 0 {
+		t.Errorf("Expected cheapest and fastest options, got %+v", first)
+	}
+	if totals.CheapestTotal.Cmp(first.Cheapest.EstimatedOrderCost.Mul(9.99, money.HalfUp)) < 0 || totals.FastestTotal.Cmp(totals.CheapestTotal) < 0 {
+		t.Errorf("Expected the totals to add up ten rows, got %+v", totals)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report = batch.Run(ctx, probe, rows[:2], 1)
+	if report.Totals.Failed != 2 {
+		t.Errorf("Expected a cancelled batch to fail its rows, got %+v", report.Totals)
+	}
+}
+
+func TestBatchCreateEstimatesTool(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	var batched struct {
+		Results []struct {
+			Row        int      `json:"row"`
+			Reference  string   `json:"reference"`
+			Status     string   `json:"status"`
+			EstimateID string   `json:"estimate_id"`
+			Errors     []string `json:"errors"`
+		} `json:"results"`
+		Totals batch.Totals `json:"totals"`
+	}
+	output := callTool(t, mcpClient, "batch_create_estimates", map[string]interface{}{
+		"csv":         shipmentsCSV,
+		"concurrency": 2,
+	})
+	if err := json.Unmarshal([]byte(output), &batched); err != nil {
+		t.Fatalf("Failed to parse batch: %v", err)
+	}
+	if len(batched.Results) != 2 || batched.Totals.Succeeded != 1 || batched.Totals.Invalid != 1 {
+		t.Fatalf("Expected one quoted and one invalid shipment, got %s", output)
+	}
+	if invalid := batched.Results[1]; invalid.Reference != "B2" || invalid.EstimateID != "" || len(invalid.Errors) != 2 {
+		t.Errorf("Expected B2 to report its vehicle type and zip code, got %+v", invalid)
+	}
+
+	// Each quoted row is saved like a single estimate
+	quoted := batched.Results[0]
+	callTool(t, mcpClient, "select_delivery_option", map[string]interface{}{
+		"estimate_id":       quoted.EstimateID,
+		"delivery_scenario": "cheapest",
+	})
+
+	output = callTool(t, mcpClient, "batch_create_estimates", map[string]interface{}{
+		"shipments": []interface{}{estimateArgs(), estimateArgs()},
+	})
+	if err := json.Unmarshal([]byte(output), &batched); err != nil || batched.Totals.Succeeded != 2 {
+		t.Errorf("Expected both shipments to be quoted, got %s", output)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{},
+		{"csv": shipmentsCSV, "shipments": []interface{}{estimateArgs()}},
+		{"shipments": []interface{}{estimateArgs()}, "concurrency": 50},
+	} {
+		callToolError(t, mcpClient, "batch_create_estimates", args)
+	}
+}
@@ -0,0 +1,173 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/cassette"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useRecordedTraffic points the Dispatch and AI Hub clients at the cassettes
+// in testdata/cassettes so tests run offline. Set DISPATCH_CASSETTE_MODE=record
+// (with real credentials) to re-record them.
+func useRecordedTraffic(t *testing.T) {
+	t.Helper()
+
+	if os.Getenv("DISPATCH_CASSETTE_MODE") != string(cassette.ModeRecord) {
+		t.Setenv("DISPATCH_CASSETTE_MODE", string(cassette.ModeReplay))
+	}
+	t.Setenv("DISPATCH_CASSETTE_DIR", "testdata/cassettes")
+	t.Setenv("USE_AI_HUB", "true")
+}
+
+func recordedEstimateInput() dispatch.CreateEstimateInput {
+	return dispatch.CreateEstimateInput{
+		PickupInfo: dispatch.PickupInfoInput{
+			BusinessName: "Demo Business",
+			Location: dispatch.LocationInput{
+				Address: &dispatch.AddressInput{Street: "123 Market St", City: "San Francisco", State: "CA", ZipCode: "94105", Country: "US"},
+			},
+		},
+		DropOffs: []dispatch.DropOffInfoInput{
+			{
+				BusinessName: "Customer Location",
+				Location: dispatch.LocationInput{
+					Address: &dispatch.AddressInput{Street: "456 Oak Ave", City: "Oakland", State: "CA", ZipCode: "94610", Country: "US"},
+				},
+			},
+		},
+		VehicleType: "cargo_van",
+	}
+}
+
+func recordedOrderInput() dispatch.CreateOrderInput {
+	return dispatch.CreateOrderInput{
+		DeliveryInfo: dispatch.DeliveryInfoInput{ServiceType: "standard"},
+		PickupInfo: dispatch.CreateOrderPickupInfoInput{
+			BusinessName:       stringPtr("Demo Business"),
+			ContactName:        stringPtr("Jordan Lee"),
+			ContactPhoneNumber: stringPtr("415-555-0134"),
+			Location: &dispatch.LocationInput{
+				Address: &dispatch.AddressInput{Street: "123 Market St", City: "San Francisco", State: "CA", ZipCode: "94105", Country: "US"},
+			},
+		},
+		DropOffs: []dispatch.CreateOrderDropOffInfoInput{
+			{
+				BusinessName:       stringPtr("Customer Location"),
+				ContactName:        stringPtr("Sam Rivera"),
+				ContactPhoneNumber: stringPtr("510-555-0199"),
+				Location: &dispatch.LocationInput{
+					Address: &dispatch.AddressInput{Street: "456 Oak Ave", City: "Oakland", State: "CA", ZipCode: "94610", Country: "US"},
+				},
+			},
+		},
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestReplayDispatchCassette(t *testing.T) {
+	useRecordedTraffic(t)
+
+	cfg, _ := config.Load()
+	api, err := dispatch.NewAPIWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewAPIWithConfig failed: %v", err)
+	}
+	if _, ok := api.(*dispatch.Client); !ok {
+		t.Fatalf("Expected replay to use *dispatch.Client, got %T", api)
+	}
+
+	estimate, err := api.CreateEstimate(context.Background(), recordedEstimateInput())
+	if err != nil {
+		t.Fatalf("CreateEstimate replay failed: %v", err)
+	}
+	options := estimate.Data.CreateEstimate.Estimate.AvailableOrderOptions
+	if len(options) != 3 {
+		t.Fatalf("Expected 3 recorded delivery options, got %d", len(options))
+	}
+	if options[0].EstimatedOrderCost.Cmp(options[len(options)-1].EstimatedOrderCost) <= 0 {
+		t.Errorf("Expected fastest option to cost more than cheapest, got %s and %s",
+			options[0].EstimatedOrderCost, options[len(options)-1].EstimatedOrderCost)
+	}
+
+	// Recorded PII was scrubbed, but the scrubbed request still matches
+	order, err := api.CreateOrder(context.Background(), recordedOrderInput())
+	if err != nil {
+		t.Fatalf("CreateOrder replay failed: %v", err)
+	}
+	if order.Data.CreateOrder.Order.ID == "" {
+		t.Error("Expected recorded order ID")
+	}
+
+	unrecorded := recordedEstimateInput()
+	unrecorded.VehicleType = "box_truck"
+	if _, err := api.CreateEstimate(context.Background(), unrecorded); err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("Expected unrecorded request to fail, got %v", err)
+	}
+}
+
+func TestRecordScrubsSecretsAndPII(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc123")
+		w.Write([]byte(`{"data":{"createOrder":{"order":{"id":"ORD-1","status":"scheduled"}}}}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		GraphQLEndpoint: server.URL,
+		AuthToken:       "super-secret-token",
+		CassetteMode:    string(cassette.ModeRecord),
+		CassetteDir:     dir,
+	}
+	client, err := dispatch.NewClientWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewClientWithConfig failed: %v", err)
+	}
+	if _, err := client.CreateOrder(context.Background(), recordedOrderInput()); err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "dispatch.json"))
+	if err != nil {
+		t.Fatalf("Expected cassette to be written: %v", err)
+	}
+	for _, secret := range []string{"super-secret-token", "Jordan Lee", "415-555-0134", "abc123"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q to be scrubbed from cassette", secret)
+		}
+	}
+
+	// Replay the new cassette with the server gone
+	server.Close()
+	cfg.CassetteMode = string(cassette.ModeReplay)
+	replayClient, err := dispatch.NewClientWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewClientWithConfig failed: %v", err)
+	}
+	response, err := replayClient.CreateOrder(context.Background(), recordedOrderInput())
+	if err != nil {
+		t.Fatalf("Replaying recorded order failed: %v", err)
+	}
+	if response.Data.CreateOrder.Order.ID != "ORD-1" {
+		t.Errorf("Expected replayed order ORD-1, got %s", response.Data.CreateOrder.Order.ID)
+	}
+}
+
+func TestScrubBodyMasksFreeText(t *testing.T) {
+	scrubbed := string(cassette.ScrubBody([]byte(`{"messages":[{"role":"user","content":"Call me at 415-555-0134 or jordan@example.com"}],"contactPhone":"4155550134"}`)))
+	for _, secret := range []string{"415-555-0134", "jordan@example.com", "4155550134"} {
+		if strings.Contains(scrubbed, secret) {
+			t.Errorf("Expected %q to be scrubbed, got %s", secret, scrubbed)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package test
 
 import (
+	"context"
+	"dispatch-mcp-server/internal/config"
 	"dispatch-mcp-server/internal/conversation"
+	"dispatch-mcp-server/internal/dispatch"
 	"fmt"
 	"strings"
 	"testing"
@@ -269,3 +272,153 @@ func TestPricingScenarios(t *testing.T) {
 func contains(s, substr string) bool {
 	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
 }
+
+// reviewContext returns a conversation that has collected a complete order and
+// is waiting for the customer to confirm it
+func reviewContext(input dispatch.CreateOrderInput) *conversation.ConversationContext {
+	return &conversation.ConversationContext{
+		SessionID:       "review_session",
+		CustomerProfile: conversation.CustomerProfile{Tier: "silver"},
+		OrderCreation: conversation.OrderCreationState{
+			InProgress:     true,
+			Step:           "review",
+			PickupInfo:     &input.PickupInfo,
+			DropOffs:       input.DropOffs,
+			VehicleType:    &conversation.VehicleTypeInfo{VehicleTypeID: "cargo_van", VehicleTypeName: "cargo van"},
+			Capabilities:   []string{"signature_required"},
+			SchedulingInfo: &conversation.SchedulingInfo{PickupTime: "10am"},
+		},
+	}
+}
+
+func TestReviewStepSubmitsOrder(t *testing.T) {
+	t.Setenv("USE_AI_HUB", "false")
+	engine, err := conversation.NewClaudeConversationEngine()
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	engine.SetDispatchClient(dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t)))
+
+	context := reviewContext(recordedOrderInput())
+
+	// Anything but an explicit confirmation shows the summary again
+	for _, message := range []string{"what does it look like?", "No, don't create it yet", "yesterday", "not yet", "cancel the order", "yes but change the pickup time"} {
+		response, err := engine.ProcessMessage(message, context)
+		if err != nil {
+			t.Fatalf("ProcessMessage failed: %v", err)
+		}
+		if !strings.Contains(response.Message, "Order Summary") || context.SubmittedOrder != nil {
+			t.Errorf("%q: Expected the summary without submitting, got %q", message, response.Message)
+		}
+	}
+
+	response, err := engine.ProcessMessage("Yes, create it", context)
+	if err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+	submitted := response.UpdatedContext.SubmittedOrder
+	if submitted == nil || !strings.HasPrefix(submitted.OrderID, "ORD-") || !strings.HasPrefix(submitted.TrackingNumber, "TRK-") {
+		t.Fatalf("Expected the created order in the context, got %+v", submitted)
+	}
+	if !strings.Contains(response.Message, submitted.OrderID) || !strings.Contains(response.Message, submitted.TrackingNumber) || strings.Contains(response.Message, "email") {
+		t.Errorf("Expected the reply to report the order, got %q", response.Message)
+	}
+	if context.OrderCreation.InProgress {
+		t.Error("Expected order creation to finish after submission")
+	}
+}
+
+// submitRecorder records the orders submitted through the wrapped dispatch.API
+type submitRecorder struct {
+	dispatch.API
+	submitted []*dispatch.OrderCreationInput
+}
+
+func (r *submitRecorder) SubmitOrder(ctx context.Context, input *dispatch.OrderCreationInput) (*dispatch.OrderSubmission, error) {
+	r.submitted = append(r.submitted, input)
+	return r.API.SubmitOrder(ctx, input)
+}
+
+func TestReviewStepSubmitsEveryCollectedField(t *testing.T) {
+	t.Setenv("USE_AI_HUB", "false")
+	engine, err := conversation.NewClaudeConversationEngine()
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	recorder := &submitRecorder{API: dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t))}
+	engine.SetDispatchClient(recorder)
+
+	first := reviewContext(recordedOrderInput())
+	first.OrderCreation.VehicleType = &conversation.VehicleTypeInfo{VehicleTypeID: "box_truck", VehicleTypeName: "box truck"}
+	if _, err := engine.ProcessMessage("Yes, create it", first); err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+	if len(recorder.submitted) != 1 || first.SubmittedOrder == nil {
+		t.Fatalf("Expected one submitted order, got %d", len(recorder.submitted))
+	}
+	input := recorder.submitted[0]
+	if input.VehicleTypeID != "box_truck" || len(input.Capabilities) != 1 || input.Capabilities[0] != "signature_required" ||
+		input.Scheduling == nil || input.Scheduling.PickupTime != "10am" {
+		t.Errorf("Expected the vehicle, capabilities and schedule to be submitted, got %+v", input)
+	}
+
+	// Another conversation placing the same order gets its own
+	second := reviewContext(recordedOrderInput())
+	second.SessionID = "other_session"
+	second.OrderCreation.VehicleType = first.OrderCreation.VehicleType
+	if _, err := engine.ProcessMessage("Yes, create it", second); err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+	if second.SubmittedOrder == nil || second.SubmittedOrder.OrderID == first.SubmittedOrder.OrderID || len(recorder.submitted) != 2 {
+		t.Errorf("Expected a separate order for the second conversation, got %+v", second.SubmittedOrder)
+	}
+}
+
+func TestReviewStepReportsFieldErrors(t *testing.T) {
+	t.Setenv("USE_AI_HUB", "false")
+	engine, err := conversation.NewClaudeConversationEngine()
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	engine.SetDispatchClient(dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t)))
+
+	tests := []struct {
+		name      string
+		context   *conversation.ConversationContext
+		wantField string
+	}{
+		{
+			name: "rejected by Dispatch",
+			context: func() *conversation.ConversationContext {
+				input := recordedOrderInput()
+				input.DropOffs[0].Location.Address.ZipCode = "10001"
+				return reviewContext(input)
+			}(),
+			wantField: "dropOffs.0.address.zipCode",
+		},
+		{
+			name: "incomplete order",
+			context: func() *conversation.ConversationContext {
+				context := reviewContext(recordedOrderInput())
+				context.OrderCreation.DropOffs = nil
+				return context
+			}(),
+			wantField: "dropOffs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := engine.ProcessMessage("confirm", tt.context)
+			if err != nil {
+				t.Fatalf("ProcessMessage failed: %v", err)
+			}
+			if !strings.Contains(response.Message, tt.wantField) {
+				t.Errorf("Expected the reply to name %s, got %q", tt.wantField, response.Message)
+			}
+			if tt.context.SubmittedOrder != nil || tt.context.OrderCreation.Step != "review" {
+				t.Errorf("Expected the order to stay in review, got step %q", tt.context.OrderCreation.Step)
+			}
+		})
+	}
+}
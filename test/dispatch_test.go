@@ -0,0 +1,200 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/mcp"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeDispatchAPI is a minimal dispatch.API that tests can inject in place of the real client
+type fakeDispatchAPI struct{}
+
+func (f *fakeDispatchAPI) CreateEstimate(ctx context.Context, input dispatch.CreateEstimateInput) (*dispatch.CreateEstimateResponse, error) {
+	return &dispatch.CreateEstimateResponse{}, nil
+}
+
+func (f *fakeDispatchAPI) CreateOrder(ctx context.Context, input dispatch.CreateOrderInput) (*dispatch.CreateOrderResponse, error) {
+	return &dispatch.CreateOrderResponse{}, nil
+}
+
+func (f *fakeDispatchAPI) GetOrder(ctx context.Context, orderID string) (*dispatch.GetOrderResponse, error) {
+	return &dispatch.GetOrderResponse{}, nil
+}
+
+func (f *fakeDispatchAPI) ListOrders(ctx context.Context, input dispatch.ListOrdersInput) (*dispatch.ListOrdersResponse, error) {
+	return &dispatch.ListOrdersResponse{}, nil
+}
+
+func (f *fakeDispatchAPI) CancelOrder(ctx context.Context, input dispatch.CancelOrderInput) (*dispatch.CancelOrderResponse, error) {
+	return &dispatch.CancelOrderResponse{}, nil
+}
+
+func (f *fakeDispatchAPI) UpdateOrder(ctx context.Context, input dispatch.UpdateOrderInput) (*dispatch.UpdateOrderResponse, error) {
+	return &dispatch.UpdateOrderResponse{}, nil
+}
+
+func (f *fakeDispatchAPI) ValidateOrder(ctx context.Context, input *dispatch.OrderCreationInput) (*dispatch.OrderValidation, error) {
+	return &dispatch.OrderValidation{Valid: true}, nil
+}
+
+func (f *fakeDispatchAPI) SubmitOrder(ctx context.Context, input *dispatch.OrderCreationInput) (*dispatch.OrderSubmission, error) {
+	return &dispatch.OrderSubmission{}, nil
+}
+
+func TestNewAPISelectsImplementation(t *testing.T) {
+	t.Run("mock_without_credentials", func(t *testing.T) {
+		api, err := dispatch.NewAPIWithConfig(&config.Config{GraphQLEndpoint: "http://localhost"})
+		if err != nil {
+			t.Fatalf("NewAPIWithConfig failed: %v", err)
+		}
+		if _, ok := api.(*dispatch.MockClient); !ok {
+			t.Errorf("Expected *dispatch.MockClient, got %T", api)
+		}
+	})
+
+	t.Run("graphql_with_token", func(t *testing.T) {
+		api, err := dispatch.NewAPIWithConfig(&config.Config{GraphQLEndpoint: "http://localhost", AuthToken: "token"})
+		if err != nil {
+			t.Fatalf("NewAPIWithConfig failed: %v", err)
+		}
+		if _, ok := api.(*dispatch.Client); !ok {
+			t.Errorf("Expected *dispatch.Client, got %T", api)
+		}
+	})
+}
+
+func TestMockClientEstimate(t *testing.T) {
+	mock, err := dispatch.NewMockClientWithConfig(&config.Config{})
+	if err != nil {
+		t.Fatalf("NewMockClientWithConfig failed: %v", err)
+	}
+	var api dispatch.API = mock
+
+	response, err := api.CreateEstimate(context.Background(), recordedEstimateInput())
+	if err != nil {
+		t.Fatalf("CreateEstimate failed: %v", err)
+	}
+
+	options := response.Data.CreateEstimate.Estimate.AvailableOrderOptions
+	if len(options) == 0 {
+		t.Fatal("Expected at least one delivery option")
+	}
+	if options[0].VehicleType != "cargo_van" {
+		t.Errorf("Expected vehicle type cargo_van, got %s", options[0].VehicleType)
+	}
+}
+
+func TestMCPServerAcceptsInjectedAPI(t *testing.T) {
+	server, err := mcp.NewMCPServerWithClient(&fakeDispatchAPI{})
+	if err != nil {
+		t.Fatalf("NewMCPServerWithClient failed: %v", err)
+	}
+	if server == nil {
+		t.Fatal("Expected server, got nil")
+	}
+}
+
+func TestClientSurfacesAPIErrors(t *testing.T) {
+	testCases := []struct {
+		name         string
+		status       int
+		body         string
+		expectedKind dispatch.ErrorKind
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"message":"invalid token"}`, dispatch.ErrorKindAuth},
+		{"rate_limited", http.StatusTooManyRequests, ``, dispatch.ErrorKindRateLimit},
+		{"server_error", http.StatusBadGateway, `bad gateway`, dispatch.ErrorKindServer},
+		{"graphql_validation", http.StatusOK, `{"data":null,"errors":[{"message":"Invalid zip code","path":["createEstimate","input","pickup_info"]}]}`, dispatch.ErrorKindValidation},
+		{"graphql_unauthenticated", http.StatusOK, `{"errors":[{"message":"not logged in","extensions":{"code":"UNAUTHENTICATED"}}]}`, dispatch.ErrorKindAuth},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			client, err := dispatch.NewClientWithConfig(&config.Config{GraphQLEndpoint: server.URL, AuthToken: "token"})
+			if err != nil {
+				t.Fatalf("NewClientWithConfig failed: %v", err)
+			}
+
+			_, err = client.CreateEstimate(context.Background(), dispatch.CreateEstimateInput{VehicleType: "cargo_van"})
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			var apiErr *dispatch.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected *dispatch.APIError, got %T: %v", err, err)
+			}
+			if apiErr.Kind != tc.expectedKind {
+				t.Errorf("Expected kind %s, got %s (%v)", tc.expectedKind, apiErr.Kind, err)
+			}
+			if apiErr.StatusCode != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, apiErr.StatusCode)
+			}
+		})
+	}
+
+	t.Run("graphql_error_path", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"errors":[{"message":"Invalid zip code","path":["createEstimate","input","pickup_info"]}]}`))
+		}))
+		defer server.Close()
+
+		client, _ := dispatch.NewClientWithConfig(&config.Config{GraphQLEndpoint: server.URL, AuthToken: "token"})
+		_, err := client.CreateEstimate(context.Background(), dispatch.CreateEstimateInput{VehicleType: "cargo_van"})
+
+		var apiErr *dispatch.APIError
+		if !errors.As(err, &apiErr) || len(apiErr.GraphQLErrors) != 1 {
+			t.Fatalf("Expected one GraphQL error, got %v", err)
+		}
+		if path := apiErr.GraphQLErrors[0].PathString(); path != "createEstimate.input.pickup_info" {
+			t.Errorf("Expected error path createEstimate.input.pickup_info, got %s", path)
+		}
+	})
+}
+
+func TestClientHonoursContextCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := dispatch.NewClientWithConfig(&config.Config{GraphQLEndpoint: server.URL, AuthToken: "token"})
+	if err != nil {
+		t.Fatalf("NewClientWithConfig failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = client.CreateEstimate(ctx, dispatch.CreateEstimateInput{VehicleType: "cargo_van"})
+	if !dispatch.IsKind(err, dispatch.ErrorKindTransport) {
+		t.Fatalf("Expected transport error, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded in error chain, got %v", err)
+	}
+
+	mockCtx, mockCancel := context.WithCancel(context.Background())
+	mockCancel()
+	mock, err := dispatch.NewMockClientWithConfig(&config.Config{})
+	if err != nil {
+		t.Fatalf("NewMockClientWithConfig failed: %v", err)
+	}
+	if _, err := mock.CreateEstimate(mockCtx, dispatch.CreateEstimateInput{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected mock client to return context.Canceled, got %v", err)
+	}
+}
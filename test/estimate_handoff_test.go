@@ -0,0 +1,220 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/estimate"
+	"dispatch-mcp-server/internal/money"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// callToolError calls name and returns the error text it reports, failing
+// the test if the call succeeds
+func callToolError(t *testing.T, mcpClient *client.Client, name string, args map[string]interface{}) string {
+	t.Helper()
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	result, err := mcpClient.CallTool(context.Background(), request)
+	if err != nil {
+		t.Fatalf("%s failed: %v", name, err)
+	}
+	text := ""
+	if len(result.Content) > 0 {
+		if content, ok := mcp.AsTextContent(result.Content[0]); ok {
+			text = content.Text
+		}
+	}
+	if !result.IsError {
+		t.Fatalf("Expected %s to fail, got %s", name, text)
+	}
+	return text
+}
+
+func TestEstimateRecordOrderInput(t *testing.T) {
+	store := estimate.NewStore(time.Hour)
+	response := &dispatch.CreateEstimateResponse{}
+	response.Data.CreateEstimate.Estimate.AvailableOrderOptions = []dispatch.AvailableOrderOption{
+		{ServiceType: "rush", EstimatedOrderCost: money.Dollars(60)},
+		{ServiceType: "standard", EstimatedOrderCost: money.Dollars(40)},
+	}
+	record, err := store.Save("session-a", recordedEstimateInput(), response)
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if _, err := store.Lookup("session-b", record.ID); !errors.Is(err, estimate.ErrNotFound) {
+		t.Errorf("Expected another owner's lookup to fail with ErrNotFound, got %v", err)
+	}
+
+	// Contact details are kept; everything else comes from the estimate
+	input, err := record.OrderInput(1, dispatch.CreateOrderInput{
+		PickupInfo: dispatch.CreateOrderPickupInfoInput{ContactName: stringPtr("Jordan Lee")},
+	})
+	if err != nil {
+		t.Fatalf("OrderInput failed: %v", err)
+	}
+	if input.DeliveryInfo.ServiceType != "standard" || *input.PickupInfo.ContactName != "Jordan Lee" || *input.PickupInfo.BusinessName != "Demo Business" {
+		t.Errorf("Expected the standard option with the estimate's pickup, got %+v", input)
+	}
+	if len(input.DropOffs) != 1 || input.DropOffs[0].Location.Address.ZipCode != "94610" {
+		t.Errorf("Expected the estimate's drop-off, got %+v", input.DropOffs)
+	}
+
+	// Matching stops given in full are accepted, in any letter case
+	order := recordedOrderInput()
+	order.DropOffs[0].Location.Address.City = "OAKLAND"
+	order.DeliveryInfo.ServiceType = ""
+	if _, err := record.OrderInput(1, order); err != nil {
+		t.Errorf("Expected matching stops to be accepted, got %v", err)
+	}
+
+	// A different drop-off or service type is drift
+	order = recordedOrderInput()
+	order.DropOffs[0].Location.Address.ZipCode = "94612"
+	_, err = record.OrderInput(0, order)
+	var drift *estimate.DriftError
+	if !errors.As(err, &drift) || len(drift.Fields) != 2 || drift.Fields[0] != "delivery_info.service_type" || drift.Fields[1] != "drop_offs[0].location" {
+		t.Errorf("Expected service type and drop-off drift, got %v", err)
+	}
+
+	if _, err := record.OrderInput(2, dispatch.CreateOrderInput{}); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("Expected an out of range option_index to fail, got %v", err)
+	}
+
+	expiring := estimate.NewStore(time.Millisecond)
+	record, _ = expiring.Save("session-a", recordedEstimateInput(), response)
+	time.Sleep(5 * time.Millisecond)
+	if _, err := expiring.Lookup("session-a", record.ID); !errors.Is(err, estimate.ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+}
+
+func TestCreateOrderFromEstimateID(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	var estimated struct {
+		EstimateID string `json:"estimate_id"`
+	}
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "create_estimate", estimateArgs())), &estimated); err != nil {
+		t.Fatalf("Failed to parse estimate: %v", err)
+	}
+
+	var selected struct {
+		EstimateID     string                        `json:"estimate_id"`
+		OptionIndex    int                           `json:"option_index"`
+		SelectedOption dispatch.AvailableOrderOption `json:"selected_option"`
+	}
+	output := callTool(t, mcpClient, "select_delivery_option", map[string]interface{}{
+		"estimate_id":       estimated.EstimateID,
+		"delivery_scenario": "cheapest",
+	})
+	if err := json.Unmarshal([]byte(output), &selected); err != nil {
+		t.Fatalf("Failed to parse selection: %v", err)
+	}
+	if selected.EstimateID != estimated.EstimateID || selected.OptionIndex != 2 || selected.SelectedOption.ServiceType != "end_of_day" {
+		t.Fatalf("Expected the cheapest option of the saved estimate, got %s", output)
+	}
+
+	var created struct {
+		dispatch.CreateOrderResponse
+		EstimateID  string `json:"estimate_id"`
+		OptionIndex *int   `json:"option_index"`
+	}
+	output = callTool(t, mcpClient, "create_order", map[string]interface{}{
+		"estimate_id":     selected.EstimateID,
+		"option_index":    selected.OptionIndex,
+		"pickup_info":     map[string]interface{}{"contact_name": "Jordan Lee", "contact_phone_number": "415-555-0134"},
+		"idempotency_key": t.Name() + time.Now().String(),
+	})
+	if err := json.Unmarshal([]byte(output), &created); err != nil {
+		t.Fatalf("Failed to parse order: %v", err)
+	}
+	if created.EstimateID != estimated.EstimateID || created.OptionIndex == nil || *created.OptionIndex != 2 {
+		t.Errorf("Expected the order to reference the estimate, got %s", output)
+	}
+
+	var fetched dispatch.GetOrderResponse
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "get_order", map[string]interface{}{"order_id": created.Data.CreateOrder.Order.ID})), &fetched); err != nil {
+		t.Fatalf("Failed to parse get_order: %v", err)
+	}
+	order := fetched.Data.Order
+	if order.ServiceType != "end_of_day" || order.Pickup.ContactName != "Jordan Lee" || order.Pickup.BusinessName != "Warehouse" || order.DropOffs[0].Address.City != "Oakland" {
+		t.Errorf("Expected the order to be built from the estimate, got %+v", order)
+	}
+
+	// Changing a drop-off after quoting is rejected
+	dropOffs := estimateArgs()["drop_offs"].([]interface{})
+	dropOffs[0].(map[string]interface{})["location"] = map[string]interface{}{
+		"address": map[string]interface{}{"street": "1 Broadway", "city": "Oakland", "state": "CA", "zip_code": "94607", "country": "US"},
+	}
+	text := callToolError(t, mcpClient, "create_order", map[string]interface{}{
+		"estimate_id":  estimated.EstimateID,
+		"option_index": 0,
+		"drop_offs":    dropOffs,
+	})
+	if !strings.Contains(text, "no longer matches") || !strings.Contains(text, "drop_offs[0].location") {
+		t.Errorf("Expected a drift error naming the drop-off, got %s", text)
+	}
+
+	text = callToolError(t, mcpClient, "create_order", map[string]interface{}{"estimate_id": estimated.EstimateID})
+	if !strings.Contains(text, "option_index is required") {
+		t.Errorf("Expected option_index to be required, got %s", text)
+	}
+	text = callToolError(t, mcpClient, "create_order", map[string]interface{}{"estimate_id": "est_unknown", "option_index": 0})
+	if !strings.Contains(text, estimate.ErrNotFound.Error()) {
+		t.Errorf("Expected an unknown estimate to be rejected, got %s", text)
+	}
+}
+
+func TestCreateOrderRejectsExpiredEstimate(t *testing.T) {
+	t.Setenv("DISPATCH_ESTIMATE_TTL", "50ms")
+	mcpClient := startMCPClient(t)
+
+	var estimated struct {
+		EstimateID string `json:"estimate_id"`
+	}
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "create_estimate", estimateArgs())), &estimated); err != nil {
+		t.Fatalf("Failed to parse estimate: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	text := callToolError(t, mcpClient, "create_order", map[string]interface{}{"estimate_id": estimated.EstimateID, "option_index": 0})
+	if !strings.Contains(text, estimate.ErrExpired.Error()) || !strings.Contains(text, "call create_estimate again") {
+		t.Errorf("Expected the expired estimate to be rejected, got %s", text)
+	}
+}
+
+func TestCreateOrderRejectsEstimateForOtherVehicle(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	args := estimateArgs()
+	args["vehicle_type"] = "box_truck"
+	var estimated struct {
+		EstimateID string `json:"estimate_id"`
+	}
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "create_estimate", args)), &estimated); err != nil {
+		t.Fatalf("Failed to parse estimate: %v", err)
+	}
+
+	// The order would be booked as a cargo van, so neither a booking nor a
+	// preview is attempted
+	for _, dryRun := range []string{"false", "true"} {
+		text := callToolError(t, mcpClient, "create_order", map[string]interface{}{
+			"estimate_id":  estimated.EstimateID,
+			"option_index": 0,
+			"dry_run":      dryRun,
+		})
+		if !strings.Contains(text, "is for a box_truck") || !strings.Contains(text, "vehicle_type cargo_van") {
+			t.Errorf("Expected the box_truck estimate to be rejected (dry_run=%s), got %s", dryRun, text)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/fakegraph"
+	"dispatch-mcp-server/internal/graphql"
+	"dispatch-mcp-server/internal/order"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// startFakeGraph serves the fake Dispatch gateway over HTTP with instant
+// fixtures and returns a dispatch.Client pointed at it
+func startFakeGraph(t *testing.T) (*fakegraph.Server, *httptest.Server, *dispatch.Client) {
+	t.Helper()
+
+	fake := fakegraph.NewServerWithClient(dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t)))
+	fake.SetAuthToken("ci-token")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client, err := dispatch.NewClientWithConfig(&config.Config{GraphQLEndpoint: server.URL + "/graphql", AuthToken: "ci-token"})
+	if err != nil {
+		t.Fatalf("NewClientWithConfig failed: %v", err)
+	}
+	return fake, server, client
+}
+
+// decodeData re-encodes a generic GraphQL data payload into out
+func decodeData(t *testing.T, data interface{}, out interface{}) {
+	t.Helper()
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("Failed to encode data: %v", err)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+}
+
+func TestFakeGraphEndToEnd(t *testing.T) {
+	fake, server, client := startFakeGraph(t)
+	ctx := context.Background()
+
+	estimate, err := client.CreateEstimate(ctx, recordedEstimateInput())
+	if err != nil {
+		t.Fatalf("CreateEstimate failed: %v", err)
+	}
+	if got := len(estimate.Data.CreateEstimate.Estimate.AvailableOrderOptions); got != 3 {
+		t.Fatalf("Expected 3 delivery options, got %d", got)
+	}
+
+	input := recordedOrderInput()
+	input.IdempotencyKey = "e2e-order-1"
+	first, err := client.CreateOrder(ctx, input)
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	second, err := client.CreateOrder(ctx, input)
+	if err != nil {
+		t.Fatalf("Repeated CreateOrder failed: %v", err)
+	}
+	if first.Data.CreateOrder.Order.ID == "" || first.Data.CreateOrder.Order.ID != second.Data.CreateOrder.Order.ID {
+		t.Errorf("Expected the idempotency key to return the same order, got %q and %q",
+			first.Data.CreateOrder.Order.ID, second.Data.CreateOrder.Order.ID)
+	}
+	if got := len(fake.Store().List()); got != 1 {
+		t.Errorf("Expected 1 stored order, got %d", got)
+	}
+
+	// The order and catalog queries used by order.OrderCreator
+	gql, err := graphql.NewGraphQLClient(server.URL + "/graphql")
+	if err != nil {
+		t.Fatalf("NewGraphQLClient failed: %v", err)
+	}
+	gql.SetHeader("Authorization", "Bearer ci-token")
+
+	response, err := gql.Execute(ctx, `query GetOrder($id: ID!) { order(id: $id) { id status totalCost } }`,
+		map[string]interface{}{"id": first.Data.CreateOrder.Order.ID})
+	if err != nil {
+		t.Fatalf("order query failed: %v", err)
+	}
+	var fetched struct {
+		Order fakegraph.Order `json:"order"`
+	}
+	decodeData(t, response.Data, &fetched)
+	if fetched.Order.TotalCost != first.Data.CreateOrder.Order.TotalCost {
+		t.Errorf("Expected stored total %s, got %s", first.Data.CreateOrder.Order.TotalCost, fetched.Order.TotalCost)
+	}
+
+	response, err = gql.Execute(ctx, order.GetVehicleTypesQuery, nil)
+	if err != nil {
+		t.Fatalf("vehicleTypes query failed: %v", err)
+	}
+	var vehicles struct {
+		VehicleTypes []fakegraph.VehicleType `json:"vehicleTypes"`
+	}
+	decodeData(t, response.Data, &vehicles)
+	if len(vehicles.VehicleTypes) != 5 || vehicles.VehicleTypes[0].ID != "car" {
+		t.Errorf("Expected 5 vehicle types starting with car, got %+v", vehicles.VehicleTypes)
+	}
+
+	if _, err := gql.Execute(ctx, order.GetCapabilitiesQuery, nil); err != nil {
+		t.Errorf("capabilities query failed: %v", err)
+	}
+
+	response, err = gql.Execute(ctx, order.ValidateOrderMutation, map[string]interface{}{"input": order.OrderCreationInput{
+		PickupInfo:    &order.PickupInfoInput{BusinessName: "Warehouse", Address: &order.AddressInput{ZipCode: "94105"}},
+		DropOffs:      []order.DropOffInfoInput{{BusinessName: "Customer", Address: &order.AddressInput{ZipCode: "10001"}}},
+		VehicleTypeID: "cargo_van",
+	}})
+	if err != nil {
+		t.Fatalf("validateOrder failed: %v", err)
+	}
+	var validation struct {
+		ValidateOrder struct {
+			Valid  bool `json:"valid"`
+			Errors []struct {
+				Field string `json:"field"`
+			} `json:"errors"`
+		} `json:"validateOrder"`
+	}
+	decodeData(t, response.Data, &validation)
+	if validation.ValidateOrder.Valid || len(validation.ValidateOrder.Errors) != 1 || validation.ValidateOrder.Errors[0].Field != "dropOffs.0.address.zipCode" {
+		t.Errorf("Expected out-of-area drop-off to fail validation, got %+v", validation.ValidateOrder)
+	}
+}
+
+func TestFakeGraphErrors(t *testing.T) {
+	_, server, client := startFakeGraph(t)
+	ctx := context.Background()
+
+	outOfArea := recordedEstimateInput()
+	outOfArea.DropOffs[0].Location.Address.ZipCode = "10001"
+	if _, err := client.CreateEstimate(ctx, outOfArea); !dispatch.IsKind(err, dispatch.ErrorKindValidation) {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+
+	rateLimited := recordedEstimateInput()
+	rateLimited.PickupInfo.Location.Address.ZipCode = "94197"
+	_, err := client.CreateEstimate(ctx, rateLimited)
+	apiErr, ok := err.(*dispatch.APIError)
+	if !ok || apiErr.Kind != dispatch.ErrorKindRateLimit || apiErr.RetryAfter() != 2*time.Second {
+		t.Errorf("Expected rate limit error with 2s Retry-After, got %v", err)
+	}
+
+	unreachable := recordedEstimateInput()
+	unreachable.PickupInfo.Location.Address.ZipCode = "94196"
+	if _, err := client.CreateEstimate(ctx, unreachable); !dispatch.IsKind(err, dispatch.ErrorKindTransport) {
+		t.Errorf("Expected transport error from dropped connection, got %v", err)
+	}
+
+	wrongToken, _ := dispatch.NewClientWithConfig(&config.Config{GraphQLEndpoint: server.URL + "/graphql", AuthToken: "wrong"})
+	if _, err := wrongToken.CreateEstimate(ctx, recordedEstimateInput()); !dispatch.IsKind(err, dispatch.ErrorKindAuth) {
+		t.Errorf("Expected auth error, got %v", err)
+	}
+}
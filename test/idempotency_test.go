@@ -0,0 +1,195 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/idempotency"
+	"dispatch-mcp-server/internal/order"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeOrder struct {
+	ID string `json:"id"`
+}
+
+func TestGuardReplaysStoredResult(t *testing.T) {
+	guard := idempotency.NewGuard(idempotency.NewMemoryStore(), time.Hour)
+
+	var calls int32
+	create := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return &fakeOrder{ID: "ORD-1"}, nil
+	}
+
+	var first, second fakeOrder
+	replayed, err := guard.Do("key-1", "fp-1", &first, create)
+	if err != nil || replayed {
+		t.Fatalf("Expected fresh call, got replayed=%v err=%v", replayed, err)
+	}
+
+	replayed, err = guard.Do("key-1", "fp-1", &second, create)
+	if err != nil || !replayed {
+		t.Fatalf("Expected replay, got replayed=%v err=%v", replayed, err)
+	}
+	if second.ID != "ORD-1" {
+		t.Errorf("Expected replayed order ORD-1, got %s", second.ID)
+	}
+	if calls != 1 {
+		t.Errorf("Expected create to run once, ran %d times", calls)
+	}
+
+	if _, err := guard.Do("key-1", "fp-2", &second, create); !errors.Is(err, idempotency.ErrKeyReused) {
+		t.Errorf("Expected ErrKeyReused for a different request, got %v", err)
+	}
+}
+
+func TestGuardDoesNotRecordFailures(t *testing.T) {
+	guard := idempotency.NewGuard(idempotency.NewMemoryStore(), time.Hour)
+
+	var out fakeOrder
+	if _, err := guard.Do("key", "fp", &out, func() (interface{}, error) {
+		return nil, errors.New("gateway timeout")
+	}); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	replayed, err := guard.Do("key", "fp", &out, func() (interface{}, error) {
+		return &fakeOrder{ID: "ORD-2"}, nil
+	})
+	if err != nil || replayed || out.ID != "ORD-2" {
+		t.Errorf("Expected retry after failure to create ORD-2, got replayed=%v order=%s err=%v", replayed, out.ID, err)
+	}
+}
+
+func TestGuardSerializesConcurrentDuplicates(t *testing.T) {
+	guard := idempotency.NewGuard(idempotency.NewMemoryStore(), time.Hour)
+
+	var calls int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var out fakeOrder
+			guard.Do("key", "fp", &out, func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(10 * time.Millisecond)
+				return &fakeOrder{ID: "ORD-3"}, nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected one order for concurrent duplicates, got %d", calls)
+	}
+}
+
+func TestFileStorePersistsRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idempotency.json")
+
+	store, err := idempotency.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	var out fakeOrder
+	idempotency.NewGuard(store, time.Hour).Do("key", "fp", &out, func() (interface{}, error) {
+		return &fakeOrder{ID: "ORD-4"}, nil
+	})
+
+	reopened, err := idempotency.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Reopening store failed: %v", err)
+	}
+	replayed, err := idempotency.NewGuard(reopened, time.Hour).Do("key", "fp", &out, func() (interface{}, error) {
+		t.Error("Expected stored record to be replayed after restart")
+		return nil, nil
+	})
+	if err != nil || !replayed || out.ID != "ORD-4" {
+		t.Errorf("Expected replay of ORD-4, got replayed=%v order=%s err=%v", replayed, out.ID, err)
+	}
+}
+
+func TestOrderCreatorReplaysByIdempotencyKey(t *testing.T) {
+	backend := &countingBackend{}
+	creator := order.NewOrderCreatorWithBackend(backend)
+	creator.SetIdempotencyGuard(idempotency.NewGuard(idempotency.NewMemoryStore(), time.Hour))
+
+	input := canonicalOrder()
+	input.IdempotencyKey = "conversation-42"
+
+	first, err := creator.CreateOrder(context.Background(), input)
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	if first.Replayed || first.IdempotencyKey != "conversation-42" {
+		t.Errorf("Expected fresh order under conversation-42, got replayed=%v key=%s", first.Replayed, first.IdempotencyKey)
+	}
+
+	second, err := creator.CreateOrder(context.Background(), input)
+	if err != nil {
+		t.Fatalf("CreateOrder retry failed: %v", err)
+	}
+	if !second.Replayed || second.Order.ID != first.Order.ID {
+		t.Errorf("Expected retry to replay order %s, got replayed=%v id=%s", first.Order.ID, second.Replayed, second.Order.ID)
+	}
+	if backend.calls != 1 {
+		t.Errorf("Expected one submission, got %d", backend.calls)
+	}
+}
+
+// keyRecordingBackend is a countingBackend that records the key each order
+// was submitted with
+type keyRecordingBackend struct {
+	countingBackend
+	keys []string
+}
+
+func (b *keyRecordingBackend) Submit(ctx context.Context, o *order.Order) (*order.OrderResult, error) {
+	b.keys = append(b.keys, o.IdempotencyKey)
+	return b.countingBackend.Submit(ctx, o)
+}
+
+func TestOrderCreatorDerivedKeysOnlyCoverRetries(t *testing.T) {
+	backend := &keyRecordingBackend{}
+	creator := order.NewOrderCreatorWithBackend(backend)
+	guard := idempotency.NewGuard(idempotency.NewMemoryStore(), time.Hour)
+	guard.SetRetryWindow(50 * time.Millisecond)
+	creator.SetIdempotencyGuard(guard)
+
+	// A quick retry of the same order is replayed
+	first, err := creator.CreateOrder(context.Background(), canonicalOrder())
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	retry, err := creator.CreateOrder(context.Background(), canonicalOrder())
+	if err != nil || !retry.Replayed || retry.Order.ID != first.Order.ID {
+		t.Fatalf("Expected the retry to replay %s, got %+v, %v", first.Order.ID, retry, err)
+	}
+
+	// Booking the same route again later is a new order
+	time.Sleep(100 * time.Millisecond)
+	again, err := creator.CreateOrder(context.Background(), canonicalOrder())
+	if err != nil || again.Replayed || again.Order.ID == first.Order.ID {
+		t.Errorf("Expected a second order after the retry window, got %+v, %v", again, err)
+	}
+	if len(backend.keys) != 2 || backend.keys[0] != "" || backend.keys[1] != "" {
+		t.Errorf("Expected derived keys to stay out of the backend request, got %q", backend.keys)
+	}
+
+	// An explicit key is kept for the guard's TTL and sent along
+	keyed := canonicalOrder()
+	keyed.IdempotencyKey = "booking-7"
+	creator.CreateOrder(context.Background(), keyed)
+	time.Sleep(100 * time.Millisecond)
+	if replay, err := creator.CreateOrder(context.Background(), keyed); err != nil || !replay.Replayed {
+		t.Errorf("Expected the explicit key to be replayed after the retry window, got %+v, %v", replay, err)
+	}
+	if backend.keys[len(backend.keys)-1] != "booking-7" {
+		t.Errorf("Expected the explicit key to be sent, got %q", backend.keys)
+	}
+}
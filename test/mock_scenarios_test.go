@@ -0,0 +1,184 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// instantScenarios returns the built-in fixtures with latency disabled so
+// tests don't pay for simulated round trips
+func instantScenarios(t *testing.T) *dispatch.ScenarioSet {
+	t.Helper()
+
+	set, err := dispatch.DefaultScenarios()
+	if err != nil {
+		t.Fatalf("DefaultScenarios failed: %v", err)
+	}
+	set.Latency = dispatch.LatencySpec{Distribution: "fixed"}
+	for i := range set.Scenarios {
+		set.Scenarios[i].Latency = nil
+	}
+	return set
+}
+
+func estimateBetween(pickupZip string, dropOffZips ...string) dispatch.CreateEstimateInput {
+	input := dispatch.CreateEstimateInput{
+		PickupInfo: dispatch.PickupInfoInput{
+			Location: dispatch.LocationInput{Address: &dispatch.AddressInput{ZipCode: pickupZip}},
+		},
+		VehicleType: "cargo_van",
+	}
+	for _, zip := range dropOffZips {
+		input.DropOffs = append(input.DropOffs, dispatch.DropOffInfoInput{
+			Location: dispatch.LocationInput{Address: &dispatch.AddressInput{ZipCode: zip}},
+		})
+	}
+	return input
+}
+
+func TestMockScenarioTiersAndDistancePricing(t *testing.T) {
+	mock := dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t))
+
+	near, err := mock.CreateEstimate(context.Background(), estimateBetween("94105", "94103"))
+	if err != nil {
+		t.Fatalf("CreateEstimate failed: %v", err)
+	}
+	options := near.Data.CreateEstimate.Estimate.AvailableOrderOptions
+	if len(options) != 3 {
+		t.Fatalf("Expected 3 service tiers, got %d", len(options))
+	}
+	if options[0].ServiceType != "rush" || options[2].ServiceType != "end_of_day" {
+		t.Errorf("Expected tiers fastest first, got %s..%s", options[0].ServiceType, options[2].ServiceType)
+	}
+	if options[0].EstimatedOrderCost.Cmp(options[2].EstimatedOrderCost) <= 0 {
+		t.Errorf("Expected rush to cost more than end_of_day, got %s and %s",
+			options[0].EstimatedOrderCost, options[2].EstimatedOrderCost)
+	}
+
+	far, err := mock.CreateEstimate(context.Background(), estimateBetween("94105", "95112"))
+	if err != nil {
+		t.Fatalf("CreateEstimate failed: %v", err)
+	}
+	farOptions := far.Data.CreateEstimate.Estimate.AvailableOrderOptions
+	if farOptions[1].EstimatedOrderCost.Cmp(options[1].EstimatedOrderCost) <= 0 {
+		t.Errorf("Expected San Jose to cost more than SoMa, got %s and %s",
+			farOptions[1].EstimatedOrderCost, options[1].EstimatedOrderCost)
+	}
+
+	boxTruck := estimateBetween("94105", "94103")
+	boxTruck.VehicleType = "box_truck"
+	scheduled, err := mock.CreateEstimate(context.Background(), boxTruck)
+	if err != nil {
+		t.Fatalf("CreateEstimate failed: %v", err)
+	}
+	if got := scheduled.Data.CreateEstimate.Estimate.AvailableOrderOptions; len(got) != 1 || got[0].ServiceType != "standard" {
+		t.Errorf("Expected box trucks to only offer standard, got %+v", got)
+	}
+}
+
+func TestMockScenarioServiceAreaErrors(t *testing.T) {
+	mock := dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t))
+
+	_, err := mock.CreateEstimate(context.Background(), estimateBetween("94105", "94103", "10001"))
+	if !dispatch.IsKind(err, dispatch.ErrorKindValidation) {
+		t.Fatalf("Expected validation error, got %v", err)
+	}
+	apiErr := err.(*dispatch.APIError)
+	if len(apiErr.GraphQLErrors) != 1 {
+		t.Fatalf("Expected one GraphQL error, got %d", len(apiErr.GraphQLErrors))
+	}
+	if path := apiErr.GraphQLErrors[0].PathString(); path != "createEstimate.input.drop_offs.1.location.address.zip_code" {
+		t.Errorf("Unexpected error path %s", path)
+	}
+
+	none, err := mock.CreateEstimate(context.Background(), estimateBetween("94199", "94103"))
+	if err != nil {
+		t.Fatalf("CreateEstimate failed: %v", err)
+	}
+	if got := len(none.Data.CreateEstimate.Estimate.AvailableOrderOptions); got != 0 {
+		t.Errorf("Expected no_capacity scenario to return no options, got %d", got)
+	}
+}
+
+func TestMockScenarioFaultInjection(t *testing.T) {
+	mock := dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t))
+
+	_, err := mock.CreateEstimate(context.Background(), estimateBetween("94197", "94103"))
+	if !dispatch.IsKind(err, dispatch.ErrorKindRateLimit) {
+		t.Fatalf("Expected rate limit error, got %v", err)
+	}
+	apiErr := err.(*dispatch.APIError)
+	if apiErr.StatusCode != 429 || apiErr.RetryAfter() != 2*time.Second {
+		t.Errorf("Expected 429 with 2s Retry-After, got %d and %v", apiErr.StatusCode, apiErr.RetryAfter())
+	}
+
+	_, err = mock.CreateEstimate(context.Background(), estimateBetween("94196", "94103"))
+	if !dispatch.IsKind(err, dispatch.ErrorKindTransport) {
+		t.Errorf("Expected transport error, got %v", err)
+	}
+
+	// Same seed, same sequence of injected failures
+	outcomes := func() string {
+		seeded := dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 42}, instantScenarios(t))
+		var sequence strings.Builder
+		for i := 0; i < 20; i++ {
+			if _, err := seeded.CreateEstimate(context.Background(), estimateBetween("94198", "94103")); err != nil {
+				sequence.WriteByte('x')
+			} else {
+				sequence.WriteByte('.')
+			}
+		}
+		return sequence.String()
+	}
+	first := outcomes()
+	if !strings.Contains(first, "x") || !strings.Contains(first, ".") {
+		t.Errorf("Expected flaky_gateway to fail some calls, got %s", first)
+	}
+	if second := outcomes(); second != first {
+		t.Errorf("Expected seeded runs to match, got %s and %s", first, second)
+	}
+}
+
+func TestMockScenarioFixtureFile(t *testing.T) {
+	fixture := `{
+  "service_area": {"zip_prefixes": ["100"]},
+  "zip_coordinates": {"10001": [40.7506, -73.9972], "10013": [40.7209, -74.0048]},
+  "tiers": [
+    {"service_type": "same_day", "base_fare": 20, "per_mile": 3, "lead_time": "3h"},
+    {"service_type": "express", "base_fare": 40, "per_mile": 5, "lead_time": "1h"}
+  ],
+  "latency": {"distribution": "fixed", "mean": "1ms"}
+}`
+	path := filepath.Join(t.TempDir(), "nyc.json")
+	if err := os.WriteFile(path, []byte(fixture), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	mock, err := dispatch.NewMockClientWithConfig(&config.Config{MockFixturesPath: path})
+	if err != nil {
+		t.Fatalf("NewMockClientWithConfig failed: %v", err)
+	}
+
+	response, err := mock.CreateEstimate(context.Background(), estimateBetween("10001", "10013"))
+	if err != nil {
+		t.Fatalf("CreateEstimate failed: %v", err)
+	}
+	options := response.Data.CreateEstimate.Estimate.AvailableOrderOptions
+	if len(options) != 2 || options[0].ServiceType != "express" {
+		t.Fatalf("Expected express then same_day, got %+v", options)
+	}
+	if options[1].EstimatedOrderCost.Cmp(money.Dollars(20)) <= 0 {
+		t.Errorf("Expected distance to be priced in, got %s", options[1].EstimatedOrderCost)
+	}
+
+	if _, err := mock.CreateEstimate(context.Background(), estimateBetween("94105", "94103")); !dispatch.IsKind(err, dispatch.ErrorKindValidation) {
+		t.Errorf("Expected San Francisco to be outside the fixture's service area, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package test
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
+	"dispatch-mcp-server/internal/pricing"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMoneyRounding(t *testing.T) {
+	tests := []struct {
+		amount   money.Money
+		factor   float64
+		mode     money.RoundingMode
+		expected int64
+	}{
+		{money.New(4599, money.USD), 0.85, money.HalfUp, 3909}, // 3909.15
+		{money.New(4590, money.USD), 0.85, money.HalfUp, 3902}, // 3901.5
+		{money.New(45, money.USD), 0.7, money.HalfUp, 32},      // 31.5, which float math puts just below the half
+		{money.New(4590, money.USD), 0.85, money.HalfEven, 3902},
+		{money.New(4610, money.USD), 0.85, money.HalfEven, 3918}, // 3918.5
+		{money.New(4610, money.USD), 0.85, money.HalfUp, 3919},
+		{money.New(4599, money.USD), 0.85, money.Down, 3909},
+		{money.New(4599, money.USD), 0.85, money.Up, 3910},
+		{money.New(-4590, money.USD), 0.85, money.HalfUp, -3902},
+		{money.New(-4599, money.USD), 0.85, money.Down, -3909},
+		{money.New(-4599, money.USD), 0.85, money.Up, -3910},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.Mul(tt.factor, tt.mode); got.Amount != tt.expected || got.Currency != money.USD {
+			t.Errorf("%s × %g (mode %d): Expected %d, got %+v", tt.amount, tt.factor, tt.mode, tt.expected, got)
+		}
+	}
+
+	if got := money.New(1999, money.USD).Percent(15, money.HalfUp); got.Amount != 300 {
+		t.Errorf("Expected 15%% of $19.99 to be $3.00, got %s", got)
+	}
+	if got := money.Dollars(0.285); got.Amount != 29 {
+		t.Errorf("Expected 0.285 to round half up to 29 cents, got %d", got.Amount)
+	}
+	if got, err := money.Parse("39.091499999", money.USD); err != nil || got.Amount != 3909 {
+		t.Errorf("Expected 39.091499999 to round to 3909 cents, got %+v, %v", got, err)
+	}
+	if got, err := money.Parse("1500", money.JPY); err != nil || got.Amount != 1500 || got.Decimal() != "1500" {
+		t.Errorf("Expected yen to have no minor units, got %+v, %v", got, err)
+	}
+	for _, invalid := range []string{"", "abc", "1/3", "$5"} {
+		if _, err := money.Parse(invalid, money.USD); err == nil {
+			t.Errorf("%q: Expected the amount to be rejected", invalid)
+		}
+	}
+	for _, huge := range []string{"1e30", "99999999999999999999", "-92233720368547758.09"} {
+		if got, err := money.Parse(huge, money.USD); err == nil || !strings.Contains(err.Error(), "out of range") {
+			t.Errorf("%q: Expected the amount to be out of range, got %s, %v", huge, got, err)
+		}
+	}
+	if got, err := money.Parse("92233720368547758.07", money.USD); err != nil || got.Amount != math.MaxInt64 {
+		t.Errorf("Expected the largest amount to fit, got %+v, %v", got, err)
+	}
+
+	sum := money.Dollars(0.1).Add(money.Dollars(0.2))
+	if sum != money.Dollars(0.3) || sum.String() != "0.30 USD" || money.Dollars(-0.05).Decimal() != "-0.05" {
+		t.Errorf("Expected exact cents, got %s", sum)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected adding euros to dollars to panic")
+		}
+	}()
+	money.Dollars(1).Add(money.FromFloat(1, money.EUR))
+}
+
+// panics reports whether f panics with a message containing want
+func panics(f func(), want string) (ok bool) {
+	defer func() {
+		recovered := recover()
+		ok = recovered != nil && strings.Contains(fmt.Sprint(recovered), want)
+	}()
+	f()
+	return false
+}
+
+func TestMoneyArithmeticOverflow(t *testing.T) {
+	largest := money.New(math.MaxInt64, money.USD)
+	smallest := money.New(math.MinInt64, money.USD)
+	cent := money.New(1, money.USD)
+
+	overflows := map[string]func(){
+		"add":                 func() { largest.Add(cent) },
+		"add negative":        func() { smallest.Add(cent.Neg()) },
+		"sub":                 func() { smallest.Sub(cent) },
+		"sub negative":        func() { largest.Sub(cent.Neg()) },
+		"neg":                 func() { smallest.Neg() },
+		"mul":                 func() { largest.Mul(2, money.HalfUp) },
+		"percent":             func() { largest.Percent(200, money.HalfUp) },
+		"from float":          func() { money.Dollars(1e30) },
+		"mul by NaN":          func() { cent.Mul(math.NaN(), money.HalfUp) },
+		"mul by infinity":     func() { cent.Mul(math.Inf(1), money.HalfUp) },
+		"percent of NaN":      func() { cent.Percent(math.NaN(), money.HalfUp) },
+		"percent of infinity": func() { cent.Percent(math.Inf(-1), money.HalfUp) },
+	}
+	for name, overflow := range overflows {
+		if !panics(overflow, "money: ") {
+			t.Errorf("%s: Expected a money panic", name)
+		}
+	}
+
+	// The extremes themselves are still reachable
+	if got := largest.Sub(cent).Add(cent); got != largest {
+		t.Errorf("Expected to get back to the largest amount, got %s", got)
+	}
+	if got := smallest.Add(cent).Sub(cent); got != smallest {
+		t.Errorf("Expected to get back to the smallest amount, got %s", got)
+	}
+}
+
+func TestMoneyJSONCompatibility(t *testing.T) {
+	// The API sends option costs as numbers and the breakdown as strings
+	data := `{"serviceType":"standard","estimatedOrderCost":45.99,"estimateInfo":{"tollAmount":"3.50","estimatedOrderCost":"45.99","dedicatedVehicleFee":""}}`
+	var option dispatch.AvailableOrderOption
+	if err := json.Unmarshal([]byte(data), &option); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if option.EstimatedOrderCost != money.New(4599, money.USD) || option.EstimateInfo.TollAmount.Money != money.New(350, money.USD) || !option.EstimateInfo.DedicatedVehicleFee.IsZero() {
+		t.Errorf("Expected cents in dollars, got %+v", option)
+	}
+
+	var huge dispatch.AvailableOrderOption
+	if err := json.Unmarshal([]byte(`{"estimatedOrderCost":99999999999999999999}`), &huge); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("Expected an overflowing amount to be rejected, got %+v, %v", huge, err)
+	}
+
+	// Created orders are priced in cents too
+	var submission dispatch.OrderSubmission
+	if err := json.Unmarshal([]byte(`{"order":{"pricing":{"totalPrice":39.095,"basePrice":45.99,"discounts":[{"type":"multi_delivery","amount":6.895}]}}}`), &submission); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	orderPricing := submission.Order.Pricing
+	if orderPricing.TotalPrice != money.New(3910, money.USD) || orderPricing.BasePrice != money.New(4599, money.USD) || orderPricing.Discounts[0].Amount != money.New(690, money.USD) {
+		t.Errorf("Expected order pricing in cents, got %+v", orderPricing)
+	}
+
+	encoded, _ := json.Marshal(option)
+	for _, field := range []string{`"estimatedOrderCost":45.99`, `"tollAmount":"3.50"`, `"estimatedOrderCost":"45.99"`, `"dedicatedVehicleFee":"0.00"`} {
+		if !strings.Contains(string(encoded), field) {
+			t.Errorf("Expected %s in %s", field, encoded)
+		}
+	}
+
+	// Old clients decoding into floats still see plain numbers, rounded to the cent
+	comparison := pricing.NewPricingEngine().ComparePricingModels(&option, pricing.PricingContext{DeliveryCount: 2})
+	encoded, _ = json.Marshal(comparison)
+	var legacy struct {
+		PricingModels []struct {
+			Model        string  `json:"model"`
+			AdjustedCost float64 `json:"adjusted_cost"`
+			Savings      float64 `json:"savings"`
+		} `json:"pricing_models"`
+		Savings float64 `json:"savings"`
+	}
+	if err := json.Unmarshal(encoded, &legacy); err != nil {
+		t.Fatalf("Failed to decode into floats: %v", err)
+	}
+	multi := legacy.PricingModels[0]
+	for _, result := range legacy.PricingModels {
+		if result.Model == string(pricing.MultiDeliveryPricing) {
+			multi = result
+		}
+	}
+	if multi.Model != string(pricing.MultiDeliveryPricing) || multi.AdjustedCost != 39.09 || multi.Savings != 6.9 || legacy.Savings != 6.9 {
+		t.Errorf("Expected $39.09 after the multi-delivery discount, got %s", encoded)
+	}
+	if !strings.Contains(string(encoded), `"adjusted_cost":39.09`) {
+		t.Errorf("Expected cent-precision amounts, got %s", encoded)
+	}
+}
@@ -0,0 +1,129 @@
+package test
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/estimate"
+	"dispatch-mcp-server/internal/money"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// shuffledOptions returns four options deliberately out of speed and price
+// order. Option 3 arrives after option 1 for more money.
+func shuffledOptions() []dispatch.AvailableOrderOption {
+	return []dispatch.AvailableOrderOption{
+		{ServiceType: "standard", EstimatedDeliveryTimeUTC: "2024-01-15T16:00:00Z", EstimatedOrderCost: money.Dollars(50)},
+		{ServiceType: "rush", EstimatedDeliveryTimeUTC: "2024-01-15T13:00:00Z", EstimatedOrderCost: money.Dollars(80)},
+		{ServiceType: "end_of_day", EstimatedDeliveryTimeUTC: "2024-01-15T20:00:00Z", EstimatedOrderCost: money.Dollars(40)},
+		{ServiceType: "priority", EstimatedDeliveryTimeUTC: "2024-01-15T14:00:00Z", EstimatedOrderCost: money.Dollars(95)},
+	}
+}
+
+func TestSelectOptionScenarios(t *testing.T) {
+	deadline, _ := time.Parse(time.RFC3339, "2024-01-15T17:00:00Z")
+	tests := []struct {
+		criteria estimate.Criteria
+		expected int
+	}{
+		{estimate.Criteria{Scenario: estimate.ScenarioFastest}, 1},
+		{estimate.Criteria{Scenario: estimate.ScenarioCheapest}, 2},
+		{estimate.Criteria{Scenario: estimate.ScenarioDeliverBy, DeliverBy: deadline}, 0},
+		{estimate.Criteria{Scenario: estimate.ScenarioCheapestWithin, Budget: money.Dollars(85)}, 1},
+		{estimate.Criteria{Scenario: estimate.ScenarioCheapestWithin, Budget: money.Dollars(60)}, 0},
+		// rush saves 7h for $40, $5.71/h; standard saves 4h for $10, $2.50/h
+		{estimate.Criteria{Scenario: estimate.ScenarioBestValue}, 0},
+	}
+	for _, tt := range tests {
+		selection, err := estimate.SelectOption(shuffledOptions(), tt.criteria)
+		if err != nil {
+			t.Fatalf("%+v: SelectOption failed: %v", tt.criteria, err)
+		}
+		if selection.OptionIndex != tt.expected {
+			t.Errorf("%+v: expected option %d, got %d", tt.criteria, tt.expected, selection.OptionIndex)
+		}
+	}
+
+	// Verdicts run fastest first and explain every option
+	selection, _ := estimate.SelectOption(shuffledOptions(), estimate.Criteria{Scenario: estimate.ScenarioBestValue})
+	order := []int{1, 3, 0, 2}
+	for i, verdict := range selection.Verdicts {
+		if verdict.OptionIndex != order[i] || verdict.Reason == "" {
+			t.Fatalf("Expected verdicts for options %v with reasons, got %+v", order, selection.Verdicts)
+		}
+	}
+	if v := selection.Verdicts[1]; v.CostPerHourSaved != nil || !strings.Contains(v.Reason, "option 1") {
+		t.Errorf("Expected option 3 to be dominated by option 1, got %+v", v)
+	}
+	if v := selection.Verdicts[2]; !v.Selected || v.CostPerHourSaved == nil || *v.CostPerHourSaved != 2.5 {
+		t.Errorf("Expected option 0 at $2.50 per hour saved, got %+v", v)
+	}
+
+	tooEarly, _ := time.Parse(time.RFC3339, "2024-01-15T12:00:00Z")
+	if _, err := estimate.SelectOption(shuffledOptions(), estimate.Criteria{Scenario: estimate.ScenarioDeliverBy, DeliverBy: tooEarly}); err == nil || !strings.Contains(err.Error(), "2024-01-15T13:00:00Z") {
+		t.Errorf("Expected an unreachable deadline to name the earliest arrival, got %v", err)
+	}
+	if _, err := estimate.SelectOption(shuffledOptions(), estimate.Criteria{Scenario: estimate.ScenarioCheapestWithin, Budget: money.Dollars(30)}); err == nil || !strings.Contains(err.Error(), "$40.00") {
+		t.Errorf("Expected a low budget to name the cheapest price, got %v", err)
+	}
+}
+
+func TestParseCriteria(t *testing.T) {
+	criteria, err := estimate.ParseCriteria("cheapest_within $45.50")
+	if err != nil || criteria.Scenario != estimate.ScenarioCheapestWithin || criteria.Budget != money.Dollars(45.5) {
+		t.Errorf("Expected a $45.50 budget, got %+v, %v", criteria, err)
+	}
+	criteria, err = estimate.ParseCriteria("deliver_by 2024-01-15T17:00:00Z")
+	if err != nil || criteria.DeliverBy.Hour() != 17 {
+		t.Errorf("Expected a 17:00 deadline, got %+v, %v", criteria, err)
+	}
+	if criteria, _ := estimate.ParseCriteria("ASAP"); criteria.Scenario != estimate.ScenarioFastest {
+		t.Errorf("Expected asap to mean fastest, got %s", criteria.Scenario)
+	}
+	if _, err := estimate.ParseCriteria("deliver_by 5pm"); err == nil {
+		t.Error("Expected a non-RFC 3339 deadline to fail")
+	}
+}
+
+func TestSelectDeliveryOptionToolExplainsChoice(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	response := &dispatch.CreateEstimateResponse{}
+	response.Data.CreateEstimate.Estimate.AvailableOrderOptions = shuffledOptions()
+
+	var selected struct {
+		OptionIndex int                `json:"option_index"`
+		Verdicts    []estimate.Verdict `json:"verdicts"`
+	}
+	output := callTool(t, mcpClient, "select_delivery_option", map[string]interface{}{
+		"estimate_response": response,
+		"delivery_scenario": "deliver_by",
+		"deliver_by":        "2024-01-15T15:00:00Z",
+	})
+	if err := json.Unmarshal([]byte(output), &selected); err != nil {
+		t.Fatalf("Failed to parse selection: %v", err)
+	}
+	if selected.OptionIndex != 1 || len(selected.Verdicts) != 4 {
+		t.Fatalf("Expected rush, the cheapest option arriving by 15:00, got %s", output)
+	}
+	if late := selected.Verdicts[2]; late.OptionIndex != 0 || !strings.Contains(late.Reason, "after the 2024-01-15T15:00:00Z deadline") {
+		t.Errorf("Expected standard to be rejected as late, got %+v", late)
+	}
+
+	output = callTool(t, mcpClient, "select_delivery_option", map[string]interface{}{
+		"estimate_response": response,
+		"delivery_scenario": "cheapest_within 60",
+	})
+	if err := json.Unmarshal([]byte(output), &selected); err != nil || selected.OptionIndex != 0 {
+		t.Errorf("Expected standard within a $60 budget, got %s", output)
+	}
+
+	text := callToolError(t, mcpClient, "select_delivery_option", map[string]interface{}{
+		"estimate_response": response,
+		"delivery_scenario": "cheapest_within",
+	})
+	if !strings.Contains(text, "budget") {
+		t.Errorf("Expected a missing budget to be rejected, got %s", text)
+	}
+}
@@ -0,0 +1,221 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// exerciseOrderLifecycle creates two orders through api and walks the first
+// through get, list, update and cancel, returning its ID
+func exerciseOrderLifecycle(t *testing.T, api dispatch.API) string {
+	t.Helper()
+	ctx := context.Background()
+
+	created, err := api.CreateOrder(ctx, recordedOrderInput())
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	order := created.Data.CreateOrder.Order
+	if _, err := api.CreateOrder(ctx, recordedOrderInput()); err != nil {
+		t.Fatalf("Second CreateOrder failed: %v", err)
+	}
+
+	fetched, err := api.GetOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder failed: %v", err)
+	}
+	got := fetched.Data.Order
+	if got.Status != dispatch.OrderStatusPending || got.TotalCost != order.TotalCost {
+		t.Errorf("Expected the pending order at %s, got %s at %s", order.TotalCost, got.Status, got.TotalCost)
+	}
+	if got.Pickup == nil || got.Pickup.ContactName != "Jordan Lee" || len(got.DropOffs) != 1 || got.DropOffs[0].Address.City != "Oakland" {
+		t.Errorf("Expected the order's stops, got pickup %+v and drop-offs %+v", got.Pickup, got.DropOffs)
+	}
+	if len(got.StatusHistory) != 1 || got.StatusHistory[0].Status != dispatch.OrderStatusPending {
+		t.Errorf("Expected a pending history entry, got %+v", got.StatusHistory)
+	}
+
+	listed, err := api.ListOrders(ctx, dispatch.ListOrdersInput{Statuses: []string{dispatch.OrderStatusPending}, Limit: 1})
+	if err != nil {
+		t.Fatalf("ListOrders failed: %v", err)
+	}
+	if len(listed.Data.Orders) != 1 {
+		t.Errorf("Expected the limit to apply, got %d orders", len(listed.Data.Orders))
+	}
+
+	// A farther drop-off re-prices the order
+	update := dispatch.UpdateOrderInput{OrderID: order.ID, PickupNotes: stringPtr("Use the loading dock")}
+	update.DropOffs = recordedOrderInput().DropOffs
+	update.DropOffs[0].Location = &dispatch.LocationInput{
+		Address: &dispatch.AddressInput{Street: "200 E Santa Clara St", City: "San Jose", State: "CA", ZipCode: "95113", Country: "US"},
+	}
+	updated, err := api.UpdateOrder(ctx, update)
+	if err != nil {
+		t.Fatalf("UpdateOrder failed: %v", err)
+	}
+	amended := updated.Data.UpdateOrder.Order
+	if amended.Pickup.Notes != "Use the loading dock" || amended.DropOffs[0].Address.City != "San Jose" {
+		t.Errorf("Expected the update to apply, got pickup %+v and drop-offs %+v", amended.Pickup, amended.DropOffs)
+	}
+	if amended.TotalCost.Cmp(order.TotalCost) <= 0 {
+		t.Errorf("Expected the longer route to cost more than %s, got %s", order.TotalCost, amended.TotalCost)
+	}
+
+	reason := "customer closed"
+	cancelled, err := api.CancelOrder(ctx, dispatch.CancelOrderInput{OrderID: order.ID, Reason: &reason})
+	if err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	history := cancelled.Data.CancelOrder.Order.StatusHistory
+	if len(history) != 3 || history[2].Status != dispatch.OrderStatusCancelled || !strings.Contains(history[2].Note, reason) {
+		t.Errorf("Expected created, updated and cancelled history entries, got %+v", history)
+	}
+
+	// Cancelling again succeeds; amending a cancelled order doesn't
+	if _, err := api.CancelOrder(ctx, dispatch.CancelOrderInput{OrderID: order.ID}); err != nil {
+		t.Errorf("Expected a repeated cancellation to succeed, got %v", err)
+	}
+	_, err = api.UpdateOrder(ctx, dispatch.UpdateOrderInput{OrderID: order.ID, PickupNotes: stringPtr("too late")})
+	expectDispatchCode(t, err, "ORDER_NOT_MODIFIABLE")
+
+	listed, err = api.ListOrders(ctx, dispatch.ListOrdersInput{Statuses: []string{dispatch.OrderStatusCancelled}})
+	if err != nil {
+		t.Fatalf("ListOrders failed: %v", err)
+	}
+	if len(listed.Data.Orders) != 1 || listed.Data.Orders[0].ID != order.ID {
+		t.Errorf("Expected only the cancelled order, got %+v", listed.Data.Orders)
+	}
+
+	_, err = api.GetOrder(ctx, "ord_missing")
+	expectDispatchCode(t, err, "NOT_FOUND")
+
+	return order.ID
+}
+
+// orderArgs returns create_order arguments for the estimateArgs route. The
+// idempotency guard is shared by every server in the process, so each call
+// gets its own key.
+func orderArgs(t *testing.T) map[string]interface{} {
+	return map[string]interface{}{
+		"delivery_info":   map[string]interface{}{"service_type": "standard"},
+		"pickup_info":     estimateArgs()["pickup_info"],
+		"drop_offs":       estimateArgs()["drop_offs"],
+		"idempotency_key": fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano()),
+	}
+}
+
+func expectDispatchCode(t *testing.T, err error, code string) {
+	t.Helper()
+
+	var apiErr *dispatch.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected a %s APIError, got %v", code, err)
+	}
+	if len(apiErr.GraphQLErrors) == 0 || apiErr.GraphQLErrors[0].Code() != code {
+		t.Errorf("Expected code %s, got %v", code, err)
+	}
+}
+
+func TestMockOrderLifecycle(t *testing.T) {
+	exerciseOrderLifecycle(t, dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t)))
+}
+
+func TestFakeGraphOrderLifecycle(t *testing.T) {
+	fake, _, client := startFakeGraph(t)
+	exerciseOrderLifecycle(t, client)
+
+	// Once picked up an order can't be cancelled
+	created, err := client.CreateOrder(context.Background(), recordedOrderInput())
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	stored := fake.Store().Get(created.Data.CreateOrder.Order.ID)
+	stored.Status = dispatch.OrderStatusPickedUp
+	fake.Store().Put(stored)
+
+	_, err = client.CancelOrder(context.Background(), dispatch.CancelOrderInput{OrderID: stored.ID})
+	expectDispatchCode(t, err, "ORDER_NOT_MODIFIABLE")
+}
+
+func TestOrderLifecycleTools(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	var created dispatch.CreateOrderResponse
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "create_order", orderArgs(t))), &created); err != nil {
+		t.Fatalf("Failed to parse order: %v", err)
+	}
+	orderID := created.Data.CreateOrder.Order.ID
+
+	var fetched dispatch.GetOrderResponse
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "get_order", map[string]interface{}{"order_id": orderID})), &fetched); err != nil {
+		t.Fatalf("Failed to parse get_order: %v", err)
+	}
+	if fetched.Data.Order.ID != orderID || len(fetched.Data.Order.StatusHistory) != 1 {
+		t.Errorf("Expected the order with its history, got %+v", fetched.Data.Order)
+	}
+
+	var updated dispatch.UpdateOrderResponse
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "update_order", map[string]interface{}{"order_id": orderID, "pickup_notes": "Ring twice"})), &updated); err != nil {
+		t.Fatalf("Failed to parse update_order: %v", err)
+	}
+	if updated.Data.UpdateOrder.Order.Pickup.Notes != "Ring twice" {
+		t.Errorf("Expected the pickup notes to change, got %+v", updated.Data.UpdateOrder.Order.Pickup)
+	}
+
+	// Replacement drop-offs are validated before the order is touched
+	badDropOffs := []interface{}{map[string]interface{}{
+		"business_name": "Store",
+		"location": map[string]interface{}{
+			"address": map[string]interface{}{"street": "456 Oak Ave", "city": "Oakland", "state": "CA", "zip_code": "9461", "country": "US"},
+		},
+	}}
+	message := callToolError(t, mcpClient, "update_order", map[string]interface{}{"order_id": orderID, "drop_offs": badDropOffs})
+	if !strings.Contains(message, "drop_offs[0].location.address.zip_code") {
+		t.Errorf("Expected the invalid drop-off zip code to be named, got %q", message)
+	}
+	callToolError(t, mcpClient, "update_order", map[string]interface{}{"order_id": orderID, "drop_offs": []interface{}{}, "pickup_notes": "Ring twice"})
+	callTool(t, mcpClient, "update_order", map[string]interface{}{"order_id": orderID, "drop_offs": estimateArgs()["drop_offs"]})
+
+	var cancelled dispatch.CancelOrderResponse
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "cancel_order", map[string]interface{}{"order_id": orderID, "reason": "duplicate"})), &cancelled); err != nil {
+		t.Fatalf("Failed to parse cancel_order: %v", err)
+	}
+	if cancelled.Data.CancelOrder.Order.Status != dispatch.OrderStatusCancelled {
+		t.Errorf("Expected the order to be cancelled, got %s", cancelled.Data.CancelOrder.Order.Status)
+	}
+
+	var listed dispatch.ListOrdersResponse
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "list_orders", map[string]interface{}{"statuses": []interface{}{"cancelled"}, "limit": 5})), &listed); err != nil {
+		t.Fatalf("Failed to parse list_orders: %v", err)
+	}
+	if len(listed.Data.Orders) != 1 || listed.Data.Orders[0].ID != orderID {
+		t.Errorf("Expected the cancelled order, got %+v", listed.Data.Orders)
+	}
+
+	invalid := map[string]map[string]interface{}{
+		"get_order":    {"order_id": "ord_missing"},
+		"update_order": {"order_id": orderID, "pickup_notes": "too late"},
+		"list_orders":  {"statuses": []interface{}{"lost"}},
+	}
+	for name, args := range invalid {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = name
+		request.Params.Arguments = args
+		result, err := mcpClient.CallTool(context.Background(), request)
+		if err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		if !result.IsError {
+			t.Errorf("Expected %s to fail for %v", name, args)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/conversation"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/idempotency"
+	"dispatch-mcp-server/internal/money"
+	"dispatch-mcp-server/internal/order"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingBackend is an order.Backend that records submissions without a network
+type countingBackend struct {
+	calls int
+}
+
+func (b *countingBackend) Submit(ctx context.Context, o *order.Order) (*order.OrderResult, error) {
+	b.calls++
+	result := &order.OrderResult{}
+	result.Order.ID = fmt.Sprintf("ORD-%d", b.calls)
+	result.Order.Status = "pending"
+	return result, nil
+}
+
+func canonicalOrder() *order.Order {
+	return &order.Order{
+		Pickup: order.Stop{
+			BusinessName: "Warehouse",
+			ContactName:  "Jordan Lee",
+			ContactPhone: "415-555-0134",
+			Address:      &order.Address{Street: "123 Market St", City: "San Francisco", State: "CA", ZipCode: "94105", Country: "US"},
+		},
+		DropOffs: []order.Stop{{
+			BusinessName: "Store",
+			ContactName:  "Sam Rivera",
+			ContactPhone: "510-555-0199",
+			Address:      &order.Address{Street: "456 Oak Ave", City: "Oakland", State: "CA", ZipCode: "94610", Country: "US"},
+		}},
+		VehicleType:  "sprinter_van",
+		ServiceType:  "rush",
+		Capabilities: []string{"fragile_handling"},
+		Schedule:     &order.Schedule{PickupDate: "10/20/2026", PickupTime: "9am"},
+	}
+}
+
+func TestOrderConvertsToEachBackendSchema(t *testing.T) {
+	// The Dispatch schema round-trips through the canonical model
+	dispatchInput := recordedOrderInput()
+	if got := order.FromDispatchInput(dispatchInput).ToDispatchInput(); !reflect.DeepEqual(got, dispatchInput) {
+		t.Errorf("Expected Dispatch input to round-trip\nwant %+v\ngot  %+v", dispatchInput, got)
+	}
+
+	o := canonicalOrder()
+	gql := o.ToGraphQLInput()
+	if gql.VehicleTypeID != "sprinter_van" || gql.PickupInfo.ContactPhone != "415-555-0134" || gql.DropOffs[0].Address.ZipCode != "94610" {
+		t.Errorf("Unexpected GraphQL input %+v", gql)
+	}
+	if gql.Scheduling == nil || gql.Scheduling.PickupTime != "9am" {
+		t.Errorf("Expected scheduling to carry over, got %+v", gql.Scheduling)
+	}
+
+	dispatchOrder := o.ToDispatchInput()
+	if dispatchOrder.DeliveryInfo.ServiceType != "rush" || *dispatchOrder.DropOffs[0].ContactName != "Sam Rivera" {
+		t.Errorf("Unexpected Dispatch input %+v", dispatchOrder)
+	}
+}
+
+func TestOrderValidateReportsFields(t *testing.T) {
+	o := canonicalOrder()
+	o.Pickup.Address = nil
+	o.DropOffs = append(o.DropOffs, order.Stop{Address: &order.Address{ZipCode: "94103"}})
+	o.VehicleType = ""
+
+	var validationErr *order.ValidationError
+	if err := o.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("Expected *order.ValidationError, got %v", err)
+	}
+
+	var fields []string
+	for _, fieldErr := range validationErr.Errors {
+		fields = append(fields, fieldErr.Field)
+	}
+	want := []string{"pickup.address", "dropOffs.1.businessName", "vehicleType"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("Expected errors for %v, got %v", want, fields)
+	}
+
+	backend := &countingBackend{}
+	if _, err := order.NewOrderCreatorWithBackend(backend).CreateOrder(context.Background(), o); err == nil || backend.calls != 0 {
+		t.Errorf("Expected invalid order to be rejected before submission, got err=%v calls=%d", err, backend.calls)
+	}
+}
+
+func graphqlBackend(t *testing.T, endpoint string) *order.GraphQLBackend {
+	t.Helper()
+	backend, err := order.NewGraphQLBackend(endpoint)
+	if err != nil {
+		t.Fatalf("NewGraphQLBackend failed: %v", err)
+	}
+	return backend
+}
+
+func TestGraphQLBackendAppliesOrderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	t.Setenv("DISPATCH_ORDER_TIMEOUT", "50ms")
+	backend := graphqlBackend(t, server.URL+"/graphql")
+
+	// The caller's context has no deadline; DISPATCH_ORDER_TIMEOUT bounds each call
+	start := time.Now()
+	if _, err := backend.Submit(context.Background(), canonicalOrder()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Submit to time out, got %v", err)
+	}
+	if _, err := backend.Validate(context.Background(), canonicalOrder()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Validate to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the calls to give up after the order timeout, took %v", elapsed)
+	}
+}
+
+func TestOrderCreatorBackends(t *testing.T) {
+	_, server, client := startFakeGraph(t)
+	t.Setenv("GRAPHQL_AUTH_TOKEN", "ci-token")
+
+	backends := map[string]order.Backend{
+		"dispatch_api":        order.NewDispatchBackend(client),
+		"order_mutation":      graphqlBackend(t, server.URL+"/graphql"),
+		"dispatch_mutation":   order.NewMutationBackend(client),
+		"mock_order_mutation": order.NewMutationBackend(dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t))),
+	}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			o := canonicalOrder()
+			if name == "dispatch_api" {
+				// The createOrder input has no place for these
+				o.VehicleType, o.Capabilities, o.Schedule = order.DefaultVehicleType, nil, nil
+			}
+
+			creator := order.NewOrderCreatorWithBackend(backend)
+			creator.SetIdempotencyGuard(idempotency.NewGuard(idempotency.NewMemoryStore(), time.Hour))
+			result, err := creator.CreateOrder(context.Background(), o)
+			if err != nil {
+				t.Fatalf("CreateOrder failed: %v", err)
+			}
+			total := result.Order.Pricing.TotalPrice
+			if result.Order.ID == "" || total.Amount <= 0 || total.Currency != money.USD {
+				t.Errorf("Expected an order priced in dollars, got %+v", result.Order)
+			}
+			if cost := result.ToDispatchResponse().Data.CreateOrder.Order.TotalCost; cost != total {
+				t.Errorf("Expected the Dispatch response to carry %s, got %s", total, cost)
+			}
+		})
+	}
+}
+
+func TestDispatchBackendRejectsFieldsItCannotSend(t *testing.T) {
+	mock := dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t))
+	backend := order.NewDispatchBackend(mock)
+	fields := []string{"vehicleType", "capabilities", "schedule"}
+
+	_, err := backend.Submit(context.Background(), canonicalOrder())
+	var validationErr *order.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+	for _, field := range fields {
+		if !hasField(validationErr.Errors, field) {
+			t.Errorf("Expected %s to be rejected, got %v", field, err)
+		}
+	}
+
+	validation, err := backend.Validate(context.Background(), canonicalOrder())
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	for _, field := range fields {
+		if validation.Valid || !hasField(validation.Errors, field) {
+			t.Errorf("Expected validation to report %s, got %+v", field, validation)
+		}
+	}
+
+	// The order mutation carries all of them
+	if _, err := order.NewMutationBackend(mock).Submit(context.Background(), canonicalOrder()); err != nil {
+		t.Errorf("Expected the order mutation to accept the order, got %v", err)
+	}
+}
+
+func TestConversationStateToOrder(t *testing.T) {
+	input := recordedOrderInput()
+	state := conversation.OrderCreationState{
+		PickupInfo:     &input.PickupInfo,
+		DropOffs:       input.DropOffs,
+		VehicleType:    &conversation.VehicleTypeInfo{VehicleTypeID: "box_truck", VehicleTypeName: "box truck"},
+		Capabilities:   []string{"signature_required"},
+		SchedulingInfo: &conversation.SchedulingInfo{PickupTime: "2pm"},
+	}
+
+	o := state.ToOrder()
+	if err := o.Validate(); err != nil {
+		t.Fatalf("Expected collected state to be a valid order, got %v", err)
+	}
+	if o.VehicleType != "box_truck" || o.Pickup.ContactName != "Jordan Lee" || o.Schedule.PickupTime != "2pm" {
+		t.Errorf("Unexpected order %+v", o)
+	}
+
+	// Partially collected state converts without panicking and reports what's missing
+	empty := conversation.OrderCreationState{PickupInfo: &dispatch.CreateOrderPickupInfoInput{}}
+	if err := empty.ToOrder().Validate(); err == nil {
+		t.Error("Expected empty state to fail validation")
+	}
+}
+
+func TestCreateOrderToolUsesOrderCreator(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	// The canonical model's checks run before anything is sent
+	args := orderArgs(t)
+	args["pickup_info"] = map[string]interface{}{"location": estimateArgs()["pickup_info"].(map[string]interface{})["location"]}
+	if text := callToolError(t, mcpClient, "create_order", args); !strings.Contains(text, "error_kind: validation") || !strings.Contains(text, "pickup.businessName") {
+		t.Errorf("Expected the missing business name to be reported, got %s", text)
+	}
+
+	// Without a key, nothing is sent to the backend as the Idempotency-Key and
+	// the order is only deduplicated locally for the retry window
+	args = orderArgs(t)
+	delete(args, "idempotency_key")
+	args["tags"] = []interface{}{map[string]interface{}{"name": "test", "value": fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano())}}
+	args["dry_run"] = true
+	if p := dryRun(t, callTool(t, mcpClient, "create_order", args)); p.Mutation.IdempotencyKey != "" {
+		t.Errorf("Expected no Idempotency-Key header for a keyless order, got %q", p.Mutation.IdempotencyKey)
+	}
+	delete(args, "dry_run")
+	var created struct {
+		IdempotencyKey string `json:"idempotency_key"`
+		Replayed       bool   `json:"replayed"`
+	}
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "create_order", args)), &created); err != nil {
+		t.Fatalf("Failed to parse create_order result: %v", err)
+	}
+	if !strings.HasPrefix(created.IdempotencyKey, "order:") || created.Replayed {
+		t.Errorf("Expected a new order under a derived key, got %+v", created)
+	}
+}
@@ -0,0 +1,190 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/order"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// preview is the create_order dry_run response
+type preview struct {
+	DryRun   bool               `json:"dry_run"`
+	Valid    bool               `json:"valid"`
+	Errors   []order.FieldError `json:"errors"`
+	Warnings []order.FieldError `json:"warnings"`
+	Pricing  *struct {
+		ServiceType string   `json:"service_type"`
+		TotalCost   float64  `json:"total_cost"`
+		QuotedCost  *float64 `json:"quoted_cost"`
+	} `json:"pricing"`
+	Mutation struct {
+		Operation string `json:"operation"`
+		Variables struct {
+			Input dispatch.CreateOrderInput `json:"input"`
+		} `json:"variables"`
+		IdempotencyKey string `json:"idempotency_key"`
+	} `json:"mutation"`
+}
+
+func dryRun(t *testing.T, output string) preview {
+	t.Helper()
+
+	var p preview
+	if err := json.Unmarshal([]byte(output), &p); err != nil {
+		t.Fatalf("Failed to parse preview: %v", err)
+	}
+	if !p.DryRun {
+		t.Fatalf("Expected a dry run, got %s", output)
+	}
+	return p
+}
+
+func hasField(fields []order.FieldError, name string) bool {
+	for _, field := range fields {
+		if field.Field == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCreateOrderDryRun(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	args := orderArgs(t)
+	args["dry_run"] = true
+	p := dryRun(t, callTool(t, mcpClient, "create_order", args))
+	if !p.Valid || len(p.Errors) != 0 {
+		t.Fatalf("Expected the order to be valid, got %+v", p.Errors)
+	}
+	if !hasField(p.Warnings, "pickupInfo.contactPhone") || !hasField(p.Warnings, "scheduling") {
+		t.Errorf("Expected warnings for the missing phone and schedule, got %+v", p.Warnings)
+	}
+	if p.Pricing == nil || p.Pricing.ServiceType != "standard" || p.Pricing.TotalCost <= 0 {
+		t.Errorf("Expected a standard price, got %+v", p.Pricing)
+	}
+	input := p.Mutation.Variables.Input
+	if p.Mutation.Operation != "createOrder" || input.DeliveryInfo.ServiceType != "standard" || input.PickupInfo.Location.Address.ZipCode != "94105" {
+		t.Errorf("Expected the createOrder variables, got %+v", p.Mutation)
+	}
+	if p.Mutation.IdempotencyKey != args["idempotency_key"] {
+		t.Errorf("Expected the idempotency key %v, got %s", args["idempotency_key"], p.Mutation.IdempotencyKey)
+	}
+
+	// Nothing was created, and the idempotency key is still free
+	var listed dispatch.ListOrdersResponse
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "list_orders", map[string]interface{}{})), &listed); err != nil {
+		t.Fatalf("Failed to parse list_orders: %v", err)
+	}
+	if len(listed.Data.Orders) != 0 {
+		t.Errorf("Expected a dry run not to create an order, got %+v", listed.Data.Orders)
+	}
+	delete(args, "dry_run")
+	var created dispatch.CreateOrderResponse
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "create_order", args)), &created); err != nil || created.Data.CreateOrder.Order.ID == "" {
+		t.Errorf("Expected the order to be created after the dry run, got %v", err)
+	}
+
+	// Problems are reported in the preview rather than as tool errors
+	outside := orderArgs(t)
+	outside["dry_run"] = "true"
+	outside["drop_offs"] = []interface{}{map[string]interface{}{
+		"location": map[string]interface{}{
+			"address": map[string]interface{}{"street": "1 Main St", "city": "New York", "state": "NY", "zip_code": "10001", "country": "US"},
+		},
+	}}
+	p = dryRun(t, callTool(t, mcpClient, "create_order", outside))
+	if p.Valid || !hasField(p.Errors, "dropOffs.0.address.zipCode") || p.Pricing != nil {
+		t.Errorf("Expected the out-of-area drop-off to be rejected, got %+v", p)
+	}
+
+	overnight := orderArgs(t)
+	overnight["dry_run"] = true
+	overnight["delivery_info"] = map[string]interface{}{"service_type": "overnight"}
+	p = dryRun(t, callTool(t, mcpClient, "create_order", overnight))
+	if p.Valid || !hasField(p.Errors, "delivery_info.service_type") || !strings.Contains(p.Errors[0].Message, "standard") {
+		t.Errorf("Expected the unoffered service type to be rejected, got %+v", p.Errors)
+	}
+
+	// A missing service type previews and prices the standard service the
+	// order would be booked with
+	unset := orderArgs(t)
+	unset["dry_run"] = true
+	unset["delivery_info"] = map[string]interface{}{}
+	p = dryRun(t, callTool(t, mcpClient, "create_order", unset))
+	if !p.Valid || p.Pricing == nil || p.Pricing.ServiceType != "standard" || p.Pricing.TotalCost <= 0 {
+		t.Errorf("Expected the order to be priced as standard, got %+v", p)
+	}
+	if service := p.Mutation.Variables.Input.DeliveryInfo.ServiceType; service != "standard" {
+		t.Errorf("Expected the previewed mutation to send the standard service type, got %q", service)
+	}
+
+	invalid := orderArgs(t)
+	invalid["dry_run"] = "maybe"
+	callToolError(t, mcpClient, "create_order", invalid)
+}
+
+func TestCreateOrderDryRunFromEstimate(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	var estimated struct {
+		EstimateID string `json:"estimate_id"`
+	}
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "create_estimate", estimateArgs())), &estimated); err != nil {
+		t.Fatalf("Failed to parse estimate: %v", err)
+	}
+
+	p := dryRun(t, callTool(t, mcpClient, "create_order", map[string]interface{}{
+		"estimate_id":  estimated.EstimateID,
+		"option_index": 0,
+		"dry_run":      true,
+	}))
+	if !p.Valid || p.Pricing == nil || p.Pricing.QuotedCost == nil {
+		t.Fatalf("Expected the quoted price alongside the current one, got %+v", p)
+	}
+	if *p.Pricing.QuotedCost != p.Pricing.TotalCost && !hasField(p.Warnings, "pricing") {
+		t.Errorf("Expected a pricing warning when the price moved, got %+v", p.Warnings)
+	}
+}
+
+// decoratedAPI wraps a dispatch.API the way a logging or metrics decorator would
+type decoratedAPI struct {
+	dispatch.API
+}
+
+func TestValidateOrderAgainstGraphQL(t *testing.T) {
+	_, server, client := startFakeGraph(t)
+	t.Setenv("GRAPHQL_AUTH_TOKEN", "ci-token")
+	mock := dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t))
+
+	for name, backend := range map[string]order.Validator{
+		"dispatch":         order.NewMutationBackend(client),
+		"graphql":          graphqlBackend(t, server.URL+"/graphql"),
+		"mock":             order.NewMutationBackend(mock),
+		"decorated_client": order.NewMutationBackend(decoratedAPI{client}),
+		"decorated_mock":   order.NewMutationBackend(decoratedAPI{mock}),
+	} {
+		validation, err := backend.Validate(context.Background(), canonicalOrder())
+		if err != nil {
+			t.Fatalf("%s: Validate failed: %v", name, err)
+		}
+		if !validation.Valid || len(validation.Warnings) != 0 {
+			t.Errorf("%s: Expected the canonical order to pass cleanly, got %+v", name, validation)
+		}
+
+		o := canonicalOrder()
+		o.VehicleType = "spaceship"
+		o.Pickup.ContactPhone = ""
+		validation, err = backend.Validate(context.Background(), o)
+		if err != nil {
+			t.Fatalf("%s: Validate failed: %v", name, err)
+		}
+		if validation.Valid || !hasField(validation.Errors, "vehicleTypeId") || !hasField(validation.Warnings, "pickupInfo.contactPhone") {
+			t.Errorf("%s: Expected the vehicle type error and phone warning, got %+v", name, validation)
+		}
+	}
+}
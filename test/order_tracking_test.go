@@ -0,0 +1,183 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	dispatchmcp "dispatch-mcp-server/internal/mcp"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// fastProgressionScenarios returns instant fixtures whose orders are quoted
+// for immediate delivery and move through every status within about a
+// second, arriving one second later than quoted
+func fastProgressionScenarios(t *testing.T) *dispatch.ScenarioSet {
+	t.Helper()
+
+	set := instantScenarios(t)
+	for i := range set.Tiers {
+		set.Tiers[i].LeadTime = 0
+	}
+	set.MinutesPerMile = 0
+	set.Progression = dispatch.ProgressionSpec{
+		Scheduled:      100 * time.Millisecond,
+		DriverAssigned: 200 * time.Millisecond,
+		PickedUp:       300 * time.Millisecond,
+		ETADriftMin:    time.Second,
+		ETADriftMax:    time.Second,
+		Drivers:        []string{"Alex Kim"},
+	}
+	return set
+}
+
+func TestMockOrderProgression(t *testing.T) {
+	mock := dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, fastProgressionScenarios(t))
+	ctx := context.Background()
+
+	created, err := mock.CreateOrder(ctx, recordedOrderInput())
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	trackingNumber := created.Data.CreateOrder.Order.TrackingNumber
+
+	var order dispatch.Order
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		listed, err := mock.ListOrders(ctx, dispatch.ListOrdersInput{TrackingNumber: &trackingNumber})
+		if err != nil {
+			t.Fatalf("ListOrders failed: %v", err)
+		}
+		if len(listed.Data.Orders) != 1 {
+			t.Fatalf("Expected the tracking number to match one order, got %d", len(listed.Data.Orders))
+		}
+		order = listed.Data.Orders[0]
+		if order.Status == dispatch.OrderStatusDelivered {
+			break
+		}
+	}
+	if order.Status != dispatch.OrderStatusDelivered {
+		t.Fatalf("Expected the order to be delivered, got %s", order.Status)
+	}
+
+	var statuses []string
+	for _, change := range order.StatusHistory {
+		statuses = append(statuses, change.Status)
+	}
+	expected := []string{dispatch.OrderStatusPending, dispatch.OrderStatusScheduled, dispatch.OrderStatusDriverAssigned, dispatch.OrderStatusPickedUp, dispatch.OrderStatusDelivered}
+	if len(statuses) != len(expected) {
+		t.Fatalf("Expected history %v, got %v", expected, statuses)
+	}
+	for i := range expected {
+		if statuses[i] != expected[i] {
+			t.Fatalf("Expected history %v, got %v", expected, statuses)
+		}
+	}
+	if order.Driver == nil || order.Driver.Name != "Alex Kim" || order.Tracking == nil {
+		t.Errorf("Expected a driver and tracking once delivered, got %+v and %+v", order.Driver, order.Tracking)
+	}
+
+	timeline := order.Timeline(order.EstimatedArrival)
+	if timeline[0].ETADriftSeconds != 0 || timeline[2].ETADrift != "+1s" || timeline[4].ETA != timeline[4].At {
+		t.Errorf("Expected the quote, a one second drift from driver assignment and delivery at the ETA, got %+v", timeline)
+	}
+}
+
+func TestTrackOrderToolReportsProgress(t *testing.T) {
+	server := serveHTTPTransport(t, newMCPServerWithScenarios(t, fastProgressionScenarios(t)), dispatchmcp.TransportSSE)
+	mcpClient, err := client.NewSSEMCPClient(server.URL+"/sse", transport.WithHeaders(map[string]string{"Authorization": "Bearer team-token"}))
+	if err != nil {
+		t.Fatalf("NewSSEMCPClient failed: %v", err)
+	}
+	initializeClient(t, mcpClient)
+
+	var mu sync.Mutex
+	var progress []float64
+	mcpClient.OnNotification(func(notification mcp.JSONRPCNotification) {
+		if notification.Method != "notifications/progress" || notification.Params.AdditionalFields["progressToken"] != "track-1" {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		value, _ := notification.Params.AdditionalFields["progress"].(float64)
+		progress = append(progress, value)
+	})
+
+	var created dispatch.CreateOrderResponse
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "create_order", orderArgs(t))), &created); err != nil {
+		t.Fatalf("Failed to parse order: %v", err)
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "track_order"
+	request.Params.Arguments = map[string]interface{}{
+		"tracking_number":       created.Data.CreateOrder.Order.TrackingNumber,
+		"poll_interval_seconds": 0.5,
+		"timeout_seconds":       10,
+	}
+	request.Params.Meta = &mcp.Meta{ProgressToken: "track-1"}
+	result, err := mcpClient.CallTool(context.Background(), request)
+	if err != nil {
+		t.Fatalf("track_order failed: %v", err)
+	}
+	text, _ := mcp.AsTextContent(result.Content[0])
+	if result.IsError {
+		t.Fatalf("track_order returned an error: %s", text.Text)
+	}
+
+	var tracked struct {
+		OrderID  string                   `json:"order_id"`
+		Status   string                   `json:"status"`
+		Final    bool                     `json:"final"`
+		TimedOut bool                     `json:"timed_out"`
+		ETADrift string                   `json:"eta_drift"`
+		Timeline []dispatch.TimelineEntry `json:"timeline"`
+	}
+	if err := json.Unmarshal([]byte(text.Text), &tracked); err != nil {
+		t.Fatalf("Failed to parse track_order: %v", err)
+	}
+	if tracked.OrderID != created.Data.CreateOrder.Order.ID || tracked.Status != dispatch.OrderStatusDelivered || !tracked.Final || tracked.TimedOut {
+		t.Errorf("Expected the order to be tracked to delivery, got %s", text.Text)
+	}
+	if len(tracked.Timeline) != 5 || tracked.ETADrift != "+1s" {
+		t.Errorf("Expected five timeline entries ending a second late, got %s", text.Text)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(progress) != 4 || progress[0] != 1 || progress[3] != 4 {
+		t.Errorf("Expected progress 1 through 4 for each status change, got %v", progress)
+	}
+}
+
+func TestTrackOrderToolTimesOut(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	var created dispatch.CreateOrderResponse
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "create_order", orderArgs(t))), &created); err != nil {
+		t.Fatalf("Failed to parse order: %v", err)
+	}
+
+	var tracked struct {
+		Status   string `json:"status"`
+		Final    bool   `json:"final"`
+		TimedOut bool   `json:"timed_out"`
+		Polls    int    `json:"polls"`
+	}
+	output := callTool(t, mcpClient, "track_order", map[string]interface{}{
+		"order_id":              created.Data.CreateOrder.Order.ID,
+		"timeout_seconds":       1,
+		"poll_interval_seconds": 0.5,
+	})
+	if err := json.Unmarshal([]byte(output), &tracked); err != nil {
+		t.Fatalf("Failed to parse track_order: %v", err)
+	}
+	if tracked.Status != dispatch.OrderStatusPending || tracked.Final || !tracked.TimedOut || tracked.Polls < 2 {
+		t.Errorf("Expected a pending order to time out after polling, got %s", output)
+	}
+}
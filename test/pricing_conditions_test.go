@@ -0,0 +1,94 @@
+package test
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
+	"dispatch-mcp-server/internal/pricing"
+	"testing"
+)
+
+func TestEligibilityConditions(t *testing.T) {
+	bulk := pricing.PricingRule{VolumeThreshold: 10}
+	tests := []struct {
+		condition string
+		rule      pricing.PricingRule
+		context   pricing.PricingContext
+		reason    string // empty when eligible
+	}{
+		{"", pricing.PricingRule{}, pricing.PricingContext{}, ""},
+		{"delivery_count >= 5 && order_frequency >= 3", pricing.PricingRule{}, pricing.PricingContext{DeliveryCount: 5, OrderFrequency: 3}, ""},
+		{"delivery_count >= 5 && order_frequency >= 3", pricing.PricingRule{}, pricing.PricingContext{DeliveryCount: 5, OrderFrequency: 2},
+			"Requires 3+ orders/month, you have 2 orders/month"},
+		{"delivery_count >= 5 && order_frequency >= 3", pricing.PricingRule{}, pricing.PricingContext{DeliveryCount: 1, OrderFrequency: 1},
+			"Requires 5+ deliveries and 3+ orders/month, you have 1 deliveries and 1 orders/month"},
+		{"customer_tier == 'gold' || total_order_value > 1000", pricing.PricingRule{}, pricing.PricingContext{CustomerTier: "silver", TotalOrderValue: money.Dollars(200)},
+			"Requires gold tier or more than $1000.00 order value, you have silver tier and $200.00 order value"},
+		{"customer_tier == 'gold' || total_order_value > 1000", pricing.PricingRule{}, pricing.PricingContext{CustomerTier: "silver", TotalOrderValue: money.Dollars(1500)}, ""},
+		{`customer_tier == "Gold"`, pricing.PricingRule{}, pricing.PricingContext{CustomerTier: "gold"}, ""},
+		{"customer_tier == loyalty_tier", pricing.PricingRule{LoyaltyTier: "gold"}, pricing.PricingContext{},
+			"Requires gold tier, you have no tier"},
+		{"delivery_count >= volume_threshold && is_bulk_order", bulk, pricing.PricingContext{DeliveryCount: 12},
+			"Requires bulk order, you have no bulk order"},
+		{"delivery_count >= volume_threshold && is_bulk_order", bulk, pricing.PricingContext{DeliveryCount: 12, IsBulkOrder: true}, ""},
+		{"!is_bulk_order", pricing.PricingRule{}, pricing.PricingContext{IsBulkOrder: true}, "Requires not a bulk order"},
+		{"3 <= delivery_count", pricing.PricingRule{}, pricing.PricingContext{DeliveryCount: 1}, "Requires 3+ deliveries, you have 1 deliveries"},
+		{"(delivery_count > 2 || order_frequency < 4) && customer_tier != 'bronze'", pricing.PricingRule{}, pricing.PricingContext{DeliveryCount: 1, OrderFrequency: 5, CustomerTier: "bronze"},
+			"Requires more than 2 deliveries or under 4 orders/month and tier other than bronze, you have 1 deliveries and 5 orders/month and bronze tier"},
+	}
+	for _, tt := range tests {
+		condition, err := pricing.ParseCondition(tt.condition)
+		if err != nil {
+			t.Errorf("%q: ParseCondition failed: %v", tt.condition, err)
+			continue
+		}
+		eligible, reason := condition.Eval(tt.rule, tt.context)
+		if eligible != (tt.reason == "") || reason != tt.reason {
+			t.Errorf("%q with %+v:\nwant eligible=%t %q\ngot  eligible=%t %q", tt.condition, tt.context, tt.reason == "", tt.reason, eligible, reason)
+		}
+	}
+
+	for _, invalid := range []string{
+		"delivery_count",
+		"delivery_count >= 'gold'",
+		"customer_tier > 'gold'",
+		"weight > 10",
+		"(delivery_count > 1",
+		"delivery_count > 1 &&",
+		"delivery_count > 1)",
+		"customer_tier == 'gold",
+		"delivery_count # 1",
+	} {
+		if _, err := pricing.ParseCondition(invalid); err == nil {
+			t.Errorf("%q: Expected the condition to be rejected", invalid)
+		}
+	}
+}
+
+func TestPricingModelDefinedOnlyInRules(t *testing.T) {
+	rules, err := pricing.ParseRules([]byte(`version: 1
+rules:
+  - model: standard
+    name: Standard Pricing
+    base_multiplier: 1
+  - model: partner
+    name: Partner Discount
+    base_multiplier: 0.8
+    eligibility: organization_druid == 'org_123' && delivery_count >= 2
+`))
+	if err != nil {
+		t.Fatalf("ParseRules failed: %v", err)
+	}
+	engine := pricing.NewPricingEngineWithRules(rules)
+	estimate := &dispatch.AvailableOrderOption{EstimatedOrderCost: money.Dollars(100)}
+
+	comparison := engine.ComparePricingModels(estimate, pricing.PricingContext{DeliveryCount: 2, OrganizationDruid: "org_123"})
+	if comparison.BestOption == nil || comparison.BestOption.Model != "partner" || comparison.BestOption.AdjustedCost.Cmp(money.Dollars(100)) >= 0 {
+		t.Errorf("Expected the partner discount to apply, got %+v", comparison.BestOption)
+	}
+
+	comparison = engine.ComparePricingModels(estimate, pricing.PricingContext{DeliveryCount: 2})
+	partner := comparison.PricingModels[1]
+	if partner.Eligible || partner.Reason != "Requires organization org_123, you have no organization" {
+		t.Errorf("Expected the organization requirement to be explained, got %+v", partner)
+	}
+}
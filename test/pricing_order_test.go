@@ -0,0 +1,138 @@
+package test
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
+	"dispatch-mcp-server/internal/pricing"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var orderingRules = []string{
+	"  - {model: standard, name: Standard, base_multiplier: 1}",
+	"  - {model: members, name: Members, base_multiplier: 0.5, eligibility: is_bulk_order}",
+	"  - {model: promo_b, name: Beta Promo, base_multiplier: 0.8, priority: 1}",
+	"  - {model: promo_a, name: Alpha Promo, base_multiplier: 0.8, priority: 1}",
+	"  - {model: seasonal, name: Seasonal, base_multiplier: 0.9, priority: 1}",
+	"  - {model: vip, name: VIP, base_multiplier: 0.6, priority: 1, eligibility: \"customer_tier == 'gold'\"}",
+	"  - {model: partner, name: Partner, base_multiplier: 0.7, priority: 2, eligibility: \"organization_druid == 'org_1'\"}",
+}
+
+func orderingEngine(t *testing.T, rules []string) *pricing.PricingEngine {
+	t.Helper()
+	parsed, err := pricing.ParseRules([]byte("version: 1\nrules:\n" + strings.Join(rules, "\n") + "\n"))
+	if err != nil {
+		t.Fatalf("ParseRules failed: %v", err)
+	}
+	return pricing.NewPricingEngineWithRules(parsed)
+}
+
+func resultModels(results []pricing.PricingResult) string {
+	models := make([]string, len(results))
+	for i, result := range results {
+		models[i] = string(result.Model)
+	}
+	return strings.Join(models, ",")
+}
+
+func TestPricingComparisonOrder(t *testing.T) {
+	estimate := &dispatch.AvailableOrderOption{EstimatedOrderCost: money.Dollars(100)}
+	engine := orderingEngine(t, orderingRules)
+
+	tests := []struct {
+		sortBy   pricing.SortOrder
+		expected string
+	}{
+		// Priority, then cost, then eligible first, then model
+		{"", "standard,members,promo_a,promo_b,seasonal,vip,partner"},
+		{pricing.SortByPriority, "standard,members,promo_a,promo_b,seasonal,vip,partner"},
+		{pricing.SortByCost, "promo_a,promo_b,seasonal,standard,members,vip,partner"},
+		{pricing.SortByName, "promo_a,promo_b,members,partner,seasonal,standard,vip"},
+	}
+	for _, tt := range tests {
+		comparison, err := engine.ComparePricingModelsWithOptions(estimate, pricing.PricingContext{}, pricing.CompareOptions{SortBy: tt.sortBy})
+		if err != nil {
+			t.Fatalf("%q: ComparePricingModelsWithOptions failed: %v", tt.sortBy, err)
+		}
+		if got := resultModels(comparison.PricingModels); got != tt.expected {
+			t.Errorf("%q: Expected %s, got %s", tt.sortBy, tt.expected, got)
+		}
+		if comparison.SortBy == "" || (tt.sortBy != "" && comparison.SortBy != tt.sortBy) {
+			t.Errorf("%q: Expected the sort order to be reported, got %q", tt.sortBy, comparison.SortBy)
+		}
+	}
+
+	if _, err := engine.ComparePricingModelsWithOptions(estimate, pricing.PricingContext{}, pricing.CompareOptions{SortBy: "random"}); err == nil || !strings.Contains(err.Error(), "sort_by") {
+		t.Errorf("Expected an unknown sort order to be rejected, got %v", err)
+	}
+}
+
+func TestPricingComparisonTieBreaks(t *testing.T) {
+	estimate := &dispatch.AvailableOrderOption{EstimatedOrderCost: money.Dollars(100)}
+	engine := orderingEngine(t, orderingRules)
+
+	// Both promotions cost $80 at the same priority, so the model name decides,
+	// and the exclusive discount agrees with the best option
+	comparison := engine.ComparePricingModels(estimate, pricing.PricingContext{})
+	if comparison.BestOption == nil || comparison.BestOption.Model != "promo_a" || comparison.Combined.LineItems[0].Model != "promo_a" {
+		t.Errorf("Expected promo_a to win the tie, got %+v", comparison.BestOption)
+	}
+	if comparison.BestOption != &comparison.PricingModels[2] {
+		t.Error("Expected the best option to point into the sorted results")
+	}
+
+	// A lower priority number wins a tie on cost
+	prioritized := append([]string{}, orderingRules...)
+	prioritized[2] = strings.Replace(prioritized[2], "priority: 1", "priority: 0", 1)
+	comparison = orderingEngine(t, prioritized).ComparePricingModels(estimate, pricing.PricingContext{})
+	if comparison.BestOption == nil || comparison.BestOption.Model != "promo_b" || comparison.Combined.LineItems[0].Model != "promo_b" {
+		t.Errorf("Expected the higher-priority promo_b to win the tie, got %+v", comparison.BestOption)
+	}
+
+	// Listing the rules in another order changes nothing
+	reversed := make([]string, len(orderingRules))
+	for i, rule := range orderingRules {
+		reversed[len(orderingRules)-1-i] = rule
+	}
+	context := pricing.PricingContext{CustomerTier: "gold", OrganizationDruid: "org_1"}
+	for _, policy := range pricing.StackingPolicies {
+		for _, sortBy := range pricing.SortOrders {
+			opts := pricing.CompareOptions{StackingPolicy: pricing.StackingPolicy(policy), SortBy: pricing.SortOrder(sortBy)}
+			original, _ := engine.ComparePricingModelsWithOptions(estimate, context, opts)
+			shuffled, _ := orderingEngine(t, reversed).ComparePricingModelsWithOptions(estimate, context, opts)
+			want, _ := json.Marshal(original)
+			got, _ := json.Marshal(shuffled)
+			if string(want) != string(got) {
+				t.Errorf("%s/%s: Expected the same comparison regardless of file order\nwant %s\n got %s", policy, sortBy, want, got)
+			}
+		}
+	}
+}
+
+func TestComparePricingModelsSortBy(t *testing.T) {
+	mcpClient := startMCPClient(t)
+	args := map[string]interface{}{
+		"original_estimate": map[string]interface{}{"serviceType": "standard", "estimatedOrderCost": 100},
+		"delivery_count":    2,
+		"customer_tier":     "gold",
+		"sort_by":           "cost",
+	}
+
+	output := callTool(t, mcpClient, "compare_pricing_models", args)
+	var comparison pricing.PricingComparison
+	if err := json.Unmarshal([]byte(output), &comparison); err != nil {
+		t.Fatalf("Failed to parse comparison: %v", err)
+	}
+	if comparison.SortBy != pricing.SortByCost || comparison.PricingModels[0].Model != comparison.BestOption.Model {
+		t.Errorf("Expected the cheapest option first, got %s", output)
+	}
+	for i := 1; i < len(comparison.PricingModels); i++ {
+		if comparison.PricingModels[i].AdjustedCost.Cmp(comparison.PricingModels[i-1].AdjustedCost) < 0 {
+			t.Errorf("Expected costs in ascending order, got %s", output)
+		}
+	}
+
+	args["sort_by"] = "random"
+	callToolError(t, mcpClient, "compare_pricing_models", args)
+}
@@ -0,0 +1,245 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	dispatchmcp "dispatch-mcp-server/internal/mcp"
+	"dispatch-mcp-server/internal/money"
+	"dispatch-mcp-server/internal/pricing"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const customRules = `version: 2
+rules:
+  - model: standard
+    name: Standard Pricing
+    base_multiplier: 1.0
+  - model: multi_delivery
+    name: Multi-Delivery Discount
+    description: Two or more stops
+    base_multiplier: 0.7
+    max_discount: 25
+    volume_threshold: 3
+    eligibility: delivery_count >= volume_threshold
+`
+
+func writeRules(t *testing.T, path, text string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatalf("Failed to write rules: %v", err)
+	}
+}
+
+func TestDefaultPricingRulesDescribeThemselves(t *testing.T) {
+	rules, err := pricing.DefaultRules()
+	if err != nil {
+		t.Fatalf("DefaultRules failed: %v", err)
+	}
+	if rules.Version != 1 || len(rules.Rules) != 5 {
+		t.Errorf("Expected version 1 with every model, got %+v", rules)
+	}
+
+	prompt := rules.Prompt()
+	for _, line := range []string{
+		"- **Multi-Delivery Discount**: 15% off for 2+ deliveries",
+		"- **Volume Discount**: 20% off for 5+ deliveries + 3+ orders/month",
+		"- **Loyalty Discount**: 10% off for gold tier",
+		"- **Bulk Order Discount**: 25% off for 10+ deliveries + bulk order",
+	} {
+		if !strings.Contains(prompt, line) {
+			t.Errorf("Expected the prompt to contain %q, got\n%s", line, prompt)
+		}
+	}
+
+	// The volume rule's frequency requirement comes from the file
+	comparison := pricing.NewPricingEngine().ComparePricingModels(&dispatch.AvailableOrderOption{EstimatedOrderCost: money.Dollars(100)},
+		pricing.PricingContext{DeliveryCount: 5, OrderFrequency: 2})
+	for _, result := range comparison.PricingModels {
+		if result.Model == pricing.VolumeDiscountPricing && (result.Eligible || !strings.Contains(result.Reason, "3+ orders/month")) {
+			t.Errorf("Expected 2 orders/month to miss the volume discount, got %+v", result)
+		}
+	}
+	if comparison.RulesVersion != 1 {
+		t.Errorf("Expected the comparison to record the rules version, got %d", comparison.RulesVersion)
+	}
+}
+
+func TestParsePricingRulesValidatesSchema(t *testing.T) {
+	rules, err := pricing.ParseRules([]byte(`{"version": 3, "rules": [{"model": "loyalty_discount", "name": "Loyalty", "base_multiplier": 0.9, "loyalty_tier": "silver"}]}`))
+	if err != nil || rules.Version != 3 || rules.Rules[0].LoyaltyTier != "silver" {
+		t.Fatalf("Expected JSON rules to parse, got %+v, %v", rules, err)
+	}
+
+	invalid := map[string]string{
+		"unknown field":      "version: 1\nrules:\n  - model: standard\n    name: Standard\n    base_multiplier: 1\n    discount: 5\n",
+		"missing version":    "rules:\n  - model: standard\n    name: Standard\n    base_multiplier: 1\n",
+		"no rules":           "version: 1\n",
+		"missing model":      "version: 1\nrules:\n  - name: Surge\n    base_multiplier: 1\n",
+		"bad eligibility":    "version: 1\nrules:\n  - {model: standard, name: A, base_multiplier: 1, eligibility: 'delivery_count >='}\n",
+		"duplicate model":    "version: 1\nrules:\n  - {model: standard, name: A, base_multiplier: 1}\n  - {model: standard, name: B, base_multiplier: 1}\n",
+		"markup":             "version: 1\nrules:\n  - {model: standard, name: A, base_multiplier: 1.2}\n",
+		"inverted discounts": "version: 1\nrules:\n  - {model: standard, name: A, base_multiplier: 1, min_discount: 20, max_discount: 10}\n",
+	}
+	for name, text := range invalid {
+		if _, err := pricing.ParseRules([]byte(text)); err == nil {
+			t.Errorf("%s: Expected the rules to be rejected", name)
+		}
+	}
+
+	// Every problem is reported at once
+	_, err = pricing.ParseRules([]byte("version: 0\nrules:\n  - {model: standard, base_multiplier: 0}\n"))
+	if err == nil || !strings.Contains(err.Error(), "version") || !strings.Contains(err.Error(), "name is required") || !strings.Contains(err.Error(), "base_multiplier") {
+		t.Errorf("Expected every problem to be reported, got %v", err)
+	}
+}
+
+func TestServerIsBuiltOnce(t *testing.T) {
+	mcpServer := newMCPServer(t)
+
+	// Every transport shares one protocol server, so the pricing rules
+	// listener isn't registered again for each
+	srv := mcpServer.Server()
+	if _, err := mcpServer.HTTPHandler(dispatchmcp.ServeOptions{Transport: dispatchmcp.TransportHTTP}); err != nil {
+		t.Fatalf("HTTPHandler failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if mcpServer.Server() != srv {
+			t.Fatal("Expected Server to return the same protocol server")
+		}
+	}
+
+	server := serveHTTPTransport(t, mcpServer, dispatchmcp.TransportSSE)
+	mcpClient, err := client.NewSSEMCPClient(server.URL+"/sse", transport.WithHeaders(map[string]string{"Authorization": "Bearer team-token"}))
+	if err != nil {
+		t.Fatalf("NewSSEMCPClient failed: %v", err)
+	}
+	initializeClient(t, mcpClient)
+	updated := make(chan string, 10)
+	mcpClient.OnNotification(func(notification mcp.JSONRPCNotification) {
+		if notification.Method == mcp.MethodNotificationResourceUpdated {
+			uri, _ := notification.Params.AdditionalFields["uri"].(string)
+			updated <- uri
+		}
+	})
+
+	rules, err := pricing.ParseRules([]byte(customRules))
+	if err != nil {
+		t.Fatalf("ParseRules failed: %v", err)
+	}
+	if err := mcpServer.PricingEngine().SetRules(rules); err != nil {
+		t.Fatalf("SetRules failed: %v", err)
+	}
+	select {
+	case uri := <-updated:
+		if uri != dispatchmcp.PricingRulesURI {
+			t.Errorf("Expected a pricing rules update, got %s", uri)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected clients to be told the pricing rules changed")
+	}
+}
+
+func TestPricingRulesReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	writeRules(t, path, customRules)
+	t.Setenv("DISPATCH_PRICING_RULES", path)
+
+	mcpServer := newMCPServer(t)
+	engine := mcpServer.PricingEngine()
+	if engine.Rules().Version != 2 || len(engine.GetAvailableModels()) != 2 {
+		t.Fatalf("Expected the rules file to be loaded, got %+v", engine.Rules())
+	}
+
+	server := serveHTTPTransport(t, mcpServer, dispatchmcp.TransportSSE)
+	mcpClient, err := client.NewSSEMCPClient(server.URL+"/sse", transport.WithHeaders(map[string]string{"Authorization": "Bearer team-token"}))
+	if err != nil {
+		t.Fatalf("NewSSEMCPClient failed: %v", err)
+	}
+	initializeClient(t, mcpClient)
+	updated := make(chan string, 10)
+	mcpClient.OnNotification(func(notification mcp.JSONRPCNotification) {
+		if notification.Method == mcp.MethodNotificationResourceUpdated {
+			uri, _ := notification.Params.AdditionalFields["uri"].(string)
+			updated <- uri
+		}
+	})
+
+	reloaded := make(chan int, 10)
+	engine.OnRulesChanged(func(rules *pricing.RuleSet) { reloaded <- rules.Version })
+	failures := make(chan error, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go engine.WatchRules(ctx, path, 10*time.Millisecond, func(err error) { failures <- err })
+
+	// A broken edit is reported and the rules in effect are kept
+	writeRules(t, path, strings.Replace(customRules, "base_multiplier: 0.7", "base_multiplier: 1.7", 1))
+	select {
+	case err := <-failures:
+		if !strings.Contains(err.Error(), "base_multiplier") {
+			t.Errorf("Expected the invalid multiplier to be reported, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the invalid rules file to be reported")
+	}
+	if engine.Rules().Version != 2 {
+		t.Errorf("Expected version 2 to stay in effect, got %d", engine.Rules().Version)
+	}
+
+	writeRules(t, path, strings.Replace(customRules, "version: 2", "version: 3\n# bulk pricing removed", 1))
+	select {
+	case version := <-reloaded:
+		if version != 3 {
+			t.Errorf("Expected version 3 to be loaded, got %d", version)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the edited rules file to be reloaded")
+	}
+	select {
+	case uri := <-updated:
+		if uri != dispatchmcp.PricingRulesURI {
+			t.Errorf("Expected a pricing rules update, got %s", uri)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected clients to be told the pricing rules changed")
+	}
+
+	var comparison pricing.PricingComparison
+	output := callTool(t, mcpClient, "compare_pricing_models", map[string]interface{}{
+		"original_estimate": map[string]interface{}{"serviceType": "standard", "estimatedOrderCost": 100},
+		"delivery_count":    3,
+	})
+	if err := json.Unmarshal([]byte(output), &comparison); err != nil {
+		t.Fatalf("Failed to parse comparison: %v", err)
+	}
+	if comparison.RulesVersion != 3 || comparison.BestOption == nil || comparison.BestOption.Model != pricing.MultiDeliveryPricing {
+		t.Errorf("Expected the reloaded rules to price the comparison, got %s", output)
+	}
+
+	os.Remove(path)
+	select {
+	case err := <-failures:
+		if !strings.Contains(err.Error(), "no such file") {
+			t.Errorf("Expected the missing file to be reported, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the removed rules file to be reported")
+	}
+	if engine.Rules().Version != 3 {
+		t.Errorf("Expected version 3 to stay in effect, got %d", engine.Rules().Version)
+	}
+
+	t.Setenv("DISPATCH_PRICING_RULES", filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := dispatchmcp.NewMCPServerWithClient(dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, instantScenarios(t))); err == nil {
+		t.Error("Expected a missing rules file to stop the server from starting")
+	}
+}
@@ -0,0 +1,172 @@
+package test
+
+import (
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/money"
+	"dispatch-mcp-server/internal/pricing"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const stackingRules = `version: 1
+stacking:
+  policy: additive
+  min_cost_percent: 0
+rules:
+  - {model: standard, name: Standard, base_multiplier: 1}
+  - {model: loyalty_discount, name: Loyalty, base_multiplier: 0.9, group: loyalty, eligibility: "customer_tier == 'gold'"}
+  - {model: multi_delivery, name: Multi, base_multiplier: 0.8, group: volume, eligibility: delivery_count >= 2}
+  - {model: bulk_order, name: Bulk, base_multiplier: 0.7, group: volume, eligibility: is_bulk_order}
+`
+
+func stackingEngine(t *testing.T, replace ...string) *pricing.PricingEngine {
+	t.Helper()
+	rules, err := pricing.ParseRules([]byte(strings.NewReplacer(replace...).Replace(stackingRules)))
+	if err != nil {
+		t.Fatalf("ParseRules failed: %v", err)
+	}
+	return pricing.NewPricingEngineWithRules(rules)
+}
+
+// checkLineItems verifies the breakdown adds up to the combined price to the cent
+func checkLineItems(t *testing.T, combined *pricing.CombinedPricing) {
+	t.Helper()
+	total := money.New(0, combined.OriginalCost.Currency)
+	cost := combined.OriginalCost
+	for _, item := range combined.LineItems {
+		total = total.Add(item.Amount)
+		cost = cost.Sub(item.Amount)
+		if cost != item.CostAfter {
+			t.Errorf("%s: Expected the running cost to be %s, got %s", item.Name, cost, item.CostAfter)
+		}
+	}
+	if total != combined.Discount || combined.OriginalCost.Sub(combined.Discount) != combined.AdjustedCost {
+		t.Errorf("Expected the line items to add up to the discount, got %+v", combined)
+	}
+}
+
+func TestDiscountStackingPolicies(t *testing.T) {
+	estimate := &dispatch.AvailableOrderOption{EstimatedOrderCost: money.Dollars(200)}
+	context := pricing.PricingContext{DeliveryCount: 3, CustomerTier: "gold", IsBulkOrder: true}
+
+	tests := []struct {
+		policy   pricing.StackingPolicy
+		adjusted float64
+		amounts  []float64 // line items in order, largest discount first, in dollars
+	}{
+		{pricing.StackExclusive, 140, []float64{60}},
+		{pricing.StackAdditive, 80, []float64{60, 40, 20}},
+		{pricing.StackMultiplicative, 100.8, []float64{60, 28, 11.2}},
+		{pricing.StackBestOfGroup, 126, []float64{60, 14}},
+	}
+	engine := stackingEngine(t)
+	for _, tt := range tests {
+		comparison, err := engine.ComparePricingModelsWithOptions(estimate, context, pricing.CompareOptions{StackingPolicy: tt.policy})
+		if err != nil {
+			t.Fatalf("%s: ComparePricingModelsWithOptions failed: %v", tt.policy, err)
+		}
+		combined := comparison.Combined
+		if comparison.StackingPolicy != tt.policy || combined.AdjustedCost != money.Dollars(tt.adjusted) || comparison.Savings != money.Dollars(200-tt.adjusted) {
+			t.Errorf("%s: Expected $%.2f, got %+v", tt.policy, tt.adjusted, combined)
+			continue
+		}
+		if len(combined.LineItems) != len(tt.amounts) {
+			t.Errorf("%s: Expected %d line items, got %+v", tt.policy, len(tt.amounts), combined.LineItems)
+			continue
+		}
+		for i, amount := range tt.amounts {
+			if item := combined.LineItems[i]; item.Kind != pricing.LineItemDiscount || item.Amount != money.Dollars(amount) {
+				t.Errorf("%s: Expected line item %d to take off $%.2f, got %+v", tt.policy, i, amount, item)
+			}
+		}
+		checkLineItems(t, combined)
+	}
+
+	// The rules' own policy applies unless one is requested
+	if comparison := engine.ComparePricingModels(estimate, context); comparison.StackingPolicy != pricing.StackAdditive || comparison.Combined.AdjustedCost != money.Dollars(80) {
+		t.Errorf("Expected the additive policy from the rules, got %+v", comparison.Combined)
+	}
+	if prompt := engine.Rules().Prompt(); !strings.Contains(prompt, "- **Combining discounts**: Eligible discounts add together") {
+		t.Errorf("Expected the prompt to explain stacking, got\n%s", prompt)
+	}
+	if _, err := engine.ComparePricingModelsWithOptions(estimate, context, pricing.CompareOptions{StackingPolicy: "greedy"}); err == nil {
+		t.Error("Expected an unknown policy to be rejected")
+	}
+
+	// Only eligible discounts stack
+	comparison := engine.ComparePricingModels(estimate, pricing.PricingContext{DeliveryCount: 1, CustomerTier: "gold"})
+	if len(comparison.Combined.LineItems) != 1 || comparison.Combined.LineItems[0].Model != pricing.LoyaltyDiscountPricing || comparison.Combined.AdjustedCost != money.Dollars(180) {
+		t.Errorf("Expected only the loyalty discount, got %+v", comparison.Combined)
+	}
+}
+
+func TestDiscountStackingCapAndFloor(t *testing.T) {
+	estimate := &dispatch.AvailableOrderOption{EstimatedOrderCost: money.Dollars(200)}
+	context := pricing.PricingContext{DeliveryCount: 3, CustomerTier: "gold", IsBulkOrder: true}
+
+	// The 60% additive discount is capped at 45%
+	combined := stackingEngine(t, "min_cost_percent: 0", "min_cost_percent: 0\n  max_discount: 45").ComparePricingModels(estimate, context).Combined
+	last := combined.LineItems[len(combined.LineItems)-1]
+	if combined.AdjustedCost != money.Dollars(110) || last.Kind != pricing.LineItemCap || last.Amount != money.Dollars(-30) {
+		t.Errorf("Expected the cap to give back $30, got %+v", combined)
+	}
+	checkLineItems(t, combined)
+
+	// The floor keeps at least 60% of the original cost
+	combined = stackingEngine(t, "min_cost_percent: 0", "min_cost_percent: 60").ComparePricingModels(estimate, context).Combined
+	last = combined.LineItems[len(combined.LineItems)-1]
+	if combined.AdjustedCost != money.Dollars(120) || last.Kind != pricing.LineItemFloor || last.Amount != money.Dollars(-40) {
+		t.Errorf("Expected the floor to give back $40, got %+v", combined)
+	}
+	checkLineItems(t, combined)
+
+	// A discount within both limits is left alone
+	combined = stackingEngine(t, "min_cost_percent: 0", "min_cost_percent: 30\n  max_discount: 65").ComparePricingModels(estimate, context).Combined
+	if combined.AdjustedCost != money.Dollars(80) || len(combined.LineItems) != 3 {
+		t.Errorf("Expected no cap or floor adjustments, got %+v", combined)
+	}
+
+	for name, replace := range map[string][]string{
+		"unknown policy": {"policy: additive", "policy: greedy"},
+		"cap over 100":   {"min_cost_percent: 0", "max_discount: 150"},
+		"negative floor": {"min_cost_percent: 0", "min_cost_percent: -5"},
+	} {
+		if _, err := pricing.ParseRules([]byte(strings.NewReplacer(replace...).Replace(stackingRules))); err == nil || !strings.Contains(err.Error(), "stacking.") {
+			t.Errorf("%s: Expected the stacking section to be rejected, got %v", name, err)
+		}
+	}
+}
+
+func TestComparePricingModelsStackingPolicy(t *testing.T) {
+	mcpClient := startMCPClient(t)
+	args := map[string]interface{}{
+		"original_estimate": map[string]interface{}{"serviceType": "standard", "estimatedOrderCost": 100},
+		"delivery_count":    2,
+		"customer_tier":     "gold",
+	}
+
+	// The built-in rules use the single best discount
+	var comparison pricing.PricingComparison
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "compare_pricing_models", args)), &comparison); err != nil {
+		t.Fatalf("Failed to parse comparison: %v", err)
+	}
+	if comparison.StackingPolicy != pricing.StackExclusive || len(comparison.Combined.LineItems) != 1 || comparison.Combined.AdjustedCost != comparison.BestOption.AdjustedCost {
+		t.Errorf("Expected the best single discount, got %+v", comparison.Combined)
+	}
+
+	// Loyalty and multi-delivery are in different groups, so both apply
+	args["stacking_policy"] = "best_of_group"
+	output := callTool(t, mcpClient, "compare_pricing_models", args)
+	comparison = pricing.PricingComparison{}
+	if err := json.Unmarshal([]byte(output), &comparison); err != nil {
+		t.Fatalf("Failed to parse comparison: %v", err)
+	}
+	if comparison.StackingPolicy != pricing.StackBestOfGroup || len(comparison.Combined.LineItems) != 2 || comparison.Savings.Cmp(money.Dollars(100).Sub(comparison.BestOption.AdjustedCost)) <= 0 {
+		t.Errorf("Expected loyalty and multi-delivery to stack, got %s", output)
+	}
+	checkLineItems(t, comparison.Combined)
+
+	args["stacking_policy"] = "greedy"
+	callToolError(t, mcpClient, "compare_pricing_models", args)
+}
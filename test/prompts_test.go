@@ -0,0 +1,156 @@
+package test
+
+import (
+	"context"
+	dispatchmcp "dispatch-mcp-server/internal/mcp"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// getPrompt expands prompt name and returns its single message's text
+func getPrompt(t *testing.T, mcpClient *client.Client, name string, args map[string]string) (string, error) {
+	t.Helper()
+
+	request := mcp.GetPromptRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	result, err := mcpClient.GetPrompt(context.Background(), request)
+	if err != nil {
+		return "", err
+	}
+	if len(result.Messages) != 1 {
+		t.Fatalf("Expected one message from %s, got %d", name, len(result.Messages))
+	}
+	text, ok := mcp.AsTextContent(result.Messages[0].Content)
+	if !ok {
+		t.Fatalf("Expected text content from %s, got %T", name, result.Messages[0].Content)
+	}
+	return text.Text, nil
+}
+
+func TestPromptsAreListed(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	prompts, err := mcpClient.ListPrompts(context.Background(), mcp.ListPromptsRequest{})
+	if err != nil {
+		t.Fatalf("ListPrompts failed: %v", err)
+	}
+	required := map[string][]string{}
+	for _, prompt := range prompts.Prompts {
+		required[prompt.Name] = nil
+		for _, argument := range prompt.Arguments {
+			if argument.Required {
+				required[prompt.Name] = append(required[prompt.Name], argument.Name)
+			}
+		}
+	}
+
+	expected := map[string]int{
+		dispatchmcp.QuoteMultiStopPrompt:   2,
+		dispatchmcp.CheapestTomorrowPrompt: 2,
+		dispatchmcp.AuditSpendPrompt:       0,
+	}
+	for name, count := range expected {
+		arguments, ok := required[name]
+		if !ok {
+			t.Errorf("Expected prompt %s to be listed", name)
+		} else if len(arguments) != count {
+			t.Errorf("Expected %s to have %d required arguments, got %v", name, count, arguments)
+		}
+	}
+}
+
+func TestPromptsExpandIntoToolInstructions(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	text, err := getPrompt(t, mcpClient, dispatchmcp.QuoteMultiStopPrompt, map[string]string{
+		"pickup_address":     "123 Market St, San Francisco, CA 94105",
+		"drop_off_addresses": "456 Oak Ave, Oakland, CA 94610; 789 Pine St, Berkeley, CA 94704\n1 Main St, Alameda, CA 94501",
+		"customer_tier":      "Gold",
+		"orders_per_month":   "4",
+	})
+	if err != nil {
+		t.Fatalf("GetPrompt failed: %v", err)
+	}
+	for _, want := range []string{"create_estimate", "compare_pricing_models", "delivery_count 3", `customer_tier "gold"`, "order_frequency 4", "1 Main St, Alameda", dispatchmcp.VehicleTypesURI} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the multi-stop quote to mention %q, got:\n%s", want, text)
+		}
+	}
+
+	text, err = getPrompt(t, mcpClient, dispatchmcp.CheapestTomorrowPrompt, map[string]string{
+		"pickup_address":   "123 Market St, San Francisco, CA 94105",
+		"drop_off_address": "456 Oak Ave, Oakland, CA 94610",
+		"vehicle_type":     "cargo_van",
+		"deliver_by":       "17:00",
+	})
+	if err != nil {
+		t.Fatalf("GetPrompt failed: %v", err)
+	}
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	deadline := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 17, 0, 0, 0, time.Local)
+	zone, _ := deadline.Zone()
+	for _, want := range []string{"create_estimate", `delivery_scenario "deliver_by"`, fmt.Sprintf("deliver_by %q", deadline.Format(time.RFC3339)), "the " + zone + " time zone", `vehicle_type "cargo_van"`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the cheapest-tomorrow prompt to mention %q, got:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "local time") {
+		t.Errorf("Expected the deadline to be checked by select_delivery_option, got:\n%s", text)
+	}
+
+	// Without deliver_by the deadline is the end of tomorrow
+	text, err = getPrompt(t, mcpClient, dispatchmcp.CheapestTomorrowPrompt, map[string]string{
+		"pickup_address":   "123 Market St, San Francisco, CA 94105",
+		"drop_off_address": "456 Oak Ave, Oakland, CA 94610",
+	})
+	if err != nil {
+		t.Fatalf("GetPrompt failed: %v", err)
+	}
+	endOfDay := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 23, 59, 59, 0, time.Local)
+	if want := fmt.Sprintf("deliver_by %q", endOfDay.Format(time.RFC3339)); !strings.Contains(text, want) {
+		t.Errorf("Expected the cheapest-tomorrow prompt to mention %q, got:\n%s", want, text)
+	}
+
+	text, err = getPrompt(t, mcpClient, dispatchmcp.AuditSpendPrompt, map[string]string{"month": "2024-02", "customer_id": "cust-42"})
+	if err != nil {
+		t.Fatalf("GetPrompt failed: %v", err)
+	}
+	for _, want := range []string{"analyze_historical_savings", `start_date "2024-02-01"`, `end_date "2024-02-29"`, `customer_id "cust-42"`, "February 2024"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the audit prompt to mention %q, got:\n%s", want, text)
+		}
+	}
+}
+
+func TestPromptsRejectInvalidArguments(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	invalid := map[string]map[string]string{
+		dispatchmcp.QuoteMultiStopPrompt: {
+			"pickup_address":     "123 Market St",
+			"drop_off_addresses": "456 Oak Ave",
+			"vehicle_type":       "spaceship",
+		},
+		dispatchmcp.CheapestTomorrowPrompt: {
+			"pickup_address":   "123 Market St",
+			"drop_off_address": "456 Oak Ave",
+			"deliver_by":       "5pm",
+		},
+		dispatchmcp.AuditSpendPrompt: {"month": "last month"},
+	}
+	for name, args := range invalid {
+		if _, err := getPrompt(t, mcpClient, name, args); err == nil {
+			t.Errorf("Expected %s to reject %v", name, args)
+		}
+	}
+
+	if _, err := getPrompt(t, mcpClient, dispatchmcp.QuoteMultiStopPrompt, map[string]string{"pickup_address": "123 Market St"}); err == nil {
+		t.Error("Expected a missing drop_off_addresses argument to be rejected")
+	}
+}
@@ -0,0 +1,251 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	"dispatch-mcp-server/internal/graphql"
+	"dispatch-mcp-server/internal/resilience"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const estimateResponse = `{"data":{"createEstimate":{"estimate":{"availableOrderOptions":[{"serviceType":"standard","vehicleType":"cargo_van","estimatedOrderCost":45.99}]}}}}`
+
+func retryConfig(endpoint string) *config.Config {
+	return &config.Config{
+		GraphQLEndpoint:         endpoint,
+		AuthToken:               "token",
+		RetryMaxAttempts:        3,
+		RetryBaseDelay:          time.Millisecond,
+		RetryMaxDelay:           5 * time.Millisecond,
+		BreakerFailureThreshold: 2,
+		BreakerCooldown:         50 * time.Millisecond,
+	}
+}
+
+func TestClientRetriesTransientFailures(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(estimateResponse))
+	}))
+	defer server.Close()
+
+	client, err := dispatch.NewClientWithConfig(retryConfig(server.URL))
+	if err != nil {
+		t.Fatalf("NewClientWithConfig failed: %v", err)
+	}
+
+	if _, err := client.CreateEstimate(context.Background(), dispatch.CreateEstimateInput{}); err != nil {
+		t.Fatalf("Expected estimate to succeed after retry, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 attempts, got %d", calls)
+	}
+}
+
+func TestClientDoesNotRetryValidationErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"errors":[{"message":"Invalid zip code"}]}`))
+	}))
+	defer server.Close()
+
+	client, _ := dispatch.NewClientWithConfig(retryConfig(server.URL))
+	if _, err := client.CreateEstimate(context.Background(), dispatch.CreateEstimateInput{}); !dispatch.IsKind(err, dispatch.ErrorKindValidation) {
+		t.Fatalf("Expected validation error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single attempt, got %d", calls)
+	}
+}
+
+func TestRetryAfterIsCappedAtMaxDelay(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"data":{}}`))
+	}))
+	defer server.Close()
+
+	// An hour-long Retry-After is cut to the 5ms RetryMaxDelay
+	client := graphql.NewGraphQLClientWithConfig(server.URL, retryConfig(server.URL))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Execute(ctx, `query { ping }`, nil); err != nil {
+		t.Fatalf("Expected query to succeed after a capped wait, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 attempts, got %d", calls)
+	}
+}
+
+func TestCreateOrderRetriesOnlyWithIdempotencyKey(t *testing.T) {
+	var calls int32
+	var lastKey atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		lastKey.Store(r.Header.Get("Idempotency-Key"))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := retryConfig(server.URL)
+	cfg.BreakerFailureThreshold = 0
+	client, _ := dispatch.NewClientWithConfig(cfg)
+
+	if _, err := client.CreateOrder(context.Background(), dispatch.CreateOrderInput{}); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("Expected order without idempotency key to be attempted once, got %d", calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	if _, err := client.CreateOrder(context.Background(), dispatch.CreateOrderInput{IdempotencyKey: "order-123"}); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if calls != 3 {
+		t.Errorf("Expected order with idempotency key to be attempted 3 times, got %d", calls)
+	}
+	if key := lastKey.Load(); key != "order-123" {
+		t.Errorf("Expected Idempotency-Key header order-123, got %v", key)
+	}
+}
+
+func TestOrderChangesRetryOnlyWhenNotSent(t *testing.T) {
+	var calls int32
+	var status atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	cfg := retryConfig(server.URL)
+	cfg.BreakerFailureThreshold = 0
+	client, _ := dispatch.NewClientWithConfig(cfg)
+
+	changes := map[string]func() error{
+		"update": func() error {
+			_, err := client.UpdateOrder(context.Background(), dispatch.UpdateOrderInput{OrderID: "ord_1"})
+			return err
+		},
+		"cancel": func() error {
+			_, err := client.CancelOrder(context.Background(), dispatch.CancelOrderInput{OrderID: "ord_1"})
+			return err
+		},
+	}
+	tests := []struct {
+		status   int
+		attempts int32
+	}{
+		{http.StatusTooManyRequests, 3},    // turned away before processing
+		{http.StatusServiceUnavailable, 3}, // turned away before processing
+		{http.StatusBadGateway, 1},         // may have been applied
+		{http.StatusInternalServerError, 1},
+	}
+	for name, change := range changes {
+		for _, tt := range tests {
+			status.Store(int32(tt.status))
+			atomic.StoreInt32(&calls, 0)
+			if err := change(); err == nil {
+				t.Fatalf("%s: Expected error, got nil", name)
+			}
+			if calls != tt.attempts {
+				t.Errorf("%s after %d: Expected %d attempts, got %d", name, tt.status, tt.attempts, calls)
+			}
+		}
+	}
+
+	// A refused connection never reached the API
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	listener.Close()
+	refused, _ := dispatch.NewClientWithConfig(retryConfig("http://" + listener.Addr().String()))
+	_, err = refused.UpdateOrder(context.Background(), dispatch.UpdateOrderInput{OrderID: "ord_1"})
+	if !dispatch.NotSent(err) {
+		t.Errorf("Expected a refused connection to count as not sent, got %v", err)
+	}
+}
+
+func TestCircuitBreakerTripsPerEndpoint(t *testing.T) {
+	var calls int32
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(estimateResponse))
+	}))
+	defer server.Close()
+
+	cfg := retryConfig(server.URL)
+	cfg.RetryMaxAttempts = 1
+	client, _ := dispatch.NewClientWithConfig(cfg)
+
+	for i := 0; i < 2; i++ {
+		client.CreateEstimate(context.Background(), dispatch.CreateEstimateInput{})
+	}
+
+	_, err := client.CreateEstimate(context.Background(), dispatch.CreateEstimateInput{})
+	if !errors.Is(err, resilience.ErrCircuitOpen) {
+		t.Fatalf("Expected circuit open error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected open breaker to skip the endpoint, got %d calls", calls)
+	}
+	if status := findBreaker(server.URL); status == nil || status.State != resilience.StateOpen {
+		t.Errorf("Expected breaker for %s to be reported open, got %+v", server.URL, status)
+	}
+
+	// After the cooldown a successful probe closes the breaker again
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.CreateEstimate(context.Background(), dispatch.CreateEstimateInput{}); err != nil {
+		t.Fatalf("Expected probe to succeed, got %v", err)
+	}
+	if status := findBreaker(server.URL); status == nil || status.State != resilience.StateClosed {
+		t.Errorf("Expected breaker to close after probe, got %+v", status)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := resilience.ParseRetryAfter("2"); d != 2*time.Second {
+		t.Errorf("Expected 2s, got %v", d)
+	}
+	if d := resilience.ParseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); d <= 0 || d > time.Minute {
+		t.Errorf("Expected HTTP date within a minute, got %v", d)
+	}
+	if d := resilience.ParseRetryAfter("soon"); d != 0 {
+		t.Errorf("Expected invalid header to be ignored, got %v", d)
+	}
+}
+
+func findBreaker(endpoint string) *resilience.BreakerStatus {
+	for _, status := range resilience.DefaultRegistry.Snapshot() {
+		if status.Endpoint == endpoint {
+			return &status
+		}
+	}
+	return nil
+}
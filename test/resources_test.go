@@ -0,0 +1,186 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/dispatch"
+	dispatchmcp "dispatch-mcp-server/internal/mcp"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// estimateArgs returns create_estimate arguments for a one-stop Bay Area route
+func estimateArgs() map[string]interface{} {
+	return map[string]interface{}{
+		"pickup_info": map[string]interface{}{
+			"business_name": "Warehouse",
+			"location": map[string]interface{}{
+				"address": map[string]interface{}{"street": "123 Market St", "city": "San Francisco", "state": "CA", "zip_code": "94105", "country": "US"},
+			},
+		},
+		"drop_offs": []interface{}{map[string]interface{}{
+			"business_name": "Store",
+			"location": map[string]interface{}{
+				"address": map[string]interface{}{"street": "456 Oak Ave", "city": "Oakland", "state": "CA", "zip_code": "94610", "country": "US"},
+			},
+		}},
+		"vehicle_type": "cargo_van",
+	}
+}
+
+// readResource reads uri and returns its text
+func readResource(t *testing.T, mcpClient *client.Client, uri string) (string, error) {
+	t.Helper()
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = uri
+	result, err := mcpClient.ReadResource(context.Background(), request)
+	if err != nil {
+		return "", err
+	}
+	if len(result.Contents) != 1 {
+		t.Fatalf("Expected one content item for %s, got %d", uri, len(result.Contents))
+	}
+	text, ok := result.Contents[0].(mcp.TextResourceContents)
+	if !ok {
+		t.Fatalf("Expected text contents for %s, got %T", uri, result.Contents[0])
+	}
+	return text.Text, nil
+}
+
+func TestReferenceDataResources(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	resources, err := mcpClient.ListResources(context.Background(), mcp.ListResourcesRequest{})
+	if err != nil {
+		t.Fatalf("ListResources failed: %v", err)
+	}
+	listed := map[string]bool{}
+	for _, resource := range resources.Resources {
+		listed[resource.URI] = true
+	}
+	for _, uri := range []string{dispatchmcp.VehicleTypesURI, dispatchmcp.CapabilitiesURI, dispatchmcp.PricingRulesURI, dispatchmcp.EstimatesURI} {
+		if !listed[uri] {
+			t.Errorf("Expected %s to be listed, got %v", uri, listed)
+		}
+	}
+
+	text, err := readResource(t, mcpClient, dispatchmcp.VehicleTypesURI)
+	if err != nil {
+		t.Fatalf("Reading vehicle types failed: %v", err)
+	}
+	var vehicles []dispatch.VehicleInfo
+	if err := json.Unmarshal([]byte(text), &vehicles); err != nil {
+		t.Fatalf("Failed to parse vehicle types: %v", err)
+	}
+	if len(vehicles) != 4 || vehicles[0].Name == "" || len(vehicles[0].Capabilities) == 0 {
+		t.Errorf("Expected the four create_estimate vehicle types with details, got %+v", vehicles)
+	}
+
+	text, err = readResource(t, mcpClient, dispatchmcp.CapabilitiesURI)
+	if err != nil {
+		t.Fatalf("Reading capabilities failed: %v", err)
+	}
+	if !strings.Contains(text, "white_glove_service") {
+		t.Errorf("Expected capabilities to include white_glove_service, got %s", text)
+	}
+
+	text, err = readResource(t, mcpClient, dispatchmcp.PricingRulesURI)
+	if err != nil {
+		t.Fatalf("Reading pricing rules failed: %v", err)
+	}
+	var rules []struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal([]byte(text), &rules); err != nil {
+		t.Fatalf("Failed to parse pricing rules: %v", err)
+	}
+	if len(rules) != 5 {
+		t.Errorf("Expected five pricing rules, got %s", text)
+	}
+	// Repeated reads return the same document
+	if again, _ := readResource(t, mcpClient, dispatchmcp.PricingRulesURI); again != text {
+		t.Error("Expected pricing rules to be listed in a stable order")
+	}
+}
+
+func TestSavedEstimateResources(t *testing.T) {
+	server := startHTTPTransport(t, dispatchmcp.TransportSSE)
+
+	connect := func() *client.Client {
+		mcpClient, err := client.NewSSEMCPClient(server.URL+"/sse", transport.WithHeaders(map[string]string{"Authorization": "Bearer team-token"}))
+		if err != nil {
+			t.Fatalf("NewSSEMCPClient failed: %v", err)
+		}
+		initializeClient(t, mcpClient)
+		return mcpClient
+	}
+	mcpClient, other := connect(), connect()
+
+	updated := make(chan string, 10)
+	mcpClient.OnNotification(func(notification mcp.JSONRPCNotification) {
+		if notification.Method == mcp.MethodNotificationResourceUpdated {
+			uri, _ := notification.Params.AdditionalFields["uri"].(string)
+			updated <- uri
+		}
+	})
+
+	var estimate struct {
+		dispatch.CreateEstimateResponse
+		EstimateID  string `json:"estimate_id"`
+		EstimateURI string `json:"estimate_uri"`
+	}
+	if err := json.Unmarshal([]byte(callTool(t, mcpClient, "create_estimate", estimateArgs())), &estimate); err != nil {
+		t.Fatalf("Failed to parse estimate: %v", err)
+	}
+	if estimate.EstimateID == "" || estimate.EstimateURI != dispatchmcp.EstimateURI(estimate.EstimateID) {
+		t.Fatalf("Expected the estimate to be saved, got id=%q uri=%q", estimate.EstimateID, estimate.EstimateURI)
+	}
+
+	select {
+	case uri := <-updated:
+		if uri != dispatchmcp.EstimatesURI {
+			t.Errorf("Expected an update for %s, got %s", dispatchmcp.EstimatesURI, uri)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected a resource updated notification after create_estimate")
+	}
+
+	text, err := readResource(t, mcpClient, estimate.EstimateURI)
+	if err != nil {
+		t.Fatalf("Reading the saved estimate failed: %v", err)
+	}
+	var saved struct {
+		ID       string                           `json:"id"`
+		Input    dispatch.CreateEstimateInput     `json:"input"`
+		Response *dispatch.CreateEstimateResponse `json:"response"`
+	}
+	if err := json.Unmarshal([]byte(text), &saved); err != nil {
+		t.Fatalf("Failed to parse saved estimate: %v", err)
+	}
+	options := saved.Response.Data.CreateEstimate.Estimate.AvailableOrderOptions
+	if saved.ID != estimate.EstimateID || saved.Input.VehicleType != "cargo_van" || len(options) != len(estimate.Data.CreateEstimate.Estimate.AvailableOrderOptions) {
+		t.Errorf("Unexpected saved estimate %s", text)
+	}
+
+	text, err = readResource(t, mcpClient, dispatchmcp.EstimatesURI)
+	if err != nil {
+		t.Fatalf("Reading saved estimates failed: %v", err)
+	}
+	if !strings.Contains(text, estimate.EstimateID) {
+		t.Errorf("Expected %s in the saved estimate list, got %s", estimate.EstimateID, text)
+	}
+
+	// Another session sees neither the estimate nor the list entry
+	if _, err := readResource(t, other, estimate.EstimateURI); err == nil {
+		t.Error("Expected another session to be unable to read the estimate")
+	}
+	if text, _ := readResource(t, other, dispatchmcp.EstimatesURI); strings.Contains(text, estimate.EstimateID) {
+		t.Errorf("Expected another session's list to be empty, got %s", text)
+	}
+}
@@ -0,0 +1,273 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/config"
+	"dispatch-mcp-server/internal/dispatch"
+	dispatchmcp "dispatch-mcp-server/internal/mcp"
+	"dispatch-mcp-server/internal/money"
+	"dispatch-mcp-server/internal/pricing"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// newMCPServer creates an MCP server backed by the scenario mock
+func newMCPServer(t *testing.T) *dispatchmcp.MCPServer {
+	t.Helper()
+	return newMCPServerWithScenarios(t, instantScenarios(t))
+}
+
+// newMCPServerWithScenarios creates an MCP server backed by a mock driven by
+// scenarios
+func newMCPServerWithScenarios(t *testing.T, scenarios *dispatch.ScenarioSet) *dispatchmcp.MCPServer {
+	t.Helper()
+	t.Setenv("USE_AI_HUB", "false")
+
+	server, err := dispatchmcp.NewMCPServerWithClient(dispatch.NewMockClientWithScenarios(&config.Config{MockSeed: 1}, scenarios))
+	if err != nil {
+		t.Fatalf("NewMCPServerWithClient failed: %v", err)
+	}
+	return server
+}
+
+// startMCPClient connects an in-process MCP client to a server backed by the
+// scenario mock
+func startMCPClient(t *testing.T) *client.Client {
+	t.Helper()
+
+	mcpClient, err := client.NewInProcessClient(newMCPServer(t).Server())
+	if err != nil {
+		t.Fatalf("NewInProcessClient failed: %v", err)
+	}
+	initializeClient(t, mcpClient)
+	return mcpClient
+}
+
+// initializeClient starts mcpClient and performs the MCP handshake
+func initializeClient(t *testing.T, mcpClient *client.Client) {
+	t.Helper()
+	t.Cleanup(func() { mcpClient.Close() })
+
+	ctx := context.Background()
+	if err := mcpClient.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{Name: "test", Version: "1.0.0"}
+	if _, err := mcpClient.Initialize(ctx, initRequest); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+}
+
+// callTool calls a tool and returns its text output, failing on tool errors
+func callTool(t *testing.T, mcpClient *client.Client, name string, args map[string]interface{}) string {
+	t.Helper()
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	result, err := mcpClient.CallTool(context.Background(), request)
+	if err != nil {
+		t.Fatalf("%s failed: %v", name, err)
+	}
+
+	text := ""
+	if len(result.Content) > 0 {
+		if content, ok := mcp.AsTextContent(result.Content[0]); ok {
+			text = content.Text
+		}
+	}
+	if result.IsError {
+		t.Fatalf("%s returned an error: %s", name, text)
+	}
+	return text
+}
+
+// pricingModels indexes a compare_pricing_models result by model
+func pricingModels(t *testing.T, output string) map[pricing.PricingModel]pricing.PricingResult {
+	t.Helper()
+
+	var comparison pricing.PricingComparison
+	if err := json.Unmarshal([]byte(output), &comparison); err != nil {
+		t.Fatalf("Failed to parse comparison: %v", err)
+	}
+	models := map[pricing.PricingModel]pricing.PricingResult{}
+	for _, result := range comparison.PricingModels {
+		models[result.Model] = result
+	}
+	return models
+}
+
+func TestToolSchemasAreTyped(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	tools, err := mcpClient.ListTools(context.Background(), mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	schemas := map[string]map[string]interface{}{}
+	for _, tool := range tools.Tools {
+		schemas[tool.Name] = tool.InputSchema.Properties
+	}
+
+	property := func(tool, name string) map[string]interface{} {
+		schema, _ := schemas[tool][name].(map[string]interface{})
+		if schema == nil {
+			t.Fatalf("%s has no %s argument", tool, name)
+		}
+		return schema
+	}
+
+	expectedTypes := map[string]map[string]string{
+		"create_estimate":        {"pickup_info": "object", "drop_offs": "array", "add_ons": "array", "dedicated_vehicle": "boolean"},
+		"create_order":           {"delivery_info": "object", "pickup_info": "object", "drop_offs": "array", "tags": "array"},
+		"compare_pricing_models": {"original_estimate": "object", "delivery_count": "number", "is_bulk_order": "boolean"},
+		"select_delivery_option": {"estimate_response": "object"},
+	}
+	for tool, arguments := range expectedTypes {
+		for name, want := range arguments {
+			if got := property(tool, name)["type"]; got != want {
+				t.Errorf("Expected %s.%s to be %s, got %v", tool, name, want, got)
+			}
+		}
+	}
+
+	// Nested schemas come from the dispatch input structs
+	location := property("create_estimate", "pickup_info")["properties"].(map[string]interface{})["location"].(map[string]interface{})
+	address := location["properties"].(map[string]interface{})["address"].(map[string]interface{})
+	if _, ok := address["properties"].(map[string]interface{})["zip_code"]; !ok {
+		t.Errorf("Expected pickup_info.location.address to describe zip_code, got %v", address)
+	}
+	if required, _ := property("create_estimate", "pickup_info")["required"].([]interface{}); len(required) != 2 {
+		t.Errorf("Expected business_name and location to be required, got %v", required)
+	}
+
+	if enum, _ := property("create_estimate", "vehicle_type")["enum"].([]interface{}); len(enum) != 4 {
+		t.Errorf("Expected vehicle_type enum, got %v", property("create_estimate", "vehicle_type"))
+	}
+	if enum, _ := property("select_delivery_option", "delivery_scenario")["enum"].([]interface{}); len(enum) == 0 {
+		t.Errorf("Expected delivery_scenario enum, got %v", property("select_delivery_option", "delivery_scenario"))
+	}
+}
+
+func TestMoneySchemasMatchTheWireFormat(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	tools, err := mcpClient.ListTools(context.Background(), mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	schemas := map[string]map[string]interface{}{}
+	for _, tool := range tools.Tools {
+		schemas[tool.Name] = tool.InputSchema.Properties
+	}
+	properties := func(schema interface{}) map[string]interface{} {
+		t.Helper()
+		object, _ := schema.(map[string]interface{})
+		props, _ := object["properties"].(map[string]interface{})
+		if props == nil {
+			t.Fatalf("Expected an object schema, got %v", schema)
+		}
+		return props
+	}
+
+	// Money is a JSON number and money.Quoted a string
+	option := properties(schemas["compare_pricing_models"]["original_estimate"])
+	if got := option["estimatedOrderCost"].(map[string]interface{})["type"]; got != "number" {
+		t.Errorf("Expected original_estimate.estimatedOrderCost to be a number, got %v", option["estimatedOrderCost"])
+	}
+	response := properties(schemas["select_delivery_option"]["estimate_response"])
+	estimate := properties(properties(properties(response["data"])["createEstimate"])["estimate"])
+	option = properties(estimate["availableOrderOptions"].(map[string]interface{})["items"])
+	if got := option["estimatedOrderCost"].(map[string]interface{})["type"]; got != "number" {
+		t.Errorf("Expected estimate_response option costs to be numbers, got %v", option["estimatedOrderCost"])
+	}
+	info := properties(option["estimateInfo"])
+	for _, name := range []string{"tollAmount", "estimatedOrderCost", "dedicatedVehicleFee"} {
+		if got := info[name].(map[string]interface{})["type"]; got != "string" {
+			t.Errorf("Expected estimateInfo.%s to be a string, got %v", name, info[name])
+		}
+	}
+
+	// A call that follows the schema is accepted
+	output := callTool(t, mcpClient, "compare_pricing_models", map[string]interface{}{
+		"original_estimate": map[string]interface{}{
+			"serviceType":        "standard",
+			"estimatedOrderCost": 45.99,
+			"estimateInfo":       map[string]interface{}{"tollAmount": "3.50", "estimatedOrderCost": "45.99", "dedicatedVehicleFee": "0.00"},
+		},
+	})
+	if standard := pricingModels(t, output)[pricing.StandardPricing]; standard.AdjustedCost != money.Dollars(45.99) {
+		t.Errorf("Expected the $45.99 estimate to be priced, got %s", output)
+	}
+}
+
+func TestToolsAcceptTypedAndLegacyArguments(t *testing.T) {
+	mcpClient := startMCPClient(t)
+
+	pickupInfo := map[string]interface{}{
+		"business_name": "Warehouse",
+		"location": map[string]interface{}{
+			"address": map[string]interface{}{"street": "123 Market St", "city": "San Francisco", "state": "CA", "zip_code": "94105", "country": "US"},
+		},
+	}
+	dropOffs := []interface{}{map[string]interface{}{
+		"business_name": "Store",
+		"location": map[string]interface{}{
+			"address": map[string]interface{}{"street": "456 Oak Ave", "city": "Oakland", "state": "CA", "zip_code": "94610", "country": "US"},
+		},
+	}}
+
+	typed := callTool(t, mcpClient, "create_estimate", map[string]interface{}{
+		"pickup_info":       pickupInfo,
+		"drop_offs":         dropOffs,
+		"vehicle_type":      "cargo_van",
+		"add_ons":           []interface{}{"fragile_handling"},
+		"dedicated_vehicle": true,
+	})
+	legacy := callTool(t, mcpClient, "create_estimate", map[string]interface{}{
+		"pickup_info":       jsonString(pickupInfo),
+		"drop_offs":         jsonString(dropOffs),
+		"vehicle_type":      "cargo_van",
+		"add_ons":           `["fragile_handling"]`,
+		"dedicated_vehicle": "true",
+	})
+	var estimate, legacyEstimate dispatch.CreateEstimateResponse
+	if err := json.Unmarshal([]byte(typed), &estimate); err != nil {
+		t.Fatalf("Failed to parse estimate: %v", err)
+	}
+	if err := json.Unmarshal([]byte(legacy), &legacyEstimate); err != nil {
+		t.Fatalf("Failed to parse estimate: %v", err)
+	}
+	if !reflect.DeepEqual(estimate, legacyEstimate) {
+		t.Errorf("Expected both argument forms to produce the same estimate\ntyped:  %s\nlegacy: %s", typed, legacy)
+	}
+	option := estimate.Data.CreateEstimate.Estimate.AvailableOrderOptions[0]
+
+	typedComparison := callTool(t, mcpClient, "compare_pricing_models", map[string]interface{}{
+		"original_estimate": option,
+		"delivery_count":    3,
+		"customer_tier":     "gold",
+		"is_bulk_order":     true,
+	})
+	legacyComparison := callTool(t, mcpClient, "compare_pricing_models", map[string]interface{}{
+		"original_estimate": jsonString(option),
+		"delivery_count":    "3",
+		"customer_tier":     "gold",
+		"is_bulk_order":     "true",
+	})
+	if typedModels, legacyModels := pricingModels(t, typedComparison), pricingModels(t, legacyComparison); !reflect.DeepEqual(typedModels, legacyModels) {
+		t.Errorf("Expected both argument forms to produce the same comparison\ntyped:  %+v\nlegacy: %+v", typedModels, legacyModels)
+	}
+
+	// The estimate response is passed back as the object create_estimate returned
+	callTool(t, mcpClient, "select_delivery_option", map[string]interface{}{
+		"estimate_response": estimate,
+		"delivery_scenario": "cheapest",
+	})
+}
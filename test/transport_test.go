@@ -0,0 +1,174 @@
+package test
+
+import (
+	"context"
+	"dispatch-mcp-server/internal/dispatch"
+	dispatchmcp "dispatch-mcp-server/internal/mcp"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// startHTTPTransport serves a scenario-backed MCP server over transportName
+// with bearer token "team-token"
+func startHTTPTransport(t *testing.T, transportName string) *httptest.Server {
+	t.Helper()
+	return serveHTTPTransport(t, newMCPServer(t), transportName)
+}
+
+// serveHTTPTransport serves mcpServer over transportName with bearer token
+// "team-token"
+func serveHTTPTransport(t *testing.T, mcpServer *dispatchmcp.MCPServer, transportName string) *httptest.Server {
+	t.Helper()
+
+	handler, err := mcpServer.HTTPHandler(dispatchmcp.ServeOptions{Transport: transportName, AuthToken: "team-token"})
+	if err != nil {
+		t.Fatalf("HTTPHandler failed: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPTransportsRequireBearerToken(t *testing.T) {
+	paths := map[string]string{
+		dispatchmcp.TransportSSE:  "/sse",
+		dispatchmcp.TransportHTTP: "/mcp",
+	}
+	for transportName, path := range paths {
+		t.Run(transportName, func(t *testing.T) {
+			server := startHTTPTransport(t, transportName)
+
+			for _, authorization := range []string{"", "Bearer wrong-token"} {
+				request, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+				if authorization != "" {
+					request.Header.Set("Authorization", authorization)
+				}
+				response, err := http.DefaultClient.Do(request)
+				if err != nil {
+					t.Fatalf("Request failed: %v", err)
+				}
+				response.Body.Close()
+				if response.StatusCode != http.StatusUnauthorized {
+					t.Errorf("Expected 401 for Authorization %q, got %d", authorization, response.StatusCode)
+				}
+			}
+
+			// Health checks don't need the token
+			response, err := http.Get(server.URL + "/healthz")
+			if err != nil {
+				t.Fatalf("Health check failed: %v", err)
+			}
+			response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				t.Errorf("Expected healthz to return 200, got %d", response.StatusCode)
+			}
+		})
+	}
+
+	if _, err := newMCPServer(t).HTTPHandler(dispatchmcp.ServeOptions{Transport: "carrier-pigeon"}); err == nil {
+		t.Error("Expected an unknown transport to be rejected")
+	}
+}
+
+func TestSSETransportServesTools(t *testing.T) {
+	server := startHTTPTransport(t, dispatchmcp.TransportSSE)
+
+	mcpClient, err := client.NewSSEMCPClient(server.URL+"/sse", transport.WithHeaders(map[string]string{"Authorization": "Bearer team-token"}))
+	if err != nil {
+		t.Fatalf("NewSSEMCPClient failed: %v", err)
+	}
+	initializeClient(t, mcpClient)
+
+	tools, err := mcpClient.ListTools(context.Background(), mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	if len(tools.Tools) == 0 {
+		t.Error("Expected tools over SSE")
+	}
+}
+
+func TestStreamableHTTPSessionsAreIsolated(t *testing.T) {
+	server := startHTTPTransport(t, dispatchmcp.TransportHTTP)
+
+	connect := func() *client.Client {
+		mcpClient, err := client.NewStreamableHttpClient(server.URL+"/mcp", transport.WithHTTPHeaders(map[string]string{"Authorization": "Bearer team-token"}))
+		if err != nil {
+			t.Fatalf("NewStreamableHttpClient failed: %v", err)
+		}
+		initializeClient(t, mcpClient)
+		return mcpClient
+	}
+
+	input := recordedOrderInput()
+	args := map[string]interface{}{
+		"delivery_info": input.DeliveryInfo,
+		"pickup_info":   input.PickupInfo,
+		"drop_offs":     input.DropOffs,
+	}
+	createOrder := func(mcpClient *client.Client) (string, bool) {
+		var result struct {
+			dispatch.CreateOrderResponse
+			Replayed bool `json:"replayed"`
+		}
+		if err := json.Unmarshal([]byte(callTool(t, mcpClient, "create_order", args)), &result); err != nil {
+			t.Fatalf("Failed to parse create_order result: %v", err)
+		}
+		return result.Data.CreateOrder.Order.ID, result.Replayed
+	}
+
+	alice, bob := connect(), connect()
+
+	aliceOrder, _ := createOrder(alice)
+	if retried, replayed := createOrder(alice); retried != aliceOrder || !replayed {
+		t.Errorf("Expected a retry in the same session to replay %s, got %s (replayed=%v)", aliceOrder, retried, replayed)
+	}
+
+	// The same order from another client is that client's own order
+	if bobOrder, replayed := createOrder(bob); bobOrder == aliceOrder || replayed {
+		t.Errorf("Expected another session to create its own order, got %s (replayed=%v)", bobOrder, replayed)
+	}
+}
+
+func TestServeShutsDownGracefully(t *testing.T) {
+	server := newMCPServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, dispatchmcp.ServeOptions{
+			Transport:       dispatchmcp.TransportHTTP,
+			Addr:            "127.0.0.1:0",
+			ShutdownTimeout: time.Second,
+		})
+	}()
+
+	// Serve returns straight away if it can't listen
+	select {
+	case err := <-done:
+		t.Fatalf("Serve exited early: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after its context was cancelled")
+	}
+
+	if err := server.Serve(context.Background(), dispatchmcp.ServeOptions{Transport: dispatchmcp.TransportSSE, Addr: "bad address"}); err == nil || !strings.Contains(err.Error(), "failed to listen") {
+		t.Errorf("Expected a listen error, got %v", err)
+	}
+}